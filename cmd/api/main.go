@@ -2,19 +2,27 @@ package main
 
 import (
 	"context"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/grpcauth"
 	"elearning/pkg/grpcclient"
 	"elearning/pkg/logger"
+	"elearning/pkg/mailer"
 	"elearning/pkg/metrics"
+	"elearning/pkg/oidc"
+	"elearning/pkg/payment"
+	"elearning/pkg/secretbox"
 	"elearning/pkg/storage"
 	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"elearning/internal/config"
+	"elearning/internal/domain"
 	"elearning/internal/handler"
 	"elearning/internal/repository"
 	"elearning/internal/router"
@@ -66,6 +74,10 @@ func main() {
 	}
 	zapLogger.Info("Database connected successfully")
 
+	if err := repository.Migrate(db); err != nil {
+		zapLogger.Fatal("database migration error", zap.Error(err))
+	}
+
 	// Start database metrics collector
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -83,15 +95,33 @@ func main() {
 	tokenBlacklist := token.NewInMemoryBlacklist(1 * time.Hour)
 	zapLogger.Info("Token blacklist initialized")
 
-	// Initialize notification client (optional, don't fail if it's unavailable)
-	var notifClient *grpcclient.NotificationClient
-	notifClient, err = grpcclient.NewNotificationClient(cfg.NotificationGRPC)
+	// Initialize notification client (connects lazily and reconnects on its own)
+	var notifTLS *grpcauth.TLSConfig
+	if cfg.NotificationGRPC.TLS.Enabled {
+		notifTLS = &grpcauth.TLSConfig{
+			CAFile:     cfg.NotificationGRPC.TLS.CAFile,
+			CertFile:   cfg.NotificationGRPC.TLS.CertFile,
+			KeyFile:    cfg.NotificationGRPC.TLS.KeyFile,
+			ServerName: cfg.NotificationGRPC.TLS.ServerName,
+		}
+	}
+	notifClient, err := grpcclient.NewNotificationClient(grpcclient.NotificationClientConfig{
+		Address:          cfg.NotificationGRPC.Address,
+		RequestTimeout:   cfg.NotificationGRPC.RequestTimeout,
+		MaxRetries:       cfg.NotificationGRPC.MaxRetries,
+		BreakerThreshold: cfg.NotificationGRPC.BreakerThreshold,
+		BreakerCooldown:  cfg.NotificationGRPC.BreakerCooldown,
+		TLS:              notifTLS,
+		ServiceName:      cfg.NotificationGRPC.ServiceName,
+		ServiceToken:     cfg.NotificationGRPC.ServiceToken,
+	})
 	if err != nil {
-		zapLogger.Warn("Notification service not available", zap.Error(err))
-		notifClient = nil
-	} else {
-		zapLogger.Info("Notification client connected", zap.String("address", cfg.NotificationGRPC))
+		zapLogger.Fatal("notification client error", zap.Error(err))
 	}
+	zapLogger.Info("Notification client initialized",
+		zap.String("address", cfg.NotificationGRPC.Address),
+		zap.Bool("tls", cfg.NotificationGRPC.TLS.Enabled),
+	)
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
@@ -100,19 +130,198 @@ func main() {
 	enrollmentRepo := repository.NewEnrollmentRepository(db)
 	progressRepo := repository.NewProgressRepository(db)
 	dashboardRepo := repository.NewDashboardRepository(db)
+	announcementRepo := repository.NewAnnouncementRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	loginThrottleRepo := repository.NewLoginThrottleRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	staffRepo := repository.NewCourseStaffRepository(db)
+	impersonationRepo := repository.NewImpersonationRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	trashRepo := repository.NewTrashRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	userImportRepo := repository.NewUserImportRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	couponRepo := repository.NewCouponRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+
+	// Initialize mailer (logs emails when SMTP is disabled)
+	var mail mailer.Mailer = mailer.NewLogMailer(zapLogger)
+	if cfg.Mail.SMTPEnabled {
+		mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+		})
+	}
+	zapLogger.Info("Mailer initialized", zap.Bool("smtp", cfg.Mail.SMTPEnabled))
+
+	// Initialize domain event bus
+	eventBus := eventbus.New(zapLogger, cfg.EventBus.Workers, cfg.EventBus.QueueSize)
+	zapLogger.Info("Event bus initialized", zap.Int("workers", cfg.EventBus.Workers))
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, tokenMaker, tokenBlacklist, cfg.JWT.Expiration)
+	webhookService := service.NewWebhookService(webhookRepo, service.WebhookOptions{
+		RequestTimeout: cfg.Webhook.RequestTimeout,
+		MaxAttempts:    cfg.Webhook.MaxAttempts,
+		RetryBaseDelay: cfg.Webhook.RetryBaseDelay,
+		PollInterval:   cfg.Webhook.PollInterval,
+	})
+	verificationPolicy := service.VerificationPolicy(cfg.Verification.Policy)
+	verificationService := service.NewEmailVerificationService(userRepo, userTokenRepo, mail, service.EmailVerificationOptions{
+		TokenTTL:       cfg.Verification.TokenTTL,
+		ResendLimit:    cfg.Verification.ResendLimit,
+		ResendInterval: cfg.Verification.ResendInterval,
+		AppBaseURL:     cfg.Mail.AppBaseURL,
+	})
+	sessionService := service.NewSessionService(sessionRepo, tokenBlacklist, cfg.JWT.Expiration)
+	rbacService := service.NewRBACService(roleRepo)
+	accountStatusService := service.NewAccountStatusService(userRepo, sessionService, notifClient, mail)
+	if err := rbacService.EnsureDefaults(); err != nil {
+		zapLogger.Fatal("failed to set up built-in roles", zap.Error(err))
+	}
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, service.APIKeyOptions{
+		DefaultTTL: cfg.APIKey.DefaultTTL,
+		MaxTTL:     cfg.APIKey.MaxTTL,
+		MaxPerUser: cfg.APIKey.MaxPerUser,
+	})
+	auditService := service.NewAuditService(auditLogRepo)
+	impersonationService := service.NewImpersonationService(impersonationRepo, userRepo, tokenMaker, sessionService, rbacService, service.ImpersonationOptions{
+		TTL: cfg.Impersonation.TTL,
+	})
+	loginGuard := service.NewLoginGuard(loginThrottleRepo, userRepo, eventBus, service.LoginGuardOptions{
+		Account: service.LoginThrottlePolicy{
+			DelayAfter: cfg.LoginProtection.AccountDelayAfter,
+			LockAfter:  cfg.LoginProtection.AccountLockAfter,
+		},
+		IP: service.LoginThrottlePolicy{
+			DelayAfter: cfg.LoginProtection.IPDelayAfter,
+			LockAfter:  cfg.LoginProtection.IPLockAfter,
+		},
+		BaseDelay:       cfg.LoginProtection.BaseDelay,
+		MaxDelay:        cfg.LoginProtection.MaxDelay,
+		LockoutDuration: cfg.LoginProtection.LockoutDuration,
+		FailureWindow:   cfg.LoginProtection.FailureWindow,
+	})
+	passwordResetService := service.NewPasswordResetService(userRepo, userTokenRepo, sessionService, apiKeyService, mail, loginGuard, service.PasswordResetOptions{
+		TokenTTL:        cfg.PasswordReset.TokenTTL,
+		RequestLimit:    cfg.PasswordReset.RequestLimit,
+		RequestInterval: cfg.PasswordReset.RequestInterval,
+		AppBaseURL:      cfg.Mail.AppBaseURL,
+	})
+	mfaBox, err := secretbox.New(cfg.MFA.EncryptionKey)
+	if err != nil {
+		zapLogger.Fatal("Failed to init MFA secret encryption", zap.Error(err))
+	}
+	enforcedRoles := make([]domain.UserRole, 0, len(cfg.MFA.EnforcedRoles))
+	for _, role := range cfg.MFA.EnforcedRoles {
+		enforcedRoles = append(enforcedRoles, domain.UserRole(role))
+	}
+	mfaService := service.NewMFAService(userRepo, mfaRepo, userTokenRepo, rbacService, mfaBox, service.MFAOptions{
+		Issuer:        cfg.MFA.Issuer,
+		EnforcedRoles: enforcedRoles,
+		ChallengeTTL:  cfg.MFA.ChallengeTTL,
+		MaxAttempts:   cfg.MFA.MaxAttempts,
+	})
+	authService := service.NewAuthService(userRepo, tokenMaker, tokenBlacklist, cfg.JWT.Expiration, eventBus, verificationPolicy, mfaService, loginGuard, sessionService)
+	ssoProviders := make([]service.SSOProvider, 0, len(cfg.SSO.Providers))
+	for _, p := range cfg.SSO.Providers {
+		domainRoles := make(map[string]domain.UserRole, len(p.DomainRoles))
+		for emailDomain, role := range p.DomainRoles {
+			domainRoles[emailDomain] = domain.UserRole(role)
+		}
+		ssoProviders = append(ssoProviders, service.SSOProvider{
+			Name:        p.Name,
+			DisplayName: p.DisplayName,
+			Client: oidc.NewProvider(oidc.Config{
+				Issuer:       p.Issuer,
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  cfg.SSO.RedirectURL(p.Name),
+				Scopes:       p.Scopes,
+			}, nil),
+			DomainRoles: domainRoles,
+			AllowSignup: p.AllowSignup,
+		})
+		zapLogger.Info("SSO provider configured", zap.String("provider", p.Name), zap.String("issuer", p.Issuer))
+	}
+	ssoService := service.NewSSOService(ssoProviders, userRepo, identityRepo, userTokenRepo, authService, eventBus, service.SSOOptions{
+		StateTTL:            cfg.SSO.StateTTL,
+		LoginCodeTTL:        cfg.SSO.LoginCodeTTL,
+		FrontendCallbackURL: cfg.SSO.FrontendCallbackURL,
+	})
+
+	// Initialize the payment provider (fake for local development)
+	var paymentProvider payment.Provider
+	switch cfg.Payment.Provider {
+	case "stripe":
+		paymentProvider = payment.NewStripe(payment.StripeConfig{
+			SecretKey:     cfg.Payment.StripeSecretKey,
+			WebhookSecret: cfg.Payment.StripeWebhookSecret,
+			APIURL:        cfg.Payment.StripeAPIURL,
+			Tolerance:     cfg.Payment.WebhookTolerance,
+		}, nil)
+	default:
+		paymentProvider = payment.NewFake(strings.TrimSuffix(cfg.SSO.APIBaseURL, "/")+"/api/v1/payments/fake", cfg.Payment.FakeWebhookSecret)
+	}
+	zapLogger.Info("Payment provider initialized", zap.String("provider", paymentProvider.Name()))
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, userRepo, paymentProvider, eventBus, notifClient, service.SubscriptionOptions{
+		SuccessURL:  cfg.Payment.SubscriptionSuccessURL,
+		CancelURL:   cfg.Payment.SubscriptionCancelURL,
+		CheckoutTTL: cfg.Payment.CheckoutTTL,
+		Grace:       cfg.Payment.SubscriptionGrace,
+	})
+
 	lessonService := service.NewLessonService(lessonRepo)
-	courseService := service.NewCourseService(courseRepo, lessonRepo)
-	enrollmentService := service.NewEnrollmentService(enrollmentRepo, courseRepo, userRepo, notifClient)
-	progressService := service.NewProgressService(progressRepo, enrollmentRepo, lessonRepo, courseRepo, notifClient)
-	userService := service.NewUserService(userRepo)
+	courseService := service.NewCourseService(courseRepo, lessonRepo, eventBus)
+	staffService := service.NewCourseStaffService(staffRepo, courseRepo, userRepo, eventBus)
+	enrollmentService := service.NewEnrollmentService(enrollmentRepo, courseRepo, userRepo, orderRepo, subscriptionService, staffService, eventBus, verificationPolicy)
+	progressService := service.NewProgressService(progressRepo, enrollmentRepo, lessonRepo, eventBus)
+	userService := service.NewUserService(userRepo, sessionService, eventBus, rbacService)
 	dashboardService := service.NewDashboardService(dashboardRepo, notifClient, userRepo)
+	announcementService := service.NewAnnouncementService(announcementRepo, enrollmentRepo, courseRepo, staffService, notifClient, eventBus)
+	userImportService := service.NewUserImportService(userImportRepo, userRepo, courseRepo, rbacService, enrollmentService, passwordResetService, notifClient, eventBus, service.UserImportOptions{
+		MaxRows: cfg.UserImport.MaxRows,
+	})
+	couponService := service.NewCouponService(couponRepo, courseRepo, staffService)
+	orderService := service.NewOrderService(orderRepo, courseRepo, userRepo, enrollmentService, couponService, subscriptionService, paymentProvider, eventBus, service.OrderOptions{
+		SuccessURL:  cfg.Payment.SuccessURL,
+		CancelURL:   cfg.Payment.CancelURL,
+		CheckoutTTL: cfg.Payment.CheckoutTTL,
+	})
+	refundService := service.NewRefundService(refundRepo, orderRepo, progressRepo, enrollmentService, paymentProvider, eventBus, notifClient, service.RefundOptions{
+		Window:      cfg.Payment.RefundWindow,
+		MaxProgress: float64(cfg.Payment.RefundMaxProgress),
+	})
+	ledgerService := service.NewLedgerService(ledgerRepo, orderRepo, refundRepo, subscriptionRepo, service.LedgerOptions{
+		TeacherSharePercent: cfg.Payment.TeacherSharePercent,
+	})
+
+	// Register event subscribers
+	service.NewNotificationSubscriber(notifClient, courseRepo, staffRepo).Subscribe(eventBus)
+	webhookService.Subscribe(eventBus)
+	verificationService.Subscribe(eventBus)
+	loginGuard.SubscribeAlerts(eventBus, mail)
+	ledgerService.Subscribe(eventBus)
+	announcementService.Subscribe(eventBus)
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService)
-	lessonHandler := handler.NewLessonHandler(lessonService)
+	authHandler := handler.NewAuthHandler(authService, verificationService, passwordResetService)
+	mfaHandler := handler.NewMFAHandler(authService, mfaService)
+	ssoHandler := handler.NewSSOHandler(ssoService)
+	lessonMedia := service.NewLessonMedia(service.LessonMediaOptions{
+		Secret: cfg.JWT.Secret,
+		TTL:    6 * time.Hour,
+	})
+	lessonHandler := handler.NewLessonHandler(lessonService, lessonMedia)
 	courseHandler := handler.NewCourseHandler(courseService)
 	enrollmentHandler := handler.NewEnrollmentHandler(enrollmentService)
 	progressHandler := handler.NewProgressHandler(progressService)
@@ -120,6 +329,36 @@ func main() {
 	dashboardHandler := handler.NewDashboardHandler(dashboardService)
 	adminHandler := handler.NewAdminHandler(userService, notifClient)
 	reportsHandler := handler.NewReportsHandler(db, zapLogger)
+	announcementHandler := handler.NewAnnouncementHandler(announcementService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	lockoutHandler := handler.NewLockoutHandler(loginGuard)
+	sessionHandler := handler.NewSessionHandler(sessionService, apiKeyService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	roleHandler := handler.NewRoleHandler(rbacService)
+	staffHandler := handler.NewCourseStaffHandler(staffService)
+	impersonationHandler := handler.NewImpersonationHandler(impersonationService)
+	auditLogHandler := handler.NewAuditLogHandler(auditService)
+	userImportHandler := handler.NewUserImportHandler(userImportService)
+	accountStatusHandler := handler.NewAccountStatusHandler(accountStatusService)
+	orderHandler := handler.NewOrderHandler(orderService)
+	couponHandler := handler.NewCouponHandler(couponService)
+	refundHandler := handler.NewRefundHandler(refundService)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
+	payoutHandler := handler.NewPayoutHandler(ledgerService)
+
+	// Start webhook delivery worker
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go webhookService.Run(workerCtx)
+	zapLogger.Info("Webhook delivery worker started")
+	go loginGuard.Run(workerCtx, time.Hour)
+	go sessionService.Run(workerCtx, time.Hour)
+	go userImportService.Run(workerCtx, 5*time.Second)
+	go accountStatusService.Run(workerCtx, time.Minute)
+	go subscriptionService.Run(workerCtx, time.Hour)
+	go orderService.Run(workerCtx, time.Minute)
+	go ledgerService.Run(workerCtx, 15*time.Minute)
+	go passwordResetService.Run(workerCtx)
 
 	// Initialize GCS uploader (optional)
 	var gcsUploader *storage.GCSUploader
@@ -138,13 +377,41 @@ func main() {
 
 	userHandler := handler.NewUserHandler(userService, enrollmentRepo, courseRepo, gcsUploader, cfg.GCS.Enabled)
 
+	// Trash purge and account erasure remove avatars from cloud storage when it is enabled
+	var avatarStore service.AvatarStore
+	if gcsUploader != nil {
+		avatarStore = gcsUploader
+	}
+	trashService := service.NewTrashService(trashRepo, courseRepo, avatarStore, service.TrashOptions{
+		Retention: cfg.Trash.Retention,
+	})
+	trashHandler := handler.NewTrashHandler(trashService)
+	go trashService.Run(workerCtx, time.Hour)
+
+	privacyService := service.NewPrivacyService(privacyRepo, sessionService, subscriptionService, avatarStore, notifClient, service.PrivacyOptions{
+		ExportDir:      cfg.Privacy.ExportDir,
+		ExportTTL:      cfg.Privacy.ExportTTL,
+		ExportInterval: cfg.Privacy.ExportInterval,
+		CoolingOff:     cfg.Privacy.CoolingOff,
+	})
+	privacyHandler := handler.NewPrivacyHandler(privacyService)
+	go privacyService.Run(workerCtx, time.Minute)
+
 	// Initialize router with logger
 	r := router.New(
 		cfg,
 		db,
 		tokenMaker,
 		tokenBlacklist,
+		sessionService,
+		accountStatusService,
+		apiKeyService,
+		rbacService,
+		impersonationService,
+		auditService,
 		authHandler,
+		mfaHandler,
+		ssoHandler,
 		courseHandler,
 		lessonHandler,
 		enrollmentHandler,
@@ -154,8 +421,28 @@ func main() {
 		dashboardHandler,
 		adminHandler,
 		reportsHandler,
+		announcementHandler,
+		webhookHandler,
+		lockoutHandler,
+		sessionHandler,
+		apiKeyHandler,
+		roleHandler,
+		staffHandler,
+		impersonationHandler,
+		auditLogHandler,
+		trashHandler,
+		privacyHandler,
+		userImportHandler,
+		accountStatusHandler,
+		orderHandler,
+		couponHandler,
+		refundHandler,
+		subscriptionHandler,
+		payoutHandler,
 		courseService,
 		lessonService,
+		enrollmentService,
+		staffService,
 		zapLogger,
 	)
 
@@ -191,6 +478,12 @@ func main() {
 		zapLogger.Error("Graceful shutdown failed", zap.Error(err))
 	}
 
+	// Drain pending domain events, then stop background workers
+	if err := eventBus.Close(ctx); err != nil {
+		zapLogger.Error("Event bus did not drain before shutdown", zap.Error(err))
+	}
+	stopWorkers()
+
 	// Close database connection
 	if err := config.CloseDatabase(db); err != nil {
 		zapLogger.Error("Failed to close database", zap.Error(err))
@@ -199,10 +492,8 @@ func main() {
 	}
 
 	// Close notification client
-	if notifClient != nil {
-		if err := notifClient.Close(); err != nil {
-			zapLogger.Error("Failed to close notification client", zap.Error(err))
-		}
+	if err := notifClient.Close(); err != nil {
+		zapLogger.Error("Failed to close notification client", zap.Error(err))
 	}
 
 	zapLogger.Info("Server exited gracefully")
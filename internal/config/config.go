@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,8 +15,22 @@ type Config struct {
 	Server           ServerConfig
 	Database         DatabaseConfig
 	JWT              JWTConfig
-	NotificationGRPC string
+	NotificationGRPC NotificationGRPCConfig
 	GCS              GCSConfig
+	Webhook          WebhookConfig
+	EventBus         EventBusConfig
+	Mail             MailConfig
+	Verification     VerificationConfig
+	PasswordReset    PasswordResetConfig
+	MFA              MFAConfig
+	LoginProtection  LoginProtectionConfig
+	SSO              SSOConfig
+	APIKey           APIKeyConfig
+	Impersonation    ImpersonationConfig
+	Trash            TrashConfig
+	Privacy          PrivacyConfig
+	UserImport       UserImportConfig
+	Payment          PaymentConfig
 	LogConfig        LogConfig
 }
 
@@ -39,6 +54,189 @@ type JWTConfig struct {
 	Expiration time.Duration
 }
 
+// NotificationGRPCConfig holds settings for the notification service client
+type NotificationGRPCConfig struct {
+	Address          string
+	RequestTimeout   time.Duration
+	MaxRetries       int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	TLS              GRPCTLSConfig
+	ServiceName      string
+	ServiceToken     string
+}
+
+// GRPCTLSConfig holds certificate paths for (m)TLS between services.
+// CertFile and KeyFile are optional on the client; when set they are
+// presented to the server for mutual TLS.
+type GRPCTLSConfig struct {
+	Enabled    bool
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// WebhookConfig holds settings for outbound webhook delivery
+type WebhookConfig struct {
+	RequestTimeout time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	PollInterval   time.Duration
+}
+
+// EventBusConfig sizes the worker pool for asynchronous event subscribers
+type EventBusConfig struct {
+	Workers   int
+	QueueSize int
+}
+
+// MailConfig holds outgoing email settings. When SMTP is disabled emails
+// are written to the log instead of being sent.
+type MailConfig struct {
+	SMTPEnabled  bool
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	// AppBaseURL is the frontend URL used to build links in emails
+	AppBaseURL string
+}
+
+// VerificationConfig holds email verification settings. Policy is one of
+// "none", "enroll" (unverified users cannot enroll) or "login" (unverified
+// users cannot log in).
+type VerificationConfig struct {
+	Policy         string
+	TokenTTL       time.Duration
+	ResendLimit    int
+	ResendInterval time.Duration
+}
+
+// PasswordResetConfig holds password reset settings
+type PasswordResetConfig struct {
+	TokenTTL        time.Duration
+	RequestLimit    int
+	RequestInterval time.Duration
+}
+
+// MFAConfig holds two-factor authentication settings. EncryptionKey
+// protects stored TOTP secrets; it falls back to the JWT secret when unset.
+type MFAConfig struct {
+	Issuer        string
+	EnforcedRoles []string
+	EncryptionKey string
+	ChallengeTTL  time.Duration
+	MaxAttempts   int
+}
+
+// LoginProtectionConfig holds brute-force protection settings. Failed
+// logins are counted per account and per client IP; past the Delay
+// thresholds attempts are delayed progressively, past the Lock thresholds
+// the account or IP is locked out for LockoutDuration.
+type LoginProtectionConfig struct {
+	AccountDelayAfter int
+	AccountLockAfter  int
+	IPDelayAfter      int
+	IPLockAfter       int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	LockoutDuration   time.Duration
+	FailureWindow     time.Duration
+}
+
+// SSOConfig holds OpenID Connect single sign-on settings. Providers are
+// listed in OIDC_PROVIDERS and configured with OIDC_<NAME>_* variables.
+type SSOConfig struct {
+	Providers []OIDCProviderConfig
+	// APIBaseURL is this API's public URL, used to build redirect URIs
+	APIBaseURL          string
+	FrontendCallbackURL string
+	StateTTL            time.Duration
+	LoginCodeTTL        time.Duration
+}
+
+// OIDCProviderConfig configures one identity provider. DomainRoles maps
+// email domains ("*" for any) to the role of accounts created on first
+// sign-in; emails from other domains are rejected.
+type OIDCProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	DomainRoles  map[string]string
+	AllowSignup  bool
+}
+
+// APIKeyConfig holds settings for API keys used by integrations
+type APIKeyConfig struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	MaxPerUser int
+}
+
+// ImpersonationConfig holds settings for admins acting as other users
+type ImpersonationConfig struct {
+	TTL time.Duration
+}
+
+// TrashConfig holds settings for soft-deleted users, courses and lessons
+type TrashConfig struct {
+	Retention time.Duration
+}
+
+// PrivacyConfig holds settings for personal data exports and account
+// erasure
+type PrivacyConfig struct {
+	ExportDir      string
+	ExportTTL      time.Duration
+	ExportInterval time.Duration
+	CoolingOff     time.Duration
+}
+
+// UserImportConfig holds settings for bulk user imports
+type UserImportConfig struct {
+	MaxRows int
+}
+
+// PaymentConfig holds settings for selling courses
+type PaymentConfig struct {
+	// Provider is "fake" for local development or "stripe"
+	Provider    string
+	SuccessURL  string
+	CancelURL   string
+	CheckoutTTL time.Duration
+	// RefundWindow is how long after payment a refund can be requested,
+	// and RefundMaxProgress the course progress (percent) up to which
+	// one can be requested
+	RefundWindow      time.Duration
+	RefundMaxProgress int
+	// SubscriptionSuccessURL and SubscriptionCancelURL are where the
+	// provider sends a subscriber after checkout; {SUBSCRIPTION_ID} is
+	// replaced with the subscription ID
+	SubscriptionSuccessURL string
+	SubscriptionCancelURL  string
+	// SubscriptionGrace is how long access outlasts an unrenewed period
+	SubscriptionGrace time.Duration
+	// TeacherSharePercent is the part of each course sale credited to the
+	// course's teacher
+	TeacherSharePercent int
+	// WebhookTolerance is how old a signed webhook may be
+	WebhookTolerance    time.Duration
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripeAPIURL        string
+	FakeWebhookSecret   string
+}
+
+// RedirectURL is the callback registered with the provider
+func (c SSOConfig) RedirectURL(provider string) string {
+	return strings.TrimSuffix(c.APIBaseURL, "/") + "/api/v1/auth/sso/" + provider + "/callback"
+}
+
 type GCSConfig struct {
 	BucketName string
 	Enabled    bool
@@ -95,11 +293,116 @@ func Load() (*Config, error) {
 			Secret:     getEnv("JWT_SECRET", ""),
 			Expiration: time.Duration(expHours) * time.Hour,
 		},
-		NotificationGRPC: getEnv("NOTIFICATION_GRPC_ADDR", "localhost:50051"),
+		NotificationGRPC: NotificationGRPCConfig{
+			Address:          getEnv("NOTIFICATION_GRPC_ADDR", "localhost:50051"),
+			RequestTimeout:   time.Duration(getEnvInt("NOTIFICATION_GRPC_TIMEOUT_MS", 3000)) * time.Millisecond,
+			MaxRetries:       getEnvInt("NOTIFICATION_GRPC_MAX_RETRIES", 3),
+			BreakerThreshold: getEnvInt("NOTIFICATION_GRPC_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  time.Duration(getEnvInt("NOTIFICATION_GRPC_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second,
+			TLS: GRPCTLSConfig{
+				Enabled:    getEnv("NOTIFICATION_GRPC_TLS_ENABLED", "false") == "true",
+				CAFile:     getEnv("NOTIFICATION_GRPC_TLS_CA_FILE", ""),
+				CertFile:   getEnv("NOTIFICATION_GRPC_TLS_CERT_FILE", ""),
+				KeyFile:    getEnv("NOTIFICATION_GRPC_TLS_KEY_FILE", ""),
+				ServerName: getEnv("NOTIFICATION_GRPC_TLS_SERVER_NAME", ""),
+			},
+			ServiceName:  getEnv("NOTIFICATION_GRPC_SERVICE_NAME", "api-gateway"),
+			ServiceToken: getEnv("NOTIFICATION_GRPC_SERVICE_TOKEN", ""),
+		},
 		GCS: GCSConfig{
 			BucketName: getEnv("GCS_BUCKET_NAME", ""),
 			Enabled:    getEnv("GCS_ENABLED", "false") == "true",
 		},
+		Webhook: WebhookConfig{
+			RequestTimeout: time.Duration(getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+			MaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			RetryBaseDelay: time.Duration(getEnvInt("WEBHOOK_RETRY_BASE_SECONDS", 30)) * time.Second,
+			PollInterval:   time.Duration(getEnvInt("WEBHOOK_POLL_INTERVAL_SECONDS", 5)) * time.Second,
+		},
+		EventBus: EventBusConfig{
+			Workers:   getEnvInt("EVENT_BUS_WORKERS", 4),
+			QueueSize: getEnvInt("EVENT_BUS_QUEUE_SIZE", 1024),
+		},
+		Mail: MailConfig{
+			SMTPEnabled:  getEnv("SMTP_ENABLED", "false") == "true",
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnvInt("SMTP_PORT", 587),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("MAIL_FROM", "no-reply@elearning.local"),
+			AppBaseURL:   getEnv("APP_BASE_URL", "http://localhost:3000"),
+		},
+		Verification: VerificationConfig{
+			Policy:         getEnv("EMAIL_VERIFICATION_POLICY", "enroll"),
+			TokenTTL:       time.Duration(getEnvInt("EMAIL_VERIFICATION_TTL_HOURS", 24)) * time.Hour,
+			ResendLimit:    getEnvInt("EMAIL_VERIFICATION_RESEND_PER_HOUR", 3),
+			ResendInterval: time.Duration(getEnvInt("EMAIL_VERIFICATION_RESEND_INTERVAL_SECONDS", 60)) * time.Second,
+		},
+		PasswordReset: PasswordResetConfig{
+			TokenTTL:        time.Duration(getEnvInt("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute,
+			RequestLimit:    getEnvInt("PASSWORD_RESET_REQUESTS_PER_HOUR", 3),
+			RequestInterval: time.Duration(getEnvInt("PASSWORD_RESET_REQUEST_INTERVAL_SECONDS", 60)) * time.Second,
+		},
+		MFA: MFAConfig{
+			Issuer:        getEnv("MFA_ISSUER", "E-Learning"),
+			EnforcedRoles: getEnvList("MFA_ENFORCED_ROLES", ""),
+			EncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+			ChallengeTTL:  time.Duration(getEnvInt("MFA_CHALLENGE_TTL_SECONDS", 300)) * time.Second,
+			MaxAttempts:   getEnvInt("MFA_MAX_ATTEMPTS", 5),
+		},
+		LoginProtection: LoginProtectionConfig{
+			AccountDelayAfter: getEnvInt("LOGIN_ACCOUNT_DELAY_AFTER", 3),
+			AccountLockAfter:  getEnvInt("LOGIN_ACCOUNT_LOCK_AFTER", 10),
+			IPDelayAfter:      getEnvInt("LOGIN_IP_DELAY_AFTER", 20),
+			IPLockAfter:       getEnvInt("LOGIN_IP_LOCK_AFTER", 100),
+			BaseDelay:         time.Duration(getEnvInt("LOGIN_DELAY_BASE_SECONDS", 1)) * time.Second,
+			MaxDelay:          time.Duration(getEnvInt("LOGIN_DELAY_MAX_SECONDS", 60)) * time.Second,
+			LockoutDuration:   time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+			FailureWindow:     time.Duration(getEnvInt("LOGIN_FAILURE_WINDOW_MINUTES", 60)) * time.Minute,
+		},
+		SSO: SSOConfig{
+			APIBaseURL:          getEnv("API_BASE_URL", "http://localhost:8080"),
+			FrontendCallbackURL: getEnv("SSO_FRONTEND_CALLBACK_URL", getEnv("APP_BASE_URL", "http://localhost:3000")+"/sso/callback"),
+			StateTTL:            time.Duration(getEnvInt("SSO_STATE_TTL_SECONDS", 600)) * time.Second,
+			LoginCodeTTL:        time.Duration(getEnvInt("SSO_LOGIN_CODE_TTL_SECONDS", 60)) * time.Second,
+		},
+		APIKey: APIKeyConfig{
+			DefaultTTL: time.Duration(getEnvInt("API_KEY_DEFAULT_TTL_DAYS", 90)) * 24 * time.Hour,
+			MaxTTL:     time.Duration(getEnvInt("API_KEY_MAX_TTL_DAYS", 365)) * 24 * time.Hour,
+			MaxPerUser: getEnvInt("API_KEY_MAX_PER_USER", 10),
+		},
+		Impersonation: ImpersonationConfig{
+			TTL: time.Duration(getEnvInt("IMPERSONATION_TTL_MINUTES", 15)) * time.Minute,
+		},
+		Trash: TrashConfig{
+			Retention: time.Duration(getEnvInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		},
+		Privacy: PrivacyConfig{
+			ExportDir:      getEnv("DATA_EXPORT_DIR", "exports"),
+			ExportTTL:      time.Duration(getEnvInt("DATA_EXPORT_TTL_HOURS", 72)) * time.Hour,
+			ExportInterval: time.Duration(getEnvInt("DATA_EXPORT_MIN_INTERVAL_HOURS", 24)) * time.Hour,
+			CoolingOff:     time.Duration(getEnvInt("ERASURE_COOLING_OFF_DAYS", 14)) * 24 * time.Hour,
+		},
+		UserImport: UserImportConfig{
+			MaxRows: getEnvInt("USER_IMPORT_MAX_ROWS", 5000),
+		},
+		Payment: PaymentConfig{
+			Provider:               getEnv("PAYMENT_PROVIDER", ""),
+			SuccessURL:             getEnv("PAYMENT_SUCCESS_URL", "http://localhost:3000/orders/{ORDER_ID}?status=success"),
+			CancelURL:              getEnv("PAYMENT_CANCEL_URL", "http://localhost:3000/orders/{ORDER_ID}?status=cancelled"),
+			CheckoutTTL:            time.Duration(getEnvInt("PAYMENT_CHECKOUT_TTL_MINUTES", 30)) * time.Minute,
+			RefundWindow:           time.Duration(getEnvInt("REFUND_WINDOW_DAYS", 14)) * 24 * time.Hour,
+			RefundMaxProgress:      getEnvInt("REFUND_MAX_PROGRESS_PERCENT", 30),
+			SubscriptionSuccessURL: getEnv("SUBSCRIPTION_SUCCESS_URL", "http://localhost:3000/subscriptions/{SUBSCRIPTION_ID}?status=success"),
+			SubscriptionCancelURL:  getEnv("SUBSCRIPTION_CANCEL_URL", "http://localhost:3000/subscriptions/{SUBSCRIPTION_ID}?status=cancelled"),
+			SubscriptionGrace:      time.Duration(getEnvInt("SUBSCRIPTION_GRACE_DAYS", 3)) * 24 * time.Hour,
+			TeacherSharePercent:    getEnvInt("TEACHER_REVENUE_SHARE_PERCENT", 70),
+			WebhookTolerance:       time.Duration(getEnvInt("PAYMENT_WEBHOOK_TOLERANCE_SECONDS", 300)) * time.Second,
+			StripeSecretKey:        getEnv("STRIPE_SECRET_KEY", ""),
+			StripeWebhookSecret:    getEnv("STRIPE_WEBHOOK_SECRET", ""),
+			StripeAPIURL:           getEnv("STRIPE_API_URL", "https://api.stripe.com"),
+			FakeWebhookSecret:      getEnv("PAYMENT_FAKE_WEBHOOK_SECRET", ""),
+		},
 		LogConfig: LogConfig{
 			Level:      getEnv("LOG_LEVEL", "info"),
 			File:       getEnv("LOG_FILE", "logs/app.log"),
@@ -120,6 +423,65 @@ func Load() (*Config, error) {
 	log.Printf("  DB_NAME: %s", cfg.Database.DBName)
 	log.Printf("  DB_SSLMODE: %s", cfg.Database.SSLMode)
 
+	if cfg.NotificationGRPC.TLS.Enabled && cfg.NotificationGRPC.TLS.CAFile == "" {
+		return nil, fmt.Errorf("NOTIFICATION_GRPC_TLS_CA_FILE is required when NOTIFICATION_GRPC_TLS_ENABLED=true")
+	}
+
+	if (cfg.NotificationGRPC.TLS.CertFile == "") != (cfg.NotificationGRPC.TLS.KeyFile == "") {
+		return nil, fmt.Errorf("NOTIFICATION_GRPC_TLS_CERT_FILE and NOTIFICATION_GRPC_TLS_KEY_FILE must be set together")
+	}
+
+	switch cfg.Verification.Policy {
+	case "none", "enroll", "login":
+	default:
+		return nil, fmt.Errorf("EMAIL_VERIFICATION_POLICY must be one of none, enroll, login")
+	}
+
+	if cfg.Mail.SMTPEnabled && cfg.Mail.SMTPHost == "" {
+		return nil, fmt.Errorf("SMTP_HOST is required when SMTP_ENABLED=true")
+	}
+
+	switch cfg.Payment.Provider {
+	case "fake":
+		// The fake provider lets anyone pay for their own orders
+		if cfg.Server.GinMode == "release" {
+			return nil, fmt.Errorf("PAYMENT_PROVIDER=fake is for local development and cannot be used with GIN_MODE=release")
+		}
+		if cfg.Payment.FakeWebhookSecret == "" || cfg.Payment.FakeWebhookSecret == "whsec_fake" {
+			return nil, fmt.Errorf("PAYMENT_FAKE_WEBHOOK_SECRET must be set to a random value when PAYMENT_PROVIDER=fake")
+		}
+	case "stripe":
+		if cfg.Payment.StripeSecretKey == "" || cfg.Payment.StripeWebhookSecret == "" {
+			return nil, fmt.Errorf("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET are required when PAYMENT_PROVIDER=stripe")
+		}
+	default:
+		return nil, fmt.Errorf("PAYMENT_PROVIDER is required and must be one of fake, stripe")
+	}
+
+	// Stripe checkout sessions expire between 30 minutes and 24 hours
+	if cfg.Payment.CheckoutTTL < 30*time.Minute || cfg.Payment.CheckoutTTL > 24*time.Hour {
+		return nil, fmt.Errorf("PAYMENT_CHECKOUT_TTL_MINUTES must be between 30 and 1440")
+	}
+	if cfg.Payment.RefundMaxProgress < 0 || cfg.Payment.RefundMaxProgress > 100 {
+		return nil, fmt.Errorf("REFUND_MAX_PROGRESS_PERCENT must be between 0 and 100")
+	}
+	if cfg.Payment.TeacherSharePercent < 0 || cfg.Payment.TeacherSharePercent > 100 {
+		return nil, fmt.Errorf("TEACHER_REVENUE_SHARE_PERCENT must be between 0 and 100")
+	}
+
+	if cfg.MFA.EncryptionKey == "" {
+		log.Printf("warning: MFA_ENCRYPTION_KEY is not set; TOTP secrets are encrypted with the JWT secret")
+		cfg.MFA.EncryptionKey = cfg.JWT.Secret
+	}
+
+	for _, name := range getEnvList("OIDC_PROVIDERS", "") {
+		provider, err := loadOIDCProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SSO.Providers = append(cfg.SSO.Providers, provider)
+	}
+
 	if cfg.GCS.Enabled {
 		log.Printf("  GCS_ENABLED: true")
 		log.Printf("  GCS_BUCKET: %s", cfg.GCS.BucketName)
@@ -128,9 +490,54 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// loadOIDCProvider reads the OIDC_<NAME>_* variables for one provider
+func loadOIDCProvider(name string) (OIDCProviderConfig, error) {
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	provider := OIDCProviderConfig{
+		Name:         strings.ToLower(name),
+		DisplayName:  getEnv(prefix+"DISPLAY_NAME", name),
+		Issuer:       getEnv(prefix+"ISSUER", ""),
+		ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+		ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+		Scopes:       getEnvList(prefix+"SCOPES", "openid,email,profile"),
+		DomainRoles:  make(map[string]string),
+		AllowSignup:  getEnv(prefix+"ALLOW_SIGNUP", "true") == "true",
+	}
+	if provider.Issuer == "" || provider.ClientID == "" {
+		return provider, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+	}
+
+	for _, entry := range getEnvList(prefix+"DOMAIN_ROLES", "") {
+		emailDomain, role, ok := strings.Cut(entry, "=")
+		if !ok {
+			return provider, fmt.Errorf("%sDOMAIN_ROLES entry %q must be domain=role", prefix, entry)
+		}
+		// Admins are never provisioned from an email domain alone
+		if role != "student" && role != "teacher" {
+			return provider, fmt.Errorf("%sDOMAIN_ROLES role %q must be student or teacher", prefix, role)
+		}
+		provider.DomainRoles[strings.ToLower(strings.TrimSpace(emailDomain))] = role
+	}
+	if len(provider.DomainRoles) == 0 {
+		return provider, fmt.Errorf("%sDOMAIN_ROLES is required", prefix)
+	}
+	return provider, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getEnvList splits a comma-separated environment variable, dropping empty items
+func getEnvList(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Announcement is a message posted by a teacher to everyone enrolled in a course
+type Announcement struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	CourseID uint   `json:"course_id" gorm:"not null;index:idx_announcements_course"`
+	AuthorID uint   `json:"author_id" gorm:"not null"`
+	Title    string `json:"title" gorm:"type:varchar(200);not null"`
+	Message  string `json:"message" gorm:"type:text;not null"`
+	// Recipients counts the students notified so far; notifications are
+	// sent in the background after the announcement is posted
+	Recipients int       `json:"recipients" gorm:"not null;default:0"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index:idx_announcements_created"`
+
+	// Relations
+	Author User `json:"author,omitempty" gorm:"foreignKey:AuthorID;constraint:OnDelete:CASCADE"`
+}
+
+func (Announcement) TableName() string {
+	return "announcements"
+}
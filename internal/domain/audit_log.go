@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// AuditLog is one entry of the append-only audit trail of administrative
+// and sensitive actions. Entries form a hash chain: Hash covers the entry's
+// fields and the Hash of the entry before it, so editing or deleting an
+// entry breaks the chain from that point on.
+type AuditLog struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	ActorID        *uint        `json:"actor_id,omitempty" gorm:"index"`
+	ActorEmail     string       `json:"actor_email" gorm:"type:varchar(255)"`
+	ImpersonatorID *uint        `json:"impersonator_id,omitempty"`
+	Action         string       `json:"action" gorm:"type:varchar(100);not null;index"`
+	TargetType     string       `json:"target_type" gorm:"type:varchar(50);index:idx_audit_logs_target"`
+	TargetID       string       `json:"target_id" gorm:"type:varchar(100);index:idx_audit_logs_target"`
+	Changes        AuditChanges `json:"changes,omitempty" gorm:"type:text"`
+	IP             string       `json:"ip" gorm:"type:varchar(64)"`
+	RequestID      string       `json:"request_id" gorm:"type:varchar(100)"`
+	CreatedAt      time.Time    `json:"created_at" gorm:"not null;index"`
+	PrevHash       string       `json:"prev_hash" gorm:"type:varchar(64);not null"`
+	Hash           string       `json:"hash" gorm:"type:varchar(64);not null;uniqueIndex"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditChanges is the JSON diff of an audited change, mapping each changed
+// field to its "from" and "to" values. It is stored as text rather than
+// jsonb so the bytes covered by the hash are returned unchanged.
+type AuditChanges []byte
+
+// MarshalJSON embeds the diff as JSON
+func (a AuditChanges) MarshalJSON() ([]byte, error) {
+	if len(a) == 0 {
+		return []byte("null"), nil
+	}
+	return a, nil
+}
+
+// Value implements driver.Valuer
+func (a AuditChanges) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	return string(a), nil
+}
+
+// Scan implements sql.Scanner
+func (a *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+	case string:
+		*a = AuditChanges(v)
+	case []byte:
+		*a = append(AuditChanges(nil), v...)
+	default:
+		return errors.New("unsupported type for audit changes")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DiscountType is how a coupon reduces the price
+type DiscountType string
+
+const (
+	DiscountPercent DiscountType = "percent"
+	DiscountFixed   DiscountType = "fixed"
+)
+
+// Coupon is a promo code giving a discount at checkout. Empty CourseIDs and
+// CategoryIDs make it valid for every course; otherwise the course must be
+// listed or be in a listed category.
+type Coupon struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Code        string       `json:"code" gorm:"type:varchar(40);not null;uniqueIndex"`
+	Description string       `json:"description" gorm:"type:varchar(255)"`
+	Type        DiscountType `json:"type" gorm:"type:varchar(20);not null"`
+	// PercentOff applies to percent coupons, AmountOff (in the minor unit
+	// of Currency) to fixed ones
+	PercentOff  int           `json:"percent_off,omitempty"`
+	AmountOff   int64         `json:"amount_off,omitempty"`
+	Currency    string        `json:"currency,omitempty" gorm:"type:varchar(3)"`
+	CourseIDs   pq.Int64Array `json:"course_ids" gorm:"type:bigint[]"`
+	CategoryIDs pq.Int64Array `json:"category_ids" gorm:"type:bigint[]"`
+	StartsAt    *time.Time    `json:"starts_at,omitempty"`
+	ExpiresAt   *time.Time    `json:"expires_at,omitempty"`
+	// MaxRedemptions limits uses of the code overall and MaxPerUser uses
+	// by one customer; zero is unlimited. Redemptions counts reserved and
+	// redeemed uses.
+	MaxRedemptions int       `json:"max_redemptions" gorm:"not null;default:0"`
+	MaxPerUser     int       `json:"max_per_user" gorm:"not null;default:1"`
+	Redemptions    int       `json:"redemptions" gorm:"not null;default:0"`
+	Active         bool      `json:"active" gorm:"not null;default:true"`
+	CreatedBy      uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (Coupon) TableName() string {
+	return "coupons"
+}
+
+// Discount returns how much the coupon takes off a price, never more than
+// the price. Fixed discounts only apply in their own currency.
+func (c *Coupon) Discount(price int64, currency string) int64 {
+	var discount int64
+	switch c.Type {
+	case DiscountPercent:
+		discount = (price*int64(c.PercentOff) + 50) / 100
+	case DiscountFixed:
+		if c.Currency == currency {
+			discount = c.AmountOff
+		}
+	}
+	if discount > price {
+		discount = price
+	}
+	return discount
+}
+
+// AppliesTo reports whether the coupon can be used for the course
+func (c *Coupon) AppliesTo(course *Course) bool {
+	if len(c.CourseIDs) == 0 && len(c.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range c.CourseIDs {
+		if id == course.ID {
+			return true
+		}
+	}
+	if course.CategoryID != nil {
+		for _, id := range c.CategoryIDs {
+			if id == *course.CategoryID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CouponRedemptionStatus tracks a use of a coupon
+type CouponRedemptionStatus string
+
+const (
+	// CouponReserved holds a use for an order awaiting payment
+	CouponReserved CouponRedemptionStatus = "reserved"
+	CouponRedeemed CouponRedemptionStatus = "redeemed"
+)
+
+// CouponRedemption records a coupon used on an order. The use is reserved
+// at checkout, counting toward the coupon's limits, and redeemed when the
+// order is paid. Reservations of orders that fail or expire are released.
+type CouponRedemption struct {
+	ID        uint                   `json:"id" gorm:"primaryKey"`
+	CouponID  uint                   `json:"coupon_id" gorm:"not null;index"`
+	UserID    uint                   `json:"user_id" gorm:"not null;index"`
+	OrderID   uint                   `json:"order_id" gorm:"not null;uniqueIndex"`
+	CourseID  uint                   `json:"course_id" gorm:"not null"`
+	Discount  int64                  `json:"discount" gorm:"not null"`
+	Currency  string                 `json:"currency" gorm:"type:varchar(3);not null"`
+	Status    CouponRedemptionStatus `json:"status" gorm:"type:varchar(20);not null;default:'redeemed'"`
+	CreatedAt time.Time              `json:"created_at"`
+
+	Coupon *Coupon `json:"-" gorm:"foreignKey:CouponID;constraint:OnDelete:CASCADE"`
+}
+
+func (CouponRedemption) TableName() string {
+	return "coupon_redemptions"
+}
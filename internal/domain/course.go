@@ -1,15 +1,31 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Course struct {
-	ID          int64     `json:"id" gorm:"primaryKey;autoIncrement"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Thumbnail   string    `json:"thumbnail"`
-	CategoryID  *int64    `json:"category_id"`
-	TeacherID   int64     `json:"teacher_id"`
-	IsPublished bool      `json:"is_published"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int64  `json:"id" gorm:"primaryKey;autoIncrement"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Thumbnail   string `json:"thumbnail"`
+	CategoryID  *int64 `json:"category_id"`
+	TeacherID   int64  `json:"teacher_id"`
+	IsPublished bool   `json:"is_published"`
+	// IsFree courses can be enrolled in without paying. Price is in the
+	// currency's minor unit, e.g. cents.
+	IsFree    bool      `json:"is_free" gorm:"not null;default:true"`
+	Price     int64     `json:"price" gorm:"not null;default:0"`
+	Currency  string    `json:"currency" gorm:"type:varchar(3);not null;default:'USD'"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the course is in the trash
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// RequiresPayment reports whether enrolling needs a paid order
+func (c *Course) RequiresPayment() bool {
+	return !c.IsFree && c.Price > 0
 }
@@ -0,0 +1,70 @@
+package domain
+
+import "time"
+
+// CourseStaffRole is a user's role on the teaching staff of one course
+type CourseStaffRole string
+
+const (
+	// StaffOwner is the course's teacher (Course.TeacherID). Owners are not
+	// stored as CourseStaff rows.
+	StaffOwner        CourseStaffRole = "owner"
+	StaffCoInstructor CourseStaffRole = "co_instructor"
+	StaffTA           CourseStaffRole = "ta"
+)
+
+// IsInvitable reports whether users can be invited to the role
+func (r CourseStaffRole) IsInvitable() bool {
+	return r == StaffCoInstructor || r == StaffTA
+}
+
+// StaffAccess is a level of access to a course, from viewing to owning it
+type StaffAccess int
+
+const (
+	// StaffAccessRead views the roster and announcements
+	StaffAccessRead StaffAccess = iota + 1
+	// StaffAccessGrade also updates students' progress
+	StaffAccessGrade
+	// StaffAccessEdit also edits the course, its lessons and announcements
+	StaffAccessEdit
+	// StaffAccessOwner also deletes the course and manages its staff
+	StaffAccessOwner
+)
+
+// Allows reports whether the staff role grants the access level
+func (r CourseStaffRole) Allows(access StaffAccess) bool {
+	switch r {
+	case StaffOwner:
+		return true
+	case StaffCoInstructor:
+		return access <= StaffAccessEdit
+	case StaffTA:
+		return access <= StaffAccessGrade
+	}
+	return false
+}
+
+// CourseStaff makes a user a co-instructor or teaching assistant on a
+// course. The membership is an invitation until the user accepts it.
+type CourseStaff struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	CourseID   uint            `json:"course_id" gorm:"not null;uniqueIndex:idx_course_staff_member"`
+	UserID     uint            `json:"user_id" gorm:"not null;uniqueIndex:idx_course_staff_member;index"`
+	Role       CourseStaffRole `json:"role" gorm:"type:varchar(20);not null"`
+	InvitedBy  uint            `json:"invited_by"`
+	CreatedAt  time.Time       `json:"created_at"`
+	AcceptedAt *time.Time      `json:"accepted_at,omitempty"`
+
+	User   *User   `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Course *Course `json:"course,omitempty" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE"`
+}
+
+func (CourseStaff) TableName() string {
+	return "course_staff"
+}
+
+// IsAccepted reports whether the invitation was accepted
+func (s *CourseStaff) IsAccepted() bool {
+	return s.AcceptedAt != nil
+}
@@ -45,8 +45,12 @@ type TeacherDashboard struct {
 	TotalStudents     int                `json:"total_students"`
 	RecentEnrollments []RecentEnrollment `json:"recent_enrollments"`
 	Stats             TeacherStats       `json:"stats"`
+	Earnings          []TeacherEarnings  `json:"earnings"`
 }
 
+// TeacherCourse summarizes a course for its teacher. Revenue is from paid
+// orders in the course's currency, net of refunds; Earnings is the
+// teacher's share of it.
 type TeacherCourse struct {
 	ID                int64     `json:"id"`
 	Title             string    `json:"title"`
@@ -56,9 +60,24 @@ type TeacherCourse struct {
 	TotalStudents     int       `json:"total_students"`
 	ActiveStudents    int       `json:"active_students"`
 	CompletedStudents int       `json:"completed_students"`
+	Revenue           int64     `json:"revenue"`
+	Earnings          int64     `json:"earnings"`
+	Currency          string    `json:"currency"`
 	CreatedAt         time.Time `json:"created_at"`
 }
 
+// TeacherEarnings is a teacher's revenue share in one currency, from the
+// ledger. Balance is what has been earned, less refunds and payouts sent.
+// Pending is the part of Balance in payouts that are not confirmed yet.
+type TeacherEarnings struct {
+	Currency string `json:"currency"`
+	Earned   int64  `json:"earned"`
+	Refunded int64  `json:"refunded"`
+	PaidOut  int64  `json:"paid_out"`
+	Pending  int64  `json:"pending"`
+	Balance  int64  `json:"balance"`
+}
+
 type RecentEnrollment struct {
 	ID           uint      `json:"id"`
 	StudentName  string    `json:"student_name"`
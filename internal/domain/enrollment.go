@@ -9,6 +9,8 @@ const (
 	EnrollmentStatusActive    EnrollmentStatus = "active"
 	EnrollmentStatusCompleted EnrollmentStatus = "completed"
 	EnrollmentStatusDropped   EnrollmentStatus = "dropped"
+	// EnrollmentStatusRefunded ends access to a course that was refunded
+	EnrollmentStatusRefunded EnrollmentStatus = "refunded"
 )
 
 type Enrollment struct {
@@ -18,6 +20,10 @@ type Enrollment struct {
 	EnrolledAt  time.Time        `json:"enrolled_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
 	Status      EnrollmentStatus `json:"status" gorm:"type:enrollment_status;not null;default:'active'"`
 	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	// MaxProgress is the highest course progress, in percent, the student
+	// has reached. It never goes down, so refund eligibility cannot be
+	// regained by un-completing lessons.
+	MaxProgress float64 `json:"max_progress" gorm:"not null;default:0"`
 
 	// Relations
 	User   User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -0,0 +1,152 @@
+package domain
+
+import "time"
+
+// Domain events published on the event bus after the change they describe
+// has been committed. Subscribers (notifications, webhooks, ...) register
+// independently of the services that publish them.
+
+// LessonCompleted is published when a student marks a lesson as completed
+type LessonCompleted struct {
+	UserID     uint
+	CourseID   uint
+	LessonID   uint
+	OccurredAt time.Time
+}
+
+func (LessonCompleted) EventName() string { return "lesson.completed" }
+
+// CourseCompleted is published when an enrollment first becomes completed
+type CourseCompleted struct {
+	EnrollmentID uint
+	UserID       uint
+	CourseID     uint
+	OccurredAt   time.Time
+}
+
+func (CourseCompleted) EventName() string { return "course.completed" }
+
+// UserEnrolled is published when a user enrolls in a course
+type UserEnrolled struct {
+	EnrollmentID uint
+	UserID       uint
+	CourseID     uint
+	TeacherID    uint
+	CourseTitle  string
+	Status       EnrollmentStatus
+	EnrolledAt   time.Time
+}
+
+func (UserEnrolled) EventName() string { return "user.enrolled" }
+
+// CoursePublished is published when a course goes from draft to published
+type CoursePublished struct {
+	CourseID   uint
+	TeacherID  uint
+	Title      string
+	OccurredAt time.Time
+}
+
+func (CoursePublished) EventName() string { return "course.published" }
+
+// UserCreated is published when an account is created. Source is
+// "registration" for self sign-up, "admin" for accounts made by an admin,
+// "import" for accounts from a bulk CSV import or "sso" for accounts
+// created on first single sign-on.
+type UserCreated struct {
+	UserID     uint
+	Name       string
+	Email      string
+	Role       UserRole
+	Source     string
+	OccurredAt time.Time
+}
+
+func (UserCreated) EventName() string { return "user.created" }
+
+// AccountLocked is published when repeated failed logins lock an account
+type AccountLocked struct {
+	UserID      uint
+	Email       string
+	IP          string
+	Failures    int
+	LockedUntil time.Time
+	OccurredAt  time.Time
+}
+
+func (AccountLocked) EventName() string { return "account.locked" }
+
+// CourseStaffInvited is published when a user is invited to teach a course
+type CourseStaffInvited struct {
+	CourseID    uint
+	CourseTitle string
+	UserID      uint
+	Role        CourseStaffRole
+	InvitedBy   uint
+	OccurredAt  time.Time
+}
+
+func (CourseStaffInvited) EventName() string { return "course.staff_invited" }
+
+// AnnouncementPosted is published when a course announcement is stored
+type AnnouncementPosted struct {
+	AnnouncementID uint
+	CourseID       uint
+	CourseTitle    string
+	Title          string
+	Message        string
+	OccurredAt     time.Time
+}
+
+func (AnnouncementPosted) EventName() string { return "announcement.posted" }
+
+// OrderPaid is published when a payment for an order is confirmed
+type OrderPaid struct {
+	OrderID    uint
+	UserID     uint
+	CourseID   uint
+	Amount     int64
+	Currency   string
+	OccurredAt time.Time
+}
+
+func (OrderPaid) EventName() string { return "order.paid" }
+
+// OrderRefunded is published when a refund for an order is issued
+type OrderRefunded struct {
+	OrderID    uint
+	RefundID   uint
+	UserID     uint
+	CourseID   uint
+	Amount     int64
+	Currency   string
+	OccurredAt time.Time
+}
+
+func (OrderRefunded) EventName() string { return "order.refunded" }
+
+// SubscriptionChanged is published when a subscription changes status or
+// is renewed
+type SubscriptionChanged struct {
+	SubscriptionID   uint
+	UserID           uint
+	PlanID           uint
+	Status           SubscriptionStatus
+	CurrentPeriodEnd *time.Time
+	OccurredAt       time.Time
+}
+
+func (SubscriptionChanged) EventName() string { return "subscription.changed" }
+
+// SubscriptionInvoicePaid is published when a subscription payment is
+// recorded
+type SubscriptionInvoicePaid struct {
+	InvoiceID      uint
+	SubscriptionID uint
+	UserID         uint
+	Amount         int64
+	Currency       string
+	OccurredAt     time.Time
+}
+
+func (SubscriptionInvoicePaid) EventName() string { return "subscription.invoice_paid" }
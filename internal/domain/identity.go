@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// UserIdentity links a user to an account at an external identity
+// provider. Subject is the provider's stable user identifier (the "sub"
+// claim), so the link survives email changes on either side.
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Provider    string     `json:"provider" gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_provider_subject,priority:1"`
+	Subject     string     `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject,priority:2"`
+	Email       string     `json:"email" gorm:"type:varchar(255)"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// SSOLoginState is the server-side half of an in-flight single sign-on
+// login: the PKCE verifier and nonce bound to the hashed state parameter
+type SSOLoginState struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	StateHash    string     `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	Provider     string     `json:"provider" gorm:"type:varchar(50);not null"`
+	Nonce        string     `json:"-" gorm:"type:varchar(100);not null"`
+	CodeVerifier string     `json:"-" gorm:"type:varchar(128);not null"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt       *time.Time `json:"used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (SSOLoginState) TableName() string {
+	return "sso_login_states"
+}
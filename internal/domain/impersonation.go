@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Impersonation records an admin signing in as another user. The token
+// issued for it is tied to a session through TokenID. Impersonations are
+// an audit trail, so they have no foreign keys and outlive purged users;
+// Admin and User are nil once purged.
+type Impersonation struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	AdminID   uint       `json:"admin_id" gorm:"not null;index"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Reason    string     `json:"reason" gorm:"type:varchar(500);not null"`
+	TokenID   string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	IP        string     `json:"ip" gorm:"type:varchar(64)"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+
+	Admin *User `json:"admin,omitempty" gorm:"foreignKey:AdminID;constraint:-"`
+	User  *User `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:-"`
+}
+
+func (Impersonation) TableName() string {
+	return "impersonations"
+}
+
+// ImpersonationRequest is one API request made with an impersonation token
+type ImpersonationRequest struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	ImpersonationID uint      `json:"impersonation_id" gorm:"not null;index"`
+	Method          string    `json:"method" gorm:"type:varchar(10);not null"`
+	Path            string    `json:"path" gorm:"type:varchar(500);not null"`
+	Route           string    `json:"route" gorm:"type:varchar(255)"`
+	Status          int       `json:"status"`
+	IP              string    `json:"ip" gorm:"type:varchar(64)"`
+	CreatedAt       time.Time `json:"created_at" gorm:"index"`
+}
+
+func (ImpersonationRequest) TableName() string {
+	return "impersonation_requests"
+}
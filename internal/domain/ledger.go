@@ -0,0 +1,252 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LedgerAccount is an account of the revenue ledger
+type LedgerAccount string
+
+const (
+	// LedgerCash is money collected from customers and not yet refunded
+	// or paid out
+	LedgerCash LedgerAccount = "cash"
+	// LedgerPlatformRevenue is the platform's share of sales
+	LedgerPlatformRevenue LedgerAccount = "platform_revenue"
+	// LedgerTeacherPayable is what is owed to teachers; its entries carry
+	// the teacher's ID
+	LedgerTeacherPayable LedgerAccount = "teacher_payable"
+)
+
+// LedgerKind is what a ledger transaction records
+type LedgerKind string
+
+const (
+	LedgerSale   LedgerKind = "sale"
+	LedgerRefund LedgerKind = "refund"
+	LedgerPayout LedgerKind = "payout"
+	// LedgerSubscription is a paid subscription invoice
+	LedgerSubscription LedgerKind = "subscription"
+)
+
+// LedgerTransaction is a balanced set of ledger entries recording one
+// sale, refund, payout or subscription payment. Like orders, ledger rows are financial records
+// without foreign keys to users or courses; they are never updated.
+type LedgerTransaction struct {
+	ID   uint       `json:"id" gorm:"primaryKey"`
+	Kind LedgerKind `json:"kind" gorm:"type:varchar(20);not null;index"`
+	// SourceKey identifies what was posted, such as "sale:12" for order
+	// 12, so that each sale, refund, payout and invoice is posted once
+	SourceKey string `json:"source_key" gorm:"type:varchar(50);not null;uniqueIndex"`
+	OrderID   *uint  `json:"order_id,omitempty" gorm:"index"`
+	RefundID  *uint  `json:"refund_id,omitempty" gorm:"index"`
+	PayoutID  *uint  `json:"payout_id,omitempty" gorm:"index"`
+	InvoiceID *uint  `json:"invoice_id,omitempty" gorm:"index"`
+	CourseID  *uint  `json:"course_id,omitempty" gorm:"index"`
+	// TeacherID is the teacher whose earnings changed; nil when a sale had
+	// no teacher to pay
+	TeacherID *uint  `json:"teacher_id,omitempty" gorm:"index"`
+	Amount    int64  `json:"amount" gorm:"not null"`
+	Currency  string `json:"currency" gorm:"type:varchar(3);not null"`
+	// SharePercent is the teacher's revenue share applied to a sale, or
+	// reversed by a refund
+	SharePercent int           `json:"share_percent" gorm:"not null;default:0"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Entries      []LedgerEntry `json:"entries,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+func (LedgerTransaction) TableName() string {
+	return "ledger_transactions"
+}
+
+// LedgerEntry is one side of a ledger transaction. Exactly one of Debit
+// and Credit is set. Cash is debited when money comes in; revenue and
+// payables are credited when they grow.
+type LedgerEntry struct {
+	ID            uint          `json:"id" gorm:"primaryKey"`
+	TransactionID uint          `json:"transaction_id" gorm:"not null;index"`
+	Account       LedgerAccount `json:"account" gorm:"type:varchar(30);not null;index:idx_ledger_entries_account_teacher"`
+	TeacherID     *uint         `json:"teacher_id,omitempty" gorm:"index:idx_ledger_entries_account_teacher"`
+	Debit         int64         `json:"debit" gorm:"not null;default:0"`
+	Credit        int64         `json:"credit" gorm:"not null;default:0"`
+	Currency      string        `json:"currency" gorm:"type:varchar(3);not null"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+func (LedgerEntry) TableName() string {
+	return "ledger_entries"
+}
+
+// Balanced reports whether the transaction's debits equal its credits
+func (t *LedgerTransaction) Balanced() bool {
+	var debits, credits int64
+	for _, e := range t.Entries {
+		debits += e.Debit
+		credits += e.Credit
+	}
+	return debits == credits
+}
+
+// TeacherShare returns the teacher's part of the transaction: what a sale
+// credited to the teacher or a refund or payout debited from them
+func (t *LedgerTransaction) TeacherShare() int64 {
+	var share int64
+	for _, e := range t.Entries {
+		if e.Account == LedgerTeacherPayable {
+			share += e.Credit + e.Debit
+		}
+	}
+	return share
+}
+
+// SaleTransaction splits a paid order between the platform and the
+// course's teacher. With no teacher, the platform keeps the whole amount.
+func SaleTransaction(order *Order, teacherID *uint, sharePercent int) *LedgerTransaction {
+	if teacherID == nil {
+		sharePercent = 0
+	}
+	teacherShare := order.Amount * int64(sharePercent) / 100
+
+	t := &LedgerTransaction{
+		Kind:         LedgerSale,
+		SourceKey:    fmt.Sprintf("sale:%d", order.ID),
+		OrderID:      &order.ID,
+		CourseID:     &order.CourseID,
+		TeacherID:    teacherID,
+		Amount:       order.Amount,
+		Currency:     order.Currency,
+		SharePercent: sharePercent,
+	}
+	t.debit(LedgerCash, nil, order.Amount)
+	t.credit(LedgerPlatformRevenue, nil, order.Amount-teacherShare)
+	t.credit(LedgerTeacherPayable, teacherID, teacherShare)
+	return t
+}
+
+// RefundTransaction reverses a sale in proportion to the refunded amount
+func RefundTransaction(refund *Refund, sale *LedgerTransaction) *LedgerTransaction {
+	var teacherShare int64
+	if sale.Amount > 0 {
+		teacherShare = sale.TeacherShare() * refund.Amount / sale.Amount
+	}
+
+	t := &LedgerTransaction{
+		Kind:         LedgerRefund,
+		SourceKey:    fmt.Sprintf("refund:%d", refund.ID),
+		OrderID:      sale.OrderID,
+		RefundID:     &refund.ID,
+		CourseID:     sale.CourseID,
+		TeacherID:    sale.TeacherID,
+		Amount:       refund.Amount,
+		Currency:     refund.Currency,
+		SharePercent: sale.SharePercent,
+	}
+	t.debit(LedgerPlatformRevenue, nil, refund.Amount-teacherShare)
+	t.debit(LedgerTeacherPayable, sale.TeacherID, teacherShare)
+	t.credit(LedgerCash, nil, refund.Amount)
+	return t
+}
+
+// PayoutTransaction settles what was paid out to a teacher, once the
+// payout is confirmed sent
+func PayoutTransaction(payout *Payout) *LedgerTransaction {
+	t := &LedgerTransaction{
+		Kind:      LedgerPayout,
+		SourceKey: fmt.Sprintf("payout:%d", payout.ID),
+		PayoutID:  &payout.ID,
+		TeacherID: &payout.TeacherID,
+		Amount:    payout.Amount,
+		Currency:  payout.Currency,
+	}
+	t.debit(LedgerTeacherPayable, &payout.TeacherID, payout.Amount)
+	t.credit(LedgerCash, nil, payout.Amount)
+	return t
+}
+
+// SubscriptionTransaction records a paid subscription invoice. Subscription
+// revenue is not tied to a course and stays with the platform.
+func SubscriptionTransaction(invoice *SubscriptionInvoice) *LedgerTransaction {
+	t := &LedgerTransaction{
+		Kind:      LedgerSubscription,
+		SourceKey: fmt.Sprintf("subscription:%d", invoice.ID),
+		InvoiceID: &invoice.ID,
+		Amount:    invoice.Amount,
+		Currency:  invoice.Currency,
+	}
+	t.debit(LedgerCash, nil, invoice.Amount)
+	t.credit(LedgerPlatformRevenue, nil, invoice.Amount)
+	return t
+}
+
+func (t *LedgerTransaction) debit(account LedgerAccount, teacherID *uint, amount int64) {
+	if amount != 0 {
+		t.Entries = append(t.Entries, LedgerEntry{Account: account, TeacherID: teacherID, Debit: amount, Currency: t.Currency})
+	}
+}
+
+func (t *LedgerTransaction) credit(account LedgerAccount, teacherID *uint, amount int64) {
+	if amount != 0 {
+		t.Entries = append(t.Entries, LedgerEntry{Account: account, TeacherID: teacherID, Credit: amount, Currency: t.Currency})
+	}
+}
+
+// TeacherBalance is what the platform owes a teacher in one currency
+type TeacherBalance struct {
+	TeacherID    uint   `json:"teacher_id"`
+	TeacherName  string `json:"teacher_name"`
+	TeacherEmail string `json:"teacher_email"`
+	Currency     string `json:"currency"`
+	Balance      int64  `json:"balance"`
+}
+
+// PayoutBatch is a set of payouts generated together, to be exported and
+// paid outside the platform
+type PayoutBatch struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CreatedBy   uint      `json:"created_by" gorm:"not null"`
+	PayoutCount int       `json:"payout_count" gorm:"not null;default:0"`
+	CreatedAt   time.Time `json:"created_at"`
+	Payouts     []Payout  `json:"payouts,omitempty" gorm:"foreignKey:BatchID;constraint:OnDelete:CASCADE"`
+}
+
+func (PayoutBatch) TableName() string {
+	return "payout_batches"
+}
+
+// PayoutStatus tracks a payout until the money is known to have arrived
+type PayoutStatus string
+
+const (
+	// PayoutPending payouts await payment; their amount is held back from
+	// the teacher's balance
+	PayoutPending PayoutStatus = "pending"
+	// PayoutSent payouts were paid and are settled in the ledger
+	PayoutSent PayoutStatus = "sent"
+	// PayoutFailed payouts were not paid; their amount is due again
+	PayoutFailed PayoutStatus = "failed"
+)
+
+// Payout pays a teacher's balance in one currency. The teacher's name and
+// email are copied when the batch is generated.
+type Payout struct {
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	BatchID      uint         `json:"batch_id" gorm:"not null;index"`
+	TeacherID    uint         `json:"teacher_id" gorm:"not null;index"`
+	TeacherName  string       `json:"teacher_name" gorm:"type:varchar(255)"`
+	TeacherEmail string       `json:"teacher_email" gorm:"type:varchar(255)"`
+	Amount       int64        `json:"amount" gorm:"not null"`
+	Currency     string       `json:"currency" gorm:"type:varchar(3);not null"`
+	Status       PayoutStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	// Reference identifies the transfer, such as a bank reference; Note
+	// explains a failure
+	Reference string     `json:"reference,omitempty" gorm:"type:varchar(255)"`
+	Note      string     `json:"note,omitempty" gorm:"type:varchar(255)"`
+	SettledBy *uint      `json:"settled_by,omitempty"`
+	SettledAt *time.Time `json:"settled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (Payout) TableName() string {
+	return "payouts"
+}
@@ -0,0 +1,156 @@
+package domain
+
+import "testing"
+
+// accountTotals sums a transaction's entries as credit minus debit per
+// account
+func accountTotals(t *LedgerTransaction) map[LedgerAccount]int64 {
+	totals := map[LedgerAccount]int64{}
+	for _, e := range t.Entries {
+		totals[e.Account] += e.Credit - e.Debit
+	}
+	return totals
+}
+
+func TestSaleTransaction(t *testing.T) {
+	teacherID := uint(7)
+
+	tests := []struct {
+		name         string
+		amount       int64
+		teacher      *uint
+		sharePercent int
+		wantTeacher  int64
+		wantPlatform int64
+	}{
+		{"revenue share", 10000, &teacherID, 70, 7000, 3000},
+		{"share rounds down for the teacher", 999, &teacherID, 70, 699, 300},
+		{"no teacher keeps it all", 10000, nil, 70, 0, 10000},
+		{"zero share", 10000, &teacherID, 0, 0, 10000},
+		{"full share", 10000, &teacherID, 100, 10000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &Order{ID: 12, CourseID: 3, Amount: tt.amount, Currency: "USD"}
+			txn := SaleTransaction(order, tt.teacher, tt.sharePercent)
+
+			if !txn.Balanced() {
+				t.Fatalf("sale is not balanced: %+v", txn.Entries)
+			}
+			if txn.SourceKey != "sale:12" {
+				t.Errorf("SourceKey = %q, want sale:12", txn.SourceKey)
+			}
+			if got := txn.TeacherShare(); got != tt.wantTeacher {
+				t.Errorf("TeacherShare = %d, want %d", got, tt.wantTeacher)
+			}
+
+			totals := accountTotals(txn)
+			if totals[LedgerCash] != -tt.amount {
+				t.Errorf("cash = %d, want debit of %d", totals[LedgerCash], tt.amount)
+			}
+			if totals[LedgerPlatformRevenue] != tt.wantPlatform {
+				t.Errorf("platform revenue = %d, want %d", totals[LedgerPlatformRevenue], tt.wantPlatform)
+			}
+			if totals[LedgerTeacherPayable] != tt.wantTeacher {
+				t.Errorf("teacher payable = %d, want %d", totals[LedgerTeacherPayable], tt.wantTeacher)
+			}
+			for _, e := range txn.Entries {
+				if e.Currency != "USD" {
+					t.Errorf("entry currency = %q, want USD", e.Currency)
+				}
+				if (e.Debit == 0) == (e.Credit == 0) {
+					t.Errorf("entry %+v must set exactly one of debit and credit", e)
+				}
+			}
+		})
+	}
+}
+
+func TestRefundTransaction(t *testing.T) {
+	teacherID := uint(7)
+
+	tests := []struct {
+		name         string
+		saleAmount   int64
+		teacher      *uint
+		sharePercent int
+		refund       int64
+		wantTeacher  int64
+		wantPlatform int64
+	}{
+		{"full refund reverses the sale", 10000, &teacherID, 70, 10000, 7000, 3000},
+		{"partial refund in proportion", 10000, &teacherID, 70, 2500, 1750, 750},
+		{"partial refund rounds down for the teacher", 999, &teacherID, 70, 333, 233, 100},
+		{"sale without a teacher", 10000, nil, 70, 4000, 0, 4000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale := SaleTransaction(&Order{ID: 12, CourseID: 3, Amount: tt.saleAmount, Currency: "USD"}, tt.teacher, tt.sharePercent)
+			refund := &Refund{ID: 4, OrderID: 12, Amount: tt.refund, Currency: "USD"}
+			txn := RefundTransaction(refund, sale)
+
+			if !txn.Balanced() {
+				t.Fatalf("refund is not balanced: %+v", txn.Entries)
+			}
+			if txn.SourceKey != "refund:4" {
+				t.Errorf("SourceKey = %q, want refund:4", txn.SourceKey)
+			}
+
+			totals := accountTotals(txn)
+			if totals[LedgerCash] != tt.refund {
+				t.Errorf("cash = %d, want credit of %d", totals[LedgerCash], tt.refund)
+			}
+			if totals[LedgerTeacherPayable] != -tt.wantTeacher {
+				t.Errorf("teacher payable = %d, want debit of %d", totals[LedgerTeacherPayable], tt.wantTeacher)
+			}
+			if totals[LedgerPlatformRevenue] != -tt.wantPlatform {
+				t.Errorf("platform revenue = %d, want debit of %d", totals[LedgerPlatformRevenue], tt.wantPlatform)
+			}
+		})
+	}
+}
+
+func TestFullRefundNetsToZero(t *testing.T) {
+	teacherID := uint(7)
+	sale := SaleTransaction(&Order{ID: 1, Amount: 4999, Currency: "EUR"}, &teacherID, 65)
+	refund := RefundTransaction(&Refund{ID: 1, OrderID: 1, Amount: 4999, Currency: "EUR"}, sale)
+
+	net := accountTotals(sale)
+	for account, total := range accountTotals(refund) {
+		net[account] += total
+	}
+	for account, total := range net {
+		if total != 0 {
+			t.Errorf("%s nets to %d after a full refund, want 0", account, total)
+		}
+	}
+}
+
+func TestPayoutAndSubscriptionTransactionsBalance(t *testing.T) {
+	tests := []struct {
+		name string
+		txn  *LedgerTransaction
+	}{
+		{"payout", PayoutTransaction(&Payout{ID: 2, TeacherID: 7, Amount: 7000, Currency: "USD"})},
+		{"subscription", SubscriptionTransaction(&SubscriptionInvoice{ID: 3, Amount: 1500, Currency: "USD"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.txn.Balanced() {
+				t.Errorf("%s is not balanced: %+v", tt.name, tt.txn.Entries)
+			}
+		})
+	}
+}
+
+func TestBalancedDetectsMismatch(t *testing.T) {
+	txn := &LedgerTransaction{Currency: "USD"}
+	txn.debit(LedgerCash, nil, 100)
+	txn.credit(LedgerPlatformRevenue, nil, 99)
+	if txn.Balanced() {
+		t.Error("Balanced accepted debits of 100 against credits of 99")
+	}
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Lesson struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
@@ -13,4 +17,6 @@ type Lesson struct {
 	Duration    int       `json:"duration"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// DeletedAt is set while the lesson is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// LoginThrottleScope is what failed logins are counted against
+type LoginThrottleScope string
+
+const (
+	LoginThrottleAccount LoginThrottleScope = "account"
+	LoginThrottleIP      LoginThrottleScope = "ip"
+)
+
+// LoginThrottle tracks recent failed logins for one account (keyed by
+// normalized email, whether or not it exists) or one client IP.
+// BlockedUntil enforces both progressive delays and lockouts; LockedAt is
+// set once the failure count reaches the lockout threshold.
+type LoginThrottle struct {
+	ID           uint               `json:"id" gorm:"primaryKey"`
+	Scope        LoginThrottleScope `json:"scope" gorm:"type:varchar(10);not null;uniqueIndex:idx_login_throttles_scope_key,priority:1"`
+	Key          string             `json:"key" gorm:"type:varchar(255);not null;uniqueIndex:idx_login_throttles_scope_key,priority:2"`
+	FailedCount  int                `json:"failed_count" gorm:"not null;default:0"`
+	LastFailedAt time.Time          `json:"last_failed_at" gorm:"not null"`
+	LastIP       string             `json:"last_ip" gorm:"type:varchar(64)"`
+	BlockedUntil *time.Time         `json:"blocked_until,omitempty" gorm:"index"`
+	LockedAt     *time.Time         `json:"locked_at,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// IsLocked reports whether the throttle is in a lockout at t
+func (l *LoginThrottle) IsLocked(t time.Time) bool {
+	return l.LockedAt != nil && l.BlockedUntil != nil && l.BlockedUntil.After(t)
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// UserMFA holds a user's TOTP two-factor configuration. The secret is
+// encrypted at rest; Enabled is only set once the user has proven they can
+// generate codes with it.
+type UserMFA struct {
+	UserID       uint       `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Secret       string     `json:"-" gorm:"type:text;not null"`
+	Enabled      bool       `json:"enabled" gorm:"not null;default:false"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `json:"-" gorm:"not null;default:0"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// MFARecoveryCode is a hashed single-use code that replaces a TOTP code
+// when the user has lost their authenticator
+type MFARecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:char(64);not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -5,11 +5,30 @@ import "time"
 type NotificationType string
 
 const (
-	NotificationTypeEnrollment NotificationType = "enrollment"
-	NotificationTypeNewLesson  NotificationType = "new_lesson"
-	NotificationTypeCompleted  NotificationType = "completed"
+	NotificationTypeEnrollment   NotificationType = "enrollment"
+	NotificationTypeNewLesson    NotificationType = "new_lesson"
+	NotificationTypeCompleted    NotificationType = "completed"
+	NotificationTypeAnnouncement NotificationType = "announcement"
+	NotificationTypeStaffInvite  NotificationType = "staff_invite"
+	// NotificationTypeAccount reports changes to the account itself, such
+	// as a suspension
+	NotificationTypeAccount      NotificationType = "account"
+	NotificationTypeRefund       NotificationType = "refund"
+	NotificationTypePrivacy      NotificationType = "privacy"
+	NotificationTypeSubscription NotificationType = "subscription"
 )
 
+// IsValid reports whether the type is one the notifications table accepts
+func (t NotificationType) IsValid() bool {
+	switch t {
+	case NotificationTypeEnrollment, NotificationTypeNewLesson, NotificationTypeCompleted,
+		NotificationTypeAnnouncement, NotificationTypeStaffInvite, NotificationTypeAccount,
+		NotificationTypeRefund, NotificationTypePrivacy, NotificationTypeSubscription:
+		return true
+	}
+	return false
+}
+
 type Notification struct {
 	ID        uint             `json:"id" gorm:"primaryKey"`
 	UserID    uint             `json:"user_id" gorm:"not null;index:idx_notifications_user"`
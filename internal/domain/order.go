@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// OrderStatus tracks an order through checkout
+type OrderStatus string
+
+const (
+	// OrderStatusPending waits for the customer to pay at the provider
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusFailed    OrderStatus = "failed"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRefunded  OrderStatus = "refunded"
+)
+
+// Order is a purchase of a course. The course title, price and currency
+// are copied at checkout, so later course changes do not affect it. Amount
+// is what the customer pays: Subtotal, the list price, less Discount. Orders
+// are financial records: they have no foreign keys and outlive purged
+// users and courses.
+type Order struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	UserID      uint        `json:"user_id" gorm:"not null;index"`
+	CourseID    uint        `json:"course_id" gorm:"not null;index"`
+	CourseTitle string      `json:"course_title" gorm:"type:varchar(255);not null"`
+	Subtotal    int64       `json:"subtotal" gorm:"not null;default:0"`
+	Discount    int64       `json:"discount" gorm:"not null;default:0"`
+	Amount      int64       `json:"amount" gorm:"not null"`
+	Currency    string      `json:"currency" gorm:"type:varchar(3);not null"`
+	CouponID    *uint       `json:"coupon_id,omitempty" gorm:"index"`
+	CouponCode  string      `json:"coupon_code,omitempty" gorm:"type:varchar(40)"`
+	Status      OrderStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	// Provider and ProviderRef identify the checkout at the payment
+	// provider; PaymentRef identifies the captured payment
+	Provider      string     `json:"provider" gorm:"type:varchar(30);not null"`
+	ProviderRef   string     `json:"-" gorm:"type:varchar(255);index"`
+	PaymentRef    string     `json:"-" gorm:"type:varchar(255)"`
+	CheckoutURL   string     `json:"checkout_url,omitempty" gorm:"type:text"`
+	FailureReason string     `json:"failure_reason,omitempty" gorm:"type:varchar(255)"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+	RefundedAt    *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (Order) TableName() string {
+	return "orders"
+}
+
+// PaymentEvent records a processed provider webhook so redelivered events
+// are applied once
+type PaymentEvent struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Provider string `json:"provider" gorm:"type:varchar(30);not null;uniqueIndex:idx_payment_events_provider_event"`
+	EventID  string `json:"event_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_payment_events_provider_event"`
+	Type     string `json:"type" gorm:"type:varchar(50);not null"`
+	OrderID  *uint  `json:"order_id,omitempty" gorm:"index"`
+	// SubscriptionID is set for subscription events instead of OrderID
+	SubscriptionID *uint     `json:"subscription_id,omitempty" gorm:"index"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (PaymentEvent) TableName() string {
+	return "payment_events"
+}
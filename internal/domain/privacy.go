@@ -0,0 +1,94 @@
+package domain
+
+import "time"
+
+// DataExportStatus tracks a personal data export through the worker
+type DataExportStatus string
+
+const (
+	DataExportPending    DataExportStatus = "pending"
+	DataExportProcessing DataExportStatus = "processing"
+	DataExportReady      DataExportStatus = "ready"
+	DataExportFailed     DataExportStatus = "failed"
+	DataExportExpired    DataExportStatus = "expired"
+)
+
+// DataExport is a user's request for an archive of their personal data.
+// The archive is built in the background and can be downloaded until
+// ExpiresAt.
+type DataExport struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	UserID      uint             `json:"user_id" gorm:"not null;index"`
+	Status      DataExportStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	FilePath    string           `json:"-" gorm:"type:varchar(255)"`
+	Size        int64            `json:"size,omitempty"`
+	Error       string           `json:"error,omitempty" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+}
+
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+// ErasureStatus tracks an account erasure request
+type ErasureStatus string
+
+const (
+	// ErasurePending waits for admin review; the user can still cancel
+	ErasurePending ErasureStatus = "pending"
+	// ErasureApproved is carried out once the cooling-off period ends
+	ErasureApproved  ErasureStatus = "approved"
+	ErasureRejected  ErasureStatus = "rejected"
+	ErasureCancelled ErasureStatus = "cancelled"
+	ErasureCompleted ErasureStatus = "completed"
+)
+
+// IsOpen reports whether the request can still lead to an erasure
+func (s ErasureStatus) IsOpen() bool {
+	return s == ErasurePending || s == ErasureApproved
+}
+
+// ErasureRequest is a user's request to have their account anonymized.
+// It needs admin approval and is only carried out after the cooling-off
+// period, during which the user can cancel it.
+type ErasureRequest struct {
+	ID              uint          `json:"id" gorm:"primaryKey"`
+	UserID          uint          `json:"user_id" gorm:"not null;index"`
+	Reason          string        `json:"reason" gorm:"type:varchar(500)"`
+	Status          ErasureStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	CoolingOffUntil time.Time     `json:"cooling_off_until" gorm:"not null"`
+	ReviewedBy      *uint         `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time    `json:"reviewed_at,omitempty"`
+	ReviewNote      string        `json:"review_note,omitempty" gorm:"type:varchar(500)"`
+	CompletedAt     *time.Time    `json:"completed_at,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (ErasureRequest) TableName() string {
+	return "erasure_requests"
+}
+
+// PersonalData is everything stored about a user, as included in their
+// data export
+type PersonalData struct {
+	Profile         User             `json:"profile"`
+	Enrollments     []Enrollment     `json:"enrollments"`
+	Orders          []Order          `json:"orders"`
+	Refunds         []Refund         `json:"refunds"`
+	Subscriptions   []Subscription   `json:"subscriptions"`
+	Payouts         []Payout         `json:"payouts"`
+	Progress        []Progress       `json:"progress"`
+	Notifications   []Notification   `json:"notifications"`
+	Sessions        []UserSession    `json:"sessions"`
+	Identities      []UserIdentity   `json:"identities"`
+	APIKeys         []APIKey         `json:"api_keys"`
+	CourseStaff     []CourseStaff    `json:"course_staff"`
+	TaughtCourses   []Course         `json:"taught_courses"`
+	Announcements   []Announcement   `json:"announcements"`
+	ErasureRequests []ErasureRequest `json:"erasure_requests"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// RefundStatus tracks a refund request
+type RefundStatus string
+
+const (
+	// RefundPending waits for admin review; the customer can still cancel
+	RefundPending   RefundStatus = "pending"
+	RefundRejected  RefundStatus = "rejected"
+	RefundCancelled RefundStatus = "cancelled"
+	// RefundFailed means the provider refused the refund; it can be
+	// approved again
+	RefundFailed    RefundStatus = "failed"
+	RefundCompleted RefundStatus = "completed"
+)
+
+// IsOpen reports whether the request still awaits a decision
+func (s RefundStatus) IsOpen() bool {
+	return s == RefundPending || s == RefundFailed
+}
+
+// Refund is a customer's request to be refunded for an order. Like orders,
+// refunds are financial records without foreign keys.
+type Refund struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	OrderID  uint   `json:"order_id" gorm:"not null;index"`
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	CourseID uint   `json:"course_id" gorm:"not null"`
+	Amount   int64  `json:"amount" gorm:"not null"`
+	Currency string `json:"currency" gorm:"type:varchar(3);not null"`
+	Reason   string `json:"reason" gorm:"type:varchar(500)"`
+	// ProgressPercent is the customer's course progress when they asked
+	ProgressPercent float64      `json:"progress_percent" gorm:"not null;default:0"`
+	Status          RefundStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	ReviewedBy      *uint        `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time   `json:"reviewed_at,omitempty"`
+	ReviewNote      string       `json:"review_note,omitempty" gorm:"type:varchar(500)"`
+	// ProviderRef identifies the refund at the payment provider
+	ProviderRef   string     `json:"-" gorm:"type:varchar(255)"`
+	FailureReason string     `json:"failure_reason,omitempty" gorm:"type:varchar(255)"`
+	RefundedAt    *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (Refund) TableName() string {
+	return "refunds"
+}
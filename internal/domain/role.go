@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Permission is an action a role may perform
+type Permission string
+
+const (
+	PermCourseCreate     Permission = "course.create"
+	PermCourseUpdate     Permission = "course.update"
+	PermCourseDelete     Permission = "course.delete"
+	PermLessonManage     Permission = "lesson.manage"
+	PermCourseEnroll     Permission = "course.enroll"
+	PermEnrollmentRead   Permission = "enrollment.read"
+	PermEnrollmentGrade  Permission = "enrollment.grade"
+	PermCourseStaff      Permission = "course.staff"
+	PermAnnouncementPost Permission = "announcement.post"
+	PermAnnouncementRead Permission = "announcement.read"
+	PermDashboardStudent Permission = "dashboard.student"
+	PermDashboardTeacher Permission = "dashboard.teacher"
+	PermDashboardAdmin   Permission = "dashboard.admin"
+	PermUserManage       Permission = "user.manage"
+	PermUserImpersonate  Permission = "user.impersonate"
+	PermAuditRead        Permission = "audit.read"
+	PermTrashManage      Permission = "trash.manage"
+	PermPrivacyManage    Permission = "privacy.manage"
+	PermOrderManage      Permission = "order.manage"
+	PermCouponManage     Permission = "coupon.manage"
+	PermRefundManage     Permission = "refund.manage"
+	PermPlanManage       Permission = "plan.manage"
+	PermPayoutManage     Permission = "payout.manage"
+	PermReportRead       Permission = "report.read"
+	PermWebhookManage    Permission = "webhook.manage"
+	PermLockoutManage    Permission = "lockout.manage"
+	PermAPIKeyManage     Permission = "apikey.manage"
+	PermRoleManage       Permission = "role.manage"
+)
+
+// PermissionScope limits a permission to resources the user owns (e.g.
+// courses they are on the staff of) or grants it on any resource
+type PermissionScope string
+
+const (
+	ScopeOwn PermissionScope = "own"
+	ScopeAny PermissionScope = "any"
+)
+
+// IsValid checks if the scope is valid
+func (s PermissionScope) IsValid() bool {
+	return s == ScopeOwn || s == ScopeAny
+}
+
+// PermissionInfo describes a permission for the role editor
+type PermissionInfo struct {
+	Permission  Permission `json:"permission"`
+	Description string     `json:"description"`
+	// Scoped permissions can be limited to the user's own resources
+	Scoped bool `json:"scoped"`
+}
+
+// Permissions lists every permission that can be granted
+var Permissions = []PermissionInfo{
+	{PermCourseCreate, "Create courses", false},
+	{PermCourseUpdate, "Edit and publish courses", true},
+	{PermCourseDelete, "Delete courses", true},
+	{PermLessonManage, "Create, edit, reorder and delete lessons", true},
+	{PermCourseEnroll, "Enroll in courses", false},
+	{PermEnrollmentRead, "View the students enrolled in a course", true},
+	{PermEnrollmentGrade, "Update enrollment progress (own: the user's enrollments and those in courses they grade)", true},
+	{PermCourseStaff, "Invite and remove a course's co-instructors and teaching assistants", true},
+	{PermAnnouncementPost, "Post course announcements", true},
+	{PermAnnouncementRead, "Read course announcements (own: courses the user teaches or is enrolled in)", true},
+	{PermDashboardStudent, "View the student dashboard", false},
+	{PermDashboardTeacher, "View the teacher dashboard", false},
+	{PermDashboardAdmin, "View the admin dashboard", false},
+	{PermUserManage, "Create, edit, delete and log out users", false},
+	{PermUserImpersonate, "Sign in as another user to see what they see", false},
+	{PermAuditRead, "Read and export the audit log", false},
+	{PermTrashManage, "Restore or permanently delete users, courses and lessons in the trash", false},
+	{PermPrivacyManage, "Review account erasure requests", false},
+	{PermOrderManage, "View all orders and payments", false},
+	{PermRefundManage, "Review refund requests and issue refunds", false},
+	{PermPlanManage, "Manage subscription plans and view subscriptions", false},
+	{PermPayoutManage, "View teacher balances and generate, export and settle payouts", false},
+	{PermCouponManage, "Create and manage promo codes (own: the user's coupons, restricted to courses they own)", true},
+	{PermReportRead, "View reports", false},
+	{PermWebhookManage, "Manage outbound webhooks", false},
+	{PermLockoutManage, "View and clear login lockouts", false},
+	{PermAPIKeyManage, "View, issue and revoke any user's API keys", false},
+	{PermRoleManage, "Define roles and their permissions", false},
+}
+
+// selfServicePermissions are the unscoped permissions that only act on the
+// user's own account or content. Every other unscoped permission is
+// administrative.
+var selfServicePermissions = map[Permission]bool{
+	PermCourseCreate:     true,
+	PermCourseEnroll:     true,
+	PermDashboardStudent: true,
+	PermDashboardTeacher: true,
+}
+
+// IsPrivileged reports whether the grant reaches beyond the user's own
+// resources: an administrative permission, or a scoped permission in the
+// "any" scope
+func (p RolePermission) IsPrivileged() bool {
+	info, ok := p.Permission.Info()
+	if !ok {
+		return false
+	}
+	if info.Scoped {
+		return p.Scope == ScopeAny
+	}
+	return !selfServicePermissions[p.Permission]
+}
+
+// Info returns the description of a permission
+func (p Permission) Info() (PermissionInfo, bool) {
+	for _, info := range Permissions {
+		if info.Permission == p {
+			return info, true
+		}
+	}
+	return PermissionInfo{}, false
+}
+
+// Role is a named set of permissions. Users reference roles by name;
+// student, teacher and admin are built in and cannot be deleted.
+type Role struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description string `json:"description" gorm:"type:varchar(255)"`
+	BuiltIn     bool   `json:"built_in" gorm:"not null;default:false"`
+	// Seeded lists the default permissions already granted to a built-in
+	// role, so defaults added later are granted once and removals stick
+	Seeded      pq.StringArray   `json:"-" gorm:"type:text[]"`
+	Permissions []RolePermission `json:"permissions" gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	ID         uint            `json:"-" gorm:"primaryKey"`
+	RoleID     uint            `json:"-" gorm:"not null;uniqueIndex:idx_role_permission"`
+	Permission Permission      `json:"permission" gorm:"type:varchar(50);not null;uniqueIndex:idx_role_permission"`
+	Scope      PermissionScope `json:"scope" gorm:"type:varchar(10);not null;default:'any'"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// IsValidRoleName reports whether name can be used for a custom role
+func IsValidRoleName(name string) bool {
+	return roleNamePattern.MatchString(name)
+}
+
+// DefaultRolePermissions are granted to the built-in student and teacher
+// roles. The admin role always holds every permission. Course permissions
+// in the "own" scope only apply to courses the user is on the staff of,
+// within what their staff role allows, so students can be invited as
+// teaching assistants.
+var DefaultRolePermissions = map[UserRole][]RolePermission{
+	RoleStudent: {
+		{Permission: PermCourseEnroll, Scope: ScopeAny},
+		{Permission: PermAnnouncementRead, Scope: ScopeOwn},
+		{Permission: PermDashboardStudent, Scope: ScopeAny},
+		{Permission: PermEnrollmentGrade, Scope: ScopeOwn},
+		{Permission: PermEnrollmentRead, Scope: ScopeOwn},
+		{Permission: PermCourseUpdate, Scope: ScopeOwn},
+		{Permission: PermLessonManage, Scope: ScopeOwn},
+		{Permission: PermAnnouncementPost, Scope: ScopeOwn},
+	},
+	RoleTeacher: {
+		{Permission: PermCourseCreate, Scope: ScopeAny},
+		{Permission: PermCourseUpdate, Scope: ScopeOwn},
+		{Permission: PermCourseDelete, Scope: ScopeOwn},
+		{Permission: PermLessonManage, Scope: ScopeOwn},
+		{Permission: PermEnrollmentRead, Scope: ScopeOwn},
+		{Permission: PermAnnouncementPost, Scope: ScopeOwn},
+		{Permission: PermAnnouncementRead, Scope: ScopeOwn},
+		{Permission: PermDashboardTeacher, Scope: ScopeAny},
+		{Permission: PermEnrollmentGrade, Scope: ScopeOwn},
+		{Permission: PermCourseStaff, Scope: ScopeOwn},
+		{Permission: PermCouponManage, Scope: ScopeOwn},
+	},
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestRolePermissionIsPrivileged(t *testing.T) {
+	tests := []struct {
+		name string
+		perm RolePermission
+		want bool
+	}{
+		{"administrative", RolePermission{Permission: PermRefundManage, Scope: ScopeAny}, true},
+		{"scoped in any scope", RolePermission{Permission: PermCourseUpdate, Scope: ScopeAny}, true},
+		{"scoped in own scope", RolePermission{Permission: PermCourseUpdate, Scope: ScopeOwn}, false},
+		{"self-service", RolePermission{Permission: PermCourseCreate, Scope: ScopeAny}, false},
+		{"unknown", RolePermission{Permission: "course.fly", Scope: ScopeAny}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.perm.IsPrivileged(); got != tt.want {
+				t.Errorf("IsPrivileged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRolesAreNotPrivileged(t *testing.T) {
+	for role, perms := range DefaultRolePermissions {
+		for _, p := range perms {
+			if p.IsPrivileged() {
+				t.Errorf("default %s permission %s in scope %s is privileged", role, p.Permission, p.Scope)
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// UserSession is the server-side record of an issued access token, keyed
+// by the token's jti claim. Revoking the session invalidates the token.
+type UserSession struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	TokenID    string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Device     string     `json:"device" gorm:"type:varchar(100)"`
+	UserAgent  string     `json:"user_agent" gorm:"type:varchar(512)"`
+	IP         string     `json:"ip" gorm:"type:varchar(64)"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// IsActive reports whether the session can still be used at t
+func (s *UserSession) IsActive(t time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(t)
+}
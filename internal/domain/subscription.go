@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// BillingInterval is how often a subscription is charged
+type BillingInterval string
+
+const (
+	BillingMonthly BillingInterval = "month"
+	BillingYearly  BillingInterval = "year"
+)
+
+// IsValid reports whether the interval is supported
+func (i BillingInterval) IsValid() bool {
+	return i == BillingMonthly || i == BillingYearly
+}
+
+// Next returns the end of a billing period starting at t
+func (i BillingInterval) Next(t time.Time) time.Time {
+	if i == BillingYearly {
+		return t.AddDate(1, 0, 0)
+	}
+	return t.AddDate(0, 1, 0)
+}
+
+// Plan is a subscription offer. Subscribers may enroll in every published
+// course, or with CategoryIDs set, every course in those categories.
+// Plans that have been subscribed to are deactivated rather than deleted.
+type Plan struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	Name        string          `json:"name" gorm:"type:varchar(100);not null"`
+	Description string          `json:"description" gorm:"type:varchar(500)"`
+	Interval    BillingInterval `json:"interval" gorm:"type:varchar(10);not null"`
+	// Price is charged every interval, in the currency's minor unit
+	Price       int64         `json:"price" gorm:"not null"`
+	Currency    string        `json:"currency" gorm:"type:varchar(3);not null"`
+	TrialDays   int           `json:"trial_days" gorm:"not null;default:0"`
+	CategoryIDs pq.Int64Array `json:"category_ids" gorm:"type:bigint[]"`
+	Active      bool          `json:"active" gorm:"not null;default:true"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+func (Plan) TableName() string {
+	return "subscription_plans"
+}
+
+// Covers reports whether the plan gives access to the course
+func (p *Plan) Covers(course *Course) bool {
+	if !course.IsPublished {
+		return false
+	}
+	if len(p.CategoryIDs) == 0 {
+		return true
+	}
+	if course.CategoryID != nil {
+		for _, id := range p.CategoryIDs {
+			if id == *course.CategoryID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SubscriptionStatus tracks a subscription through its lifecycle
+type SubscriptionStatus string
+
+const (
+	// SubscriptionIncomplete waits for the customer to finish checkout
+	SubscriptionIncomplete SubscriptionStatus = "incomplete"
+	SubscriptionTrial      SubscriptionStatus = "trial"
+	SubscriptionActive     SubscriptionStatus = "active"
+	// SubscriptionPastDue means a renewal payment failed; the provider
+	// keeps retrying it
+	SubscriptionPastDue  SubscriptionStatus = "past_due"
+	SubscriptionCanceled SubscriptionStatus = "canceled"
+)
+
+// IsLive reports whether the subscription has started and not ended
+func (s SubscriptionStatus) IsLive() bool {
+	return s == SubscriptionTrial || s == SubscriptionActive || s == SubscriptionPastDue
+}
+
+// Subscription is a customer's subscription to a plan. The payment
+// provider owns billing; its webhooks move the subscription between states
+// and extend CurrentPeriodEnd on renewal.
+type Subscription struct {
+	ID     uint               `json:"id" gorm:"primaryKey"`
+	UserID uint               `json:"user_id" gorm:"not null;index"`
+	PlanID uint               `json:"plan_id" gorm:"not null;index"`
+	Status SubscriptionStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	// Provider and CheckoutRef identify the checkout at the payment
+	// provider; ProviderRef identifies the subscription once it starts
+	Provider         string     `json:"provider" gorm:"type:varchar(30);not null"`
+	CheckoutRef      string     `json:"-" gorm:"type:varchar(255);index"`
+	ProviderRef      string     `json:"-" gorm:"type:varchar(255);index"`
+	CheckoutURL      string     `json:"checkout_url,omitempty" gorm:"type:text"`
+	TrialEndsAt      *time.Time `json:"trial_ends_at,omitempty"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end,omitempty"`
+	// CancelAtPeriodEnd subscriptions are not renewed
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end" gorm:"not null;default:false"`
+	CanceledAt        *time.Time `json:"canceled_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Plan *Plan `json:"plan,omitempty" gorm:"foreignKey:PlanID;constraint:OnDelete:RESTRICT"`
+}
+
+func (Subscription) TableName() string {
+	return "subscriptions"
+}
+
+// GrantsAccess reports whether the subscription unlocks courses at now.
+// Access lasts until the end of the paid or trial period, plus grace to
+// allow for a renewal webhook arriving late or a failed payment being
+// retried.
+func (s *Subscription) GrantsAccess(now time.Time, grace time.Duration) bool {
+	if !s.Status.IsLive() || s.CurrentPeriodEnd == nil {
+		return false
+	}
+	return now.Before(s.CurrentPeriodEnd.Add(grace))
+}
+
+// SubscriptionInvoice records a subscription payment collected by the
+// provider, one per paid invoice. Like orders, invoices are financial
+// records without foreign keys to users or plans.
+type SubscriptionInvoice struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint   `json:"subscription_id" gorm:"not null;index"`
+	UserID         uint   `json:"user_id" gorm:"not null;index"`
+	PlanID         uint   `json:"plan_id" gorm:"not null;index"`
+	Provider       string `json:"provider" gorm:"type:varchar(30);not null;uniqueIndex:idx_subscription_invoices_provider_ref"`
+	ProviderRef    string `json:"-" gorm:"type:varchar(255);not null;uniqueIndex:idx_subscription_invoices_provider_ref"`
+	// Amount is what was paid, in the currency's minor unit
+	Amount    int64      `json:"amount" gorm:"not null"`
+	Currency  string     `json:"currency" gorm:"type:varchar(3);not null"`
+	PeriodEnd *time.Time `json:"period_end,omitempty"`
+	PaidAt    time.Time  `json:"paid_at" gorm:"not null;index"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (SubscriptionInvoice) TableName() string {
+	return "subscription_invoices"
+}
@@ -2,9 +2,12 @@ package domain
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
-// UserRole represents user role types
+// UserRole is the name of the role a user holds: one of the built-in
+// roles below or a custom role defined by an admin
 type UserRole string
 
 const (
@@ -13,16 +16,37 @@ const (
 	RoleAdmin   UserRole = "admin"
 )
 
+// AccountStatus is the lifecycle state of an account
+type AccountStatus string
+
+const (
+	AccountActive AccountStatus = "active"
+	// AccountPendingVerification has not confirmed its email address yet.
+	// What it may do is decided by the email verification policy.
+	AccountPendingVerification AccountStatus = "pending_verification"
+	// AccountSuspended is locked out by an admin, until StatusUntil if set
+	AccountSuspended AccountStatus = "suspended"
+	// AccountDeactivated is locked out until an admin reinstates it
+	AccountDeactivated AccountStatus = "deactivated"
+)
+
 // User represents a user entity
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"size:100;not null" json:"name"`
-	Email     string    `gorm:"size:100;not null;uniqueIndex" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	Role      UserRole  `gorm:"type:varchar(20);not null;default:'student'" json:"role"`
-	Avatar    *string   `gorm:"size:255" json:"avatar,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Email      string     `gorm:"size:100;not null;uniqueIndex" json:"email"`
+	Password   string     `gorm:"not null" json:"-"`
+	Role       UserRole   `gorm:"type:varchar(50);not null;default:'student'" json:"role"`
+	Avatar     *string    `gorm:"size:255" json:"avatar,omitempty"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	// Status, with the reason and expiry of a suspension or deactivation
+	Status       AccountStatus `gorm:"type:varchar(30);not null;default:'active';index" json:"status"`
+	StatusReason string        `gorm:"size:500" json:"status_reason,omitempty"`
+	StatusUntil  *time.Time    `json:"status_until,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	// DeletedAt is set while the account is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 // TableName specifies the table name for User
@@ -30,7 +54,24 @@ func (User) TableName() string {
 	return "users"
 }
 
-// IsValid checks if the role is valid
+// IsVerified reports whether the user has confirmed their email address
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+// IsBlocked reports whether the account is suspended or deactivated at
+// now. An expired suspension no longer blocks, even before it is lifted.
+func (u *User) IsBlocked(now time.Time) bool {
+	switch u.Status {
+	case AccountSuspended:
+		return u.StatusUntil == nil || now.Before(*u.StatusUntil)
+	case AccountDeactivated:
+		return true
+	}
+	return false
+}
+
+// IsValid checks if the role is one of the built-in roles
 func (r UserRole) IsValid() bool {
 	switch r {
 	case RoleStudent, RoleTeacher, RoleAdmin:
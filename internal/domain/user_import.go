@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// UserImportStatus tracks a bulk user import through the worker
+type UserImportStatus string
+
+const (
+	UserImportPending    UserImportStatus = "pending"
+	UserImportProcessing UserImportStatus = "processing"
+	UserImportCompleted  UserImportStatus = "completed"
+)
+
+// UserImport is a CSV of accounts created in the background. Its rows are
+// validated before the import is queued.
+type UserImport struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	CreatedBy   uint             `json:"created_by" gorm:"not null;index"`
+	FileName    string           `json:"file_name" gorm:"type:varchar(255)"`
+	Status      UserImportStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	SendWelcome bool             `json:"send_welcome" gorm:"not null;default:false"`
+	Total       int              `json:"total" gorm:"not null;default:0"`
+	Created     int              `json:"created" gorm:"not null;default:0"`
+	Failed      int              `json:"failed" gorm:"not null;default:0"`
+	Enrolled    int              `json:"enrolled" gorm:"not null;default:0"`
+	CreatedAt   time.Time        `json:"created_at"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+
+	Rows []UserImportRow `json:"rows,omitempty" gorm:"foreignKey:ImportID;constraint:OnDelete:CASCADE"`
+}
+
+func (UserImport) TableName() string {
+	return "user_imports"
+}
+
+// UserImportRowStatus is the outcome of one imported account
+type UserImportRowStatus string
+
+const (
+	UserImportRowPending UserImportRowStatus = "pending"
+	UserImportRowCreated UserImportRowStatus = "created"
+	UserImportRowFailed  UserImportRowStatus = "failed"
+)
+
+// UserImportRow is one account of a bulk import. Line is the row's line
+// number in the uploaded file.
+type UserImportRow struct {
+	ID       uint     `json:"id" gorm:"primaryKey"`
+	ImportID uint     `json:"import_id" gorm:"not null;index"`
+	Line     int      `json:"line" gorm:"not null"`
+	Name     string   `json:"name" gorm:"size:100;not null"`
+	Email    string   `json:"email" gorm:"size:100;not null"`
+	Role     UserRole `json:"role" gorm:"type:varchar(50);not null"`
+	// CourseIDs are the courses to enroll the account in, separated by ";"
+	CourseIDs string              `json:"course_ids,omitempty" gorm:"type:varchar(255)"`
+	Status    UserImportRowStatus `json:"status" gorm:"type:varchar(20);not null"`
+	UserID    *uint               `json:"user_id,omitempty"`
+	// Error explains a failed row, or enrollments that failed for a
+	// created account
+	Error string `json:"error,omitempty" gorm:"type:text"`
+}
+
+func (UserImportRow) TableName() string {
+	return "user_import_rows"
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// TokenPurpose identifies what a single-use user token is for
+type TokenPurpose string
+
+const (
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeMFAChallenge      TokenPurpose = "mfa_challenge"
+	TokenPurposeSSOLogin          TokenPurpose = "sso_login"
+)
+
+// UserToken is a single-use, expiring token sent to a user out of band
+// (e.g. by email). Only the SHA-256 hash of the token is stored.
+type UserToken struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	UserID    uint         `json:"user_id" gorm:"not null;index:idx_user_tokens_user_purpose,priority:1"`
+	Purpose   TokenPurpose `json:"purpose" gorm:"type:varchar(30);not null;index:idx_user_tokens_user_purpose,priority:2"`
+	TokenHash string       `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time    `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time   `json:"used_at,omitempty"`
+	Attempts  int          `json:"attempts" gorm:"not null;default:0"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
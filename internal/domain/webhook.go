@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookEvent is the name of a platform event delivered to webhooks
+type WebhookEvent string
+
+const (
+	WebhookEventEnrollmentCreated   WebhookEvent = "enrollment.created"
+	WebhookEventEnrollmentCompleted WebhookEvent = "enrollment.completed"
+	WebhookEventCoursePublished     WebhookEvent = "course.published"
+	WebhookEventUserCreated         WebhookEvent = "user.created"
+	WebhookEventOrderPaid           WebhookEvent = "order.paid"
+	WebhookEventOrderRefunded       WebhookEvent = "order.refunded"
+	WebhookEventSubscriptionChanged WebhookEvent = "subscription.changed"
+)
+
+// IsValid checks if the event is a known webhook event
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookEventEnrollmentCreated, WebhookEventEnrollmentCompleted,
+		WebhookEventCoursePublished, WebhookEventUserCreated,
+		WebhookEventOrderPaid, WebhookEventOrderRefunded,
+		WebhookEventSubscriptionChanged:
+		return true
+	}
+	return false
+}
+
+// Webhook is an admin-managed subscription to platform events
+type Webhook struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	URL         string         `json:"url" gorm:"type:varchar(500);not null"`
+	Secret      string         `json:"-" gorm:"type:varchar(100);not null"`
+	Events      pq.StringArray `json:"events" gorm:"type:text[];not null"`
+	Description string         `json:"description" gorm:"type:varchar(255)"`
+	IsActive    bool           `json:"is_active" gorm:"not null;default:true"`
+	CreatedBy   uint           `json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes reports whether the webhook wants the given event
+func (w *Webhook) Subscribes(event WebhookEvent) bool {
+	for _, e := range w.Events {
+		if WebhookEvent(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus represents the state of a delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent (or to be sent) to one webhook
+type WebhookDelivery struct {
+	ID            uint                  `json:"id" gorm:"primaryKey"`
+	WebhookID     uint                  `json:"webhook_id" gorm:"not null;index:idx_webhook_deliveries_webhook"`
+	EventID       string                `json:"event_id" gorm:"type:varchar(64);not null"`
+	Event         WebhookEvent          `json:"event" gorm:"type:varchar(50);not null"`
+	Payload       string                `json:"payload" gorm:"type:text;not null"`
+	Status        WebhookDeliveryStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index:idx_webhook_deliveries_due,priority:1"`
+	Attempts      int                   `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time             `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt *time.Time            `json:"last_attempt_at,omitempty"`
+	ResponseCode  int                   `json:"response_code"`
+	ResponseBody  string                `json:"response_body,omitempty" gorm:"type:text"`
+	Error         string                `json:"error,omitempty" gorm:"type:text"`
+	RedeliveryOf  *uint                 `json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
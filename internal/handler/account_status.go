@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// AccountStatusHandler lets admins suspend, deactivate and reinstate
+// accounts
+type AccountStatusHandler struct {
+	accountService *service.AccountStatusService
+}
+
+// NewAccountStatusHandler creates a new account status handler
+func NewAccountStatusHandler(accountService *service.AccountStatusService) *AccountStatusHandler {
+	return &AccountStatusHandler{accountService: accountService}
+}
+
+// Suspend locks a user out, optionally until a given time
+// @Summary Suspend a user
+// @Description The user is logged out everywhere and refused until the suspension ends or they are reinstated, and is notified by email
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "User ID"
+// @Param request body service.SuspendRequest true "Reason and optional end"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{user_id}/suspend [post]
+func (h *AccountStatusHandler) Suspend(c *gin.Context) {
+	var req service.SuspendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.change(c, func(adminID, userID uint) (*domain.User, error) {
+		return h.accountService.Suspend(c.Request.Context(), adminID, userID, req)
+	})
+}
+
+// Deactivate locks a user out until they are reinstated
+// @Summary Deactivate a user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "User ID"
+// @Param request body service.DeactivateRequest true "Reason"
+// @Success 200 {object} domain.User
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/users/{user_id}/deactivate [post]
+func (h *AccountStatusHandler) Deactivate(c *gin.Context) {
+	var req service.DeactivateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.change(c, func(adminID, userID uint) (*domain.User, error) {
+		return h.accountService.Deactivate(c.Request.Context(), adminID, userID, req)
+	})
+}
+
+// Reinstate lifts a suspension or deactivation
+// @Summary Reinstate a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "User ID"
+// @Success 200 {object} domain.User
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/users/{user_id}/reinstate [post]
+func (h *AccountStatusHandler) Reinstate(c *gin.Context) {
+	h.change(c, func(adminID, userID uint) (*domain.User, error) {
+		return h.accountService.Reinstate(c.Request.Context(), adminID, userID)
+	})
+}
+
+func (h *AccountStatusHandler) change(c *gin.Context, apply func(adminID, userID uint) (*domain.User, error)) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	user, err := apply(claims.UserID, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	middleware.SetAuditChange(c, 0, nil, gin.H{
+		"status":        user.Status,
+		"status_reason": user.StatusReason,
+		"status_until":  user.StatusUntil,
+	})
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AccountStatusHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSuspensionInPast):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOwnAccountStatus):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAccountNotBlocked),
+		errors.Is(err, service.ErrAlreadyDeactivated):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change account status"})
+	}
+}
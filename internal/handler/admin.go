@@ -40,7 +40,7 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 		Name     string `json:"name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=6"`
-		Role     string `json:"role" binding:"required,oneof=student teacher admin"`
+		Role     string `json:"role" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -52,26 +52,25 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	middleware.SetAuditChange(c, user.ID, nil, user)
 
 	// Send notifications (best-effort)
-	if h.notifClient != nil {
-		ctx := c.Request.Context()
+	ctx := c.Request.Context()
 
-		// Notify the created user (optional)
-		if err := h.notifClient.SendNotification(ctx, int64(user.ID), string(domain.NotificationTypeCompleted), "Account Created", "Your account has been created by an admin."); err != nil {
-			c.Header("X-Notif-User-Error", err.Error())
-		}
+	// Notify the created user (optional)
+	if err := h.notifClient.SendNotification(ctx, int64(user.ID), string(domain.NotificationTypeCompleted), "Account Created", "Your account has been created by an admin."); err != nil {
+		c.Header("X-Notif-User-Error", err.Error())
+	}
 
-		// Notify the acting admin (from JWT claims)
-		claims, err := middleware.GetCurrentUser(c)
-		if err == nil {
-			if err := h.notifClient.SendNotification(ctx, int64(claims.UserID), string(domain.NotificationTypeCompleted), "User Created", fmt.Sprintf("You created user ID %d.", user.ID)); err != nil {
-				c.Header("X-Notif-Admin-Error", err.Error())
-			}
-		} else {
-			// expose reason we couldn't notify admin
-			c.Header("X-Notif-Admin-Error", "missing auth claims: "+err.Error())
+	// Notify the acting admin (from JWT claims)
+	claims, err := middleware.GetCurrentUser(c)
+	if err == nil {
+		if err := h.notifClient.SendNotification(ctx, int64(claims.UserID), string(domain.NotificationTypeCompleted), "User Created", fmt.Sprintf("You created user ID %d.", user.ID)); err != nil {
+			c.Header("X-Notif-Admin-Error", err.Error())
 		}
+	} else {
+		// expose reason we couldn't notify admin
+		c.Header("X-Notif-Admin-Error", "missing auth claims: "+err.Error())
 	}
 
 	c.JSON(http.StatusCreated, user)
@@ -86,27 +85,34 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 	var body struct {
 		Name  string `json:"name" binding:"required"`
 		Email string `json:"email" binding:"required,email"`
-		Role  string `json:"role" binding:"required,oneof=student teacher admin"`
+		Role  string `json:"role" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	before, err := h.userService.GetProfile(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
 	if err := h.userService.UpdateUser(uint(userID), body.Name, body.Email, body.Role); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if after, err := h.userService.GetProfile(uint(userID)); err == nil {
+		middleware.SetAuditChange(c, 0, before, after)
+	}
 
-	if h.notifClient != nil {
-		ctx := c.Request.Context()
-		if err := h.notifClient.SendNotification(ctx, int64(userID), string(domain.NotificationTypeCompleted), "Account Updated", "Your account has been updated by an admin."); err != nil {
-			c.Header("X-Notif-User-Error", err.Error())
-		}
-		if claims, err := middleware.GetCurrentUser(c); err == nil {
-			if err := h.notifClient.SendNotification(ctx, int64(claims.UserID), string(domain.NotificationTypeCompleted), "User Updated", fmt.Sprintf("You updated user ID %d.", userID)); err != nil {
-				c.Header("X-Notif-Admin-Error", err.Error())
-			}
+	ctx := c.Request.Context()
+	if err := h.notifClient.SendNotification(ctx, int64(userID), string(domain.NotificationTypeCompleted), "Account Updated", "Your account has been updated by an admin."); err != nil {
+		c.Header("X-Notif-User-Error", err.Error())
+	}
+	if claims, err := middleware.GetCurrentUser(c); err == nil {
+		if err := h.notifClient.SendNotification(ctx, int64(claims.UserID), string(domain.NotificationTypeCompleted), "User Updated", fmt.Sprintf("You updated user ID %d.", userID)); err != nil {
+			c.Header("X-Notif-Admin-Error", err.Error())
 		}
 	}
 
@@ -120,20 +126,25 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	before, err := h.userService.GetProfile(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
 	if err := h.userService.DeleteUser(uint(userID)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	middleware.SetAuditChange(c, 0, before, nil)
 
-	if h.notifClient != nil {
-		ctx := c.Request.Context()
-		if claims, err := middleware.GetCurrentUser(c); err == nil {
-			if err := h.notifClient.SendNotification(ctx, int64(claims.UserID), string(domain.NotificationTypeCompleted), "User Deleted", fmt.Sprintf("You deleted user ID %d.", userID)); err != nil {
-				c.Header("X-Notif-Admin-Error", err.Error())
-			}
-		} else {
-			c.Header("X-Notif-Admin-Error", "missing auth claims: "+err.Error())
+	ctx := c.Request.Context()
+	if claims, err := middleware.GetCurrentUser(c); err == nil {
+		if err := h.notifClient.SendNotification(ctx, int64(claims.UserID), string(domain.NotificationTypeCompleted), "User Deleted", fmt.Sprintf("You deleted user ID %d.", userID)); err != nil {
+			c.Header("X-Notif-Admin-Error", err.Error())
 		}
+	} else {
+		c.Header("X-Notif-Admin-Error", "missing auth claims: "+err.Error())
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
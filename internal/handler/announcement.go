@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/service"
+)
+
+// AnnouncementHandler handles course announcement requests
+type AnnouncementHandler struct {
+	service *service.AnnouncementService
+}
+
+// NewAnnouncementHandler creates a new announcement handler
+func NewAnnouncementHandler(service *service.AnnouncementService) *AnnouncementHandler {
+	return &AnnouncementHandler{service: service}
+}
+
+// Create posts an announcement to every student enrolled in the course
+// @Summary Post a course announcement
+// @Description Teacher broadcasts a message to all active students of a course
+// @Tags announcements
+// @Accept json
+// @Produce json
+// @Param course_id path int true "Course ID"
+// @Param request body service.CreateAnnouncementRequest true "Announcement"
+// @Security BearerAuth
+// @Success 201 {object} domain.Announcement
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /courses/{course_id}/announcements [post]
+func (h *AnnouncementHandler) Create(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	course, ok := middleware.GetCourseFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "course not found"})
+		return
+	}
+
+	var req service.CreateAnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	announcement, err := h.service.Create(course, claims.UserID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to post announcement"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, announcement)
+}
+
+// List returns the announcement feed of a course
+// @Summary Get course announcements
+// @Description Get announcements of a course (enrolled students, course teacher, admins)
+// @Tags announcements
+// @Produce json
+// @Param course_id path int true "Course ID"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Security BearerAuth
+// @Success 200 {array} domain.Announcement
+// @Failure 403 {object} ErrorResponse
+// @Router /courses/{course_id}/announcements [get]
+func (h *AnnouncementHandler) List(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	courseID, err := strconv.ParseUint(c.Param("course_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid course id"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	announcements, total, err := h.service.List(uint(courseID), claims.UserID, middleware.GrantedScope(c) == domain.ScopeAny, page, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrNotCourseMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "course not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get announcements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"announcements": announcements,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+	})
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// APIKeyHandler manages API keys for integrations
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// List returns the current user's API keys
+// @Summary List my API keys
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.APIKey
+// @Router /users/api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	keys, err := h.apiKeyService.List(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Create issues an API key for the current user
+// @Summary Create an API key
+// @Description The key is only returned in this response
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.CreateAPIKeyRequest true "Key name, scopes and lifetime"
+// @Success 201 {object} service.CreateAPIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /users/api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req service.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.apiKeyService.Create(claims.UserID, claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to create api key")
+		return
+	}
+	middleware.SetAuditChange(c, key.ID, nil, key.APIKey)
+	c.JSON(http.StatusCreated, key)
+}
+
+// Revoke revokes one of the current user's API keys
+// @Summary Revoke an API key
+// @Tags users
+// @Param id path int true "API key ID"
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /users/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(claims.UserID, id); err != nil {
+		h.respondError(c, err, "failed to revoke api key")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "api key revoked"})
+}
+
+// ListAll returns every API key
+// @Summary List all API keys
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.APIKey
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) ListAll(c *gin.Context) {
+	keys, err := h.apiKeyService.ListAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateForUser issues an API key owned by another user, e.g. a service
+// account
+// @Summary Create an API key for a user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "User ID"
+// @Param request body service.CreateAPIKeyRequest true "Key name, scopes and lifetime"
+// @Success 201 {object} service.CreateAPIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{user_id}/api-keys [post]
+func (h *APIKeyHandler) CreateForUser(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	var req service.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.apiKeyService.Create(userID, claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to create api key")
+		return
+	}
+	middleware.SetAuditChange(c, key.ID, nil, key.APIKey)
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAny revokes any API key
+// @Summary Revoke an API key
+// @Tags admin
+// @Param id path int true "API key ID"
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAny(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAny(id); err != nil {
+		h.respondError(c, err, "failed to revoke api key")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "api key revoked"})
+}
+
+func (h *APIKeyHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrAPIKeyNotFound),
+		errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAPIScope),
+		errors.Is(err, service.ErrAPIKeyExpiryTooFar),
+		errors.Is(err, service.ErrTooManyAPIKeys):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAdminScopeDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// AuditLogHandler lets admins read and export the audit log
+type AuditLogHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditLogHandler creates a new audit log handler
+func NewAuditLogHandler(auditService *service.AuditService) *AuditLogHandler {
+	return &AuditLogHandler{auditService: auditService}
+}
+
+// List returns audit log entries, newest first
+// @Summary List audit log entries
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "Filter by acting user"
+// @Param action query string false "Filter by action, e.g. user.delete"
+// @Param target_type query string false "Filter by target type, e.g. course"
+// @Param target_id query string false "Filter by target ID"
+// @Param from query string false "Entries at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Entries before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.AuditLog
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/audit-logs [get]
+func (h *AuditLogHandler) List(c *gin.Context) {
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
+	}
+	page, limit := pageParams(c)
+
+	entries, total, err := h.auditService.List(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": entries,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+// Export downloads matching audit log entries with their hashes
+// @Summary Export audit log
+// @Tags admin
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param format query string false "csv (default) or json"
+// @Param actor_id query int false "Filter by acting user"
+// @Param action query string false "Filter by action"
+// @Param target_type query string false "Filter by target type"
+// @Param target_id query string false "Filter by target ID"
+// @Param from query string false "Entries at or after this time"
+// @Param to query string false "Entries before this time"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/audit-logs/export [get]
+func (h *AuditLogHandler) Export(c *gin.Context) {
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	contentType := "text/csv"
+	switch format {
+	case "csv":
+	case "json":
+		contentType = "application/json"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidExportFormat.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only cut the file short
+	if err := h.auditService.Export(filter, format, c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// Verify checks the hash chain of the whole audit log
+// @Summary Verify audit log integrity
+// @Description Recomputes every entry's hash and link to the previous entry
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.AuditVerification
+// @Router /admin/audit-logs/verify [get]
+func (h *AuditLogHandler) Verify(c *gin.Context) {
+	result, err := h.auditService.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// auditLogFilter reads the audit log filter from the query string,
+// responding 400 when it is invalid
+func auditLogFilter(c *gin.Context) (repository.AuditLogFilter, bool) {
+	filter := repository.AuditLogFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_id"})
+			return filter, false
+		}
+		filter.ActorID = uint(id)
+	}
+
+	var err error
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filter, false
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filter, false
+	}
+	return filter, true
+}
+
+// parseTimeQuery parses an optional RFC 3339 timestamp or date
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("invalid " + name + ": use RFC 3339 or YYYY-MM-DD")
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -13,19 +15,27 @@ import (
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	authService service.AuthService
+	authService         service.AuthService
+	verificationService *service.EmailVerificationService
+	resetService        *service.PasswordResetService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService service.AuthService) *AuthHandler {
+func NewAuthHandler(
+	authService service.AuthService,
+	verificationService *service.EmailVerificationService,
+	resetService *service.PasswordResetService,
+) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:         authService,
+		verificationService: verificationService,
+		resetService:        resetService,
 	}
 }
 
 // Register handles user registration
 // @Summary Register a new user
-// @Description Register a new user with email and password
+// @Description Register a new student account with email and password
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -41,16 +51,14 @@ func (h *AuthHandler) Register(ctx *gin.Context) {
 		return
 	}
 
+	req.Client = clientInfo(ctx)
+
 	resp, err := h.authService.Register(req)
 	if err != nil {
 		if errors.Is(err, repository.ErrEmailAlreadyExists) {
 			ctx.JSON(http.StatusConflict, ErrorResponse{Error: "email already exists"})
 			return
 		}
-		if errors.Is(err, service.ErrInvalidRole) {
-			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid role"})
-			return
-		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to register user"})
 		return
 	}
@@ -68,6 +76,8 @@ func (h *AuthHandler) Register(ctx *gin.Context) {
 // @Success 200 {object} service.AuthResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(ctx *gin.Context) {
 	var req service.LoginRequest
@@ -75,13 +85,25 @@ func (h *AuthHandler) Login(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
+	req.Client = clientInfo(ctx)
 
 	resp, err := h.authService.Login(req)
 	if err != nil {
+		var blocked *service.LoginBlockedError
+		if errors.As(err, &blocked) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, ErrorResponse{Error: blocked.Error()})
+			return
+		}
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid email or password"})
 			return
 		}
+		var accountBlocked *service.AccountBlockedError
+		if errors.Is(err, service.ErrEmailNotVerified) || errors.As(err, &accountBlocked) {
+			ctx.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to login"})
 		return
 	}
@@ -149,11 +171,127 @@ func (h *AuthHandler) Logout(ctx *gin.Context) {
 	})
 }
 
+// VerifyEmail confirms a user's email address
+// @Summary Verify email
+// @Description Redeem the token from a verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
+	var req service.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.verificationService.Verify(req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidVerifyToken) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to verify email"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, MessageResponse{Message: "email verified"})
+}
+
+// ResendVerification sends a new verification email
+// @Summary Resend verification email
+// @Description Always responds the same way, whether or not the email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.ResendVerificationRequest true "Email"
+// @Success 202 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(ctx *gin.Context) {
+	var req service.ResendVerificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.verificationService.Resend(ctx.Request.Context(), req.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to send verification email"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, MessageResponse{
+		Message: "if the address belongs to an unverified account, a verification email has been sent",
+	})
+}
+
+// ForgotPassword starts the password reset flow
+// @Summary Forgot password
+// @Description Email a reset link. Always responds the same way, whether or not the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.ForgotPasswordRequest true "Email"
+// @Success 202 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(ctx *gin.Context) {
+	var req service.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Processed in the background so the response time does not reveal
+	// whether the address belongs to an account
+	h.resetService.Enqueue(req.Email)
+
+	ctx.JSON(http.StatusAccepted, MessageResponse{
+		Message: "if the address belongs to an account, a password reset email has been sent",
+	})
+}
+
+// ResetPassword sets a new password with a reset token
+// @Summary Reset password
+// @Description Set a new password using the token from a reset email. Signs the user out everywhere.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(ctx *gin.Context) {
+	var req service.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.resetService.ResetPassword(ctx.Request.Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) || errors.Is(err, service.ErrPasswordTooShort) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to reset password"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, MessageResponse{Message: "password has been reset; please log in again"})
+}
+
 // ErrorResponse represents error response
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// MessageResponse represents a plain confirmation message
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
 // LogoutResponse represents logout response
 type LogoutResponse struct {
 	Message string `json:"message"`
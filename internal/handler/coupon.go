@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// CouponHandler lets admins and teachers manage promo codes. Teachers see
+// and manage only their own coupons.
+type CouponHandler struct {
+	couponService *service.CouponService
+}
+
+// NewCouponHandler creates a new coupon handler
+func NewCouponHandler(couponService *service.CouponService) *CouponHandler {
+	return &CouponHandler{couponService: couponService}
+}
+
+// Create adds a coupon
+// @Summary Create a coupon
+// @Description Teachers must restrict their coupons to courses they own
+// @Tags coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.CouponRequest true "Coupon"
+// @Success 201 {object} domain.Coupon
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /coupons [post]
+func (h *CouponHandler) Create(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req service.CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coupon, err := h.couponService.Create(claims.UserID, middleware.GrantedScope(c) == domain.ScopeAny, req)
+	if err != nil {
+		h.respondError(c, err, "failed to create coupon")
+		return
+	}
+	middleware.SetAuditChange(c, coupon.ID, nil, coupon)
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// List returns coupons
+// @Summary List coupons
+// @Tags coupons
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Part of the code"
+// @Param active query bool false "Active or inactive coupons only"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Coupon
+// @Router /coupons [get]
+func (h *CouponHandler) List(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := repository.CouponFilter{Query: c.Query("q")}
+	if v := c.Query("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid active"})
+			return
+		}
+		filter.Active = &active
+	}
+	page, limit := pageParams(c)
+
+	coupons, total, err := h.couponService.List(claims.UserID, middleware.GrantedScope(c) == domain.ScopeAny, filter, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get coupons")
+		return
+	}
+	if coupons == nil {
+		coupons = []domain.Coupon{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coupons": coupons,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// Get returns a coupon
+// @Summary Get a coupon
+// @Tags coupons
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} domain.Coupon
+// @Failure 404 {object} ErrorResponse
+// @Router /coupons/{id} [get]
+func (h *CouponHandler) Get(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	coupon, err := h.couponService.Get(id, claims.UserID, middleware.GrantedScope(c) == domain.ScopeAny)
+	if err != nil {
+		h.respondError(c, err, "failed to get coupon")
+		return
+	}
+	c.JSON(http.StatusOK, coupon)
+}
+
+// Update replaces a coupon's settings
+// @Summary Update a coupon
+// @Description Set active to false to stop a coupon from being used
+// @Tags coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Param request body service.CouponRequest true "Coupon"
+// @Success 200 {object} domain.Coupon
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /coupons/{id} [put]
+func (h *CouponHandler) Update(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req service.CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	anyCoupon := middleware.GrantedScope(c) == domain.ScopeAny
+	before, err := h.couponService.Get(id, claims.UserID, anyCoupon)
+	if err != nil {
+		h.respondError(c, err, "failed to update coupon")
+		return
+	}
+	coupon, err := h.couponService.Update(id, claims.UserID, anyCoupon, req)
+	if err != nil {
+		h.respondError(c, err, "failed to update coupon")
+		return
+	}
+	middleware.SetAuditChange(c, 0, before, coupon)
+	c.JSON(http.StatusOK, coupon)
+}
+
+// Delete removes a coupon that was never redeemed
+// @Summary Delete a coupon
+// @Tags coupons
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /coupons/{id} [delete]
+func (h *CouponHandler) Delete(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	coupon, err := h.couponService.Delete(id, claims.UserID, middleware.GrantedScope(c) == domain.ScopeAny)
+	if err != nil {
+		h.respondError(c, err, "failed to delete coupon")
+		return
+	}
+	middleware.SetAuditChange(c, 0, coupon, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// ListRedemptions returns the paid orders a coupon was used on
+// @Summary List coupon redemptions
+// @Tags coupons
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.CouponRedemption
+// @Failure 404 {object} ErrorResponse
+// @Router /coupons/{id}/redemptions [get]
+func (h *CouponHandler) ListRedemptions(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	page, limit := pageParams(c)
+
+	redemptions, total, err := h.couponService.ListRedemptions(id, claims.UserID, middleware.GrantedScope(c) == domain.ScopeAny, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get redemptions")
+		return
+	}
+	if redemptions == nil {
+		redemptions = []domain.CouponRedemption{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redemptions": redemptions,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+	})
+}
+
+func (h *CouponHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrCouponNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCoupon):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCouponCoursesNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrCouponCodeExists),
+		errors.Is(err, service.ErrCouponRedeemed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -44,6 +45,10 @@ func (h *CourseHandler) Create(c *gin.Context) {
 	}
 
 	if err := h.service.Create(&course); err != nil {
+		if errors.Is(err, service.ErrInvalidThumbnail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create course"})
 		return
 	}
@@ -101,25 +106,33 @@ func (h *CourseHandler) Update(c *gin.Context) {
 		return
 	}
 
+	before := *course
 	course.Title = req.Title
 	course.Description = req.Description
 	course.Thumbnail = req.Thumbnail
 	course.CategoryID = req.CategoryID
 
 	if err := h.service.Update(course); err != nil {
+		if errors.Is(err, service.ErrInvalidThumbnail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update course"})
 		return
 	}
+	middleware.SetAuditChange(c, 0, before, course)
 
 	c.JSON(http.StatusOK, course)
 }
 
 func (h *CourseHandler) Delete(c *gin.Context) {
 	id, _ := strconv.ParseInt(c.Param("course_id"), 10, 64)
+	course, _ := middleware.GetCourseFromContext(c)
 	if err := h.service.Delete(id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete course"})
 		return
 	}
+	middleware.SetAuditChange(c, 0, course, nil)
 	c.Status(http.StatusNoContent)
 }
 
@@ -157,3 +170,40 @@ func (h *CourseHandler) Publish(c *gin.Context) {
 		"is_published": publishState,
 	})
 }
+
+// SetPricing sets the price of a course
+// @Summary Set course pricing
+// @Description Marks a course free or sets its price in the currency's minor unit
+// @Tags courses
+// @Accept json
+// @Produce json
+// @Param course_id path int true "Course ID"
+// @Param request body service.CoursePricing true "Pricing"
+// @Security BearerAuth
+// @Success 200 {object} domain.Course
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /courses/{course_id}/pricing [put]
+func (h *CourseHandler) SetPricing(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("course_id"), 10, 64)
+
+	var req service.CoursePricing
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, _ := middleware.GetCourseFromContext(c)
+	course, err := h.service.SetPricing(id, req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPricing) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update course pricing"})
+		return
+	}
+	middleware.SetAuditChange(c, 0, before, course)
+
+	c.JSON(http.StatusOK, course)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// CourseStaffHandler manages co-instructors and teaching assistants
+type CourseStaffHandler struct {
+	staffService *service.CourseStaffService
+}
+
+// NewCourseStaffHandler creates a new course staff handler
+func NewCourseStaffHandler(staffService *service.CourseStaffService) *CourseStaffHandler {
+	return &CourseStaffHandler{staffService: staffService}
+}
+
+// List returns the course's owner, staff and pending invitations
+// @Summary List course staff
+// @Tags courses
+// @Produce json
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Success 200 {array} domain.CourseStaff
+// @Router /courses/{course_id}/staff [get]
+func (h *CourseStaffHandler) List(c *gin.Context) {
+	course, ok := middleware.GetCourseFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "course not loaded"})
+		return
+	}
+
+	staff, err := h.staffService.List(course)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get course staff"})
+		return
+	}
+	c.JSON(http.StatusOK, staff)
+}
+
+// Invite invites a user to the course's staff
+// @Summary Invite a co-instructor or TA
+// @Description The user must already have an account; the role is co_instructor or ta
+// @Tags courses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Param request body service.InviteStaffRequest true "Invitee email and staff role"
+// @Success 201 {object} domain.CourseStaff
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /courses/{course_id}/staff [post]
+func (h *CourseStaffHandler) Invite(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	course, ok := middleware.GetCourseFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "course not loaded"})
+		return
+	}
+
+	var req service.InviteStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	staff, err := h.staffService.Invite(course, claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to invite staff")
+		return
+	}
+	c.JSON(http.StatusCreated, staff)
+}
+
+// UpdateRole changes a staff member's role
+// @Summary Change a staff member's role
+// @Tags courses
+// @Accept json
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Param user_id path int true "User ID"
+// @Param request body service.UpdateStaffRoleRequest true "New staff role"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /courses/{course_id}/staff/{user_id} [put]
+func (h *CourseStaffHandler) UpdateRole(c *gin.Context) {
+	course, ok := middleware.GetCourseFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "course not loaded"})
+		return
+	}
+	userID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	var req service.UpdateStaffRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.staffService.UpdateRole(course, userID, req.Role); err != nil {
+		h.respondError(c, err, "failed to update staff role")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "staff role updated"})
+}
+
+// Remove removes a staff member or withdraws an invitation
+// @Summary Remove a staff member
+// @Tags courses
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /courses/{course_id}/staff/{user_id} [delete]
+func (h *CourseStaffHandler) Remove(c *gin.Context) {
+	course, ok := middleware.GetCourseFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "course not loaded"})
+		return
+	}
+	userID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	if err := h.staffService.Remove(course, userID); err != nil {
+		h.respondError(c, err, "failed to remove staff")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "staff member removed"})
+}
+
+// Accept accepts the current user's invitation to the course's staff
+// @Summary Accept a staff invitation
+// @Tags courses
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /courses/{course_id}/staff/accept [post]
+func (h *CourseStaffHandler) Accept(c *gin.Context) {
+	h.answer(c, h.staffService.Accept, "invitation accepted")
+}
+
+// Leave declines the current user's invitation or leaves the course's staff
+// @Summary Decline a staff invitation or leave the staff
+// @Tags courses
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /courses/{course_id}/staff/leave [post]
+func (h *CourseStaffHandler) Leave(c *gin.Context) {
+	h.answer(c, h.staffService.Leave, "left the course staff")
+}
+
+// ListInvitations returns the current user's pending staff invitations
+// @Summary List my staff invitations
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.CourseStaff
+// @Router /users/staff-invitations [get]
+func (h *CourseStaffHandler) ListInvitations(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitations, err := h.staffService.ListInvitations(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get invitations"})
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (h *CourseStaffHandler) answer(c *gin.Context, fn func(courseID, userID uint) error, message string) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	courseID, err := strconv.ParseUint(c.Param("course_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid course id"})
+		return
+	}
+
+	if err := fn(uint(courseID), claims.UserID); err != nil {
+		h.respondError(c, err, "failed to update invitation")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: message})
+}
+
+func (h *CourseStaffHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrStaffNotFound),
+		errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidStaffRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAlreadyStaff),
+		errors.Is(err, service.ErrInviteOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -55,7 +55,7 @@ func (h *DashboardHandler) GetStudentDashboard(c *gin.Context) {
 
 // GetTeacherDashboard godoc
 // @Summary Get teacher dashboard
-// @Description Get teacher dashboard with courses, total students, and recent enrollments
+// @Description Get teacher dashboard with courses, total students, recent enrollments, and earnings from the revenue share
 // @Tags dashboard
 // @Accept json
 // @Produce json
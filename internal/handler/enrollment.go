@@ -34,6 +34,7 @@ func NewEnrollmentHandler(service *service.EnrollmentService) *EnrollmentHandler
 // @Success 201 {object} domain.Enrollment
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse
 // @Router /courses/{course_id}/enroll [post]
 func (h *EnrollmentHandler) Enroll(c *gin.Context) {
@@ -59,10 +60,18 @@ func (h *EnrollmentHandler) Enroll(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "course is not published"})
 			return
 		}
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "verify your email address before enrolling"})
+			return
+		}
 		if errors.Is(err, service.ErrCannotEnrollInOwnCourse) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot enroll in your own course"})
 			return
 		}
+		if errors.Is(err, service.ErrPaymentRequired) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+			return
+		}
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "course not found"})
 			return
@@ -99,7 +108,7 @@ func (h *EnrollmentHandler) Unenroll(c *gin.Context) {
 	}
 
 	if err := h.service.Unenroll(claims.UserID, uint(courseID)); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repository.ErrNotEnrolled) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "enrollment not found"})
 			return
 		}
@@ -150,28 +159,14 @@ func (h *EnrollmentHandler) GetMyEnrollments(c *gin.Context) {
 // @Failure 403 {object} ErrorResponse
 // @Router /courses/{course_id}/enrollments [get]
 func (h *EnrollmentHandler) GetCourseEnrollments(c *gin.Context) {
-	claims, err := middleware.GetCurrentUser(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
-
 	courseID, err := strconv.ParseUint(c.Param("course_id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid course id"})
 		return
 	}
 
-	enrollments, err := h.service.GetCourseEnrollments(uint(courseID), claims.UserID)
+	enrollments, err := h.service.GetCourseEnrollments(uint(courseID))
 	if err != nil {
-		if err.Error() == "not authorized to view enrollments for this course" {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "course not found"})
-			return
-		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get enrollments"})
 		return
 	}
@@ -232,6 +227,8 @@ func (h *EnrollmentHandler) GetEnrollmentStatus(c *gin.Context) {
 // @Security BearerAuth
 // @Success 200 {object} map[string]bool
 // @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Router /enrollments/{enrollment_id}/progress [put]
 func (h *EnrollmentHandler) UpdateProgress(c *gin.Context) {
 	enrollmentID, err := strconv.ParseUint(c.Param("enrollment_id"), 10, 32)
@@ -250,6 +247,14 @@ func (h *EnrollmentHandler) UpdateProgress(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateProgress(uint(enrollmentID), req.Progress); err != nil {
+		if errors.Is(err, service.ErrEnrollmentNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "enrollment not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update progress"})
 		return
 	}
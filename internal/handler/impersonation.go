@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// ImpersonationHandler lets admins act as another user for support
+type ImpersonationHandler struct {
+	impersonationService *service.ImpersonationService
+}
+
+// NewImpersonationHandler creates a new impersonation handler
+func NewImpersonationHandler(impersonationService *service.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{impersonationService: impersonationService}
+}
+
+// Start issues a short-lived token acting as a user
+// @Summary Impersonate a user
+// @Description Returns a short-lived token for the user that also names the admin in its act claim. Deleting data and managing credentials are refused with it, and every request made with it is recorded.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "User ID"
+// @Param request body service.ImpersonateRequest true "Reason for the impersonation"
+// @Success 201 {object} service.ImpersonationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{user_id}/impersonate [post]
+func (h *ImpersonationHandler) Start(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	var req service.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.impersonationService.Start(claims, userID, req, clientInfo(c))
+	if err != nil {
+		h.respondError(c, err, "failed to start impersonation")
+		return
+	}
+	middleware.SetAuditChange(c, 0, nil, resp.Impersonation)
+	c.JSON(http.StatusCreated, resp)
+}
+
+// End revokes the current impersonation token
+// @Summary Stop impersonating
+// @Tags auth
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/impersonation/end [post]
+func (h *ImpersonationHandler) End(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.impersonationService.End(claims); err != nil {
+		h.respondError(c, err, "failed to end impersonation")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "impersonation ended"})
+}
+
+// List returns past and active impersonations
+// @Summary List impersonations
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param admin_id query int false "Filter by admin"
+// @Param user_id query int false "Filter by impersonated user"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Impersonation
+// @Router /admin/impersonations [get]
+func (h *ImpersonationHandler) List(c *gin.Context) {
+	adminID, _ := strconv.ParseUint(c.Query("admin_id"), 10, 32)
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	page, limit := pageParams(c)
+
+	filter := repository.ImpersonationFilter{AdminID: uint(adminID), UserID: uint(userID)}
+	impersonations, total, err := h.impersonationService.List(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get impersonations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"impersonations": impersonations,
+		"total":          total,
+		"page":           page,
+		"limit":          limit,
+	})
+}
+
+// ListRequests returns the requests made during an impersonation
+// @Summary List impersonated requests
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Impersonation ID"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.ImpersonationRequest
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/impersonations/{id}/requests [get]
+func (h *ImpersonationHandler) ListRequests(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	page, limit := pageParams(c)
+
+	requests, total, err := h.impersonationService.ListRequests(id, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get impersonated requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+func (h *ImpersonationHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound),
+		errors.Is(err, repository.ErrImpersonationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSelfImpersonation),
+		errors.Is(err, service.ErrNotImpersonating):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNestedImpersonation),
+		errors.Is(err, service.ErrImpersonationDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// pageParams reads the page and limit query parameters, defaulting to the
+// first 20 results
+func pageParams(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
@@ -2,16 +2,15 @@ package handler
 
 import (
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"elearning/internal/domain"
+	"elearning/internal/middleware"
 	"elearning/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -20,10 +19,11 @@ import (
 
 type LessonHandler struct {
 	service service.LessonServiceInterface // INTERFACE
+	media   *service.LessonMedia
 }
 
-func NewLessonHandler(service service.LessonServiceInterface) *LessonHandler {
-	return &LessonHandler{service}
+func NewLessonHandler(service service.LessonServiceInterface, media *service.LessonMedia) *LessonHandler {
+	return &LessonHandler{service, media}
 }
 
 func (h *LessonHandler) Create(c *gin.Context) {
@@ -77,7 +77,11 @@ func (h *LessonHandler) Create(c *gin.Context) {
 			return
 		}
 
-		filename := fmt.Sprintf("videos/%d_%d%s", courseID, time.Now().Unix(), ext)
+		filename, err := service.NewUploadName("videos", ext)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save video"})
+			return
+		}
 		fullPath := "uploads/" + filename
 		if err := c.SaveUploadedFile(videoFile, fullPath); err != nil {
 			log.Printf("Failed to save video file: %v", err)
@@ -109,7 +113,11 @@ func (h *LessonHandler) Create(c *gin.Context) {
 			return
 		}
 
-		filename := fmt.Sprintf("files/%d_%d%s", courseID, time.Now().Unix(), ext)
+		filename, err := service.NewUploadName("files", ext)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
+			return
+		}
 		fullPath := "uploads/" + filename
 		if err := c.SaveUploadedFile(pdfFile, fullPath); err != nil {
 			log.Printf("Failed to save PDF file: %v", err)
@@ -138,14 +146,18 @@ func (h *LessonHandler) Create(c *gin.Context) {
 		return
 	}
 
+	h.media.SignLinks(&lesson)
 	c.JSON(http.StatusCreated, lesson)
 }
 
+// Get returns a lesson of a course the caller has access to (see
+// middleware.ContentAccess)
 func (h *LessonHandler) Get(c *gin.Context) {
+	courseID, _ := strconv.ParseInt(c.Param("course_id"), 10, 64)
 	id, _ := strconv.ParseInt(c.Param("lesson_id"), 10, 64)
 
 	lesson, err := h.service.GetLesson(id)
-	if errors.Is(err, gorm.ErrRecordNotFound) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && int64(lesson.CourseID) != courseID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "lesson not found"})
 		return
 	}
@@ -153,10 +165,17 @@ func (h *LessonHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load lesson"})
 		return
 	}
+	if !middleware.HasContentAccess(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "purchase the course or subscribe to a plan that covers it to view its lessons"})
+		return
+	}
 
+	h.media.SignLinks(lesson)
 	c.JSON(http.StatusOK, lesson)
 }
 
+// ListByCourse returns a course's lessons. Callers without access to the
+// content (see middleware.ContentAccess) get the outline only.
 func (h *LessonHandler) ListByCourse(c *gin.Context) {
 	courseID, _ := strconv.ParseInt(c.Param("course_id"), 10, 64)
 
@@ -165,6 +184,15 @@ func (h *LessonHandler) ListByCourse(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load lessons"})
 		return
 	}
+	for i := range lessons {
+		if middleware.HasContentAccess(c) {
+			h.media.SignLinks(&lessons[i])
+		} else {
+			lessons[i].Content = ""
+			lessons[i].VideoURL = ""
+			lessons[i].FileURL = ""
+		}
+	}
 
 	c.JSON(http.StatusOK, lessons)
 }
@@ -219,14 +247,17 @@ func (h *LessonHandler) Update(c *gin.Context) {
 			}
 
 			// Delete old video file if exists
-			if lesson.VideoURL != "" {
-				oldVideoPath := strings.TrimPrefix(lesson.VideoURL, "/")
+			if oldVideoPath, ok := service.LocalUploadPath(lesson.VideoURL); ok {
 				if err := os.Remove(oldVideoPath); err != nil && !os.IsNotExist(err) {
 					log.Printf("Failed to delete old video file %s: %v", oldVideoPath, err)
 				}
 			}
 
-			filename := fmt.Sprintf("videos/%d_%d%s", lesson.CourseID, time.Now().Unix(), ext)
+			filename, err := service.NewUploadName("videos", ext)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save video"})
+				return
+			}
 			fullPath := "uploads/" + filename
 			if err := c.SaveUploadedFile(videoFile, fullPath); err != nil {
 				log.Printf("Failed to save video file: %v", err)
@@ -256,14 +287,17 @@ func (h *LessonHandler) Update(c *gin.Context) {
 			}
 
 			// Delete old PDF file if exists
-			if lesson.FileURL != "" {
-				oldFilePath := strings.TrimPrefix(lesson.FileURL, "/")
+			if oldFilePath, ok := service.LocalUploadPath(lesson.FileURL); ok {
 				if err := os.Remove(oldFilePath); err != nil && !os.IsNotExist(err) {
 					log.Printf("Failed to delete old PDF file %s: %v", oldFilePath, err)
 				}
 			}
 
-			filename := fmt.Sprintf("files/%d_%d%s", lesson.CourseID, time.Now().Unix(), ext)
+			filename, err := service.NewUploadName("files", ext)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
+				return
+			}
 			fullPath := "uploads/" + filename
 			if err := c.SaveUploadedFile(pdfFile, fullPath); err != nil {
 				log.Printf("Failed to save PDF file: %v", err)
@@ -296,6 +330,12 @@ func (h *LessonHandler) Update(c *gin.Context) {
 		if body.Content != nil {
 			lesson.Content = *body.Content
 		}
+		for _, url := range []*string{body.VideoURL, body.FileURL} {
+			if url != nil && *url != "" && !service.IsMediaURL(*url) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "media URLs must be uploaded files or http(s) URLs"})
+				return
+			}
+		}
 		if body.VideoURL != nil {
 			lesson.VideoURL = *body.VideoURL
 		}
@@ -309,9 +349,43 @@ func (h *LessonHandler) Update(c *gin.Context) {
 		return
 	}
 
+	h.media.SignLinks(lesson)
 	c.JSON(http.StatusOK, lesson)
 }
 
+// Media serves a lesson's video or file through a signed link from
+// LessonMedia.SignLinks
+func (h *LessonHandler) Media(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("lesson_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "media not found"})
+		return
+	}
+	kind := c.Param("kind")
+	if err := h.media.Verify(uint(id), kind, c.Query("expires"), c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	lesson, err := h.service.GetLesson(int64(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "media not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load lesson"})
+		return
+	}
+	path, ok := h.media.Path(lesson, kind)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "media not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.File(path)
+}
+
 func (h *LessonHandler) Delete(c *gin.Context) {
 	id, _ := strconv.ParseInt(c.Param("lesson_id"), 10, 64)
 
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// LockoutHandler lets admins inspect and clear login lockouts
+type LockoutHandler struct {
+	guard *service.LoginGuard
+}
+
+// NewLockoutHandler creates a new lockout handler
+func NewLockoutHandler(guard *service.LoginGuard) *LockoutHandler {
+	return &LockoutHandler{guard: guard}
+}
+
+// List returns accounts and IPs currently blocked from logging in
+// @Summary List login lockouts
+// @Description Accounts and IPs currently delayed or locked out after failed logins
+// @Tags admin-security
+// @Produce json
+// @Param scope query string false "account or ip"
+// @Security BearerAuth
+// @Success 200 {array} domain.LoginThrottle
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/lockouts [get]
+func (h *LockoutHandler) List(c *gin.Context) {
+	scope := domain.LoginThrottleScope(c.Query("scope"))
+	switch scope {
+	case "", domain.LoginThrottleAccount, domain.LoginThrottleIP:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be account or ip"})
+		return
+	}
+
+	lockouts, err := h.guard.ListLockouts(scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get lockouts"})
+		return
+	}
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// Clear lifts a lockout and resets its failure count
+// @Summary Clear login lockout
+// @Tags admin-security
+// @Param lockout_id path int true "Lockout ID"
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/lockouts/{lockout_id} [delete]
+func (h *LockoutHandler) Clear(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := parseIDParam(c, "lockout_id")
+	if !ok {
+		return
+	}
+
+	if err := h.guard.ClearLockout(id, claims.UserID); err != nil {
+		if errors.Is(err, repository.ErrLoginThrottleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear lockout"})
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "lockout cleared"})
+}
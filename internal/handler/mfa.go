@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/middleware"
+	"elearning/internal/service"
+)
+
+// MFAHandler handles two-factor authentication requests
+type MFAHandler struct {
+	authService service.AuthService
+	mfaService  *service.MFAService
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(authService service.AuthService, mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		authService: authService,
+		mfaService:  mfaService,
+	}
+}
+
+// Verify completes a two-step login
+// @Summary Complete two-factor login
+// @Description Exchange the mfa_token from login plus a TOTP code or a recovery code for an access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.MFAVerifyRequest true "Challenge and code"
+// @Success 200 {object} service.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req service.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	req.Client = clientInfo(c)
+
+	resp, err := h.authService.VerifyMFA(req)
+	if err != nil {
+		h.respondError(c, err, "failed to verify two-factor code")
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Setup starts two-factor enrollment
+// @Summary Start two-factor setup
+// @Description Generate a TOTP secret and provisioning URI to scan with an authenticator app
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.MFASetupResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /auth/mfa/setup [post]
+func (h *MFAHandler) Setup(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	resp, err := h.mfaService.BeginSetup(claims.UserID)
+	if err != nil {
+		h.respondError(c, err, "failed to start two-factor setup")
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Enable confirms two-factor enrollment
+// @Summary Enable two-factor authentication
+// @Description Confirm setup with a code; returns recovery codes (shown once) and a new access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.MFACodeRequest true "Authenticator code"
+// @Security BearerAuth
+// @Success 200 {object} service.EnableMFAResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/mfa/enable [post]
+func (h *MFAHandler) Enable(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	resp, err := h.authService.EnableMFA(claims.UserID, req.Code, clientInfo(c))
+	if err != nil {
+		h.respondError(c, err, "failed to enable two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Disable turns two-factor authentication off
+// @Summary Disable two-factor authentication
+// @Description Requires the password and a current code. Not allowed for roles that enforce two-factor.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.DisableMFARequest true "Password and code"
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var req service.DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.mfaService.Disable(claims.UserID, req.Password, req.Code); err != nil {
+		h.respondError(c, err, "failed to disable two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+// @Summary Regenerate recovery codes
+// @Description Invalidate all recovery codes and issue new ones (shown once)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.MFACodeRequest true "Authenticator code"
+// @Security BearerAuth
+// @Success 200 {object} service.RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(claims.UserID, req.Code)
+	if err != nil {
+		h.respondError(c, err, "failed to regenerate recovery codes")
+		return
+	}
+	c.JSON(http.StatusOK, service.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) respondError(c *gin.Context, err error, fallback string) {
+	var accountBlocked *service.AccountBlockedError
+	switch {
+	case errors.Is(err, service.ErrInvalidMFAChallenge), errors.Is(err, service.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrMFANotSetUp),
+		errors.Is(err, service.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrMFARequiredForRole), errors.As(err, &accountBlocked):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: fallback})
+	}
+}
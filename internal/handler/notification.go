@@ -47,12 +47,22 @@ func (h *NotificationHandler) GetNotifications(c *gin.Context) {
 }
 
 func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
-	userID := c.GetUint("user_id")
+	payloadRaw, exists := c.Get("auth_payload")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	claims, ok := payloadRaw.(*token.Claims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid auth payload"})
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	count, err := h.notifClient.GetUnreadCount(ctx, int64(userID))
+	count, err := h.notifClient.GetUnreadCount(ctx, int64(claims.UserID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get unread count"})
 		return
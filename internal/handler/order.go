@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+	"elearning/pkg/payment"
+)
+
+// maxPaymentWebhookBytes bounds the size of provider webhook bodies
+const maxPaymentWebhookBytes = 64 << 10
+
+// OrderHandler handles course checkout, orders and payment webhooks
+type OrderHandler struct {
+	orderService *service.OrderService
+}
+
+// NewOrderHandler creates a new order handler
+func NewOrderHandler(orderService *service.OrderService) *OrderHandler {
+	return &OrderHandler{orderService: orderService}
+}
+
+// Checkout starts buying a course
+// @Summary Buy a course
+// @Description Creates a pending order and returns it with the provider's checkout URL. The user is enrolled once the payment is confirmed, or at once when a coupon covers the whole price.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Param request body service.CheckoutRequest false "Coupon"
+// @Success 201 {object} domain.Order
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /courses/{course_id}/checkout [post]
+func (h *OrderHandler) Checkout(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	courseID, ok := parseIDParam(c, "course_id")
+	if !ok {
+		return
+	}
+
+	var req service.CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.orderService.Checkout(c.Request.Context(), claims.UserID, courseID, req.CouponCode)
+	if err != nil {
+		h.respondError(c, err, "failed to start checkout")
+		return
+	}
+	c.JSON(http.StatusCreated, order)
+}
+
+// Quote previews the price of a course with a coupon
+// @Summary Preview a coupon
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Param course_id path int true "Course ID"
+// @Param coupon query string true "Coupon code"
+// @Success 200 {object} service.CouponQuote
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /courses/{course_id}/checkout/quote [get]
+func (h *OrderHandler) Quote(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	courseID, ok := parseIDParam(c, "course_id")
+	if !ok {
+		return
+	}
+	code := c.Query("coupon")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "coupon is required"})
+		return
+	}
+
+	quote, err := h.orderService.Quote(claims.UserID, courseID, code)
+	if err != nil {
+		h.respondError(c, err, "failed to apply coupon")
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// ListMine returns the current user's orders
+// @Summary List my orders
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Order
+// @Router /users/me/orders [get]
+func (h *OrderHandler) ListMine(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	page, limit := pageParams(c)
+
+	orders, total, err := h.orderService.ListMine(claims.UserID, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get orders")
+		return
+	}
+	h.respondList(c, orders, total, page, limit)
+}
+
+// GetMine returns one of the current user's orders
+// @Summary Get my order
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} domain.Order
+// @Failure 404 {object} ErrorResponse
+// @Router /users/me/orders/{id} [get]
+func (h *OrderHandler) GetMine(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	order, err := h.orderService.GetForUser(claims.UserID, id)
+	if err != nil {
+		h.respondError(c, err, "failed to get order")
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// List returns orders across the platform
+// @Summary List orders
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "Buyer"
+// @Param course_id query int false "Course"
+// @Param status query string false "pending, paid, failed, cancelled or refunded"
+// @Param from query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Order
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/orders [get]
+func (h *OrderHandler) List(c *gin.Context) {
+	filter := repository.OrderFilter{Status: domain.OrderStatus(c.Query("status"))}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		filter.UserID = uint(id)
+	}
+	if v := c.Query("course_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid course_id"})
+			return
+		}
+		filter.CourseID = uint(id)
+	}
+	var err error
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, limit := pageParams(c)
+
+	orders, total, err := h.orderService.List(filter, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get orders")
+		return
+	}
+	h.respondList(c, orders, total, page, limit)
+}
+
+// Webhook receives payment outcomes from the payment provider
+// @Summary Payment provider webhook
+// @Description Verified with the provider's signature header. Redelivered events are acknowledged without effect.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /payments/webhook [post]
+func (h *OrderHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPaymentWebhookBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	if err := h.orderService.HandleWebhook(c.Request.Context(), payload, c.Request.Header); err != nil {
+		h.respondError(c, err, "failed to process payment event")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "event processed"})
+}
+
+// FakeComplete pays for or abandons a fake provider checkout
+// @Summary Complete a fake checkout
+// @Description Only available when PAYMENT_PROVIDER=fake. Sends the signed webhook a real provider would send.
+// @Tags orders
+// @Produce json
+// @Param reference path string true "Checkout reference"
+// @Param status query string false "paid (default) or failed"
+// @Success 200 {object} domain.Order
+// @Failure 404 {object} ErrorResponse
+// @Router /payments/fake/{reference}/complete [post]
+func (h *OrderHandler) FakeComplete(c *gin.Context) {
+	succeeded, ok := fakeOutcome(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.orderService.FakeComplete(c.Request.Context(), c.Param("reference"), succeeded)
+	if err != nil {
+		h.respondError(c, err, "failed to complete checkout")
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+func (h *OrderHandler) respondList(c *gin.Context, orders []domain.Order, total int64, page, limit int) {
+	if orders == nil {
+		orders = []domain.Order{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"orders": orders,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+func (h *OrderHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrOrderNotFound),
+		errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, service.ErrFakePaymentDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, payment.ErrInvalidSignature),
+		errors.Is(err, payment.ErrStaleTimestamp),
+		errors.Is(err, payment.ErrInvalidEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCoupon),
+		errors.Is(err, service.ErrCouponExpired),
+		errors.Is(err, service.ErrCouponUsedUp),
+		errors.Is(err, service.ErrCouponNotApplicable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCourseNotPublished),
+		errors.Is(err, service.ErrCourseIsFree),
+		errors.Is(err, service.ErrCannotEnrollInOwnCourse):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": "verify your email address before buying a course"})
+	case errors.Is(err, service.ErrAlreadyPurchased),
+		errors.Is(err, repository.ErrAlreadyEnrolled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// PayoutHandler handles teacher balances and payout batches
+type PayoutHandler struct {
+	ledgerService *service.LedgerService
+}
+
+// NewPayoutHandler creates a new payout handler
+func NewPayoutHandler(ledgerService *service.LedgerService) *PayoutHandler {
+	return &PayoutHandler{ledgerService: ledgerService}
+}
+
+// Balances returns what is owed to each teacher
+// @Summary List teacher balances
+// @Description Teachers' revenue share of sales, less refunds and earlier payouts, by currency. Only positive balances are listed.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param currency query string false "Three-letter currency code"
+// @Success 200 {array} domain.TeacherBalance
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/payout-balances [get]
+func (h *PayoutHandler) Balances(c *gin.Context) {
+	balances, err := h.ledgerService.Balances(c.Query("currency"))
+	if err != nil {
+		h.respondError(c, err, "failed to get balances")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"balances": balances})
+}
+
+// Generate creates a payout batch from the balances due
+// @Summary Generate a payout batch
+// @Description Creates one pending payout per teacher and currency for every balance of at least min_amount, and holds it back from the teacher's balance. Export the batch to make the payments, then mark each payout sent or failed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.GeneratePayoutsRequest false "Balances to pay out"
+// @Success 201 {object} domain.PayoutBatch
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/payout-batches [post]
+func (h *PayoutHandler) Generate(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req service.GeneratePayoutsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := h.ledgerService.GeneratePayouts(claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to generate payouts")
+		return
+	}
+	middleware.SetAuditChange(c, batch.ID, nil, gin.H{"payout_count": batch.PayoutCount, "currency": req.Currency, "min_amount": req.MinAmount})
+	c.JSON(http.StatusCreated, batch)
+}
+
+// List returns payout batches
+// @Summary List payout batches
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.PayoutBatch
+// @Router /admin/payout-batches [get]
+func (h *PayoutHandler) List(c *gin.Context) {
+	page, limit := pageParams(c)
+
+	batches, total, err := h.ledgerService.ListBatches(page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get payout batches")
+		return
+	}
+	if batches == nil {
+		batches = []domain.PayoutBatch{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"batches": batches,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// Get returns a payout batch with its payouts
+// @Summary Get a payout batch
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Batch ID"
+// @Success 200 {object} domain.PayoutBatch
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/payout-batches/{id} [get]
+func (h *PayoutHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	batch, err := h.ledgerService.GetBatch(id)
+	if err != nil {
+		h.respondError(c, err, "failed to get payout batch")
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+// Settle records the outcome of a payout
+// @Summary Mark a payout sent or failed
+// @Description A sent payout is settled in the ledger. A failed payout returns its amount to the teacher's balance for a later batch.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payout ID"
+// @Param request body service.SettlePayoutRequest true "Outcome"
+// @Success 200 {object} domain.Payout
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/payouts/{id}/status [put]
+func (h *PayoutHandler) Settle(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req service.SettlePayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payout, err := h.ledgerService.SettlePayout(id, claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to settle payout")
+		return
+	}
+	middleware.SetAuditChange(c, payout.ID, gin.H{"status": domain.PayoutPending}, gin.H{"status": payout.Status, "reference": payout.Reference})
+	c.JSON(http.StatusOK, payout)
+}
+
+// Export downloads a payout batch as CSV
+// @Summary Export a payout batch
+// @Description Columns: payout_id, teacher_id, teacher_name, teacher_email, amount (in the currency's minor unit), currency, status and created_at.
+// @Tags admin
+// @Produce text/csv
+// @Security BearerAuth
+// @Param id path int true "Batch ID"
+// @Success 200 {file} file
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/payout-batches/{id}/export [get]
+func (h *PayoutHandler) Export(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	batch, err := h.ledgerService.GetBatch(id)
+	if err != nil {
+		h.respondError(c, err, "failed to get payout batch")
+		return
+	}
+
+	filename := fmt.Sprintf("payouts-%d-%s.csv", batch.ID, batch.CreatedAt.UTC().Format("20060102"))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only cut the file short
+	if err := h.ledgerService.ExportBatch(batch, c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+func (h *PayoutHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrPayoutBatchNotFound), errors.Is(err, repository.ErrPayoutNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrPayoutNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPayoutRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNoPayoutsDue):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
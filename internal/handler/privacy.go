@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// PrivacyHandler serves personal data exports and account erasure requests
+type PrivacyHandler struct {
+	privacyService *service.PrivacyService
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(privacyService *service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: privacyService}
+}
+
+// RequestExport queues an archive of the current user's personal data
+// @Summary Export my data
+// @Description Builds a zip archive with data.json and uploaded files in the background; the user is notified when it is ready
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} domain.DataExport
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /users/me/export [post]
+func (h *PrivacyHandler) RequestExport(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := h.privacyService.RequestExport(claims.UserID)
+	if err != nil {
+		h.respondError(c, err, "failed to request data export")
+		return
+	}
+	c.JSON(http.StatusAccepted, export)
+}
+
+// ListExports returns the current user's data exports
+// @Summary List my data exports
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.DataExport
+// @Router /users/me/exports [get]
+func (h *PrivacyHandler) ListExports(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	exports, err := h.privacyService.ListExports(claims.UserID)
+	if err != nil {
+		h.respondError(c, err, "failed to get data exports")
+		return
+	}
+	if exports == nil {
+		exports = []domain.DataExport{}
+	}
+	c.JSON(http.StatusOK, exports)
+}
+
+// DownloadExport sends a ready export archive
+// @Summary Download my data export
+// @Tags users
+// @Produce application/zip
+// @Security BearerAuth
+// @Param id path int true "Export ID"
+// @Success 200 {file} file
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /users/me/exports/{id}/download [get]
+func (h *PrivacyHandler) DownloadExport(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	path, err := h.privacyService.ExportFile(claims.UserID, id)
+	if err != nil {
+		h.respondError(c, err, "failed to download data export")
+		return
+	}
+	c.FileAttachment(path, fmt.Sprintf("personal-data-%d.zip", id))
+}
+
+// RequestErasure asks for the current user's account to be erased
+// @Summary Request account erasure
+// @Description The account is anonymized after admin approval, once the cooling-off period has passed. It can be cancelled until then.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.ErasureRequestBody false "Reason"
+// @Success 201 {object} domain.ErasureRequest
+// @Failure 409 {object} ErrorResponse
+// @Router /users/me/erasure [post]
+func (h *PrivacyHandler) RequestErasure(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req service.ErasureRequestBody
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.privacyService.RequestErasure(claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to request erasure")
+		return
+	}
+	middleware.SetAuditChange(c, request.ID, nil, request)
+	c.JSON(http.StatusCreated, request)
+}
+
+// GetErasure returns the current user's open erasure request
+// @Summary Get my erasure request
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.ErasureRequest
+// @Failure 404 {object} ErrorResponse
+// @Router /users/me/erasure [get]
+func (h *PrivacyHandler) GetErasure(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.privacyService.GetErasure(claims.UserID)
+	if err != nil {
+		h.respondError(c, err, "failed to get erasure request")
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// CancelErasure withdraws the current user's open erasure request
+// @Summary Cancel my erasure request
+// @Tags users
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /users/me/erasure [delete]
+func (h *PrivacyHandler) CancelErasure(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.privacyService.CancelErasure(claims.UserID); err != nil {
+		h.respondError(c, err, "failed to cancel erasure request")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "erasure request cancelled"})
+}
+
+// ListErasures returns erasure requests for review
+// @Summary List erasure requests
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, approved, rejected, cancelled or completed"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.ErasureRequest
+// @Router /admin/erasure-requests [get]
+func (h *PrivacyHandler) ListErasures(c *gin.Context) {
+	page, limit := pageParams(c)
+
+	requests, total, err := h.privacyService.ListErasures(domain.ErasureStatus(c.Query("status")), page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get erasure requests")
+		return
+	}
+	if requests == nil {
+		requests = []domain.ErasureRequest{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// ApproveErasure schedules an account erasure
+// @Summary Approve erasure request
+// @Description The account is erased once the cooling-off period has passed
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Erasure request ID"
+// @Param request body service.ErasureReviewRequest false "Review note"
+// @Success 200 {object} domain.ErasureRequest
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/erasure-requests/{id}/approve [post]
+func (h *PrivacyHandler) ApproveErasure(c *gin.Context) {
+	h.review(c, h.privacyService.ApproveErasure)
+}
+
+// RejectErasure closes an erasure request without erasing the account
+// @Summary Reject erasure request
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Erasure request ID"
+// @Param request body service.ErasureReviewRequest false "Review note"
+// @Success 200 {object} domain.ErasureRequest
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/erasure-requests/{id}/reject [post]
+func (h *PrivacyHandler) RejectErasure(c *gin.Context) {
+	h.review(c, h.privacyService.RejectErasure)
+}
+
+func (h *PrivacyHandler) review(c *gin.Context, decide func(id, adminID uint, req service.ErasureReviewRequest) (*domain.ErasureRequest, error)) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req service.ErasureReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := decide(id, claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to review erasure request")
+		return
+	}
+	middleware.SetAuditChange(c, request.ID, nil, gin.H{"status": request.Status, "review_note": request.ReviewNote})
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *PrivacyHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrDataExportNotFound),
+		errors.Is(err, repository.ErrErasureNotFound),
+		errors.Is(err, service.ErrErasureNotOpen):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrExportTooSoon):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrExportInProgress),
+		errors.Is(err, service.ErrExportNotReady),
+		errors.Is(err, service.ErrErasureInProgress),
+		errors.Is(err, service.ErrErasureNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrErasureSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// RefundHandler handles refund requests and their review
+type RefundHandler struct {
+	refundService *service.RefundService
+}
+
+// NewRefundHandler creates a new refund handler
+func NewRefundHandler(refundService *service.RefundService) *RefundHandler {
+	return &RefundHandler{refundService: refundService}
+}
+
+// Request asks for one of the current user's orders to be refunded
+// @Summary Request a refund
+// @Description Allowed within the refund period and while little of the course has been completed. An admin reviews the request.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body service.RefundRequestBody false "Reason"
+// @Success 201 {object} domain.Refund
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /users/me/orders/{id}/refund [post]
+func (h *RefundHandler) Request(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req service.RefundRequestBody
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.refundService.Request(claims.UserID, id, req)
+	if err != nil {
+		h.respondError(c, err, "failed to request refund")
+		return
+	}
+	middleware.SetAuditChange(c, refund.ID, nil, refund)
+	c.JSON(http.StatusCreated, refund)
+}
+
+// ListMine returns the current user's refund requests
+// @Summary List my refund requests
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Refund
+// @Router /users/me/refunds [get]
+func (h *RefundHandler) ListMine(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	page, limit := pageParams(c)
+
+	refunds, total, err := h.refundService.ListMine(claims.UserID, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get refunds")
+		return
+	}
+	h.respondList(c, refunds, total, page, limit)
+}
+
+// Cancel withdraws one of the current user's pending refund requests
+// @Summary Cancel a refund request
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Refund ID"
+// @Success 200 {object} domain.Refund
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /users/me/refunds/{id}/cancel [post]
+func (h *RefundHandler) Cancel(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	refund, err := h.refundService.Cancel(claims.UserID, id)
+	if err != nil {
+		h.respondError(c, err, "failed to cancel refund request")
+		return
+	}
+	c.JSON(http.StatusOK, refund)
+}
+
+// List returns refund requests for review
+// @Summary List refund requests
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, failed, completed, rejected or cancelled"
+// @Param user_id query int false "Customer"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Refund
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/refunds [get]
+func (h *RefundHandler) List(c *gin.Context) {
+	filter := repository.RefundFilter{Status: domain.RefundStatus(c.Query("status"))}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		filter.UserID = uint(id)
+	}
+	page, limit := pageParams(c)
+
+	refunds, total, err := h.refundService.List(filter, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get refunds")
+		return
+	}
+	h.respondList(c, refunds, total, page, limit)
+}
+
+// Approve refunds the payment and revokes access to the course
+// @Summary Approve a refund
+// @Description A refund the payment provider refuses is marked failed and can be approved again.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Refund ID"
+// @Param request body service.RefundReviewRequest false "Review note"
+// @Success 200 {object} domain.Refund
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /admin/refunds/{id}/approve [post]
+func (h *RefundHandler) Approve(c *gin.Context) {
+	h.review(c, h.refundService.Approve)
+}
+
+// Reject closes a refund request without refunding
+// @Summary Reject a refund
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Refund ID"
+// @Param request body service.RefundReviewRequest false "Review note"
+// @Success 200 {object} domain.Refund
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/refunds/{id}/reject [post]
+func (h *RefundHandler) Reject(c *gin.Context) {
+	h.review(c, h.refundService.Reject)
+}
+
+func (h *RefundHandler) review(c *gin.Context, decide func(ctx context.Context, id, adminID uint, req service.RefundReviewRequest) (*domain.Refund, error)) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req service.RefundReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := decide(c.Request.Context(), id, claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to review refund request")
+		return
+	}
+	middleware.SetAuditChange(c, refund.ID, nil, gin.H{"status": refund.Status, "review_note": refund.ReviewNote})
+	c.JSON(http.StatusOK, refund)
+}
+
+func (h *RefundHandler) respondList(c *gin.Context, refunds []domain.Refund, total int64, page, limit int) {
+	if refunds == nil {
+		refunds = []domain.Refund{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"refunds": refunds,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func (h *RefundHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrRefundNotFound),
+		errors.Is(err, repository.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRefundWindowClosed),
+		errors.Is(err, service.ErrRefundTooMuchViewed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOrderNotRefundable),
+		errors.Is(err, service.ErrRefundInProgress),
+		errors.Is(err, service.ErrRefundNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRefundSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRefundFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
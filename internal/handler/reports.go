@@ -144,7 +144,7 @@ func (h *ReportsHandler) GetEnrollmentReport(c *gin.Context) {
 		// Top enrolled courses with proper joins
 		if err := tx.Model(&domain.Enrollment{}).
 			Select("courses.id as course_id, courses.title as course_title, COUNT(enrollments.id) as enrollments").
-			Joins("JOIN courses ON courses.id = enrollments.course_id").
+			Joins("JOIN courses ON courses.id = enrollments.course_id AND courses.deleted_at IS NULL").
 			Group("courses.id, courses.title").
 			Order("enrollments DESC").
 			Limit(10).
@@ -324,12 +324,181 @@ func (h *ReportsHandler) GetCourseReport(c *gin.Context) {
 	})
 }
 
-// GetRevenueReport (placeholder for future implementation)
+// GetRevenueReport returns revenue from course sales, net of refunds, and
+// from subscription invoices. Refunds are counted in the month of the
+// sale. Amounts are in each currency's minor unit and never summed across
+// currencies.
 func (h *ReportsHandler) GetRevenueReport(c *gin.Context) {
-	h.logger.Info("Revenue report requested (not implemented)")
+	monthsStr := c.DefaultQuery("months", "12")
+	months, err := strconv.Atoi(monthsStr)
+	if err != nil || months < 1 || months > 60 {
+		months = 12
+	}
+
+	// Gross is the list price before coupon discounts; Revenue is what was
+	// paid, less Refunds. Orders includes refunded orders.
+	type CurrencyRevenue struct {
+		Currency       string `json:"currency"`
+		Gross          int64  `json:"gross"`
+		Discounts      int64  `json:"discounts"`
+		Refunds        int64  `json:"refunds"`
+		Revenue        int64  `json:"revenue"`
+		Orders         int64  `json:"orders"`
+		RefundedOrders int64  `json:"refunded_orders"`
+	}
+
+	type MonthlyRevenue struct {
+		Month          string `json:"month"`
+		Currency       string `json:"currency"`
+		Gross          int64  `json:"gross"`
+		Discounts      int64  `json:"discounts"`
+		Refunds        int64  `json:"refunds"`
+		Revenue        int64  `json:"revenue"`
+		Orders         int64  `json:"orders"`
+		RefundedOrders int64  `json:"refunded_orders"`
+	}
+
+	type CourseRevenue struct {
+		CourseID    uint   `json:"course_id"`
+		CourseTitle string `json:"course_title"`
+		Currency    string `json:"currency"`
+		Revenue     int64  `json:"revenue"`
+		Orders      int64  `json:"orders"`
+	}
+
+	type CouponRevenue struct {
+		CouponCode string `json:"coupon_code"`
+		Currency   string `json:"currency"`
+		Discounts  int64  `json:"discounts"`
+		Revenue    int64  `json:"revenue"`
+		Orders     int64  `json:"orders"`
+	}
+
+	type SubscriptionRevenue struct {
+		Month    string `json:"month,omitempty"`
+		PlanID   uint   `json:"plan_id,omitempty"`
+		PlanName string `json:"plan_name,omitempty"`
+		Currency string `json:"currency"`
+		Revenue  int64  `json:"revenue"`
+		Invoices int64  `json:"invoices"`
+	}
+
+	var totals []CurrencyRevenue
+	var monthlyRevenue []MonthlyRevenue
+	var topCourses []CourseRevenue
+	var coupons []CouponRevenue
+	subscriptionTotals := []SubscriptionRevenue{}
+	subscriptionMonthly := []SubscriptionRevenue{}
+	subscriptionPlans := []SubscriptionRevenue{}
+
+	now := time.Now()
+	startDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -months+1, 0)
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Refunded orders were paid; they stay in the period of the sale
+		sold := func() *gorm.DB {
+			return tx.Model(&domain.Order{}).
+				Where("status IN ? AND paid_at >= ?", []domain.OrderStatus{domain.OrderStatusPaid, domain.OrderStatusRefunded}, startDate)
+		}
+		paid := func() *gorm.DB {
+			return tx.Model(&domain.Order{}).
+				Where("status = ? AND paid_at >= ?", domain.OrderStatusPaid, startDate)
+		}
+		const salesColumns = "SUM(subtotal) as gross, SUM(discount) as discounts, " +
+			"SUM(CASE WHEN status = ? THEN amount ELSE 0 END) as refunds, " +
+			"SUM(CASE WHEN status = ? THEN 0 ELSE amount END) as revenue, " +
+			"COUNT(*) as orders, " +
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) as refunded_orders"
+		refunded := domain.OrderStatusRefunded
+
+		if err := sold().
+			Select("currency, "+salesColumns, refunded, refunded, refunded).
+			Group("currency").
+			Order("currency").
+			Scan(&totals).Error; err != nil {
+			return err
+		}
+
+		if err := sold().
+			Select("TO_CHAR(paid_at, 'YYYY-MM') as month, currency, "+salesColumns, refunded, refunded, refunded).
+			Group("month, currency").
+			Order("month ASC, currency").
+			Scan(&monthlyRevenue).Error; err != nil {
+			return err
+		}
+
+		// Titles come from the orders so purged courses are still listed.
+		// Refunded orders are left out.
+		if err := paid().
+			Select("course_id, MAX(course_title) as course_title, currency, SUM(amount) as revenue, COUNT(*) as orders").
+			Group("course_id, currency").
+			Order("revenue DESC").
+			Limit(10).
+			Scan(&topCourses).Error; err != nil {
+			return err
+		}
+
+		// Grouped by the code on the order, which is kept if the coupon is renamed
+		if err := paid().
+			Where("coupon_code <> ''").
+			Select("coupon_code, currency, SUM(discount) as discounts, SUM(amount) as revenue, COUNT(*) as orders").
+			Group("coupon_code, currency").
+			Order("discounts DESC").
+			Scan(&coupons).Error; err != nil {
+			return err
+		}
+
+		invoices := func() *gorm.DB {
+			return tx.Table("subscription_invoices i").Where("i.paid_at >= ?", startDate)
+		}
+		const invoiceColumns = "i.currency, SUM(i.amount) as revenue, COUNT(*) as invoices"
+
+		if err := invoices().
+			Select(invoiceColumns).
+			Group("i.currency").
+			Order("i.currency").
+			Scan(&subscriptionTotals).Error; err != nil {
+			return err
+		}
+
+		if err := invoices().
+			Select("TO_CHAR(i.paid_at, 'YYYY-MM') as month, " + invoiceColumns).
+			Group("month, i.currency").
+			Order("month ASC, i.currency").
+			Scan(&subscriptionMonthly).Error; err != nil {
+			return err
+		}
+
+		if err := invoices().
+			Joins("LEFT JOIN subscription_plans p ON p.id = i.plan_id").
+			Select("i.plan_id, MAX(p.name) as plan_name, " + invoiceColumns).
+			Group("i.plan_id, i.currency").
+			Order("revenue DESC").
+			Scan(&subscriptionPlans).Error; err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		h.logger.Error("Failed to fetch revenue report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch revenue statistics",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":         "Revenue reporting not implemented yet",
-		"total_revenue":   0,
-		"monthly_revenue": []gin.H{},
+		"since":           startDate,
+		"total_revenue":   totals,
+		"monthly_revenue": monthlyRevenue,
+		"top_courses":     topCourses,
+		"coupons":         coupons,
+		"subscriptions": gin.H{
+			"total_revenue":   subscriptionTotals,
+			"monthly_revenue": subscriptionMonthly,
+			"plans":           subscriptionPlans,
+		},
 	})
 }
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// RoleHandler lets admins define roles and their permissions
+type RoleHandler struct {
+	rbacService *service.RBACService
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(rbacService *service.RBACService) *RoleHandler {
+	return &RoleHandler{rbacService: rbacService}
+}
+
+// ListPermissions returns every permission that can be granted
+// @Summary List permissions
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.PermissionInfo
+// @Router /admin/permissions [get]
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, h.rbacService.Permissions())
+}
+
+// List returns every role with its permissions
+// @Summary List roles
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.Role
+// @Router /admin/roles [get]
+func (h *RoleHandler) List(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// Get returns a role with its permissions
+// @Summary Get a role
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param role_id path int true "Role ID"
+// @Success 200 {object} domain.Role
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/roles/{role_id} [get]
+func (h *RoleHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "role_id")
+	if !ok {
+		return
+	}
+
+	role, err := h.rbacService.GetRole(id)
+	if err != nil {
+		h.respondError(c, err, "failed to get role")
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// Create defines a custom role
+// @Summary Create a role
+// @Description Permissions marked as scoped may be limited to the user's own resources with scope "own"
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.CreateRoleRequest true "Role name and permissions"
+// @Success 201 {object} domain.Role
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/roles [post]
+func (h *RoleHandler) Create(c *gin.Context) {
+	var req service.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.rbacService.CreateRole(req)
+	if err != nil {
+		h.respondError(c, err, "failed to create role")
+		return
+	}
+	middleware.SetAuditChange(c, role.ID, nil, role)
+	c.JSON(http.StatusCreated, role)
+}
+
+// Update replaces a role's description and permissions
+// @Summary Update a role
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param role_id path int true "Role ID"
+// @Param request body service.UpdateRoleRequest true "Description and permissions"
+// @Success 200 {object} domain.Role
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/roles/{role_id} [put]
+func (h *RoleHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "role_id")
+	if !ok {
+		return
+	}
+
+	var req service.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := h.rbacService.GetRole(id)
+	if err != nil {
+		h.respondError(c, err, "failed to update role")
+		return
+	}
+
+	role, err := h.rbacService.UpdateRole(id, req)
+	if err != nil {
+		h.respondError(c, err, "failed to update role")
+		return
+	}
+	middleware.SetAuditChange(c, 0, before, role)
+	c.JSON(http.StatusOK, role)
+}
+
+// Delete removes a custom role that is not assigned to any user
+// @Summary Delete a role
+// @Tags admin
+// @Security BearerAuth
+// @Param role_id path int true "Role ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/roles/{role_id} [delete]
+func (h *RoleHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "role_id")
+	if !ok {
+		return
+	}
+
+	before, err := h.rbacService.GetRole(id)
+	if err != nil {
+		h.respondError(c, err, "failed to delete role")
+		return
+	}
+
+	if err := h.rbacService.DeleteRole(id); err != nil {
+		h.respondError(c, err, "failed to delete role")
+		return
+	}
+	middleware.SetAuditChange(c, 0, before, nil)
+	c.JSON(http.StatusOK, MessageResponse{Message: "role deleted"})
+}
+
+func (h *RoleHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidRoleName),
+		errors.Is(err, service.ErrInvalidPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRoleExists),
+		errors.Is(err, service.ErrRoleInUse),
+		errors.Is(err, service.ErrBuiltInRole),
+		errors.Is(err, service.ErrAdminRoleLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
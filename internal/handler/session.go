@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// SessionHandler lets users manage where they are logged in
+type SessionHandler struct {
+	sessionService *service.SessionService
+	apiKeyService  *service.APIKeyService
+}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler(sessionService *service.SessionService, apiKeyService *service.APIKeyService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService, apiKeyService: apiKeyService}
+}
+
+// List returns the current user's active sessions
+// @Summary List my sessions
+// @Description Devices and browsers where the current user is logged in
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} service.SessionView
+// @Router /users/sessions [get]
+func (h *SessionHandler) List(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions, err := h.sessionService.List(claims.UserID, claims.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// Revoke logs out one of the current user's sessions
+// @Summary Log out a session
+// @Description The session's token is refused at once by this server and within 5 seconds by the others
+// @Tags users
+// @Param id path int true "Session ID"
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /users/sessions/{id} [delete]
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.Revoke(claims.UserID, id); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "session logged out"})
+}
+
+// RevokeAll logs the current user out everywhere, including this session,
+// and revokes their API keys
+// @Summary Log out everywhere
+// @Description Tokens are refused at once by this server and within 5 seconds by the others. API keys are revoked too.
+// @Tags users
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Router /users/sessions [delete]
+func (h *SessionHandler) RevokeAll(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sessionService.RevokeAll(claims.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	if err := h.apiKeyService.RevokeAll(claims.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "logged out of all sessions"})
+}
+
+// ForceLogout logs a user out of every session and revokes their API keys
+// @Summary Force-logout a user
+// @Description Tokens are refused at once by this server and within 5 seconds by the others. API keys are revoked too.
+// @Tags admin
+// @Param user_id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse
+// @Router /admin/users/{user_id}/logout [post]
+func (h *SessionHandler) ForceLogout(c *gin.Context) {
+	id, ok := parseIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.RevokeAll(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	if err := h.apiKeyService.RevokeAll(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "user logged out of all sessions"})
+}
+
+// clientInfo describes the client making the request
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
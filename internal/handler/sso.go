@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/service"
+)
+
+// ssoStateCookie binds an in-flight SSO login to the browser that started
+// it, so a callback URL cannot be replayed in someone else's browser
+const ssoStateCookie = "sso_state"
+
+// SSOHandler handles OpenID Connect single sign-on
+type SSOHandler struct {
+	ssoService *service.SSOService
+}
+
+// NewSSOHandler creates a new SSO handler
+func NewSSOHandler(ssoService *service.SSOService) *SSOHandler {
+	return &SSOHandler{ssoService: ssoService}
+}
+
+// ListProviders returns the configured identity providers
+// @Summary List SSO providers
+// @Tags auth
+// @Produce json
+// @Success 200 {array} service.SSOProviderInfo
+// @Router /auth/sso/providers [get]
+func (h *SSOHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.ssoService.ListProviders())
+}
+
+// Login redirects the browser to the identity provider
+// @Summary Start SSO login
+// @Description Redirects to the provider's authorization endpoint (authorization code flow with PKCE)
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} ErrorResponse
+// @Router /auth/sso/{provider}/login [get]
+func (h *SSOHandler) Login(c *gin.Context) {
+	authURL, state, err := h.ssoService.Begin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrSSOProviderNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		log.Printf("failed to start sso login: %v", err)
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: "identity provider is unavailable"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, state, int(h.ssoService.StateTTL().Seconds()), "/api/v1/auth/sso", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles the redirect back from the identity provider and sends
+// the browser to the frontend with a one-time login code or an error
+// @Summary SSO callback
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "State"
+// @Success 302
+// @Router /auth/sso/{provider}/callback [get]
+func (h *SSOHandler) Callback(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	cookieState, _ := c.Cookie(ssoStateCookie)
+	c.SetCookie(ssoStateCookie, "", -1, "/api/v1/auth/sso", "", c.Request.TLS != nil, true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		log.Printf("sso provider returned error: %s %s", providerErr, c.Query("error_description"))
+		h.redirectFrontend(c, url.Values{"error": {"sign-in was cancelled or denied"}})
+		return
+	}
+
+	state := c.Query("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		h.redirectFrontend(c, url.Values{"error": {service.ErrSSOInvalidState.Error()}})
+		return
+	}
+
+	code, err := h.ssoService.Complete(c.Request.Context(), c.Param("provider"), c.Query("code"), state)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSSOProviderNotFound),
+			errors.Is(err, service.ErrSSOInvalidState),
+			errors.Is(err, service.ErrSSOEmailNotVerified),
+			errors.Is(err, service.ErrSSODomainNotAllowed),
+			errors.Is(err, service.ErrSSOSignupDisabled):
+			h.redirectFrontend(c, url.Values{"error": {err.Error()}})
+		default:
+			log.Printf("sso callback failed: %v", err)
+			h.redirectFrontend(c, url.Values{"error": {"sign-in failed"}})
+		}
+		return
+	}
+
+	h.redirectFrontend(c, url.Values{"code": {code}})
+}
+
+// Exchange redeems the one-time login code for an access token
+// @Summary Complete SSO login
+// @Description Exchange the code delivered to the frontend callback for the normal login response
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.SSOExchangeRequest true "Login code"
+// @Success 200 {object} service.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/sso/exchange [post]
+func (h *SSOHandler) Exchange(c *gin.Context) {
+	var req service.SSOExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	resp, err := h.ssoService.Exchange(req.Code, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSSOLoginCode) {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+			return
+		}
+		var accountBlocked *service.AccountBlockedError
+		if errors.As(err, &accountBlocked) {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to complete sign-in"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *SSOHandler) redirectFrontend(c *gin.Context, params url.Values) {
+	target := h.ssoService.FrontendCallbackURL()
+	if u, err := url.Parse(target); err == nil {
+		q := u.Query()
+		for k, v := range params {
+			q[k] = v
+		}
+		u.RawQuery = q.Encode()
+		target = u.String()
+	}
+	c.Redirect(http.StatusFound, target)
+}
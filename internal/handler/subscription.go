@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// SubscriptionHandler handles subscription plans and subscriptions
+type SubscriptionHandler struct {
+	subscriptionService *service.SubscriptionService
+}
+
+// NewSubscriptionHandler creates a new subscription handler
+func NewSubscriptionHandler(subscriptionService *service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{subscriptionService: subscriptionService}
+}
+
+// ListPlans returns the plans on sale
+// @Summary List subscription plans
+// @Tags subscriptions
+// @Produce json
+// @Success 200 {array} domain.Plan
+// @Router /subscription-plans [get]
+func (h *SubscriptionHandler) ListPlans(c *gin.Context) {
+	h.listPlans(c, true)
+}
+
+// ListAllPlans returns every plan, including inactive ones
+// @Summary List all subscription plans
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.Plan
+// @Router /admin/subscription-plans [get]
+func (h *SubscriptionHandler) ListAllPlans(c *gin.Context) {
+	h.listPlans(c, false)
+}
+
+func (h *SubscriptionHandler) listPlans(c *gin.Context, activeOnly bool) {
+	plans, err := h.subscriptionService.ListPlans(activeOnly)
+	if err != nil {
+		h.respondError(c, err, "failed to get plans")
+		return
+	}
+	if plans == nil {
+		plans = []domain.Plan{}
+	}
+	c.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+// CreatePlan adds a subscription plan
+// @Summary Create a subscription plan
+// @Description Plans without category_ids cover every published course
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.PlanRequest true "Plan"
+// @Success 201 {object} domain.Plan
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/subscription-plans [post]
+func (h *SubscriptionHandler) CreatePlan(c *gin.Context) {
+	var req service.PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.subscriptionService.CreatePlan(req)
+	if err != nil {
+		h.respondError(c, err, "failed to create plan")
+		return
+	}
+	middleware.SetAuditChange(c, plan.ID, nil, plan)
+	c.JSON(http.StatusCreated, plan)
+}
+
+// UpdatePlan replaces a plan's settings
+// @Summary Update a subscription plan
+// @Description Set active to false to stop selling a plan; running subscriptions are not affected
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Plan ID"
+// @Param request body service.PlanRequest true "Plan"
+// @Success 200 {object} domain.Plan
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/subscription-plans/{id} [put]
+func (h *SubscriptionHandler) UpdatePlan(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req service.PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := h.subscriptionService.GetPlan(id)
+	if err != nil {
+		h.respondError(c, err, "failed to update plan")
+		return
+	}
+	plan, err := h.subscriptionService.UpdatePlan(id, req)
+	if err != nil {
+		h.respondError(c, err, "failed to update plan")
+		return
+	}
+	middleware.SetAuditChange(c, 0, before, plan)
+	c.JSON(http.StatusOK, plan)
+}
+
+// Subscribe starts a subscription checkout for the current user
+// @Summary Subscribe to a plan
+// @Description Returns the subscription with a checkout_url to pay at. The first subscription of a customer starts with the plan's trial.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.SubscribeRequest true "Plan"
+// @Success 201 {object} domain.Subscription
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /users/me/subscriptions [post]
+func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req service.SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.subscriptionService.Subscribe(c.Request.Context(), claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to start checkout")
+		return
+	}
+	middleware.SetAuditChange(c, sub.ID, nil, gin.H{"plan_id": sub.PlanID, "status": sub.Status})
+	c.JSON(http.StatusCreated, sub)
+}
+
+// ListMine returns the current user's subscriptions
+// @Summary List my subscriptions
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Subscription
+// @Router /users/me/subscriptions [get]
+func (h *SubscriptionHandler) ListMine(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	page, limit := pageParams(c)
+
+	subs, total, err := h.subscriptionService.ListMine(claims.UserID, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get subscriptions")
+		return
+	}
+	h.respondList(c, subs, total, page, limit)
+}
+
+// GetMine returns one of the current user's subscriptions
+// @Summary Get my subscription
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} domain.Subscription
+// @Failure 404 {object} ErrorResponse
+// @Router /users/me/subscriptions/{id} [get]
+func (h *SubscriptionHandler) GetMine(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.subscriptionService.GetForUser(claims.UserID, id)
+	if err != nil {
+		h.respondError(c, err, "failed to get subscription")
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// Cancel stops one of the current user's subscriptions from renewing
+// @Summary Cancel a subscription
+// @Description Access lasts until the end of the current period
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} domain.Subscription
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /users/me/subscriptions/{id}/cancel [post]
+func (h *SubscriptionHandler) Cancel(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.subscriptionService.Cancel(c.Request.Context(), claims.UserID, id)
+	if err != nil {
+		h.respondError(c, err, "failed to cancel subscription")
+		return
+	}
+	middleware.SetAuditChange(c, sub.ID, nil, gin.H{"status": sub.Status, "cancel_at_period_end": sub.CancelAtPeriodEnd})
+	c.JSON(http.StatusOK, sub)
+}
+
+// List returns subscriptions
+// @Summary List subscriptions
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "incomplete, trial, active, past_due or canceled"
+// @Param user_id query int false "Customer"
+// @Param plan_id query int false "Plan"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.Subscription
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/subscriptions [get]
+func (h *SubscriptionHandler) List(c *gin.Context) {
+	filter := repository.SubscriptionFilter{Status: domain.SubscriptionStatus(c.Query("status"))}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		filter.UserID = uint(id)
+	}
+	if v := c.Query("plan_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan_id"})
+			return
+		}
+		filter.PlanID = uint(id)
+	}
+	page, limit := pageParams(c)
+
+	subs, total, err := h.subscriptionService.List(filter, page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get subscriptions")
+		return
+	}
+	h.respondList(c, subs, total, page, limit)
+}
+
+// FakeComplete starts or abandons a fake provider subscription checkout
+// @Summary Complete a fake subscription checkout
+// @Description Only available when PAYMENT_PROVIDER=fake. Applies the signed webhook a real provider would send.
+// @Tags subscriptions
+// @Produce json
+// @Param reference path string true "Checkout reference"
+// @Param status query string false "paid (default) or failed"
+// @Success 200 {object} domain.Subscription
+// @Failure 404 {object} ErrorResponse
+// @Router /payments/fake/subscriptions/{reference}/complete [post]
+func (h *SubscriptionHandler) FakeComplete(c *gin.Context) {
+	succeeded, ok := fakeOutcome(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.subscriptionService.FakeComplete(c.Request.Context(), c.Param("reference"), succeeded)
+	if err != nil {
+		h.respondError(c, err, "failed to complete checkout")
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// FakeRenew charges a fake provider subscription for its next period
+// @Summary Renew a fake subscription
+// @Description Only available when PAYMENT_PROVIDER=fake. Subscriptions set to cancel end instead.
+// @Tags subscriptions
+// @Produce json
+// @Param reference path string true "Checkout reference"
+// @Param status query string false "paid (default) or failed"
+// @Success 200 {object} domain.Subscription
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /payments/fake/subscriptions/{reference}/renew [post]
+func (h *SubscriptionHandler) FakeRenew(c *gin.Context) {
+	succeeded, ok := fakeOutcome(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.subscriptionService.FakeRenew(c.Request.Context(), c.Param("reference"), succeeded)
+	if err != nil {
+		h.respondError(c, err, "failed to renew subscription")
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// fakeOutcome reads the status query of the fake provider endpoints
+func fakeOutcome(c *gin.Context) (bool, bool) {
+	switch c.DefaultQuery("status", "paid") {
+	case "paid":
+		return true, true
+	case "failed":
+		return false, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "status must be paid or failed"})
+	return false, false
+}
+
+func (h *SubscriptionHandler) respondList(c *gin.Context, subs []domain.Subscription, total int64, page, limit int) {
+	if subs == nil {
+		subs = []domain.Subscription{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subs,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+func (h *SubscriptionHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrPlanNotFound),
+		errors.Is(err, repository.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrFakePaymentDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrInvalidPlan):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPlanInactive),
+		errors.Is(err, service.ErrAlreadySubscribed),
+		errors.Is(err, service.ErrSubscriptionNotLive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/service"
+)
+
+// TrashHandler lets admins restore or purge deleted users, courses and
+// lessons
+type TrashHandler struct {
+	trashService *service.TrashService
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(trashService *service.TrashService) *TrashHandler {
+	return &TrashHandler{trashService: trashService}
+}
+
+// List returns the deleted items of one type
+// @Summary List trash
+// @Description Deleted items can be restored until purge_at, when they and their media are removed for good
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param type path string true "users, courses or lessons"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} service.TrashItem
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/trash/{type} [get]
+func (h *TrashHandler) List(c *gin.Context) {
+	page, limit := pageParams(c)
+
+	items, total, err := h.trashService.List(c.Param("type"), page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get trash")
+		return
+	}
+	if items == nil {
+		items = []service.TrashItem{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// Restore takes an item out of the trash
+// @Summary Restore from trash
+// @Tags admin
+// @Security BearerAuth
+// @Param type path string true "users, courses or lessons"
+// @Param id path int true "ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/trash/{type}/{id}/restore [post]
+func (h *TrashHandler) Restore(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.Restore(c.Param("type"), id); err != nil {
+		h.respondError(c, err, "failed to restore")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "restored"})
+}
+
+// Purge permanently deletes an item in the trash and its media
+// @Summary Purge from trash
+// @Tags admin
+// @Security BearerAuth
+// @Param type path string true "users, courses or lessons"
+// @Param id path int true "ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/trash/{type}/{id} [delete]
+func (h *TrashHandler) Purge(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.Purge(c.Param("type"), id); err != nil {
+		h.respondError(c, err, "failed to purge")
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "permanently deleted"})
+}
+
+func (h *TrashHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrUnknownTrashType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotInTrash):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCourseInTrash):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
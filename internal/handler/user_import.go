@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/internal/middleware"
+	"elearning/internal/repository"
+	"elearning/internal/service"
+)
+
+// maxImportFileSize caps uploaded import files
+const maxImportFileSize = 5 * 1024 * 1024
+
+// UserImportHandler serves bulk user imports and exports
+type UserImportHandler struct {
+	importService *service.UserImportService
+}
+
+// NewUserImportHandler creates a new user import handler
+func NewUserImportHandler(importService *service.UserImportService) *UserImportHandler {
+	return &UserImportHandler{importService: importService}
+}
+
+// Validate checks an import file without creating accounts
+// @Summary Validate user import
+// @Description Dry run of a CSV import. Columns: name, email, role (student, teacher or admin; default student) and courses (course IDs separated by ";").
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file"
+// @Success 200 {object} service.UserImportReport
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/users/import/validate [post]
+func (h *UserImportHandler) Validate(c *gin.Context) {
+	file, _, ok := h.openUpload(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	report, err := h.importService.Validate(file)
+	if err != nil {
+		h.respondError(c, err, report, "failed to validate import")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Start queues an import once every row of the file is valid
+// @Summary Import users
+// @Description Validates the CSV like the dry run and, if every row is valid, creates the accounts in the background. With send_welcome, each account gets a welcome notification and an email to choose a password.
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file"
+// @Param send_welcome formData bool false "Send welcome notifications and password setup emails"
+// @Success 202 {object} domain.UserImport
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} service.UserImportReport
+// @Router /admin/users/import [post]
+func (h *UserImportHandler) Start(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendWelcome, _ := strconv.ParseBool(c.DefaultPostForm("send_welcome", "false"))
+
+	file, fileName, ok := h.openUpload(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	userImport, report, err := h.importService.Start(claims.UserID, fileName, file, sendWelcome)
+	if err != nil {
+		h.respondError(c, err, report, "failed to start import")
+		return
+	}
+	middleware.SetAuditChange(c, userImport.ID, nil, userImport)
+	c.JSON(http.StatusAccepted, userImport)
+}
+
+// List returns user imports
+// @Summary List user imports
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.UserImport
+// @Router /admin/users/imports [get]
+func (h *UserImportHandler) List(c *gin.Context) {
+	page, limit := pageParams(c)
+
+	imports, total, err := h.importService.List(page, limit)
+	if err != nil {
+		h.respondError(c, err, nil, "failed to get imports")
+		return
+	}
+	if imports == nil {
+		imports = []domain.UserImport{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imports": imports,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// Get returns an import's progress
+// @Summary Get user import
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Import ID"
+// @Success 200 {object} domain.UserImport
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/imports/{id} [get]
+func (h *UserImportHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	userImport, err := h.importService.Get(id)
+	if err != nil {
+		h.respondError(c, err, nil, "failed to get import")
+		return
+	}
+	c.JSON(http.StatusOK, userImport)
+}
+
+// ListRows returns the accounts of an import and their outcome
+// @Summary List user import rows
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Import ID"
+// @Param status query string false "pending, created or failed"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} domain.UserImportRow
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/imports/{id}/rows [get]
+func (h *UserImportHandler) ListRows(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	page, limit := pageParams(c)
+
+	rows, total, err := h.importService.ListRows(id, domain.UserImportRowStatus(c.Query("status")), page, limit)
+	if err != nil {
+		h.respondError(c, err, nil, "failed to get import rows")
+		return
+	}
+	if rows == nil {
+		rows = []domain.UserImportRow{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rows":  rows,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// Export downloads users as CSV
+// @Summary Export users
+// @Description The name, email and role columns can be imported again
+// @Tags admin
+// @Produce text/csv
+// @Security BearerAuth
+// @Param role query string false "Filter by role"
+// @Param status query string false "Filter by account status"
+// @Param verified query bool false "Filter by email verification"
+// @Param q query string false "Part of the name or email"
+// @Param from query string false "Created at or after this time"
+// @Param to query string false "Created before this time"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/users/export [get]
+func (h *UserImportHandler) Export(c *gin.Context) {
+	filter := repository.UserFilter{
+		Role:   domain.UserRole(c.Query("role")),
+		Status: domain.AccountStatus(c.Query("status")),
+		Query:  strings.TrimSpace(c.Query("q")),
+	}
+	if v := c.Query("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid verified"})
+			return
+		}
+		filter.Verified = &verified
+	}
+	var err error
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("users-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only cut the file short
+	if err := h.importService.Export(filter, c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// openUpload opens the uploaded import file
+func (h *UserImportHandler) openUpload(c *gin.Context) (multipart.File, string, bool) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return nil, "", false
+	}
+	if strings.ToLower(filepath.Ext(header.Filename)) != ".csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file must be a CSV"})
+		return nil, "", false
+	}
+	if header.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file size must be less than 5MB"})
+		return nil, "", false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return nil, "", false
+	}
+	return file, filepath.Base(header.Filename), true
+}
+
+func (h *UserImportHandler) respondError(c *gin.Context, err error, report *service.UserImportReport, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrImportInvalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "report": report})
+	case errors.Is(err, service.ErrImportEmpty),
+		errors.Is(err, service.ErrImportTooLarge),
+		errors.Is(err, service.ErrImportHeader),
+		errors.Is(err, service.ErrImportMalformed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrUserImportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"elearning/internal/middleware"
+	"elearning/internal/service"
+)
+
+// WebhookHandler handles admin management of outbound webhooks
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(service *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// Create registers a webhook subscription
+// @Summary Create webhook
+// @Description Subscribe a URL to platform events. The signing secret is only returned here.
+// @Tags admin-webhooks
+// @Accept json
+// @Produce json
+// @Param request body service.CreateWebhookRequest true "Webhook"
+// @Security BearerAuth
+// @Success 201 {object} service.CreateWebhookResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) Create(c *gin.Context) {
+	claims, err := middleware.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req service.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, err := h.service.Create(claims.UserID, req)
+	if err != nil {
+		h.respondError(c, err, "failed to create webhook")
+		return
+	}
+	middleware.SetAuditChange(c, hook.ID, nil, hook.Webhook)
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+// List returns all webhook subscriptions
+// @Summary List webhooks
+// @Tags admin-webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.Webhook
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.service.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// Get returns a webhook subscription
+// @Summary Get webhook
+// @Tags admin-webhooks
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Security BearerAuth
+// @Success 200 {object} domain.Webhook
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{webhook_id} [get]
+func (h *WebhookHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+
+	hook, err := h.service.Get(id)
+	if err != nil {
+		h.respondError(c, err, "failed to get webhook")
+		return
+	}
+	c.JSON(http.StatusOK, hook)
+}
+
+// Update changes a webhook subscription
+// @Summary Update webhook
+// @Tags admin-webhooks
+// @Accept json
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Param request body service.UpdateWebhookRequest true "Changes"
+// @Security BearerAuth
+// @Success 200 {object} domain.Webhook
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{webhook_id} [put]
+func (h *WebhookHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+
+	var req service.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, err := h.service.Update(id, req)
+	if err != nil {
+		h.respondError(c, err, "failed to update webhook")
+		return
+	}
+	c.JSON(http.StatusOK, hook)
+}
+
+// Delete removes a webhook subscription
+// @Summary Delete webhook
+// @Tags admin-webhooks
+// @Param webhook_id path int true "Webhook ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{webhook_id} [delete]
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		h.respondError(c, err, "failed to delete webhook")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+// ListDeliveries returns the delivery log of a webhook
+// @Summary List webhook deliveries
+// @Tags admin-webhooks
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Param status query string false "pending, succeeded or failed"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Security BearerAuth
+// @Success 200 {array} domain.WebhookDelivery
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{webhook_id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := parseIDParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	deliveries, total, err := h.service.ListDeliveries(id, c.Query("status"), page, limit)
+	if err != nil {
+		h.respondError(c, err, "failed to get deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+// Redeliver queues a previously sent payload again
+// @Summary Redeliver webhook payload
+// @Tags admin-webhooks
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Security BearerAuth
+// @Success 202 {object} domain.WebhookDelivery
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	webhookID, ok := parseIDParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseIDParam(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Redeliver(webhookID, deliveryID)
+	if err != nil {
+		h.respondError(c, err, "failed to redeliver")
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func (h *WebhookHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrInvalidWebhookURL),
+		errors.Is(err, service.ErrInvalidWebhookEvent),
+		errors.Is(err, service.ErrNoWebhookEvents):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// parseIDParam parses a numeric path parameter, responding 400 when invalid
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+	"elearning/pkg/metrics"
+)
+
+const auditChangeContext = "audit_change"
+
+// AuditRecorder appends entries to the audit log
+type AuditRecorder interface {
+	Record(entry *domain.AuditLog, before, after interface{}) error
+}
+
+type auditChange struct {
+	targetID      uint
+	before, after interface{}
+}
+
+// SetAuditChange gives the audit middleware the state of the target before
+// and after the action. targetID may be zero when the route names the
+// target in a path parameter.
+func SetAuditChange(c *gin.Context, targetID uint, before, after interface{}) {
+	c.Set(auditChangeContext, auditChange{targetID: targetID, before: before, after: after})
+}
+
+// Audit records a successful action in the audit log once the handler has
+// run. The target is identified by the targetParam path parameter, or by
+// the ID passed to SetAuditChange for newly created resources. A
+// targetType starting with ":" is read from that path parameter instead.
+//
+// The action has already taken effect when the entry is written, so the
+// handler's response is sent as is. An entry that cannot be written is
+// logged and counted in audit_write_failures_total to alert on.
+func Audit(recorder AuditRecorder, action, targetType, targetParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.IsAborted() || c.Writer.Status() >= 400 {
+			return
+		}
+
+		entry, before, after := auditEntry(c, action, targetType, targetParam)
+		if err := recorder.Record(entry, before, after); err != nil {
+			log.Printf("Failed to write audit log for completed %s %s: %v", action, entry.TargetID, err)
+			metrics.AuditWriteFailures.WithLabelValues(action).Inc()
+		}
+	}
+}
+
+// AuditAccess records an access in the audit log before the handler runs,
+// for routes that stream their response, such as exports. The query
+// parameters are recorded as the after state. The request is refused when
+// the entry cannot be written, so nothing is handed out without an audit
+// record.
+func AuditAccess(recorder AuditRecorder, action, targetType, targetParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entry, _, _ := auditEntry(c, action, targetType, targetParam)
+		var query interface{}
+		if params := c.Request.URL.Query(); len(params) > 0 {
+			query = params
+		}
+		if err := recorder.Record(entry, nil, query); err != nil {
+			log.Printf("Failed to write audit log for %s %s: %v", action, entry.TargetID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse("failed to record audit log"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// auditEntry builds the audit entry for the request and returns the state
+// passed to SetAuditChange
+func auditEntry(c *gin.Context, action, targetType, targetParam string) (*domain.AuditLog, interface{}, interface{}) {
+	entry := &domain.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		IP:         c.ClientIP(),
+		RequestID:  c.GetString(RequestIDKey),
+	}
+	if strings.HasPrefix(targetType, ":") {
+		entry.TargetType = c.Param(targetType[1:])
+	}
+	if targetParam != "" {
+		entry.TargetID = c.Param(targetParam)
+	}
+	if claims, err := GetCurrentUser(c); err == nil {
+		entry.ActorID = &claims.UserID
+		entry.ActorEmail = claims.Email
+		if claims.Act != nil {
+			entry.ImpersonatorID = &claims.Act.UserID
+		}
+	}
+
+	var before, after interface{}
+	if value, ok := c.Get(auditChangeContext); ok {
+		change := value.(auditChange)
+		if change.targetID != 0 {
+			entry.TargetID = strconv.FormatUint(uint64(change.targetID), 10)
+		}
+		before, after = change.before, change.after
+	}
+	return entry, before, after
+}
@@ -16,14 +16,105 @@ const (
 	authTypeBearer     = "bearer"
 	authPayloadContext = "auth_payload"
 	authTokenContext   = "auth_token" // Store the actual token string
+	apiScopeContext    = "api_scope"  // Resource named by APIScope for API keys
+
+	// impersonationWriteContext marks routes that impersonation tokens may
+	// call with a method other than GET, HEAD or OPTIONS
+	impersonationWriteContext = "impersonation_write"
 )
 
 func errorResponse(msg string) gin.H {
 	return gin.H{"error": msg}
 }
 
-// AuthMiddleware verifies JWT and stores claims into context
-func AuthMiddleware(tokenMaker token.TokenMaker, blacklist token.TokenBlacklist) gin.HandlerFunc {
+// SessionValidator checks that the server-side session behind a token is
+// still active
+type SessionValidator interface {
+	ValidateSession(userID uint, tokenID, ip string) error
+}
+
+// AccountChecker rejects accounts that may not use the API, such as
+// suspended ones, even while their tokens are valid
+type AccountChecker interface {
+	CheckAccount(userID uint) error
+}
+
+// APIKeyAuthenticator resolves an API key to the claims of its owner
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(key, ip string) (*token.Claims, error)
+}
+
+// AuthMiddleware verifies JWT and stores claims into context. Restricted
+// two-factor setup tokens are rejected. API keys are accepted on routes
+// marked with APIScope.
+func AuthMiddleware(tokenMaker token.TokenMaker, blacklist token.TokenBlacklist, sessions SessionValidator, accounts AccountChecker, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
+	return authenticate(tokenMaker, blacklist, sessions, accounts, apiKeys, false)
+}
+
+// MFASetupAuthMiddleware is AuthMiddleware for the two-factor enrollment
+// routes, which also accept restricted setup tokens
+func MFASetupAuthMiddleware(tokenMaker token.TokenMaker, blacklist token.TokenBlacklist, sessions SessionValidator, accounts AccountChecker, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
+	return authenticate(tokenMaker, blacklist, sessions, accounts, apiKeys, true)
+}
+
+// OptionalAuthMiddleware is AuthMiddleware for public routes that show
+// more to signed-in users. Requests without an Authorization header pass
+// through anonymously; invalid credentials are still refused.
+func OptionalAuthMiddleware(tokenMaker token.TokenMaker, blacklist token.TokenBlacklist, sessions SessionValidator, accounts AccountChecker, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
+	auth := authenticate(tokenMaker, blacklist, sessions, accounts, apiKeys, false)
+	return func(c *gin.Context) {
+		if c.GetHeader(authHeaderKey) == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// APIScope names the resource of a route group. API keys need
+// "<resource>:read" for GET requests and "<resource>:write" otherwise. It
+// must come before the auth middleware; API keys are refused on routes
+// without a scope.
+func APIScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiScopeContext, resource)
+		c.Next()
+	}
+}
+
+// AllowImpersonationWrites lets impersonation tokens call a route that
+// changes data, such as ending the impersonation. It must come before the
+// auth middleware; impersonation tokens are otherwise limited to reads.
+func AllowImpersonationWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(impersonationWriteContext, true)
+		c.Next()
+	}
+}
+
+// readOnlyMethod reports whether an HTTP method only reads data
+func readOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// DenyAPIKeys refuses API keys on a route that otherwise has a scope, such
+// as managing sessions and keys
+func DenyAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := GetCurrentUser(c)
+		if err == nil && claims.APIKeyID != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("api keys are not accepted on this endpoint"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func authenticate(tokenMaker token.TokenMaker, blacklist token.TokenBlacklist, sessions SessionValidator, accounts AccountChecker, apiKeys APIKeyAuthenticator, allowMFASetup bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader(authHeaderKey)
 		if header == "" {
@@ -44,6 +135,11 @@ func AuthMiddleware(tokenMaker token.TokenMaker, blacklist token.TokenBlacklist)
 
 		tokenStr := parts[1]
 
+		if strings.HasPrefix(tokenStr, domain.APIKeyPrefix) {
+			authenticateAPIKey(c, apiKeys, accounts, tokenStr)
+			return
+		}
+
 		// Check if token is blacklisted
 		if blacklist != nil && blacklist.IsBlacklisted(tokenStr) {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("token has been revoked"))
@@ -56,38 +152,97 @@ func AuthMiddleware(tokenMaker token.TokenMaker, blacklist token.TokenBlacklist)
 			return
 		}
 
-		c.Set(authPayloadContext, claims)
-		c.Set(authTokenContext, tokenStr) // Store token for logout
-		c.Next()
-	}
-}
+		// Check if all of the user's tokens were revoked (e.g. password reset)
+		if issuedAt := claims.IssuedAtTime(); blacklist != nil && !issuedAt.IsZero() && blacklist.IsUserRevoked(claims.UserID, issuedAt) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("token has been revoked"))
+			return
+		}
 
-// RequireRole checks whether the user has one of the allowed roles
-func RequireRole(allowedRoles ...domain.UserRole) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		payloadRaw, exists := c.Get(authPayloadContext)
-		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("no authorization payload found"))
+		// Check that the session was not logged out
+		if sessions != nil {
+			if err := sessions.ValidateSession(claims.UserID, claims.ID, c.ClientIP()); err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("session has been logged out"))
+				return
+			}
+		}
+
+		// Suspended and deactivated accounts are refused despite a valid token
+		if !checkAccount(c, accounts, claims.UserID) {
 			return
 		}
 
-		claims, ok := payloadRaw.(*token.Claims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("invalid authorization payload"))
+		if claims.MFASetup && !allowMFASetup {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("two-factor authentication must be set up for this account"))
 			return
 		}
 
-		userRole := domain.UserRole(claims.Role)
+		c.Set(authPayloadContext, claims)
+		c.Set(authTokenContext, tokenStr) // Store token for logout
 
-		for _, role := range allowedRoles {
-			if userRole == role {
-				c.Next()
-				return
-			}
+		// Impersonation is for looking, not for changing the user's data.
+		// Claims are already set so the refused attempt is still recorded.
+		if claims.Act != nil && !readOnlyMethod(c.Request.Method) && !c.GetBool(impersonationWriteContext) {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("this action is not allowed while impersonating"))
+			return
 		}
 
-		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("insufficient permissions"))
+		c.Next()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, accounts AccountChecker, key string) {
+	if apiKeys == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("invalid or expired token"))
+		return
+	}
+
+	claims, err := apiKeys.AuthenticateAPIKey(key, c.ClientIP())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("invalid or expired api key"))
+		return
+	}
+
+	if !checkAccount(c, accounts, claims.UserID) {
+		return
+	}
+
+	resource := c.GetString(apiScopeContext)
+	if resource == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("api keys are not accepted on this endpoint"))
+		return
+	}
+
+	if !hasAPIScope(claims.Scopes, resource, c.Request.Method) {
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("api key is missing scope for "+resource))
+		return
+	}
+
+	c.Set(authPayloadContext, claims)
+	c.Next()
+}
+
+// checkAccount aborts the request unless the account may use the API
+func checkAccount(c *gin.Context, accounts AccountChecker, userID uint) bool {
+	if accounts == nil {
+		return true
+	}
+	if err := accounts.CheckAccount(userID); err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err.Error()))
+		return false
+	}
+	return true
+}
+
+// hasAPIScope reports whether the scopes allow the request method on the
+// resource. A write scope also grants read.
+func hasAPIScope(scopes []string, resource, method string) bool {
+	read := method == http.MethodGet || method == http.MethodHead
+	for _, s := range scopes {
+		if s == resource+":write" || (read && s == resource+":read") {
+			return true
+		}
 	}
+	return false
 }
 
 // GetCurrentUser extracts current JWT user claims
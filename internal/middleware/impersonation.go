@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonationRecorder stores requests made with impersonation tokens
+type ImpersonationRecorder interface {
+	RecordRequest(tokenID, method, path, route string, status int, ip string) error
+}
+
+// NoImpersonation refuses impersonation tokens on credential and
+// administrative routes, including ones that only read. The auth middleware
+// already limits impersonation tokens to reads elsewhere; NoImpersonation
+// must come after it.
+func NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := GetCurrentUser(c)
+		if err == nil && claims.Act != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("this action is not allowed while impersonating"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RecordImpersonation adds every request authenticated with an
+// impersonation token to the audit trail once it has been handled
+func RecordImpersonation(recorder ImpersonationRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		claims, err := GetCurrentUser(c)
+		if err != nil || claims.Act == nil {
+			return
+		}
+		if err := recorder.RecordRequest(claims.ID, c.Request.Method, c.Request.URL.Path, c.FullPath(), c.Writer.Status(), c.ClientIP()); err != nil {
+			log.Printf("Failed to record impersonated request: %v", err)
+		}
+	}
+}
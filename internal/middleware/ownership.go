@@ -10,88 +10,145 @@ import (
 	"elearning/internal/service"
 )
 
-// RequireCourseOwnership checks if the authenticated user owns the course
-func RequireCourseOwnership(courseService service.CourseService) gin.HandlerFunc {
-	return func(c *gin.Context) {
+// CourseAccess loads the course in the course_id parameter into the context
+// and reports whether the user's role on its staff grants the access level,
+// for use with RequirePermission
+func CourseAccess(courseService service.CourseService, staff *service.CourseStaffService, access domain.StaffAccess) ResourceAccess {
+	return func(c *gin.Context, userID uint) (bool, bool) {
 		courseID, err := strconv.ParseInt(c.Param("course_id"), 10, 64)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid course_id"})
-			c.Abort()
-			return
+			return false, false
 		}
 
 		course, err := courseService.GetByID(courseID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "course not found"})
-			c.Abort()
-			return
-		}
-
-		claims, err := GetCurrentUser(c)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			c.Abort()
-			return
-		}
-
-		if claims.Role != string(domain.RoleAdmin) && course.TeacherID != int64(claims.UserID) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "you don't have permission to access this course",
-			})
-			c.Abort()
-			return
+			return false, false
 		}
 
 		c.Set("course", course)
-		c.Next()
+		return staffAllows(c, staff, course, userID, access)
 	}
 }
 
-// RequireLessonOwnership checks if the authenticated user owns the lesson's course
-func RequireLessonOwnership(lessonService service.LessonServiceInterface, courseService service.CourseService) gin.HandlerFunc {
-	return func(c *gin.Context) {
+// LessonAccess loads the lesson in the lesson_id parameter and its course
+// into the context and reports whether the user's role on the course's
+// staff grants the access level, for use with RequirePermission
+func LessonAccess(lessonService service.LessonServiceInterface, courseService service.CourseService, staff *service.CourseStaffService, access domain.StaffAccess) ResourceAccess {
+	return func(c *gin.Context, userID uint) (bool, bool) {
 		lessonID, err := strconv.ParseInt(c.Param("lesson_id"), 10, 64)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lesson_id"})
-			c.Abort()
-			return
+			return false, false
 		}
 
 		lesson, err := lessonService.GetLesson(lessonID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "lesson not found"})
-			c.Abort()
-			return
+			return false, false
 		}
 
 		course, err := courseService.GetByID(int64(lesson.CourseID))
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "course not found"})
-			c.Abort()
-			return
+			return false, false
 		}
 
-		claims, err := GetCurrentUser(c)
+		c.Set("lesson", lesson)
+		c.Set("course", course)
+		return staffAllows(c, staff, course, userID, access)
+	}
+}
+
+// EnrollmentAccess loads the enrollment in the enrollment_id parameter and
+// reports whether it is the user's own or the user's role on the course's
+// staff grants the access level, for use with RequirePermission
+func EnrollmentAccess(enrollmentService *service.EnrollmentService, courseService service.CourseService, staff *service.CourseStaffService, access domain.StaffAccess) ResourceAccess {
+	return func(c *gin.Context, userID uint) (bool, bool) {
+		enrollmentID, err := strconv.ParseUint(c.Param("enrollment_id"), 10, 32)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			c.Abort()
-			return
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid enrollment id"})
+			return false, false
 		}
 
-		if claims.Role != string(domain.RoleAdmin) && course.TeacherID != int64(claims.UserID) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "you don't have permission to access this lesson",
-			})
-			c.Abort()
-			return
+		enrollment, err := enrollmentService.GetEnrollment(uint(enrollmentID))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "enrollment not found"})
+			return false, false
+		}
+		if enrollment.UserID == userID {
+			return true, true
 		}
 
-		c.Set("lesson", lesson)
+		course, err := courseService.GetByID(int64(enrollment.CourseID))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "course not found"})
+			return false, false
+		}
+		return staffAllows(c, staff, course, userID, access)
+	}
+}
+
+const contentAccessContext = "content_access"
+
+// ContentAccess loads the course in the course_id parameter into the
+// context and records whether the caller may view its lesson content (see
+// HasContentAccess). Free courses are open to everyone; priced ones to
+// their staff, to users allowed to manage any lesson, and to students with
+// a purchase or subscription. It never aborts for lack of access, so
+// handlers can still show a course's outline.
+func ContentAccess(authz Authorizer, courseService service.CourseService, staff *service.CourseStaffService, enrollments *service.EnrollmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		courseID, err := strconv.ParseInt(c.Param("course_id"), 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, errorResponse("invalid course_id"))
+			return
+		}
+		course, err := courseService.GetByID(courseID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, errorResponse("course not found"))
+			return
+		}
 		c.Set("course", course)
+
+		allowed := !course.RequiresPayment()
+		if claims, err := GetCurrentUser(c); err == nil && !allowed {
+			role, err := staff.StaffRole(course, claims.UserID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse("failed to check course staff"))
+				return
+			}
+			scope, _ := authz.PermissionScope(claims.Role, domain.PermLessonManage)
+			allowed = role != "" || scope == domain.ScopeAny
+			if !allowed {
+				if allowed, err = enrollments.HasContentAccess(claims.UserID, course); err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse("failed to check course access"))
+					return
+				}
+			}
+		}
+
+		c.Set(contentAccessContext, allowed)
 		c.Next()
 	}
 }
 
+// HasContentAccess reports whether ContentAccess granted the caller access
+// to the course's lesson content
+func HasContentAccess(c *gin.Context) bool {
+	return c.GetBool(contentAccessContext)
+}
+
+func staffAllows(c *gin.Context, staff *service.CourseStaffService, course *domain.Course, userID uint, access domain.StaffAccess) (bool, bool) {
+	role, err := staff.StaffRole(course, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check course staff"})
+		return false, false
+	}
+	return role.Allows(access), true
+}
+
 // GetCourseFromContext Helper functions
 func GetCourseFromContext(c *gin.Context) (*domain.Course, bool) {
 	course, exists := c.Get("course")
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"elearning/internal/domain"
+)
+
+const permissionScopeContext = "permission_scope"
+
+// Authorizer resolves the permissions of a role
+type Authorizer interface {
+	PermissionScope(role string, perm domain.Permission) (domain.PermissionScope, bool)
+}
+
+// ResourceAccess loads the resource a route acts on and reports whether it
+// belongs to the user, e.g. a course the user is on the staff of. It
+// responds and returns ok=false when the resource cannot be loaded.
+type ResourceAccess func(c *gin.Context, userID uint) (owned, ok bool)
+
+// RequirePermission checks that the user's role holds the permission. With
+// a resource, a permission held only in the "own" scope is limited to
+// resources the user owns; without one the granted scope is left in the
+// context for the handler to apply (see GrantedScope).
+func RequirePermission(authz Authorizer, perm domain.Permission, resource ...ResourceAccess) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := GetCurrentUser(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse("no authorization payload found"))
+			return
+		}
+
+		scope, ok := authz.PermissionScope(claims.Role, perm)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("insufficient permissions"))
+			return
+		}
+
+		for _, resolve := range resource {
+			owned, ok := resolve(c, claims.UserID)
+			if !ok {
+				c.Abort()
+				return
+			}
+			if scope == domain.ScopeOwn && !owned {
+				c.AbortWithStatusJSON(http.StatusForbidden, errorResponse("you don't have permission to access this resource"))
+				return
+			}
+		}
+
+		c.Set(permissionScopeContext, scope)
+		c.Next()
+	}
+}
+
+// GrantedScope returns the scope of the permission checked by
+// RequirePermission
+func GrantedScope(c *gin.Context) domain.PermissionScope {
+	scope, _ := c.Get(permissionScopeContext)
+	s, _ := scope.(domain.PermissionScope)
+	return s
+}
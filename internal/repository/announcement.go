@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type AnnouncementRepository interface {
+	Create(announcement *domain.Announcement) error
+	// AddRecipients adds to the count of students an announcement reached
+	AddRecipients(id uint, count int) error
+	FindByCourse(courseID uint, page, limit int) ([]domain.Announcement, int64, error)
+}
+
+type announcementRepository struct {
+	db *gorm.DB
+}
+
+func NewAnnouncementRepository(db *gorm.DB) AnnouncementRepository {
+	return &announcementRepository{db: db}
+}
+
+func (r *announcementRepository) Create(announcement *domain.Announcement) error {
+	return r.db.Create(announcement).Error
+}
+
+func (r *announcementRepository) AddRecipients(id uint, count int) error {
+	return r.db.Model(&domain.Announcement{}).
+		Where("id = ?", id).
+		Update("recipients", gorm.Expr("recipients + ?", count)).Error
+}
+
+func (r *announcementRepository) FindByCourse(courseID uint, page, limit int) ([]domain.Announcement, int64, error) {
+	var announcements []domain.Announcement
+	var total int64
+	offset := (page - 1) * limit
+
+	if err := r.db.Model(&domain.Announcement{}).
+		Where("course_id = ?", courseID).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.Where("course_id = ?", courseID).
+		Preload("Author").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&announcements).Error
+
+	return announcements, total, err
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository interface {
+	Create(key *domain.APIKey) error
+	FindByHash(keyHash string) (*domain.APIKey, error)
+	// ListByUser returns the user's keys, including revoked and expired ones
+	ListByUser(userID uint) ([]domain.APIKey, error)
+	ListAll() ([]domain.APIKey, error)
+	CountActive(userID uint, now time.Time) (int64, error)
+	// Revoke revokes a key; userID zero matches any owner
+	Revoke(userID, id uint, at time.Time) error
+	// RevokeAllForUser revokes every unrevoked key of the user and returns
+	// how many it revoked
+	RevokeAllForUser(userID uint, at time.Time) (int64, error)
+	// TouchUsed records use of a key at most once per interval
+	TouchUsed(id uint, ip string, at time.Time, interval time.Duration) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(key *domain.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) FindByHash(keyHash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) ListByUser(userID uint) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) ListAll() ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) CountActive(userID uint, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *apiKeyRepository) Revoke(userID, id uint, at time.Time) error {
+	query := r.db.Model(&domain.APIKey{}).Where("id = ? AND revoked_at IS NULL", id)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	result := query.Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) RevokeAllForUser(userID uint, at time.Time) (int64, error) {
+	result := r.db.Model(&domain.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *apiKeyRepository) TouchUsed(id uint, ip string, at time.Time, interval time.Duration) error {
+	return r.db.Model(&domain.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ? OR last_used_ip <> ?)", id, at.Add(-interval), ip).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
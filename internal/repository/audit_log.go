@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// auditChainLock is the advisory lock key serializing appends to the audit
+// log so every entry links to the one written just before it
+const auditChainLock = 0x61756469
+
+// AuditLogFilter narrows audit log queries; zero fields match all
+type AuditLogFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditLogRepository interface {
+	// Append stores an entry after seal has set its hash from the hash of
+	// the latest entry
+	Append(entry *domain.AuditLog, seal func(entry *domain.AuditLog, prevHash string)) error
+	List(filter AuditLogFilter, page, limit int) ([]domain.AuditLog, int64, error)
+	// Each visits matching entries in chain order, batch by batch
+	Each(filter AuditLogFilter, fn func(entries []domain.AuditLog) error) error
+}
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Append(entry *domain.AuditLog, seal func(entry *domain.AuditLog, prevHash string)) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+			return err
+		}
+
+		var prevHash string
+		err := tx.Model(&domain.AuditLog{}).
+			Order("id DESC").
+			Limit(1).
+			Pluck("hash", &prevHash).Error
+		if err != nil {
+			return err
+		}
+
+		seal(entry, prevHash)
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *auditLogRepository) List(filter AuditLogFilter, page, limit int) ([]domain.AuditLog, int64, error) {
+	query := r.filtered(filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []domain.AuditLog
+	err := query.Order("id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *auditLogRepository) Each(filter AuditLogFilter, fn func(entries []domain.AuditLog) error) error {
+	var batch []domain.AuditLog
+	return r.filtered(filter).Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+func (r *auditLogRepository) filtered(filter AuditLogFilter) *gorm.DB {
+	query := r.db.Model(&domain.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCouponNotFound   = errors.New("coupon not found")
+	ErrCouponCodeExists = errors.New("coupon code already exists")
+	ErrCouponExhausted  = errors.New("coupon has no uses left")
+)
+
+type CouponFilter struct {
+	// CreatedBy limits the listing to one author's coupons
+	CreatedBy uint
+	Active    *bool
+	// Query matches part of the code, ignoring case
+	Query string
+}
+
+type CouponRepository interface {
+	Create(coupon *domain.Coupon) error
+	FindByID(id uint) (*domain.Coupon, error)
+	FindByCode(code string) (*domain.Coupon, error)
+	List(filter CouponFilter, page, limit int) ([]domain.Coupon, int64, error)
+	Update(coupon *domain.Coupon) error
+	Delete(id uint) error
+	// CountUserRedemptions counts the customer's reserved and redeemed uses
+	CountUserRedemptions(couponID, userID uint) (int64, error)
+	// Reserve holds a use of the coupon for an unpaid order, failing with
+	// ErrCouponExhausted when the coupon or the customer's allowance of
+	// maxPerUser uses (zero is unlimited) is used up
+	Reserve(redemption *domain.CouponRedemption, maxPerUser int) error
+	// Release gives back the use reserved for an order, if any
+	Release(orderID uint) error
+	// Redeem confirms the use reserved for a paid order. Without a
+	// reservation the use is recorded and counted anyway, since the
+	// customer has paid the discounted price.
+	Redeem(redemption *domain.CouponRedemption) error
+	ListRedemptions(couponID uint, page, limit int) ([]domain.CouponRedemption, int64, error)
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{db: db}
+}
+
+func (r *couponRepository) Create(coupon *domain.Coupon) error {
+	var count int64
+	if err := r.db.Model(&domain.Coupon{}).Where("code = ?", coupon.Code).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCouponCodeExists
+	}
+	return r.db.Create(coupon).Error
+}
+
+func (r *couponRepository) FindByID(id uint) (*domain.Coupon, error) {
+	var coupon domain.Coupon
+	if err := r.db.First(&coupon, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) FindByCode(code string) (*domain.Coupon, error) {
+	var coupon domain.Coupon
+	if err := r.db.Where("code = ?", strings.ToUpper(code)).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) List(filter CouponFilter, page, limit int) ([]domain.Coupon, int64, error) {
+	query := r.db.Model(&domain.Coupon{})
+	if filter.CreatedBy != 0 {
+		query = query.Where("created_by = ?", filter.CreatedBy)
+	}
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+	if filter.Query != "" {
+		query = query.Where("code ILIKE ?", "%"+filter.Query+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var coupons []domain.Coupon
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&coupons).Error
+	return coupons, total, err
+}
+
+func (r *couponRepository) Update(coupon *domain.Coupon) error {
+	return r.db.Save(coupon).Error
+}
+
+func (r *couponRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.Coupon{}, id).Error
+}
+
+func (r *couponRepository) CountUserRedemptions(couponID, userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", couponID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *couponRepository) Reserve(redemption *domain.CouponRedemption, maxPerUser int) error {
+	redemption.Status = domain.CouponReserved
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// The update locks the coupon row until commit, which also keeps
+		// the customer's count below from changing concurrently
+		result := tx.Model(&domain.Coupon{}).
+			Where("id = ? AND (max_redemptions = 0 OR redemptions < max_redemptions)", redemption.CouponID).
+			UpdateColumn("redemptions", gorm.Expr("redemptions + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCouponExhausted
+		}
+
+		if maxPerUser > 0 {
+			var used int64
+			err := tx.Model(&domain.CouponRedemption{}).
+				Where("coupon_id = ? AND user_id = ?", redemption.CouponID, redemption.UserID).
+				Count(&used).Error
+			if err != nil {
+				return err
+			}
+			if used >= int64(maxPerUser) {
+				return ErrCouponExhausted
+			}
+		}
+		return tx.Create(redemption).Error
+	})
+}
+
+func (r *couponRepository) Release(orderID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var released []domain.CouponRedemption
+		result := tx.Clauses(clause.Returning{}).
+			Where("order_id = ? AND status = ?", orderID, domain.CouponReserved).
+			Delete(&released)
+		if result.Error != nil || len(released) == 0 {
+			return result.Error
+		}
+		return tx.Model(&domain.Coupon{}).
+			Where("id = ? AND redemptions > 0", released[0].CouponID).
+			UpdateColumn("redemptions", gorm.Expr("redemptions - 1")).Error
+	})
+}
+
+func (r *couponRepository) Redeem(redemption *domain.CouponRedemption) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.CouponRedemption{}).
+			Where("order_id = ? AND status = ?", redemption.OrderID, domain.CouponReserved).
+			Update("status", domain.CouponRedeemed)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		redemption.Status = domain.CouponRedeemed
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(redemption)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&domain.Coupon{}).
+			Where("id = ?", redemption.CouponID).
+			UpdateColumn("redemptions", gorm.Expr("redemptions + 1")).Error
+	})
+}
+
+func (r *couponRepository) ListRedemptions(couponID uint, page, limit int) ([]domain.CouponRedemption, int64, error) {
+	query := r.db.Model(&domain.CouponRedemption{}).
+		Where("coupon_id = ? AND status = ?", couponID, domain.CouponRedeemed)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var redemptions []domain.CouponRedemption
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&redemptions).Error
+	return redemptions, total, err
+}
@@ -7,6 +7,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// liveCourseClause and liveUserClause leave out rows that belong to a
+// course or user in the trash, for queries on tables without soft delete
+const (
+	liveCourseClause = "course_id NOT IN (SELECT id FROM courses WHERE deleted_at IS NOT NULL)"
+	liveUserClause   = "user_id NOT IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)"
+)
+
 type CourseRepository interface {
 	Create(course *domain.Course) error
 	FindByID(id int64) (*domain.Course, error)
@@ -68,13 +75,13 @@ func (r *courseRepository) GetByInstructorID(ctx context.Context, instructorID u
 	offset := (page - 1) * limit
 
 	if err := r.db.WithContext(ctx).Model(&domain.Course{}).
-		Where("teacher_id = ?", instructorID).
+		Where(taughtByClause, instructorID, instructorID).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	err := r.db.WithContext(ctx).
-		Where("teacher_id = ?", instructorID).
+		Where(taughtByClause, instructorID, instructorID).
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var ErrStaffNotFound = errors.New("staff membership not found")
+
+// taughtByClause matches courses a user owns or has accepted a staff
+// invitation to. It takes the user ID twice.
+const taughtByClause = "(teacher_id = ? OR id IN (SELECT course_id FROM course_staff WHERE user_id = ? AND accepted_at IS NOT NULL))"
+
+type CourseStaffRepository interface {
+	Create(staff *domain.CourseStaff) error
+	// Find returns the user's membership on the course, accepted or not
+	Find(courseID, userID uint) (*domain.CourseStaff, error)
+	// ListByCourse returns the course's staff with their users, including
+	// pending invitations
+	ListByCourse(courseID uint) ([]domain.CourseStaff, error)
+	// ListAcceptedUserIDs returns the users who accepted a staff role on
+	// the course
+	ListAcceptedUserIDs(courseID uint) ([]uint, error)
+	// ListInvitations returns the user's pending invitations with their
+	// courses
+	ListInvitations(userID uint) ([]domain.CourseStaff, error)
+	UpdateRole(courseID, userID uint, role domain.CourseStaffRole) error
+	Accept(courseID, userID uint, at time.Time) error
+	Delete(courseID, userID uint) error
+}
+
+type courseStaffRepository struct {
+	db *gorm.DB
+}
+
+func NewCourseStaffRepository(db *gorm.DB) CourseStaffRepository {
+	return &courseStaffRepository{db: db}
+}
+
+func (r *courseStaffRepository) Create(staff *domain.CourseStaff) error {
+	return r.db.Create(staff).Error
+}
+
+func (r *courseStaffRepository) Find(courseID, userID uint) (*domain.CourseStaff, error) {
+	var staff domain.CourseStaff
+	if err := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).First(&staff).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStaffNotFound
+		}
+		return nil, err
+	}
+	return &staff, nil
+}
+
+func (r *courseStaffRepository) ListByCourse(courseID uint) ([]domain.CourseStaff, error) {
+	var staff []domain.CourseStaff
+	err := r.db.Preload("User").
+		Where("course_id = ?", courseID).
+		Order("created_at").
+		Find(&staff).Error
+	return staff, err
+}
+
+func (r *courseStaffRepository) ListAcceptedUserIDs(courseID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.CourseStaff{}).
+		Where("course_id = ? AND accepted_at IS NOT NULL", courseID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *courseStaffRepository) ListInvitations(userID uint) ([]domain.CourseStaff, error) {
+	var staff []domain.CourseStaff
+	err := r.db.Preload("Course").
+		Where("user_id = ? AND accepted_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&staff).Error
+	return staff, err
+}
+
+func (r *courseStaffRepository) UpdateRole(courseID, userID uint, role domain.CourseStaffRole) error {
+	result := r.db.Model(&domain.CourseStaff{}).
+		Where("course_id = ? AND user_id = ?", courseID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaffNotFound
+	}
+	return nil
+}
+
+func (r *courseStaffRepository) Accept(courseID, userID uint, at time.Time) error {
+	result := r.db.Model(&domain.CourseStaff{}).
+		Where("course_id = ? AND user_id = ? AND accepted_at IS NULL", courseID, userID).
+		Update("accepted_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaffNotFound
+	}
+	return nil
+}
+
+func (r *courseStaffRepository) Delete(courseID, userID uint) error {
+	result := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).Delete(&domain.CourseStaff{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaffNotFound
+	}
+	return nil
+}
@@ -15,6 +15,8 @@ type DashboardRepository interface {
 	GetTeacherCourses(ctx context.Context, teacherID int64) ([]domain.TeacherCourse, error)
 	GetTeacherStats(ctx context.Context, teacherID int64) (*domain.TeacherStats, error)
 	GetRecentEnrollments(ctx context.Context, teacherID int64, limit int) ([]domain.RecentEnrollment, error)
+	// GetTeacherEarnings sums the teacher's revenue share by currency
+	GetTeacherEarnings(ctx context.Context, teacherID int64) ([]domain.TeacherEarnings, error)
 
 	GetAdminStats(ctx context.Context) (*domain.AdminStatistics, error)
 	GetRecentActivities(ctx context.Context, limit int) ([]domain.RecentActivity, error)
@@ -37,6 +39,7 @@ func (r *dashboardRepository) GetStudentEnrolledCourses(ctx context.Context, use
 		Preload("Course").
 		Preload("User").
 		Where("user_id = ? AND status = ?", userID, domain.EnrollmentStatusActive).
+		Where(liveCourseClause).
 		Order("enrolled_at DESC").
 		Find(&enrollments).Error
 
@@ -67,7 +70,7 @@ func (r *dashboardRepository) GetStudentEnrolledCourses(ctx context.Context, use
 		if totalLessons > 0 {
 			r.db.WithContext(ctx).
 				Table("progress").
-				Joins("JOIN lessons ON progress.lesson_id = lessons.id").
+				Joins("JOIN lessons ON progress.lesson_id = lessons.id AND lessons.deleted_at IS NULL").
 				Where("lessons.course_id = ? AND progress.user_id = ? AND progress.is_completed = ?",
 					enrollment.Course.ID, userID, true).
 				Count(&completedCount)
@@ -102,6 +105,7 @@ func (r *dashboardRepository) GetStudentStats(ctx context.Context, userID uint)
 	if err := r.db.WithContext(ctx).
 		Model(&domain.Enrollment{}).
 		Where("user_id = ? AND status = ?", userID, domain.EnrollmentStatusActive).
+		Where(liveCourseClause).
 		Count(&totalEnrolled).Error; err != nil {
 		return nil, err
 	}
@@ -112,6 +116,7 @@ func (r *dashboardRepository) GetStudentStats(ctx context.Context, userID uint)
 	if err := r.db.WithContext(ctx).
 		Model(&domain.Enrollment{}).
 		Where("user_id = ? AND status = ?", userID, domain.EnrollmentStatusCompleted).
+		Where(liveCourseClause).
 		Count(&coursesCompleted).Error; err != nil {
 		return nil, err
 	}
@@ -139,7 +144,7 @@ func (r *dashboardRepository) GetTeacherCourses(ctx context.Context, teacherID i
 	var courses []domain.Course
 
 	err := r.db.WithContext(ctx).
-		Where("teacher_id = ?", teacherID).
+		Where(taughtByClause, teacherID, teacherID).
 		Order("created_at DESC").
 		Find(&courses).Error
 
@@ -161,12 +166,12 @@ func (r *dashboardRepository) GetTeacherCourses(ctx context.Context, teacherID i
 			Where("course_id = ?", course.ID).
 			Count(&totalLessons)
 
-		// Count enrollments by status
+		// Count enrollments by status; refunded students are not counted
 		var totalStudents, activeStudents, completedStudents int64
 
 		r.db.WithContext(ctx).
 			Model(&domain.Enrollment{}).
-			Where("course_id = ?", course.ID).
+			Where("course_id = ? AND status <> ?", course.ID, domain.EnrollmentStatusRefunded).
 			Count(&totalStudents)
 
 		r.db.WithContext(ctx).
@@ -179,6 +184,26 @@ func (r *dashboardRepository) GetTeacherCourses(ctx context.Context, teacherID i
 			Where("course_id = ? AND status = ?", course.ID, domain.EnrollmentStatusCompleted).
 			Count(&completedStudents)
 
+		// Sales in the course's current currency; refunded orders are not
+		// paid any more
+		var revenue int64
+		r.db.WithContext(ctx).
+			Model(&domain.Order{}).
+			Where("course_id = ? AND currency = ? AND status = ?", course.ID, course.Currency, domain.OrderStatusPaid).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&revenue)
+
+		// The teacher's share of those sales, as posted to the ledger
+		var earnings int64
+		r.db.WithContext(ctx).
+			Table("ledger_entries e").
+			Joins("JOIN ledger_transactions t ON t.id = e.transaction_id").
+			Where("e.account = ? AND e.teacher_id = ? AND e.currency = ? AND t.course_id = ? AND t.kind IN ?",
+				domain.LedgerTeacherPayable, teacherID, course.Currency, course.ID,
+				[]domain.LedgerKind{domain.LedgerSale, domain.LedgerRefund}).
+			Select("COALESCE(SUM(e.credit - e.debit), 0)").
+			Scan(&earnings)
+
 		teacherCourses = append(teacherCourses, domain.TeacherCourse{
 			ID:                course.ID,
 			Title:             course.Title,
@@ -188,6 +213,9 @@ func (r *dashboardRepository) GetTeacherCourses(ctx context.Context, teacherID i
 			TotalStudents:     int(totalStudents),
 			ActiveStudents:    int(activeStudents),
 			CompletedStudents: int(completedStudents),
+			Revenue:           revenue,
+			Earnings:          earnings,
+			Currency:          course.Currency,
 			CreatedAt:         course.CreatedAt,
 		})
 	}
@@ -202,7 +230,7 @@ func (r *dashboardRepository) GetTeacherStats(ctx context.Context, teacherID int
 	var totalCourses int64
 	if err := r.db.WithContext(ctx).
 		Model(&domain.Course{}).
-		Where("teacher_id = ?", teacherID).
+		Where(taughtByClause, teacherID, teacherID).
 		Count(&totalCourses).Error; err != nil {
 		return nil, err
 	}
@@ -212,7 +240,7 @@ func (r *dashboardRepository) GetTeacherStats(ctx context.Context, teacherID int
 	var publishedCourses int64
 	if err := r.db.WithContext(ctx).
 		Model(&domain.Course{}).
-		Where("teacher_id = ? AND is_published = ?", teacherID, true).
+		Where(taughtByClause+" AND is_published = ?", teacherID, teacherID, true).
 		Count(&publishedCourses).Error; err != nil {
 		return nil, err
 	}
@@ -222,7 +250,7 @@ func (r *dashboardRepository) GetTeacherStats(ctx context.Context, teacherID int
 	var courseIDs []int64
 	r.db.WithContext(ctx).
 		Model(&domain.Course{}).
-		Where("teacher_id = ?", teacherID).
+		Where(taughtByClause, teacherID, teacherID).
 		Pluck("id", &courseIDs)
 
 	if len(courseIDs) > 0 {
@@ -230,7 +258,7 @@ func (r *dashboardRepository) GetTeacherStats(ctx context.Context, teacherID int
 		var totalStudents int64
 		r.db.WithContext(ctx).
 			Model(&domain.Enrollment{}).
-			Where("course_id IN ?", courseIDs).
+			Where("course_id IN ? AND status <> ?", courseIDs, domain.EnrollmentStatusRefunded).
 			Distinct("user_id").
 			Count(&totalStudents)
 		stats.TotalStudents = int(totalStudents)
@@ -247,12 +275,32 @@ func (r *dashboardRepository) GetTeacherStats(ctx context.Context, teacherID int
 	return stats, nil
 }
 
+func (r *dashboardRepository) GetTeacherEarnings(ctx context.Context, teacherID int64) ([]domain.TeacherEarnings, error) {
+	earnings := []domain.TeacherEarnings{}
+	err := r.db.WithContext(ctx).
+		Table("ledger_entries e").
+		Joins("JOIN ledger_transactions t ON t.id = e.transaction_id").
+		Where("e.account = ? AND e.teacher_id = ?", domain.LedgerTeacherPayable, teacherID).
+		Select(`e.currency,
+			COALESCE(SUM(CASE WHEN t.kind = ? THEN e.credit - e.debit ELSE 0 END), 0) AS earned,
+			COALESCE(SUM(CASE WHEN t.kind = ? THEN e.debit - e.credit ELSE 0 END), 0) AS refunded,
+			COALESCE(SUM(CASE WHEN t.kind = ? THEN e.debit - e.credit ELSE 0 END), 0) AS paid_out,
+			COALESCE((SELECT SUM(p.amount) FROM payouts p
+				WHERE p.teacher_id = ? AND p.currency = e.currency AND p.status = ?), 0) AS pending,
+			COALESCE(SUM(e.credit - e.debit), 0) AS balance`,
+			domain.LedgerSale, domain.LedgerRefund, domain.LedgerPayout, teacherID, domain.PayoutPending).
+		Group("e.currency").
+		Order("e.currency").
+		Scan(&earnings).Error
+	return earnings, err
+}
+
 func (r *dashboardRepository) GetRecentEnrollments(ctx context.Context, teacherID int64, limit int) ([]domain.RecentEnrollment, error) {
 	// Get teacher's course IDs
 	var courseIDs []int64
 	if err := r.db.WithContext(ctx).
 		Model(&domain.Course{}).
-		Where("teacher_id = ?", teacherID).
+		Where(taughtByClause, teacherID, teacherID).
 		Pluck("id", &courseIDs).Error; err != nil {
 		return nil, err
 	}
@@ -267,6 +315,7 @@ func (r *dashboardRepository) GetRecentEnrollments(ctx context.Context, teacherI
 		Preload("User").
 		Preload("Course").
 		Where("course_id IN ?", courseIDs).
+		Where(liveUserClause).
 		Order("enrolled_at DESC").
 		Limit(limit).
 		Find(&enrollments).Error
@@ -412,6 +461,8 @@ func (r *dashboardRepository) GetRecentActivities(ctx context.Context, limit int
 	r.db.WithContext(ctx).
 		Preload("User").
 		Preload("Course").
+		Where(liveCourseClause).
+		Where(liveUserClause).
 		Order("enrolled_at DESC").
 		Limit(limit / 3).
 		Find(&enrollments)
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -24,7 +25,14 @@ type EnrollmentRepository interface {
 	Delete(id uint) error
 	IsEnrolled(userID, courseID uint) (bool, error)
 	CountEnrollmentsByCourse(courseID uint) (int64, error)
-	UpdateProgress(enrollmentID uint, progress float64) error
+	// UpdateProgress sets the progress of an active or completed enrollment
+	// and reports whether it did
+	UpdateProgress(enrollmentID uint, progress float64) (bool, error)
+	// MarkCompleted completes an active enrollment and reports whether it did
+	MarkCompleted(enrollmentID uint) (bool, error)
+	// RaiseMaxProgress records progress reached in a course if it is above
+	// the enrollment's high-water mark
+	RaiseMaxProgress(userID, courseID uint, progress float64) error
 }
 
 type enrollmentRepository struct {
@@ -36,24 +44,41 @@ func NewEnrollmentRepository(db *gorm.DB) EnrollmentRepository {
 	return &enrollmentRepository{db: db}
 }
 
-// Create creates a new enrollment
+// Create creates a new enrollment. A dropped or refunded enrollment in the
+// same course is reactivated instead, as there is one row per user and
+// course.
 func (r *enrollmentRepository) Create(enrollment *domain.Enrollment) error {
-	// Check if already enrolled
-	var count int64
-	err := r.db.Model(&domain.Enrollment{}).
-		Where("user_id = ? AND course_id = ? AND status != ?",
-			enrollment.UserID, enrollment.CourseID, domain.EnrollmentStatusDropped).
-		Count(&count).Error
-
+	var existing domain.Enrollment
+	err := r.db.Where("user_id = ? AND course_id = ?", enrollment.UserID, enrollment.CourseID).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.db.Create(enrollment).Error
+	}
 	if err != nil {
 		return err
 	}
 
-	if count > 0 {
+	if existing.Status != domain.EnrollmentStatusDropped && existing.Status != domain.EnrollmentStatusRefunded {
 		return ErrAlreadyEnrolled
 	}
 
-	return r.db.Create(enrollment).Error
+	enrollment.ID = existing.ID
+	enrollment.EnrolledAt = time.Now()
+	enrollment.CompletedAt = nil
+	result := r.db.Model(&domain.Enrollment{}).
+		Where("id = ? AND status = ?", existing.ID, existing.Status).
+		Updates(map[string]interface{}{
+			"status":       enrollment.Status,
+			"enrolled_at":  enrollment.EnrolledAt,
+			"completed_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlreadyEnrolled
+	}
+	return nil
 }
 
 // FindByID finds an enrollment by ID
@@ -82,6 +107,7 @@ func (r *enrollmentRepository) FindByUserAndCourse(userID, courseID uint) (*doma
 func (r *enrollmentRepository) FindByUser(userID uint) ([]domain.Enrollment, error) {
 	var enrollments []domain.Enrollment
 	err := r.db.Where("user_id = ?", userID).
+		Where(liveCourseClause).
 		Preload("Course").
 		Order("enrolled_at DESC").
 		Find(&enrollments).Error
@@ -92,6 +118,7 @@ func (r *enrollmentRepository) FindByUser(userID uint) ([]domain.Enrollment, err
 func (r *enrollmentRepository) FindByCourse(courseID uint) ([]domain.Enrollment, error) {
 	var enrollments []domain.Enrollment
 	err := r.db.Where("course_id = ?", courseID).
+		Where(liveUserClause).
 		Preload("User").
 		Order("enrolled_at DESC").
 		Find(&enrollments).Error
@@ -131,8 +158,25 @@ func (r *enrollmentRepository) CountEnrollmentsByCourse(courseID uint) (int64, e
 }
 
 // UpdateProgress updates the progress percentage of an enrollment
-func (r *enrollmentRepository) UpdateProgress(enrollmentID uint, progress float64) error {
+func (r *enrollmentRepository) UpdateProgress(enrollmentID uint, progress float64) (bool, error) {
+	result := r.db.Model(&domain.Enrollment{}).
+		Where("id = ? AND status IN ?", enrollmentID, []domain.EnrollmentStatus{domain.EnrollmentStatusActive, domain.EnrollmentStatusCompleted}).
+		Updates(map[string]interface{}{
+			"progress_percent": progress,
+			"max_progress":     gorm.Expr("GREATEST(max_progress, ?)", progress),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *enrollmentRepository) MarkCompleted(enrollmentID uint) (bool, error) {
+	result := r.db.Model(&domain.Enrollment{}).
+		Where("id = ? AND status = ?", enrollmentID, domain.EnrollmentStatusActive).
+		Update("status", domain.EnrollmentStatusCompleted)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *enrollmentRepository) RaiseMaxProgress(userID, courseID uint, progress float64) error {
 	return r.db.Model(&domain.Enrollment{}).
-		Where("id = ?", enrollmentID).
-		Update("progress_percent", progress).Error
+		Where("user_id = ? AND course_id = ? AND max_progress < ?", userID, courseID, progress).
+		Update("max_progress", progress).Error
 }
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrSSOStateInvalid  = errors.New("sso state is invalid or expired")
+)
+
+type IdentityRepository interface {
+	FindByProviderSubject(provider, subject string) (*domain.UserIdentity, error)
+	Create(identity *domain.UserIdentity) error
+	TouchLogin(id uint, email string, at time.Time) error
+
+	CreateState(state *domain.SSOLoginState) error
+	// ConsumeState marks a login state as used and returns it. It fails with
+	// ErrSSOStateInvalid if the state is unknown, expired or already used.
+	ConsumeState(provider, stateHash string) (*domain.SSOLoginState, error)
+	DeleteExpiredStates(before time.Time) error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) FindByProviderSubject(provider, subject string) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) Create(identity *domain.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *identityRepository) TouchLogin(id uint, email string, at time.Time) error {
+	return r.db.Model(&domain.UserIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "last_login_at": at}).Error
+}
+
+func (r *identityRepository) CreateState(state *domain.SSOLoginState) error {
+	return r.db.Create(state).Error
+}
+
+func (r *identityRepository) ConsumeState(provider, stateHash string) (*domain.SSOLoginState, error) {
+	var state domain.SSOLoginState
+	err := r.db.Where("provider = ? AND state_hash = ?", provider, stateHash).First(&state).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSSOStateInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if state.UsedAt != nil || now.After(state.ExpiresAt) {
+		return nil, ErrSSOStateInvalid
+	}
+
+	// Conditional update so a callback cannot be replayed concurrently
+	result := r.db.Model(&domain.SSOLoginState{}).
+		Where("id = ? AND used_at IS NULL", state.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrSSOStateInvalid
+	}
+
+	state.UsedAt = &now
+	return &state, nil
+}
+
+func (r *identityRepository) DeleteExpiredStates(before time.Time) error {
+	return r.db.Where("expires_at < ?", before).Delete(&domain.SSOLoginState{}).Error
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var ErrImpersonationNotFound = errors.New("impersonation not found")
+
+// ImpersonationFilter narrows the impersonation list; zero fields match all
+type ImpersonationFilter struct {
+	AdminID uint
+	UserID  uint
+}
+
+type ImpersonationRepository interface {
+	Create(impersonation *domain.Impersonation) error
+	FindByID(id uint) (*domain.Impersonation, error)
+	FindByTokenID(tokenID string) (*domain.Impersonation, error)
+	List(filter ImpersonationFilter, page, limit int) ([]domain.Impersonation, int64, error)
+	End(id uint, at time.Time) error
+	CreateRequest(request *domain.ImpersonationRequest) error
+	ListRequests(impersonationID uint, page, limit int) ([]domain.ImpersonationRequest, int64, error)
+}
+
+type impersonationRepository struct {
+	db *gorm.DB
+}
+
+func NewImpersonationRepository(db *gorm.DB) ImpersonationRepository {
+	return &impersonationRepository{db: db}
+}
+
+func (r *impersonationRepository) Create(impersonation *domain.Impersonation) error {
+	return r.db.Create(impersonation).Error
+}
+
+func (r *impersonationRepository) FindByID(id uint) (*domain.Impersonation, error) {
+	var impersonation domain.Impersonation
+	if err := r.db.Preload("Admin").Preload("User").First(&impersonation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImpersonationNotFound
+		}
+		return nil, err
+	}
+	return &impersonation, nil
+}
+
+func (r *impersonationRepository) FindByTokenID(tokenID string) (*domain.Impersonation, error) {
+	var impersonation domain.Impersonation
+	if err := r.db.Where("token_id = ?", tokenID).First(&impersonation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImpersonationNotFound
+		}
+		return nil, err
+	}
+	return &impersonation, nil
+}
+
+func (r *impersonationRepository) List(filter ImpersonationFilter, page, limit int) ([]domain.Impersonation, int64, error) {
+	query := r.db.Model(&domain.Impersonation{})
+	if filter.AdminID != 0 {
+		query = query.Where("admin_id = ?", filter.AdminID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var impersonations []domain.Impersonation
+	err := query.Preload("Admin").Preload("User").
+		Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&impersonations).Error
+	return impersonations, total, err
+}
+
+func (r *impersonationRepository) End(id uint, at time.Time) error {
+	return r.db.Model(&domain.Impersonation{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Update("ended_at", at).Error
+}
+
+func (r *impersonationRepository) CreateRequest(request *domain.ImpersonationRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *impersonationRepository) ListRequests(impersonationID uint, page, limit int) ([]domain.ImpersonationRequest, int64, error) {
+	query := r.db.Model(&domain.ImpersonationRequest{}).Where("impersonation_id = ?", impersonationID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var requests []domain.ImpersonationRequest
+	err := query.Order("created_at").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&requests).Error
+	return requests, total, err
+}
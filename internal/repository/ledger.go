@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// payoutLock is the advisory lock key serializing payout batches so a
+// balance is never paid out twice
+const payoutLock = 0x7061796f
+
+var (
+	ErrLedgerTransactionNotFound = errors.New("ledger transaction not found")
+	ErrPayoutBatchNotFound       = errors.New("payout batch not found")
+	ErrPayoutNotFound            = errors.New("payout not found")
+	ErrPayoutNotPending          = errors.New("payout is already settled")
+)
+
+type LedgerRepository interface {
+	// Post stores a balanced transaction with its entries unless its
+	// source was posted before, and reports whether it did
+	Post(txn *domain.LedgerTransaction) (bool, error)
+	// FindSale returns the sale posted for an order, with its entries
+	FindSale(orderID uint) (*domain.LedgerTransaction, error)
+	// CourseTeacher returns the teacher of a course, trashed or not, or 0
+	// when the course or its teacher was purged
+	CourseTeacher(courseID uint) (uint, error)
+	// UnpostedSales returns paid or refunded orders with no sale posted
+	UnpostedSales(limit int) ([]domain.Order, error)
+	// UnpostedRefunds returns completed refunds that were not posted
+	UnpostedRefunds(limit int) ([]domain.Refund, error)
+	// UnpostedInvoices returns subscription invoices that were not posted
+	UnpostedInvoices(limit int) ([]domain.SubscriptionInvoice, error)
+
+	// Balances returns teacher balances of at least minimum, in currency
+	// or in every currency when it is empty, less pending payouts
+	Balances(currency string, minimum int64) ([]domain.TeacherBalance, error)
+	// CreateBatch creates pending payouts of the balances Balances would
+	// return, and returns nil when there are none
+	CreateBatch(createdBy uint, currency string, minimum int64) (*domain.PayoutBatch, error)
+	// SettlePayout marks a pending payout sent, posting it, or failed
+	SettlePayout(id uint, status domain.PayoutStatus, settledBy uint, reference, note string) (*domain.Payout, error)
+	ListBatches(page, limit int) ([]domain.PayoutBatch, int64, error)
+	// FindBatch returns a batch with its payouts
+	FindBatch(id uint) (*domain.PayoutBatch, error)
+}
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+func (r *ledgerRepository) Post(txn *domain.LedgerTransaction) (bool, error) {
+	var posted bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		posted, err = post(tx, txn)
+		return err
+	})
+	return posted, err
+}
+
+func post(tx *gorm.DB, txn *domain.LedgerTransaction) (bool, error) {
+	if !txn.Balanced() {
+		return false, fmt.Errorf("ledger transaction %s is not balanced", txn.SourceKey)
+	}
+
+	entries := txn.Entries
+	result := tx.Omit("Entries").Clauses(clause.OnConflict{DoNothing: true}).Create(txn)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	for i := range entries {
+		entries[i].TransactionID = txn.ID
+	}
+	if len(entries) > 0 {
+		if err := tx.Create(&entries).Error; err != nil {
+			return false, err
+		}
+	}
+	txn.Entries = entries
+	return true, nil
+}
+
+func (r *ledgerRepository) FindSale(orderID uint) (*domain.LedgerTransaction, error) {
+	var txn domain.LedgerTransaction
+	err := r.db.Preload("Entries").
+		Where("kind = ? AND order_id = ?", domain.LedgerSale, orderID).
+		First(&txn).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLedgerTransactionNotFound
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+func (r *ledgerRepository) CourseTeacher(courseID uint) (uint, error) {
+	var teacherIDs []uint
+	err := r.db.Table("courses c").
+		Joins("JOIN users u ON u.id = c.teacher_id").
+		Where("c.id = ?", courseID).
+		Pluck("c.teacher_id", &teacherIDs).Error
+	if err != nil || len(teacherIDs) == 0 {
+		return 0, err
+	}
+	return teacherIDs[0], nil
+}
+
+func (r *ledgerRepository) UnpostedSales(limit int) ([]domain.Order, error) {
+	var orders []domain.Order
+	err := r.db.
+		Where("status IN ? AND amount > 0", []domain.OrderStatus{domain.OrderStatusPaid, domain.OrderStatusRefunded}).
+		Where("NOT EXISTS (SELECT 1 FROM ledger_transactions t WHERE t.kind = ? AND t.order_id = orders.id)", domain.LedgerSale).
+		Order("id").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+func (r *ledgerRepository) UnpostedRefunds(limit int) ([]domain.Refund, error) {
+	var refunds []domain.Refund
+	err := r.db.
+		Where("status = ? AND amount > 0", domain.RefundCompleted).
+		Where("NOT EXISTS (SELECT 1 FROM ledger_transactions t WHERE t.kind = ? AND t.refund_id = refunds.id)", domain.LedgerRefund).
+		Order("id").
+		Limit(limit).
+		Find(&refunds).Error
+	return refunds, err
+}
+
+func (r *ledgerRepository) UnpostedInvoices(limit int) ([]domain.SubscriptionInvoice, error) {
+	var invoices []domain.SubscriptionInvoice
+	err := r.db.
+		Where("amount > 0").
+		Where("NOT EXISTS (SELECT 1 FROM ledger_transactions t WHERE t.kind = ? AND t.invoice_id = subscription_invoices.id)", domain.LedgerSubscription).
+		Order("id").
+		Limit(limit).
+		Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *ledgerRepository) Balances(currency string, minimum int64) ([]domain.TeacherBalance, error) {
+	return balances(r.db, currency, minimum)
+}
+
+// balances sums the teacher payable account, less payouts that are not
+// settled yet; teachers who were purged are left out since there is
+// nobody to pay
+func balances(db *gorm.DB, currency string, minimum int64) ([]domain.TeacherBalance, error) {
+	if minimum < 1 {
+		minimum = 1
+	}
+	query := db.Table("ledger_entries e").
+		Joins("JOIN users u ON u.id = e.teacher_id").
+		Where("e.account = ?", domain.LedgerTeacherPayable)
+	if currency != "" {
+		query = query.Where("e.currency = ?", currency)
+	}
+
+	const balance = "SUM(e.credit - e.debit) - COALESCE((SELECT SUM(p.amount) FROM payouts p " +
+		"WHERE p.teacher_id = e.teacher_id AND p.currency = e.currency AND p.status = '" + string(domain.PayoutPending) + "'), 0)"
+
+	balances := []domain.TeacherBalance{}
+	err := query.
+		Select("e.teacher_id, u.name AS teacher_name, u.email AS teacher_email, e.currency, "+balance+" AS balance").
+		Group("e.teacher_id, u.name, u.email, e.currency").
+		Having(balance+" >= ?", minimum).
+		Order("e.currency, u.name, e.teacher_id").
+		Scan(&balances).Error
+	return balances, err
+}
+
+func (r *ledgerRepository) CreateBatch(createdBy uint, currency string, minimum int64) (*domain.PayoutBatch, error) {
+	var batch *domain.PayoutBatch
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", payoutLock).Error; err != nil {
+			return err
+		}
+
+		due, err := balances(tx, currency, minimum)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		batch = &domain.PayoutBatch{CreatedBy: createdBy, PayoutCount: len(due)}
+		for _, b := range due {
+			batch.Payouts = append(batch.Payouts, domain.Payout{
+				TeacherID:    b.TeacherID,
+				TeacherName:  b.TeacherName,
+				TeacherEmail: b.TeacherEmail,
+				Amount:       b.Balance,
+				Currency:     b.Currency,
+			})
+		}
+		return tx.Create(batch).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (r *ledgerRepository) SettlePayout(id uint, status domain.PayoutStatus, settledBy uint, reference, note string) (*domain.Payout, error) {
+	var payout domain.Payout
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", payoutLock).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&payout, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPayoutNotFound
+			}
+			return err
+		}
+		if payout.Status != domain.PayoutPending {
+			return ErrPayoutNotPending
+		}
+
+		now := time.Now()
+		payout.Status = status
+		payout.Reference = reference
+		payout.Note = note
+		payout.SettledBy = &settledBy
+		payout.SettledAt = &now
+		if err := tx.Save(&payout).Error; err != nil {
+			return err
+		}
+
+		if status == domain.PayoutSent {
+			if _, err := post(tx, domain.PayoutTransaction(&payout)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+func (r *ledgerRepository) ListBatches(page, limit int) ([]domain.PayoutBatch, int64, error) {
+	query := r.db.Model(&domain.PayoutBatch{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var batches []domain.PayoutBatch
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&batches).Error
+	return batches, total, err
+}
+
+func (r *ledgerRepository) FindBatch(id uint) (*domain.PayoutBatch, error) {
+	var batch domain.PayoutBatch
+	err := r.db.Preload("Payouts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("currency, teacher_name, teacher_id")
+	}).First(&batch, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPayoutBatchNotFound
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
@@ -53,12 +53,19 @@ func (r *lessonRepository) Update(lesson *domain.Lesson) error {
 	return r.db.Model(lesson).Updates(lesson).Error
 }
 
+// Delete moves a lesson to the trash. Its order number is freed (set to
+// -id, which never collides) so the remaining lessons can be reordered.
 func (r *lessonRepository) Delete(id int64) error {
-	res := r.db.Delete(&domain.Lesson{}, id)
-	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return res.Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&domain.Lesson{}).Where("id = ?", id).Update("order_number", -id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Delete(&domain.Lesson{}, id).Error
+	})
 }
 
 func (r *lessonRepository) GetLastOrder(courseID int64) (int, error) {
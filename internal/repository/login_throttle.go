@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrLoginThrottleNotFound = errors.New("lockout not found")
+
+type LoginThrottleRepository interface {
+	// FindBlocking returns the account and IP throttles that block attempts at t
+	FindBlocking(account, ip string, t time.Time) ([]domain.LoginThrottle, error)
+	// RecordFailure atomically counts a failed login. Failures older than
+	// windowStart no longer count and the streak restarts at one.
+	RecordFailure(scope domain.LoginThrottleScope, key, ip string, at, windowStart time.Time) (*domain.LoginThrottle, error)
+	// Block extends a throttle's block; a longer existing block is kept
+	Block(id uint, until time.Time, locked bool) error
+	Reset(scope domain.LoginThrottleScope, key string) error
+
+	FindByID(id uint) (*domain.LoginThrottle, error)
+	ListActive(scope domain.LoginThrottleScope, t time.Time) ([]domain.LoginThrottle, error)
+	Delete(id uint) error
+	DeleteStale(before time.Time) (int64, error)
+}
+
+type loginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{db: db}
+}
+
+func (r *loginThrottleRepository) FindBlocking(account, ip string, t time.Time) ([]domain.LoginThrottle, error) {
+	var throttles []domain.LoginThrottle
+	err := r.db.
+		Where("blocked_until > ?", t).
+		Where("(scope = ? AND key = ?) OR (scope = ? AND key = ?)",
+			domain.LoginThrottleAccount, account, domain.LoginThrottleIP, ip).
+		Find(&throttles).Error
+	return throttles, err
+}
+
+func (r *loginThrottleRepository) RecordFailure(scope domain.LoginThrottleScope, key, ip string, at, windowStart time.Time) (*domain.LoginThrottle, error) {
+	throttle := domain.LoginThrottle{
+		Scope:        scope,
+		Key:          key,
+		FailedCount:  1,
+		LastFailedAt: at,
+		LastIP:       ip,
+	}
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failed_count": gorm.Expr(
+					"CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failed_count + 1 END",
+					windowStart,
+				),
+				"locked_at": gorm.Expr(
+					"CASE WHEN login_throttles.last_failed_at < ? THEN NULL ELSE login_throttles.locked_at END",
+					windowStart,
+				),
+				"last_failed_at": at,
+				"last_ip":        ip,
+				"updated_at":     at,
+			}),
+		},
+		clause.Returning{},
+	).Create(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *loginThrottleRepository) Block(id uint, until time.Time, locked bool) error {
+	updates := map[string]interface{}{
+		"blocked_until": gorm.Expr("GREATEST(COALESCE(blocked_until, ?), ?)", until, until),
+	}
+	if locked {
+		updates["locked_at"] = gorm.Expr("COALESCE(locked_at, ?)", time.Now())
+	}
+	return r.db.Model(&domain.LoginThrottle{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *loginThrottleRepository) Reset(scope domain.LoginThrottleScope, key string) error {
+	return r.db.Where("scope = ? AND key = ?", scope, key).Delete(&domain.LoginThrottle{}).Error
+}
+
+func (r *loginThrottleRepository) FindByID(id uint) (*domain.LoginThrottle, error) {
+	var throttle domain.LoginThrottle
+	if err := r.db.First(&throttle, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLoginThrottleNotFound
+		}
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *loginThrottleRepository) ListActive(scope domain.LoginThrottleScope, t time.Time) ([]domain.LoginThrottle, error) {
+	var throttles []domain.LoginThrottle
+	query := r.db.Where("blocked_until > ?", t)
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	err := query.Order("blocked_until DESC").Find(&throttles).Error
+	return throttles, err
+}
+
+func (r *loginThrottleRepository) Delete(id uint) error {
+	result := r.db.Delete(&domain.LoginThrottle{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLoginThrottleNotFound
+	}
+	return nil
+}
+
+// DeleteStale removes throttles that have neither recent failures nor an
+// active block
+func (r *loginThrottleRepository) DeleteStale(before time.Time) (int64, error) {
+	result := r.db.
+		Where("last_failed_at < ? AND (blocked_until IS NULL OR blocked_until < ?)", before, before).
+		Delete(&domain.LoginThrottle{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrMFANotFound = errors.New("two-factor authentication is not set up")
+
+type MFARepository interface {
+	FindByUser(userID uint) (*domain.UserMFA, error)
+	Save(mfa *domain.UserMFA) error
+	Delete(userID uint) error
+	// AdvanceStep records a used TOTP step; it returns false if the step is
+	// not newer than the last one used, so a code cannot be replayed
+	AdvanceStep(userID uint, step int64) (bool, error)
+
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+	UseRecoveryCode(userID uint, hash string) (bool, error)
+	CountUnusedRecoveryCodes(userID uint) (int64, error)
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) FindByUser(userID uint) (*domain.UserMFA, error) {
+	var mfa domain.UserMFA
+	if err := r.db.First(&mfa, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFANotFound
+		}
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *mfaRepository) Save(mfa *domain.UserMFA) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(mfa).Error
+}
+
+// Delete removes the two-factor configuration and all recovery codes
+func (r *mfaRepository) Delete(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.UserMFA{}).Error
+	})
+}
+
+func (r *mfaRepository) AdvanceStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&domain.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]domain.MFARecoveryCode, len(hashes))
+		for i, h := range hashes {
+			codes[i] = domain.MFARecoveryCode{UserID: userID, CodeHash: h}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(userID uint, hash string) (bool, error) {
+	result := r.db.Model(&domain.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *mfaRepository) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
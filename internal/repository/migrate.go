@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"elearning/internal/domain"
+)
+
+// schemaPatches are idempotent statements for changes to tables and types
+// that already exist in the initial schema (elearning.sql). Existing tables
+// are not passed to AutoMigrate because their column types (e.g. enums)
+// differ from what GORM would infer.
+var schemaPatches = []string{
+	`ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'announcement'`,
+	`ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'staff_invite'`,
+	`ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'account'`,
+	`ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'refund'`,
+	`ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'privacy'`,
+	`ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'subscription'`,
+	`ALTER TYPE enrollment_status ADD VALUE IF NOT EXISTS 'refunded'`,
+	// Accounts that existed before email verification are treated as verified
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'users' AND column_name = 'verified_at'
+		) THEN
+			ALTER TABLE users ADD COLUMN verified_at TIMESTAMP;
+			UPDATE users SET verified_at = created_at;
+		END IF;
+	END $$`,
+	// Custom roles are referenced by name, so role is no longer an enum
+	`DO $$
+	BEGIN
+		IF EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'users' AND column_name = 'role' AND data_type = 'USER-DEFINED'
+		) THEN
+			ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+			ALTER TABLE users ALTER COLUMN role TYPE varchar(50) USING role::text;
+			ALTER TABLE users ALTER COLUMN role SET DEFAULT 'student';
+		END IF;
+	END $$`,
+	// Account status; unverified accounts start out pending verification
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'users' AND column_name = 'status'
+		) THEN
+			ALTER TABLE users ADD COLUMN status varchar(30) NOT NULL DEFAULT 'active';
+			UPDATE users SET status = 'pending_verification' WHERE verified_at IS NULL;
+		END IF;
+	END $$`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason varchar(500)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS status_until TIMESTAMP`,
+	`CREATE INDEX IF NOT EXISTS idx_users_status ON users (status)`,
+	// Soft delete: rows stay in the trash until the retention period ends
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+	`CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at)`,
+	`ALTER TABLE courses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+	`CREATE INDEX IF NOT EXISTS idx_courses_deleted_at ON courses (deleted_at)`,
+	`ALTER TABLE lessons ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+	`CREATE INDEX IF NOT EXISTS idx_lessons_deleted_at ON lessons (deleted_at)`,
+	// Pricing: existing courses stay free
+	`ALTER TABLE courses ADD COLUMN IF NOT EXISTS is_free boolean NOT NULL DEFAULT true`,
+	`ALTER TABLE courses ADD COLUMN IF NOT EXISTS price bigint NOT NULL DEFAULT 0`,
+	`ALTER TABLE courses ADD COLUMN IF NOT EXISTS currency varchar(3) NOT NULL DEFAULT 'USD'`,
+	// Enrollment progress; the high-water mark starts from what students
+	// had completed so far
+	`ALTER TABLE enrollments ADD COLUMN IF NOT EXISTS progress_percent double precision NOT NULL DEFAULT 0`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'enrollments' AND column_name = 'max_progress'
+		) THEN
+			ALTER TABLE enrollments ADD COLUMN max_progress double precision NOT NULL DEFAULT 0;
+			UPDATE enrollments e SET max_progress = 100.0 * done.lessons / total.lessons
+			FROM (
+				SELECT p.user_id, l.course_id, COUNT(*) AS lessons
+				FROM progress p JOIN lessons l ON l.id = p.lesson_id AND l.deleted_at IS NULL
+				WHERE p.is_completed
+				GROUP BY p.user_id, l.course_id
+			) done, (
+				SELECT course_id, COUNT(*) AS lessons
+				FROM lessons WHERE deleted_at IS NULL
+				GROUP BY course_id
+			) total
+			WHERE done.user_id = e.user_id AND done.course_id = e.course_id
+				AND total.course_id = e.course_id;
+		END IF;
+	END $$`,
+	// Payouts generated before payout statuses were settled on creation
+	`DO $$
+	BEGIN
+		IF EXISTS (
+			SELECT 1 FROM information_schema.tables WHERE table_name = 'payouts'
+		) AND NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'payouts' AND column_name = 'status'
+		) THEN
+			ALTER TABLE payouts ADD COLUMN status varchar(20) NOT NULL DEFAULT 'sent';
+			ALTER TABLE payouts ALTER COLUMN status SET DEFAULT 'pending';
+		END IF;
+	END $$`,
+	// Orders placed before coupons were paid at list price
+	`DO $$
+	BEGIN
+		IF EXISTS (
+			SELECT 1 FROM information_schema.tables WHERE table_name = 'orders'
+		) AND NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'orders' AND column_name = 'subtotal'
+		) THEN
+			ALTER TABLE orders ADD COLUMN subtotal bigint NOT NULL DEFAULT 0;
+			UPDATE orders SET subtotal = amount;
+		END IF;
+	END $$`,
+}
+
+// tablePatches run after AutoMigrate, for constraints on tables it creates
+var tablePatches = []string{
+	// The audit log is append-only; the hash chain detects tampering by
+	// anyone able to bypass this
+	`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_logs is append-only';
+	END $$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS audit_logs_no_change ON audit_logs`,
+	`CREATE TRIGGER audit_logs_no_change BEFORE UPDATE OR DELETE ON audit_logs
+		FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()`,
+	`DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs`,
+	`CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()`,
+	// Impersonations used to cascade from their users, deleting the trail
+	// when either user was purged
+	`DO $$
+	DECLARE
+		fk record;
+	BEGIN
+		FOR fk IN
+			SELECT conname FROM pg_constraint
+			WHERE conrelid = 'impersonations'::regclass AND contype = 'f'
+		LOOP
+			EXECUTE format('ALTER TABLE impersonations DROP CONSTRAINT %I', fk.conname);
+		END LOOP;
+	END $$`,
+	// A course can only be paid for once per user
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_paid_user_course ON orders (user_id, course_id) WHERE status = 'paid'`,
+	// Emails are unique ignoring case. Accounts that already share an
+	// address in different case must be merged or renamed before this
+	// applies.
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))`,
+	// A user can only hold one running subscription to each plan
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_subscriptions_live_user_plan ON subscriptions (user_id, plan_id) WHERE status IN ('trial', 'active', 'past_due')`,
+}
+
+// Migrate creates the tables added after the initial schema and applies
+// schema patches to existing ones
+func Migrate(db *gorm.DB) error {
+	for _, stmt := range schemaPatches {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("apply schema patch %q: %w", stmt, err)
+		}
+	}
+
+	if err := db.AutoMigrate(
+		&domain.Announcement{},
+		&domain.Webhook{},
+		&domain.WebhookDelivery{},
+		&domain.UserToken{},
+		&domain.UserMFA{},
+		&domain.MFARecoveryCode{},
+		&domain.LoginThrottle{},
+		&domain.UserIdentity{},
+		&domain.SSOLoginState{},
+		&domain.UserSession{},
+		&domain.APIKey{},
+		&domain.Role{},
+		&domain.RolePermission{},
+		&domain.CourseStaff{},
+		&domain.Impersonation{},
+		&domain.ImpersonationRequest{},
+		&domain.AuditLog{},
+		&domain.DataExport{},
+		&domain.ErasureRequest{},
+		&domain.UserImport{},
+		&domain.UserImportRow{},
+		&domain.Order{},
+		&domain.PaymentEvent{},
+		&domain.Coupon{},
+		&domain.CouponRedemption{},
+		&domain.Refund{},
+		&domain.Plan{},
+		&domain.Subscription{},
+		&domain.SubscriptionInvoice{},
+		&domain.LedgerTransaction{},
+		&domain.LedgerEntry{},
+		&domain.PayoutBatch{},
+		&domain.Payout{},
+	); err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+
+	for _, stmt := range tablePatches {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("apply table patch %q: %w", stmt, err)
+		}
+	}
+
+	return nil
+}
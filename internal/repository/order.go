@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrOrderNotFound = errors.New("order not found")
+
+type OrderFilter struct {
+	UserID   uint
+	CourseID uint
+	Status   domain.OrderStatus
+	From     *time.Time
+	To       *time.Time
+}
+
+type OrderRepository interface {
+	Create(order *domain.Order) error
+	FindByID(id uint) (*domain.Order, error)
+	FindByProviderRef(provider, reference string) (*domain.Order, error)
+	// FindPending returns the newest pending order for the course created
+	// after since
+	FindPending(userID, courseID uint, since time.Time) (*domain.Order, error)
+	HasPaid(userID, courseID uint) (bool, error)
+	List(filter OrderFilter, page, limit int) ([]domain.Order, int64, error)
+	Update(order *domain.Order) error
+	// MarkPaid moves a pending or failed order to paid and reports whether
+	// it did. It does not when the customer already has another paid order
+	// for the course.
+	MarkPaid(id uint, paymentRef string, at time.Time) (bool, error)
+	// MarkReturned moves an unpaid order whose payment was refunded
+	// straight away to refunded and reports whether it did
+	MarkReturned(id uint, paymentRef, reason string, at time.Time) (bool, error)
+	// MarkFailed moves a pending order to failed and reports whether it did
+	MarkFailed(id uint, reason string) (bool, error)
+	// MarkCancelled moves a pending order to cancelled and reports whether
+	// it did
+	MarkCancelled(id uint, reason string) (bool, error)
+	// ListExpired returns pending orders created before the given time
+	ListExpired(before time.Time, limit int) ([]domain.Order, error)
+	// MarkRefunded moves a paid order to refunded and reports whether it did
+	MarkRefunded(id uint, at time.Time) (bool, error)
+	EventProcessed(provider, eventID string) (bool, error)
+	RecordEvent(event *domain.PaymentEvent) error
+}
+
+type orderRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	return &orderRepository{db: db}
+}
+
+func (r *orderRepository) Create(order *domain.Order) error {
+	return r.db.Create(order).Error
+}
+
+func (r *orderRepository) FindByID(id uint) (*domain.Order, error) {
+	var order domain.Order
+	if err := r.db.First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) FindByProviderRef(provider, reference string) (*domain.Order, error) {
+	var order domain.Order
+	err := r.db.Where("provider = ? AND provider_ref = ?", provider, reference).First(&order).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) FindPending(userID, courseID uint, since time.Time) (*domain.Order, error) {
+	var order domain.Order
+	err := r.db.Where("user_id = ? AND course_id = ? AND status = ? AND created_at > ?",
+		userID, courseID, domain.OrderStatusPending, since).
+		Order("created_at DESC").
+		First(&order).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) HasPaid(userID, courseID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.Order{}).
+		Where("user_id = ? AND course_id = ? AND status = ?", userID, courseID, domain.OrderStatusPaid).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *orderRepository) List(filter OrderFilter, page, limit int) ([]domain.Order, int64, error) {
+	query := r.db.Model(&domain.Order{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.CourseID != 0 {
+		query = query.Where("course_id = ?", filter.CourseID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []domain.Order
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&orders).Error
+	return orders, total, err
+}
+
+func (r *orderRepository) Update(order *domain.Order) error {
+	return r.db.Save(order).Error
+}
+
+func (r *orderRepository) MarkPaid(id uint, paymentRef string, at time.Time) (bool, error) {
+	// A late success for a failed checkout still means the customer was
+	// charged. Cancelled checkouts were replaced or expired, and a second
+	// paid order for the course would break idx_orders_paid_user_course.
+	result := r.db.Model(&domain.Order{}).
+		Where("id = ? AND status IN ?", id, []domain.OrderStatus{domain.OrderStatusPending, domain.OrderStatusFailed}).
+		Where("NOT EXISTS (SELECT 1 FROM orders paid WHERE paid.user_id = orders.user_id AND paid.course_id = orders.course_id AND paid.status = ?)", domain.OrderStatusPaid).
+		Updates(map[string]interface{}{
+			"status":         domain.OrderStatusPaid,
+			"payment_ref":    paymentRef,
+			"failure_reason": "",
+			"paid_at":        at,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *orderRepository) MarkReturned(id uint, paymentRef, reason string, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.Order{}).
+		Where("id = ? AND status IN ?", id, []domain.OrderStatus{domain.OrderStatusPending, domain.OrderStatusFailed, domain.OrderStatusCancelled}).
+		Updates(map[string]interface{}{
+			"status":         domain.OrderStatusRefunded,
+			"payment_ref":    paymentRef,
+			"failure_reason": reason,
+			"refunded_at":    at,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *orderRepository) MarkFailed(id uint, reason string) (bool, error) {
+	result := r.db.Model(&domain.Order{}).
+		Where("id = ? AND status = ?", id, domain.OrderStatusPending).
+		Updates(map[string]interface{}{
+			"status":         domain.OrderStatusFailed,
+			"failure_reason": reason,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *orderRepository) MarkCancelled(id uint, reason string) (bool, error) {
+	result := r.db.Model(&domain.Order{}).
+		Where("id = ? AND status = ?", id, domain.OrderStatusPending).
+		Updates(map[string]interface{}{
+			"status":         domain.OrderStatusCancelled,
+			"failure_reason": reason,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *orderRepository) ListExpired(before time.Time, limit int) ([]domain.Order, error) {
+	var orders []domain.Order
+	err := r.db.Where("status = ? AND created_at < ?", domain.OrderStatusPending, before).
+		Order("created_at").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+func (r *orderRepository) MarkRefunded(id uint, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.Order{}).
+		Where("id = ? AND status = ?", id, domain.OrderStatusPaid).
+		Updates(map[string]interface{}{
+			"status":      domain.OrderStatusRefunded,
+			"refunded_at": at,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *orderRepository) EventProcessed(provider, eventID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.PaymentEvent{}).
+		Where("provider = ? AND event_id = ?", provider, eventID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *orderRepository) RecordEvent(event *domain.PaymentEvent) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event).Error
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+	ErrErasureNotFound    = errors.New("erasure request not found")
+)
+
+// AnonymizedUser holds the values that replace a user's personal data
+type AnonymizedUser struct {
+	Name         string
+	Email        string
+	PasswordHash string
+	// LoginKey is the normalized former email that failed logins were
+	// counted against
+	LoginKey string
+}
+
+type PrivacyRepository interface {
+	CreateExport(export *domain.DataExport) error
+	FindExport(id uint) (*domain.DataExport, error)
+	ListExports(userID uint) ([]domain.DataExport, error)
+	LatestExport(userID uint) (*domain.DataExport, error)
+	PendingExports(limit int) ([]domain.DataExport, error)
+	ExpiredExports(now time.Time) ([]domain.DataExport, error)
+	UpdateExport(export *domain.DataExport) error
+
+	// CollectPersonalData gathers everything stored about a user
+	CollectPersonalData(userID uint) (*domain.PersonalData, error)
+
+	CreateErasure(request *domain.ErasureRequest) error
+	FindErasure(id uint) (*domain.ErasureRequest, error)
+	// OpenErasure returns the user's pending or approved request
+	OpenErasure(userID uint) (*domain.ErasureRequest, error)
+	ListErasures(status domain.ErasureStatus, page, limit int) ([]domain.ErasureRequest, int64, error)
+	DueErasures(now time.Time) ([]domain.ErasureRequest, error)
+	UpdateErasure(request *domain.ErasureRequest) error
+
+	// Anonymize replaces the user's personal data and removes their
+	// credentials, sessions, notifications and other personal records.
+	// Enrollments and progress are kept, so aggregate statistics do not
+	// change.
+	Anonymize(userID uint, anon AnonymizedUser) error
+}
+
+type privacyRepository struct {
+	db *gorm.DB
+}
+
+func NewPrivacyRepository(db *gorm.DB) PrivacyRepository {
+	return &privacyRepository{db: db}
+}
+
+func (r *privacyRepository) CreateExport(export *domain.DataExport) error {
+	return r.db.Create(export).Error
+}
+
+func (r *privacyRepository) FindExport(id uint) (*domain.DataExport, error) {
+	var export domain.DataExport
+	if err := r.db.First(&export, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *privacyRepository) ListExports(userID uint) ([]domain.DataExport, error) {
+	var exports []domain.DataExport
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&exports).Error
+	return exports, err
+}
+
+func (r *privacyRepository) LatestExport(userID uint) (*domain.DataExport, error) {
+	var export domain.DataExport
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *privacyRepository) PendingExports(limit int) ([]domain.DataExport, error) {
+	var exports []domain.DataExport
+	err := r.db.Where("status = ?", domain.DataExportPending).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+func (r *privacyRepository) ExpiredExports(now time.Time) ([]domain.DataExport, error) {
+	var exports []domain.DataExport
+	err := r.db.Where("status = ? AND expires_at <= ?", domain.DataExportReady, now).Find(&exports).Error
+	return exports, err
+}
+
+func (r *privacyRepository) UpdateExport(export *domain.DataExport) error {
+	return r.db.Save(export).Error
+}
+
+func (r *privacyRepository) CollectPersonalData(userID uint) (*domain.PersonalData, error) {
+	var data domain.PersonalData
+	// Trashed accounts can still ask for their data
+	if err := r.db.Unscoped().First(&data.Profile, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query *gorm.DB
+	}{
+		{&data.Enrollments, r.db.Preload("Course").Where("user_id = ?", userID).Order("enrolled_at")},
+		{&data.Orders, r.db.Where("user_id = ?", userID).Order("created_at")},
+		{&data.Refunds, r.db.Where("user_id = ?", userID).Order("created_at")},
+		{&data.Subscriptions, r.db.Preload("Plan").Where("user_id = ?", userID).Order("created_at")},
+		{&data.Payouts, r.db.Where("teacher_id = ?", userID).Order("created_at")},
+		{&data.Progress, r.db.Where("user_id = ?", userID).Order("id")},
+		{&data.Notifications, r.db.Where("user_id = ?", userID).Order("created_at")},
+		{&data.Sessions, r.db.Where("user_id = ?", userID).Order("created_at")},
+		{&data.Identities, r.db.Where("user_id = ?", userID).Order("created_at")},
+		{&data.APIKeys, r.db.Where("user_id = ?", userID).Order("created_at")},
+		{&data.CourseStaff, r.db.Where("user_id = ?", userID).Order("created_at")},
+		{&data.TaughtCourses, r.db.Unscoped().Where("teacher_id = ?", userID).Order("created_at")},
+		{&data.Announcements, r.db.Where("author_id = ?", userID).Order("created_at")},
+		{&data.ErasureRequests, r.db.Where("user_id = ?", userID).Order("created_at")},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+func (r *privacyRepository) CreateErasure(request *domain.ErasureRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *privacyRepository) FindErasure(id uint) (*domain.ErasureRequest, error) {
+	var request domain.ErasureRequest
+	if err := r.db.Preload("User", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).First(&request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrErasureNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *privacyRepository) OpenErasure(userID uint) (*domain.ErasureRequest, error) {
+	var request domain.ErasureRequest
+	err := r.db.Where("user_id = ? AND status IN ?", userID, []domain.ErasureStatus{domain.ErasurePending, domain.ErasureApproved}).
+		Order("created_at DESC").
+		First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrErasureNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *privacyRepository) ListErasures(status domain.ErasureStatus, page, limit int) ([]domain.ErasureRequest, int64, error) {
+	query := r.db.Model(&domain.ErasureRequest{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var requests []domain.ErasureRequest
+	err := query.Preload("User", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&requests).Error
+	return requests, total, err
+}
+
+func (r *privacyRepository) DueErasures(now time.Time) ([]domain.ErasureRequest, error) {
+	var requests []domain.ErasureRequest
+	err := r.db.Where("status = ? AND cooling_off_until <= ?", domain.ErasureApproved, now).
+		Order("cooling_off_until ASC").
+		Find(&requests).Error
+	return requests, err
+}
+
+func (r *privacyRepository) UpdateErasure(request *domain.ErasureRequest) error {
+	return r.db.Omit("User").Save(request).Error
+}
+
+func (r *privacyRepository) Anonymize(userID uint, anon AnonymizedUser) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&domain.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"name":          anon.Name,
+			"email":         anon.Email,
+			"password":      anon.PasswordHash,
+			"avatar":        nil,
+			"verified_at":   nil,
+			"status":        domain.AccountDeactivated,
+			"status_reason": "account erased",
+			"status_until":  nil,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		personal := []interface{}{
+			&domain.UserSession{},
+			&domain.UserMFA{},
+			&domain.MFARecoveryCode{},
+			&domain.UserIdentity{},
+			&domain.UserToken{},
+			&domain.APIKey{},
+			&domain.Notification{},
+			&domain.CourseStaff{},
+			&domain.DataExport{},
+		}
+		for _, model := range personal {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("scope = ? AND key = ?", domain.LoginThrottleAccount, anon.LoginKey).
+			Delete(&domain.LoginThrottle{}).Error
+	})
+}
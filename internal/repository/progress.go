@@ -74,7 +74,7 @@ func (r *progressRepository) GetCourseProgress(userID, courseID uint) (*domain.C
 
 	// Count completed lessons
 	if err := r.db.Model(&domain.Progress{}).
-		Joins("JOIN lessons ON lessons.id = progress.lesson_id").
+		Joins("JOIN lessons ON lessons.id = progress.lesson_id AND lessons.deleted_at IS NULL").
 		Where("progress.user_id = ? AND lessons.course_id = ? AND progress.is_completed = ?",
 			userID, courseID, true).
 		Count(&completedLessons).Error; err != nil {
@@ -98,7 +98,7 @@ func (r *progressRepository) GetCourseProgress(userID, courseID uint) (*domain.C
 // GetUserProgressByCourse gets all progress records for a user in a course
 func (r *progressRepository) GetUserProgressByCourse(userID, courseID uint) ([]domain.Progress, error) {
 	var progress []domain.Progress
-	err := r.db.Joins("JOIN lessons ON lessons.id = progress.lesson_id").
+	err := r.db.Joins("JOIN lessons ON lessons.id = progress.lesson_id AND lessons.deleted_at IS NULL").
 		Where("progress.user_id = ? AND lessons.course_id = ?", userID, courseID).
 		Preload("Lesson").
 		Find(&progress).Error
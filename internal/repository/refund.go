@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var ErrRefundNotFound = errors.New("refund request not found")
+
+type RefundFilter struct {
+	UserID uint
+	Status domain.RefundStatus
+}
+
+type RefundRepository interface {
+	Create(refund *domain.Refund) error
+	FindByID(id uint) (*domain.Refund, error)
+	// FindOpen returns the pending or failed request for an order
+	FindOpen(orderID uint) (*domain.Refund, error)
+	List(filter RefundFilter, page, limit int) ([]domain.Refund, int64, error)
+	Update(refund *domain.Refund) error
+}
+
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) RefundRepository {
+	return &refundRepository{db: db}
+}
+
+func (r *refundRepository) Create(refund *domain.Refund) error {
+	return r.db.Create(refund).Error
+}
+
+func (r *refundRepository) FindByID(id uint) (*domain.Refund, error) {
+	var refund domain.Refund
+	if err := r.db.First(&refund, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *refundRepository) FindOpen(orderID uint) (*domain.Refund, error) {
+	var refund domain.Refund
+	err := r.db.Where("order_id = ? AND status IN ?", orderID, []domain.RefundStatus{domain.RefundPending, domain.RefundFailed}).
+		Order("created_at DESC").
+		First(&refund).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *refundRepository) List(filter RefundFilter, page, limit int) ([]domain.Refund, int64, error) {
+	query := r.db.Model(&domain.Refund{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var refunds []domain.Refund
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&refunds).Error
+	return refunds, total, err
+}
+
+func (r *refundRepository) Update(refund *domain.Refund) error {
+	return r.db.Save(refund).Error
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"errors"
+
+	"elearning/internal/domain"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+type RoleRepository interface {
+	Create(role *domain.Role) error
+	FindByID(id uint) (*domain.Role, error)
+	FindByName(name string) (*domain.Role, error)
+	// FindAll returns every role with its permissions
+	FindAll() ([]domain.Role, error)
+	// ReplacePermissions sets the role's permissions and description
+	ReplacePermissions(role *domain.Role, permissions []domain.RolePermission) error
+	// GrantDefaults adds permissions the role does not hold yet and records
+	// the defaults seeded so far
+	GrantDefaults(role *domain.Role, permissions []domain.RolePermission, seeded []string) error
+	Delete(id uint) error
+	// CountUsers returns the number of users holding the role
+	CountUsers(name string) (int64, error)
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) Create(role *domain.Role) error {
+	return r.db.Create(role).Error
+}
+
+func (r *roleRepository) FindByID(id uint) (*domain.Role, error) {
+	var role domain.Role
+	if err := r.db.Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) FindByName(name string) (*domain.Role, error) {
+	var role domain.Role
+	if err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) FindAll() ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.db.Preload("Permissions").Order("built_in DESC, name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) ReplacePermissions(role *domain.Role, permissions []domain.RolePermission) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Update("description", role.Description).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", role.ID).Delete(&domain.RolePermission{}).Error; err != nil {
+			return err
+		}
+		for i := range permissions {
+			permissions[i].ID = 0
+			permissions[i].RoleID = role.ID
+		}
+		if len(permissions) > 0 {
+			if err := tx.Create(&permissions).Error; err != nil {
+				return err
+			}
+		}
+		role.Permissions = permissions
+		return nil
+	})
+}
+
+func (r *roleRepository) GrantDefaults(role *domain.Role, permissions []domain.RolePermission, seeded []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range permissions {
+			grant := domain.RolePermission{RoleID: role.ID, Permission: p.Permission, Scope: p.Scope}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grant).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(role).Update("seeded", pq.StringArray(seeded)).Error
+	})
+}
+
+func (r *roleRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&domain.RolePermission{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&domain.Role{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRoleNotFound
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) CountUsers(name string) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.User{}).Where("role = ?", name).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository interface {
+	Create(session *domain.UserSession) error
+	FindByTokenID(tokenID string) (*domain.UserSession, error)
+	// ListActive returns the user's unrevoked, unexpired sessions, most
+	// recently used first
+	ListActive(userID uint, now time.Time) ([]domain.UserSession, error)
+	Touch(id uint, ip string, at time.Time) error
+	// Revoke revokes one of the user's sessions and returns its token ID
+	Revoke(userID, id uint, at time.Time) (string, error)
+	RevokeByTokenID(tokenID string, at time.Time) error
+	// RevokeAll revokes every active session of the user and returns their
+	// token IDs
+	RevokeAll(userID uint, at time.Time) ([]string, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *domain.UserSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindByTokenID(tokenID string) (*domain.UserSession, error) {
+	var session domain.UserSession
+	if err := r.db.Where("token_id = ?", tokenID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) ListActive(userID uint, now time.Time) ([]domain.UserSession, error) {
+	var sessions []domain.UserSession
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Touch(id uint, ip string, at time.Time) error {
+	return r.db.Model(&domain.UserSession{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": at, "ip": ip}).Error
+}
+
+func (r *sessionRepository) Revoke(userID, id uint, at time.Time) (string, error) {
+	var session domain.UserSession
+	err := r.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrSessionNotFound
+		}
+		return "", err
+	}
+	if err := r.db.Model(&session).Update("revoked_at", at).Error; err != nil {
+		return "", err
+	}
+	return session.TokenID, nil
+}
+
+func (r *sessionRepository) RevokeByTokenID(tokenID string, at time.Time) error {
+	return r.db.Model(&domain.UserSession{}).
+		Where("token_id = ? AND revoked_at IS NULL", tokenID).
+		Update("revoked_at", at).Error
+}
+
+func (r *sessionRepository) RevokeAll(userID uint, at time.Time) ([]string, error) {
+	var tokenIDs []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&domain.UserSession{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, at)
+		if err := query.Pluck("token_id", &tokenIDs).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.UserSession{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error
+	})
+	return tokenIDs, err
+}
+
+func (r *sessionRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&domain.UserSession{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPlanNotFound         = errors.New("subscription plan not found")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrInvoiceNotFound      = errors.New("subscription invoice not found")
+)
+
+type SubscriptionFilter struct {
+	UserID uint
+	PlanID uint
+	Status domain.SubscriptionStatus
+}
+
+type SubscriptionRepository interface {
+	CreatePlan(plan *domain.Plan) error
+	FindPlan(id uint) (*domain.Plan, error)
+	ListPlans(activeOnly bool) ([]domain.Plan, error)
+	UpdatePlan(plan *domain.Plan) error
+
+	Create(sub *domain.Subscription) error
+	FindByID(id uint) (*domain.Subscription, error)
+	FindByProviderRef(provider, reference string) (*domain.Subscription, error)
+	FindByCheckoutRef(provider, reference string) (*domain.Subscription, error)
+	// FindIncomplete returns the newest subscription to the plan still in
+	// checkout, created after since
+	FindIncomplete(userID, planID uint, since time.Time) (*domain.Subscription, error)
+	// ListLive returns the user's trial, active and past due subscriptions
+	// with their plans
+	ListLive(userID uint) ([]domain.Subscription, error)
+	// HasStarted reports whether the user ever got past checkout, which
+	// rules out another trial
+	HasStarted(userID uint) (bool, error)
+	List(filter SubscriptionFilter, page, limit int) ([]domain.Subscription, int64, error)
+	Update(sub *domain.Subscription) error
+	// ListEnded returns live subscriptions set to cancel whose period
+	// ended before cutoff
+	ListEnded(cutoff time.Time) ([]domain.Subscription, error)
+	// RecordInvoice stores a paid invoice unless it was recorded before,
+	// and reports whether it did
+	RecordInvoice(invoice *domain.SubscriptionInvoice) (bool, error)
+	FindInvoice(id uint) (*domain.SubscriptionInvoice, error)
+}
+
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{db: db}
+}
+
+func (r *subscriptionRepository) CreatePlan(plan *domain.Plan) error {
+	return r.db.Create(plan).Error
+}
+
+func (r *subscriptionRepository) FindPlan(id uint) (*domain.Plan, error) {
+	var plan domain.Plan
+	if err := r.db.First(&plan, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *subscriptionRepository) ListPlans(activeOnly bool) ([]domain.Plan, error) {
+	query := r.db.Model(&domain.Plan{})
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	var plans []domain.Plan
+	err := query.Order("price ASC, id ASC").Find(&plans).Error
+	return plans, err
+}
+
+func (r *subscriptionRepository) UpdatePlan(plan *domain.Plan) error {
+	return r.db.Save(plan).Error
+}
+
+func (r *subscriptionRepository) Create(sub *domain.Subscription) error {
+	return r.db.Create(sub).Error
+}
+
+func (r *subscriptionRepository) FindByID(id uint) (*domain.Subscription, error) {
+	return r.first(r.db.Where("id = ?", id))
+}
+
+func (r *subscriptionRepository) FindByProviderRef(provider, reference string) (*domain.Subscription, error) {
+	return r.first(r.db.Where("provider = ? AND provider_ref = ?", provider, reference))
+}
+
+func (r *subscriptionRepository) FindByCheckoutRef(provider, reference string) (*domain.Subscription, error) {
+	return r.first(r.db.Where("provider = ? AND checkout_ref = ?", provider, reference))
+}
+
+func (r *subscriptionRepository) FindIncomplete(userID, planID uint, since time.Time) (*domain.Subscription, error) {
+	return r.first(r.db.
+		Where("user_id = ? AND plan_id = ? AND status = ? AND created_at > ?", userID, planID, domain.SubscriptionIncomplete, since).
+		Order("created_at DESC"))
+}
+
+func (r *subscriptionRepository) first(query *gorm.DB) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	if err := query.Preload("Plan").First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *subscriptionRepository) ListLive(userID uint) ([]domain.Subscription, error) {
+	var subs []domain.Subscription
+	err := r.db.Preload("Plan").
+		Where("user_id = ? AND status IN ?", userID, liveSubscriptionStatuses).
+		Order("created_at DESC").
+		Find(&subs).Error
+	return subs, err
+}
+
+func (r *subscriptionRepository) HasStarted(userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.Subscription{}).
+		Where("user_id = ? AND status <> ?", userID, domain.SubscriptionIncomplete).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *subscriptionRepository) List(filter SubscriptionFilter, page, limit int) ([]domain.Subscription, int64, error) {
+	query := r.db.Model(&domain.Subscription{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.PlanID != 0 {
+		query = query.Where("plan_id = ?", filter.PlanID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var subs []domain.Subscription
+	err := query.Preload("Plan").
+		Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&subs).Error
+	return subs, total, err
+}
+
+func (r *subscriptionRepository) Update(sub *domain.Subscription) error {
+	return r.db.Omit("Plan").Save(sub).Error
+}
+
+func (r *subscriptionRepository) ListEnded(cutoff time.Time) ([]domain.Subscription, error) {
+	var subs []domain.Subscription
+	err := r.db.Preload("Plan").
+		Where("status IN ? AND cancel_at_period_end = ? AND current_period_end < ?", liveSubscriptionStatuses, true, cutoff).
+		Find(&subs).Error
+	return subs, err
+}
+
+var liveSubscriptionStatuses = []domain.SubscriptionStatus{
+	domain.SubscriptionTrial,
+	domain.SubscriptionActive,
+	domain.SubscriptionPastDue,
+}
+
+func (r *subscriptionRepository) RecordInvoice(invoice *domain.SubscriptionInvoice) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(invoice)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *subscriptionRepository) FindInvoice(id uint) (*domain.SubscriptionInvoice, error) {
+	var invoice domain.SubscriptionInvoice
+	if err := r.db.First(&invoice, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// TrashRepository reads and restores soft-deleted users, courses and
+// lessons, and purges them for good
+type TrashRepository interface {
+	ListUsers(page, limit int) ([]domain.User, int64, error)
+	ListCourses(page, limit int) ([]domain.Course, int64, error)
+	ListLessons(page, limit int) ([]domain.Lesson, int64, error)
+
+	FindUser(id uint) (*domain.User, error)
+	FindCourse(id uint) (*domain.Course, error)
+	FindLesson(id uint) (*domain.Lesson, error)
+
+	RestoreUser(id uint) error
+	RestoreCourse(id uint) error
+	// RestoreLesson brings a lesson back as the last lesson of its course
+	RestoreLesson(id uint) error
+
+	// Expired* return rows trashed before the given time
+	ExpiredUsers(before time.Time) ([]domain.User, error)
+	ExpiredCourses(before time.Time) ([]domain.Course, error)
+	ExpiredLessons(before time.Time) ([]domain.Lesson, error)
+
+	// CoursesByTeacher returns every course of a teacher, trashed or not
+	CoursesByTeacher(teacherID uint) ([]domain.Course, error)
+	// LessonsByCourse returns every lesson of a course, trashed or not
+	LessonsByCourse(courseID uint) ([]domain.Lesson, error)
+
+	// Purge* delete rows permanently, along with everything that cascades
+	// from them (lessons, enrollments, progress, ...)
+	PurgeUser(id uint) error
+	PurgeCourse(id uint) error
+	PurgeLesson(id uint) error
+}
+
+type trashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) TrashRepository {
+	return &trashRepository{db: db}
+}
+
+// trashed scopes a query to soft-deleted rows
+func (r *trashRepository) trashed(model interface{}) *gorm.DB {
+	return r.db.Unscoped().Model(model).Where("deleted_at IS NOT NULL")
+}
+
+func (r *trashRepository) ListUsers(page, limit int) ([]domain.User, int64, error) {
+	var users []domain.User
+	total, err := r.list(&domain.User{}, &users, page, limit)
+	return users, total, err
+}
+
+func (r *trashRepository) ListCourses(page, limit int) ([]domain.Course, int64, error) {
+	var courses []domain.Course
+	total, err := r.list(&domain.Course{}, &courses, page, limit)
+	return courses, total, err
+}
+
+func (r *trashRepository) ListLessons(page, limit int) ([]domain.Lesson, int64, error) {
+	var lessons []domain.Lesson
+	total, err := r.list(&domain.Lesson{}, &lessons, page, limit)
+	return lessons, total, err
+}
+
+func (r *trashRepository) list(model, dest interface{}, page, limit int) (int64, error) {
+	var total int64
+	if err := r.trashed(model).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	err := r.trashed(model).
+		Order("deleted_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(dest).Error
+	return total, err
+}
+
+func (r *trashRepository) FindUser(id uint) (*domain.User, error) {
+	var user domain.User
+	if err := r.trashed(&domain.User{}).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *trashRepository) FindCourse(id uint) (*domain.Course, error) {
+	var course domain.Course
+	if err := r.trashed(&domain.Course{}).First(&course, id).Error; err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+func (r *trashRepository) FindLesson(id uint) (*domain.Lesson, error) {
+	var lesson domain.Lesson
+	if err := r.trashed(&domain.Lesson{}).First(&lesson, id).Error; err != nil {
+		return nil, err
+	}
+	return &lesson, nil
+}
+
+func (r *trashRepository) RestoreUser(id uint) error {
+	return r.restore(r.db, &domain.User{}, id, nil)
+}
+
+func (r *trashRepository) RestoreCourse(id uint) error {
+	return r.restore(r.db, &domain.Course{}, id, nil)
+}
+
+func (r *trashRepository) RestoreLesson(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var lesson domain.Lesson
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&lesson, id).Error; err != nil {
+			return err
+		}
+
+		var lastOrder int
+		err := tx.Model(&domain.Lesson{}).
+			Where("course_id = ?", lesson.CourseID).
+			Select("COALESCE(MAX(order_number), 0)").
+			Scan(&lastOrder).Error
+		if err != nil {
+			return err
+		}
+
+		return r.restore(tx, &domain.Lesson{}, id, map[string]interface{}{"order_number": lastOrder + 1})
+	})
+}
+
+func (r *trashRepository) restore(db *gorm.DB, model interface{}, id uint, extra map[string]interface{}) error {
+	updates := map[string]interface{}{"deleted_at": nil}
+	for column, value := range extra {
+		updates[column] = value
+	}
+
+	res := db.Unscoped().Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *trashRepository) ExpiredUsers(before time.Time) ([]domain.User, error) {
+	var users []domain.User
+	err := r.trashed(&domain.User{}).Where("deleted_at < ?", before).Find(&users).Error
+	return users, err
+}
+
+func (r *trashRepository) ExpiredCourses(before time.Time) ([]domain.Course, error) {
+	var courses []domain.Course
+	err := r.trashed(&domain.Course{}).Where("deleted_at < ?", before).Find(&courses).Error
+	return courses, err
+}
+
+func (r *trashRepository) ExpiredLessons(before time.Time) ([]domain.Lesson, error) {
+	var lessons []domain.Lesson
+	err := r.trashed(&domain.Lesson{}).Where("deleted_at < ?", before).Find(&lessons).Error
+	return lessons, err
+}
+
+func (r *trashRepository) CoursesByTeacher(teacherID uint) ([]domain.Course, error) {
+	var courses []domain.Course
+	err := r.db.Unscoped().Where("teacher_id = ?", teacherID).Find(&courses).Error
+	return courses, err
+}
+
+func (r *trashRepository) LessonsByCourse(courseID uint) ([]domain.Lesson, error) {
+	var lessons []domain.Lesson
+	err := r.db.Unscoped().Where("course_id = ?", courseID).Find(&lessons).Error
+	return lessons, err
+}
+
+func (r *trashRepository) PurgeUser(id uint) error {
+	return r.db.Unscoped().Delete(&domain.User{}, id).Error
+}
+
+func (r *trashRepository) PurgeCourse(id uint) error {
+	return r.db.Unscoped().Delete(&domain.Course{}, id).Error
+}
+
+func (r *trashRepository) PurgeLesson(id uint) error {
+	return r.db.Unscoped().Delete(&domain.Lesson{}, id).Error
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"elearning/internal/domain"
 	"errors"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -12,6 +14,17 @@ var (
 	ErrEmailAlreadyExists = errors.New("email already exists")
 )
 
+// UserFilter narrows a user listing. Zero values match everything.
+type UserFilter struct {
+	Role     domain.UserRole
+	Status   domain.AccountStatus
+	Verified *bool
+	// Query matches part of the name or email, ignoring case
+	Query string
+	From  *time.Time
+	To    *time.Time
+}
+
 type UserRepository interface {
 	Create(user *domain.User) error
 	FindByID(id uint) (*domain.User, error)
@@ -19,7 +32,12 @@ type UserRepository interface {
 	Update(user *domain.User) error
 	Delete(id uint) error
 	UpdatePassword(id uint, hashedPassword string) error
+	MarkVerified(id uint, at time.Time) error
+	UpdateStatus(id uint, status domain.AccountStatus, reason string, until *time.Time) error
+	ExpiredSuspensions(now time.Time) ([]domain.User, error)
 	FindAll() ([]domain.User, error)
+	Each(filter UserFilter, fn func(users []domain.User) error) error
+	ExistingEmails(emails []string) ([]string, error)
 }
 
 type userRepository struct {
@@ -30,9 +48,23 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// normalizeEmail is the form emails are stored in. Addresses are compared
+// ignoring case, matching idx_users_email_lower.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func (r *userRepository) Create(user *domain.User) error {
+	user.Email = normalizeEmail(user.Email)
+	if user.Status == "" {
+		user.Status = domain.AccountActive
+		if !user.IsVerified() {
+			user.Status = domain.AccountPendingVerification
+		}
+	}
+	// Accounts in the trash keep their email until they are purged
 	var count int64
-	if err := r.db.Model(&domain.User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+	if err := r.db.Unscoped().Model(&domain.User{}).Where("LOWER(email) = ?", user.Email).Count(&count).Error; err != nil {
 		return err
 	}
 	if count > 0 {
@@ -55,7 +87,7 @@ func (r *userRepository) FindByID(id uint) (*domain.User, error) {
 
 func (r *userRepository) FindByEmail(email string) (*domain.User, error) {
 	var user domain.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.Where("LOWER(email) = ?", normalizeEmail(email)).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
@@ -66,6 +98,7 @@ func (r *userRepository) FindByEmail(email string) (*domain.User, error) {
 }
 
 func (r *userRepository) Update(user *domain.User) error {
+	user.Email = normalizeEmail(user.Email)
 	return r.db.Save(user).Error
 }
 
@@ -86,8 +119,99 @@ func (r *userRepository) UpdatePassword(id uint, hashedPassword string) error {
 		Update("password", hashedPassword).Error
 }
 
+// MarkVerified records the email verification time unless already verified
+// and activates accounts pending verification
+func (r *userRepository) MarkVerified(id uint, at time.Time) error {
+	return r.db.Model(&domain.User{}).
+		Where("id = ? AND verified_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"verified_at": at,
+			"status": gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END",
+				domain.AccountPendingVerification, domain.AccountActive),
+		}).Error
+}
+
+// UpdateStatus sets the account status with its reason and expiry
+func (r *userRepository) UpdateStatus(id uint, status domain.AccountStatus, reason string, until *time.Time) error {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"status_reason": reason,
+			"status_until":  until,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ExpiredSuspensions returns suspended accounts whose suspension has ended
+func (r *userRepository) ExpiredSuspensions(now time.Time) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.Where("status = ? AND status_until <= ?", domain.AccountSuspended, now).
+		Order("status_until").
+		Find(&users).Error
+	return users, err
+}
+
 func (r *userRepository) FindAll() ([]domain.User, error) {
 	var users []domain.User
 	err := r.db.Order("created_at DESC").Find(&users).Error
 	return users, err
 }
+
+// Each calls fn with batches of the users matching filter, in ID order
+func (r *userRepository) Each(filter UserFilter, fn func(users []domain.User) error) error {
+	query := r.db.Model(&domain.User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Verified != nil {
+		if *filter.Verified {
+			query = query.Where("verified_at IS NOT NULL")
+		} else {
+			query = query.Where("verified_at IS NULL")
+		}
+	}
+	if filter.Query != "" {
+		like := "%" + strings.ToLower(filter.Query) + "%"
+		query = query.Where("(LOWER(name) LIKE ? OR LOWER(email) LIKE ?)", like, like)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var batch []domain.User
+	return query.Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// ExistingEmails returns which of emails already belong to an account,
+// including accounts in the trash, compared ignoring case. The result is
+// lower-cased.
+func (r *userRepository) ExistingEmails(emails []string) ([]string, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+	lower := make([]string, len(emails))
+	for i, email := range emails {
+		lower[i] = normalizeEmail(email)
+	}
+
+	var existing []string
+	err := r.db.Unscoped().Model(&domain.User{}).
+		Where("LOWER(email) IN ?", lower).
+		Pluck("LOWER(email)", &existing).Error
+	return existing, err
+}
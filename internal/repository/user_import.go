@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"errors"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+var ErrUserImportNotFound = errors.New("user import not found")
+
+type UserImportRepository interface {
+	Create(userImport *domain.UserImport) error
+	FindByID(id uint) (*domain.UserImport, error)
+	List(page, limit int) ([]domain.UserImport, int64, error)
+	ListRows(importID uint, status domain.UserImportRowStatus, page, limit int) ([]domain.UserImportRow, int64, error)
+	NextUnfinished() (*domain.UserImport, error)
+	PendingRows(importID uint, limit int) ([]domain.UserImportRow, error)
+	Update(userImport *domain.UserImport) error
+	UpdateRow(row *domain.UserImportRow) error
+}
+
+type userImportRepository struct {
+	db *gorm.DB
+}
+
+func NewUserImportRepository(db *gorm.DB) UserImportRepository {
+	return &userImportRepository{db: db}
+}
+
+// Create stores the import and its rows
+func (r *userImportRepository) Create(userImport *domain.UserImport) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Rows").Create(userImport).Error; err != nil {
+			return err
+		}
+		for i := range userImport.Rows {
+			userImport.Rows[i].ImportID = userImport.ID
+		}
+		if len(userImport.Rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(userImport.Rows, 500).Error
+	})
+}
+
+func (r *userImportRepository) FindByID(id uint) (*domain.UserImport, error) {
+	var userImport domain.UserImport
+	if err := r.db.First(&userImport, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserImportNotFound
+		}
+		return nil, err
+	}
+	return &userImport, nil
+}
+
+func (r *userImportRepository) List(page, limit int) ([]domain.UserImport, int64, error) {
+	var total int64
+	if err := r.db.Model(&domain.UserImport{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var imports []domain.UserImport
+	err := r.db.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&imports).Error
+	return imports, total, err
+}
+
+func (r *userImportRepository) ListRows(importID uint, status domain.UserImportRowStatus, page, limit int) ([]domain.UserImportRow, int64, error) {
+	query := r.db.Model(&domain.UserImportRow{}).Where("import_id = ?", importID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []domain.UserImportRow
+	err := query.Order("line").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&rows).Error
+	return rows, total, err
+}
+
+// NextUnfinished returns the oldest import that is queued or was
+// interrupted while processing
+func (r *userImportRepository) NextUnfinished() (*domain.UserImport, error) {
+	var userImport domain.UserImport
+	err := r.db.Where("status IN ?", []domain.UserImportStatus{domain.UserImportPending, domain.UserImportProcessing}).
+		Order("id").
+		First(&userImport).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserImportNotFound
+		}
+		return nil, err
+	}
+	return &userImport, nil
+}
+
+func (r *userImportRepository) PendingRows(importID uint, limit int) ([]domain.UserImportRow, error) {
+	var rows []domain.UserImportRow
+	err := r.db.Where("import_id = ? AND status = ?", importID, domain.UserImportRowPending).
+		Order("line").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+func (r *userImportRepository) Update(userImport *domain.UserImport) error {
+	return r.db.Omit("Rows").Save(userImport).Error
+}
+
+func (r *userImportRepository) UpdateRow(row *domain.UserImportRow) error {
+	return r.db.Save(row).Error
+}
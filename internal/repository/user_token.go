@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTokenInvalid = errors.New("token is invalid or expired")
+
+type UserTokenRepository interface {
+	Create(token *domain.UserToken) error
+	// Consume marks a valid token as used and returns it. It fails with
+	// ErrTokenInvalid if the token is unknown, expired or already used.
+	Consume(purpose domain.TokenPurpose, tokenHash string) (*domain.UserToken, error)
+	// FindValid returns an unused, unexpired token without consuming it
+	FindValid(purpose domain.TokenPurpose, tokenHash string) (*domain.UserToken, error)
+	// MarkUsed consumes a token found with FindValid
+	MarkUsed(id uint) error
+	// IncrementAttempts records a failed use and returns the new count
+	IncrementAttempts(id uint) (int, error)
+	// InvalidateForUser marks all outstanding tokens of a purpose as used
+	InvalidateForUser(userID uint, purpose domain.TokenPurpose) error
+	CountSince(userID uint, purpose domain.TokenPurpose, since time.Time) (int64, error)
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+func (r *userTokenRepository) Create(token *domain.UserToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *userTokenRepository) Consume(purpose domain.TokenPurpose, tokenHash string) (*domain.UserToken, error) {
+	var token domain.UserToken
+	err := r.db.Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil || now.After(token.ExpiresAt) {
+		return nil, ErrTokenInvalid
+	}
+
+	// Conditional update so two concurrent requests cannot both use the token
+	result := r.db.Model(&domain.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTokenInvalid
+	}
+
+	token.UsedAt = &now
+	return &token, nil
+}
+
+func (r *userTokenRepository) FindValid(purpose domain.TokenPurpose, tokenHash string) (*domain.UserToken, error) {
+	var token domain.UserToken
+	err := r.db.Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, tokenHash, time.Now()).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *userTokenRepository) MarkUsed(id uint) error {
+	result := r.db.Model(&domain.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
+func (r *userTokenRepository) IncrementAttempts(id uint) (int, error) {
+	var token domain.UserToken
+	err := r.db.Model(&token).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Where("id = ?", id).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+	return token.Attempts, err
+}
+
+func (r *userTokenRepository) InvalidateForUser(userID uint, purpose domain.TokenPurpose) error {
+	return r.db.Model(&domain.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *userTokenRepository) CountSince(userID uint, purpose domain.TokenPurpose, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at >= ?", userID, purpose, since).
+		Count(&count).Error
+	return count, err
+}
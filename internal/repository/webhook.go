@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"time"
+
+	"elearning/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	Create(webhook *domain.Webhook) error
+	Update(webhook *domain.Webhook) error
+	Delete(id uint) error
+	FindByID(id uint) (*domain.Webhook, error)
+	FindAll() ([]domain.Webhook, error)
+	FindActiveByEvent(event domain.WebhookEvent) ([]domain.Webhook, error)
+
+	CreateDelivery(delivery *domain.WebhookDelivery) error
+	UpdateDelivery(delivery *domain.WebhookDelivery) error
+	FindDelivery(webhookID, deliveryID uint) (*domain.WebhookDelivery, error)
+	FindDeliveries(webhookID uint, status string, page, limit int) ([]domain.WebhookDelivery, int64, error)
+	// ClaimDueDeliveries returns pending deliveries whose next attempt is
+	// due and moves their next attempt lease into the future, so other
+	// instances skip them while they are being sent
+	ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) Create(webhook *domain.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+func (r *webhookRepository) Update(webhook *domain.Webhook) error {
+	return r.db.Save(webhook).Error
+}
+
+// Delete removes a webhook together with its delivery log
+func (r *webhookRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Webhook{}, id).Error
+	})
+}
+
+func (r *webhookRepository) FindByID(id uint) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	if err := r.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) FindAll() ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	err := r.db.Order("created_at DESC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) FindActiveByEvent(event domain.WebhookEvent) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	err := r.db.Where("is_active = ? AND ? = ANY(events)", true, string(event)).
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) CreateDelivery(delivery *domain.WebhookDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *webhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+func (r *webhookRepository) FindDelivery(webhookID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	if err := r.db.Where("webhook_id = ?", webhookID).First(&delivery, deliveryID).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) FindDeliveries(webhookID uint, status string, page, limit int) ([]domain.WebhookDelivery, int64, error) {
+	var deliveries []domain.WebhookDelivery
+	var total int64
+	offset := (page - 1) * limit
+
+	query := r.db.Model(&domain.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error
+
+	return deliveries, total, err
+}
+
+func (r *webhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Rows another instance is claiming are skipped rather than waited for
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.WebhookDeliveryPending, now).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+			deliveries[i].NextAttemptAt = now.Add(lease)
+		}
+		return tx.Model(&domain.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -22,7 +22,15 @@ func New(
 	db *gorm.DB,
 	tokenMaker token.TokenMaker,
 	tokenBlacklist token.TokenBlacklist,
+	sessionService middleware.SessionValidator,
+	accountStatus middleware.AccountChecker,
+	apiKeyService middleware.APIKeyAuthenticator,
+	authz middleware.Authorizer,
+	impersonations middleware.ImpersonationRecorder,
+	auditLog middleware.AuditRecorder,
 	authHandler *handler.AuthHandler,
+	mfaHandler *handler.MFAHandler,
+	ssoHandler *handler.SSOHandler,
 	courseHandler *handler.CourseHandler,
 	lessonHandler *handler.LessonHandler,
 	enrollmentHandler *handler.EnrollmentHandler,
@@ -32,8 +40,28 @@ func New(
 	dashboardHandler *handler.DashboardHandler,
 	adminHandler *handler.AdminHandler,
 	reportsHandler *handler.ReportsHandler,
+	announcementHandler *handler.AnnouncementHandler,
+	webhookHandler *handler.WebhookHandler,
+	lockoutHandler *handler.LockoutHandler,
+	sessionHandler *handler.SessionHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	roleHandler *handler.RoleHandler,
+	staffHandler *handler.CourseStaffHandler,
+	impersonationHandler *handler.ImpersonationHandler,
+	auditLogHandler *handler.AuditLogHandler,
+	trashHandler *handler.TrashHandler,
+	privacyHandler *handler.PrivacyHandler,
+	userImportHandler *handler.UserImportHandler,
+	accountStatusHandler *handler.AccountStatusHandler,
+	orderHandler *handler.OrderHandler,
+	couponHandler *handler.CouponHandler,
+	refundHandler *handler.RefundHandler,
+	subscriptionHandler *handler.SubscriptionHandler,
+	payoutHandler *handler.PayoutHandler,
 	courseService service.CourseService,
 	lessonService service.LessonServiceInterface,
+	enrollmentService *service.EnrollmentService,
+	staffService *service.CourseStaffService,
 	logger *zap.Logger, // Add logger parameter
 ) *gin.Engine {
 
@@ -51,9 +79,11 @@ func New(
 
 	r.Use(gin.Logger())
 	r.Use(middleware.LoggerMiddleware(logger)) // Custom Zap logger middleware
+	r.Use(middleware.RecordImpersonation(impersonations))
 
-	// Serve static files (uploaded videos and PDFs)
-	r.Static("/uploads", "./uploads")
+	// Serve avatars publicly. Lesson videos and PDFs are served through
+	// signed links (see service.LessonMedia) so paid content stays private.
+	r.Static("/uploads/avatars", "./uploads/avatars")
 
 	// Handle 404 - Not Found
 	r.NoRoute(func(c *gin.Context) {
@@ -88,6 +118,15 @@ func New(
 		})
 	})
 
+	// audit records a successful action on the route in the audit log
+	audit := func(action, targetType, targetParam string) gin.HandlerFunc {
+		return middleware.Audit(auditLog, action, targetType, targetParam)
+	}
+	// auditAccess records an access to a streamed route before it runs
+	auditAccess := func(action, targetType, targetParam string) gin.HandlerFunc {
+		return middleware.AuditAccess(auditLog, action, targetType, targetParam)
+	}
+
 	v1 := r.Group("/api/v1")
 
 	// AUTH ROUTES
@@ -96,17 +135,39 @@ func New(
 	{
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
-		auth.GET("/me", middleware.AuthMiddleware(tokenMaker, tokenBlacklist), authHandler.GetProfile)
-		auth.POST("/logout", middleware.AuthMiddleware(tokenMaker, tokenBlacklist), authHandler.Logout)
+		auth.POST("/verify-email", authHandler.VerifyEmail)
+		auth.POST("/resend-verification", authHandler.ResendVerification)
+		auth.POST("/forgot-password", authHandler.ForgotPassword)
+		auth.POST("/reset-password", authHandler.ResetPassword)
+		auth.GET("/me", middleware.MFASetupAuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService), authHandler.GetProfile)
+		auth.POST("/logout", middleware.AllowImpersonationWrites(), middleware.MFASetupAuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService), authHandler.Logout)
+
+		// Two-factor authentication. Setup and enable also accept the
+		// restricted token issued to users who must enroll first.
+		auth.POST("/mfa/verify", mfaHandler.Verify)
+		auth.POST("/mfa/setup", middleware.MFASetupAuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService), middleware.NoImpersonation(), mfaHandler.Setup)
+		auth.POST("/mfa/enable", middleware.MFASetupAuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService), middleware.NoImpersonation(), mfaHandler.Enable)
+		auth.POST("/mfa/disable", middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService), middleware.NoImpersonation(), audit("mfa.disable", "user", ""), mfaHandler.Disable)
+		auth.POST("/mfa/recovery-codes", middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService), middleware.NoImpersonation(), mfaHandler.RegenerateRecoveryCodes)
+
+		// Admin impersonation tokens can be given up before they expire
+		auth.POST("/impersonation/end", middleware.AllowImpersonationWrites(), middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService), impersonationHandler.End)
+
+		// OpenID Connect single sign-on
+		auth.GET("/sso/providers", ssoHandler.ListProviders)
+		auth.GET("/sso/:provider/login", ssoHandler.Login)
+		auth.GET("/sso/:provider/callback", ssoHandler.Callback)
+		auth.POST("/sso/exchange", ssoHandler.Exchange)
 	}
 
 	// COURSE ROUTES
 	courses := v1.Group("/courses")
+	courses.Use(middleware.APIScope("courses"))
 	{
 		// CREATE COURSE (Teacher/Admin only)
 		courses.POST("",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermCourseCreate),
 			courseHandler.Create,
 		)
 
@@ -118,104 +179,183 @@ func New(
 
 		// UPDATE COURSE (Teacher/Admin only)
 		courses.PUT("/:course_id",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
-			middleware.RequireCourseOwnership(courseService),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermCourseUpdate, middleware.CourseAccess(courseService, staffService, domain.StaffAccessEdit)),
+			audit("course.update", "course", "course_id"),
 			courseHandler.Update,
 		)
 
 		courses.PUT("/:course_id/publish",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
-			middleware.RequireCourseOwnership(courseService),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermCourseUpdate, middleware.CourseAccess(courseService, staffService, domain.StaffAccessEdit)),
+			audit("course.publish", "course", "course_id"),
 			courseHandler.Publish,
 		)
 
+		// SET COURSE PRICE (course owner)
+		courses.PUT("/:course_id/pricing",
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermCourseUpdate, middleware.CourseAccess(courseService, staffService, domain.StaffAccessOwner)),
+			audit("course.pricing", "course", "course_id"),
+			courseHandler.SetPricing,
+		)
+
 		// DELETE COURSE (Teacher/Admin only)
 		courses.DELETE("/:course_id",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
-			middleware.RequireCourseOwnership(courseService),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermCourseDelete, middleware.CourseAccess(courseService, staffService, domain.StaffAccessOwner)),
+			audit("course.delete", "course", "course_id"),
 			courseHandler.Delete,
 		)
 	}
 
 	lessons := v1.Group("/courses/:course_id/lessons")
+	lessons.Use(middleware.APIScope("courses"))
 	{
 		lessons.POST("",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
-			middleware.RequireCourseOwnership(courseService),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermLessonManage, middleware.CourseAccess(courseService, staffService, domain.StaffAccessEdit)),
 			lessonHandler.Create,
 		)
 
-		lessons.GET("", lessonHandler.ListByCourse)
-		lessons.GET("/:lesson_id", lessonHandler.Get)
+		// Lessons of priced courses show their content only to buyers,
+		// subscribers and staff; others see the outline
+		contentAccess := middleware.ContentAccess(authz, courseService, staffService, enrollmentService)
+		lessons.GET("",
+			middleware.OptionalAuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			contentAccess,
+			lessonHandler.ListByCourse,
+		)
+		lessons.GET("/:lesson_id",
+			middleware.OptionalAuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			contentAccess,
+			lessonHandler.Get,
+		)
 		// UPDATE LESSON - ownership check
 		lessons.PUT("/:lesson_id",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
-			middleware.RequireLessonOwnership(lessonService, courseService),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermLessonManage, middleware.LessonAccess(lessonService, courseService, staffService, domain.StaffAccessEdit)),
 			lessonHandler.Update,
 		)
 
 		lessons.DELETE("/:lesson_id",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
-			middleware.RequireLessonOwnership(lessonService, courseService),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermLessonManage, middleware.LessonAccess(lessonService, courseService, staffService, domain.StaffAccessEdit)),
 			lessonHandler.Delete,
 		)
 		lessons.PUT("/reorder",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
-			middleware.RequireCourseOwnership(courseService),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermLessonManage, middleware.CourseAccess(courseService, staffService, domain.StaffAccessEdit)),
 			lessonHandler.Reorder,
 		)
 	}
 
+	// LESSON MEDIA (authorized by the signed link)
+	v1.GET("/lesson-media/:lesson_id/:kind", lessonHandler.Media)
+
 	// ENROLLMENT ROUTES
 	enrollments := v1.Group("/enrollments")
-	enrollments.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist))
+	enrollments.Use(middleware.APIScope("enrollments"))
+	enrollments.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
 	{
 		// Get my enrolled courses
 		enrollments.GET("/my-courses", enrollmentHandler.GetMyEnrollments)
 
-		// Update progress
-		enrollments.PUT("/:enrollment_id/progress", enrollmentHandler.UpdateProgress)
+		// Update progress (the student, or course staff grading it)
+		enrollments.PUT("/:enrollment_id/progress",
+			middleware.RequirePermission(authz, domain.PermEnrollmentGrade, middleware.EnrollmentAccess(enrollmentService, courseService, staffService, domain.StaffAccessGrade)),
+			enrollmentHandler.UpdateProgress,
+		)
 	}
 
 	// Course-specific enrollment routes
 	courseEnrollments := v1.Group("/courses/:course_id")
+	courseEnrollments.Use(middleware.APIScope("enrollments"))
 	{
 		// Enroll in a course (students)
 		courseEnrollments.POST("/enroll",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleStudent, domain.RoleAdmin),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermCourseEnroll),
 			enrollmentHandler.Enroll,
 		)
 
+		// Buy a priced course; the user is enrolled once payment is confirmed
+		courseEnrollments.POST("/checkout",
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.NoImpersonation(),
+			middleware.RequirePermission(authz, domain.PermCourseEnroll),
+			audit("order.checkout", "course", "course_id"),
+			orderHandler.Checkout,
+		)
+
+		// Preview the price of a course with a coupon
+		courseEnrollments.GET("/checkout/quote",
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermCourseEnroll),
+			orderHandler.Quote,
+		)
+
 		// Unenroll from a course
 		courseEnrollments.POST("/unenroll",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.NoImpersonation(),
 			enrollmentHandler.Unenroll,
 		)
 
 		// Check enrollment status
 		courseEnrollments.GET("/enrollment-status",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
 			enrollmentHandler.GetEnrollmentStatus,
 		)
 
-		// Get list of enrolled students (teacher only)
+		// Post an announcement to all enrolled students (course owner and co-instructors)
+		courseEnrollments.POST("/announcements",
+			middleware.APIScope("courses"),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermAnnouncementPost, middleware.CourseAccess(courseService, staffService, domain.StaffAccessEdit)),
+			announcementHandler.Create,
+		)
+
+		// Announcement feed (enrolled students, course staff, admins)
+		courseEnrollments.GET("/announcements",
+			middleware.APIScope("courses"),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermAnnouncementRead),
+			announcementHandler.List,
+		)
+
+		// Get list of enrolled students (course staff)
 		courseEnrollments.GET("/enrollments",
-			middleware.AuthMiddleware(tokenMaker, tokenBlacklist),
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
+			middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService),
+			middleware.RequirePermission(authz, domain.PermEnrollmentRead, middleware.CourseAccess(courseService, staffService, domain.StaffAccessRead)),
 			enrollmentHandler.GetCourseEnrollments,
 		)
 	}
 
+	// COURSE STAFF ROUTES (co-instructors and teaching assistants)
+	staff := v1.Group("/courses/:course_id/staff")
+	staff.Use(middleware.APIScope("courses"))
+	staff.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
+	{
+		staff.GET("",
+			middleware.RequirePermission(authz, domain.PermEnrollmentRead, middleware.CourseAccess(courseService, staffService, domain.StaffAccessRead)),
+			staffHandler.List,
+		)
+
+		// Invite, change and remove staff (course owner only)
+		manageStaff := middleware.RequirePermission(authz, domain.PermCourseStaff, middleware.CourseAccess(courseService, staffService, domain.StaffAccessOwner))
+		staff.POST("", manageStaff, audit("course_staff.invite", "course", "course_id"), staffHandler.Invite)
+		staff.PUT("/:user_id", manageStaff, audit("course_staff.update", "course", "course_id"), staffHandler.UpdateRole)
+		staff.DELETE("/:user_id", manageStaff, audit("course_staff.remove", "course", "course_id"), staffHandler.Remove)
+
+		// Answer an invitation, or leave the staff
+		staff.POST("/accept", staffHandler.Accept)
+		staff.POST("/leave", staffHandler.Leave)
+	}
+
 	progress := v1.Group("/progress")
-	progress.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist))
+	progress.Use(middleware.APIScope("progress"))
+	progress.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
 	{
 		// Mark lesson as completed
 		progress.POST("/lessons/:lesson_id/complete", progressHandler.MarkCompleted)
@@ -232,7 +372,8 @@ func New(
 
 	// NOTIFICATION ROUTES
 	notifications := v1.Group("/notifications")
-	notifications.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist))
+	notifications.Use(middleware.APIScope("notifications"))
+	notifications.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
 	{
 		// Get all notifications for the current user
 		notifications.GET("", notificationHandler.GetNotifications)
@@ -248,7 +389,8 @@ func New(
 	}
 
 	users := v1.Group("/users")
-	users.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist))
+	users.Use(middleware.APIScope("users"))
+	users.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
 	{
 		// Get user profile
 		users.GET("/profile", userHandler.GetProfile)
@@ -257,60 +399,215 @@ func New(
 		users.PUT("/profile", userHandler.UpdateProfile)
 
 		// change user password
-		users.PUT("/change-password", userHandler.ChangePassword)
+		users.PUT("/change-password", middleware.NoImpersonation(), audit("user.change_password", "user", ""), userHandler.ChangePassword)
+
+		// Sessions (devices the user is logged in on)
+		users.GET("/sessions", middleware.DenyAPIKeys(), middleware.NoImpersonation(), sessionHandler.List)
+		users.DELETE("/sessions", middleware.DenyAPIKeys(), middleware.NoImpersonation(), sessionHandler.RevokeAll)
+		users.DELETE("/sessions/:id", middleware.DenyAPIKeys(), middleware.NoImpersonation(), sessionHandler.Revoke)
+
+		// API keys for integrations. Keys cannot manage keys.
+		users.GET("/api-keys", middleware.DenyAPIKeys(), middleware.NoImpersonation(), apiKeyHandler.List)
+		users.POST("/api-keys", middleware.DenyAPIKeys(), middleware.NoImpersonation(), audit("api_key.create", "api_key", ""), apiKeyHandler.Create)
+		users.DELETE("/api-keys/:id", middleware.DenyAPIKeys(), middleware.NoImpersonation(), audit("api_key.revoke", "api_key", "id"), apiKeyHandler.Revoke)
+
+		// Personal data export and account erasure
+		users.POST("/me/export", middleware.DenyAPIKeys(), middleware.NoImpersonation(), audit("user.data_export", "user", ""), privacyHandler.RequestExport)
+		users.GET("/me/exports", middleware.DenyAPIKeys(), middleware.NoImpersonation(), privacyHandler.ListExports)
+		users.GET("/me/exports/:id/download", middleware.DenyAPIKeys(), middleware.NoImpersonation(), privacyHandler.DownloadExport)
+		users.GET("/me/erasure", middleware.DenyAPIKeys(), middleware.NoImpersonation(), privacyHandler.GetErasure)
+		users.POST("/me/erasure", middleware.DenyAPIKeys(), middleware.NoImpersonation(), audit("erasure.request", "erasure_request", ""), privacyHandler.RequestErasure)
+		users.DELETE("/me/erasure", middleware.DenyAPIKeys(), middleware.NoImpersonation(), audit("erasure.cancel", "user", ""), privacyHandler.CancelErasure)
+
+		// Course purchases
+		users.GET("/me/orders", orderHandler.ListMine)
+		users.GET("/me/orders/:id", orderHandler.GetMine)
+		users.POST("/me/orders/:id/refund", middleware.NoImpersonation(), audit("refund.request", "order", "id"), refundHandler.Request)
+		users.GET("/me/refunds", refundHandler.ListMine)
+		users.POST("/me/refunds/:id/cancel", middleware.NoImpersonation(), audit("refund.cancel", "refund", "id"), refundHandler.Cancel)
+
+		// Subscriptions
+		users.GET("/me/subscriptions", subscriptionHandler.ListMine)
+		users.POST("/me/subscriptions", middleware.NoImpersonation(), audit("subscription.create", "subscription", ""), subscriptionHandler.Subscribe)
+		users.GET("/me/subscriptions/:id", subscriptionHandler.GetMine)
+		users.POST("/me/subscriptions/:id/cancel", middleware.NoImpersonation(), audit("subscription.cancel", "subscription", "id"), subscriptionHandler.Cancel)
 
 		// Get courses the user is enrolled in (students and admins)
 		users.GET("/enrolled-courses",
-			middleware.RequireRole(domain.RoleStudent, domain.RoleAdmin),
+			middleware.RequirePermission(authz, domain.PermCourseEnroll),
 			userHandler.GetEnrolledCourses,
 		)
 
+		// Pending invitations to teach courses
+		users.GET("/staff-invitations", staffHandler.ListInvitations)
+
 		// Get courses the user is teaching (teachers and admins)
 		users.GET("/taught-courses",
-			middleware.RequireRole(domain.RoleTeacher, domain.RoleAdmin),
+			middleware.RequirePermission(authz, domain.PermCourseCreate),
 			userHandler.GetTaughtCourses,
 		)
 	}
 
+	// COUPON ROUTES (admins; teachers for their own courses)
+	coupons := v1.Group("/coupons")
+	coupons.Use(middleware.APIScope("courses"))
+	coupons.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
+	coupons.Use(middleware.NoImpersonation())
+	coupons.Use(middleware.RequirePermission(authz, domain.PermCouponManage))
+	{
+		coupons.GET("", couponHandler.List)
+		coupons.POST("", audit("coupon.create", "coupon", ""), couponHandler.Create)
+		coupons.GET("/:id", couponHandler.Get)
+		coupons.PUT("/:id", audit("coupon.update", "coupon", "id"), couponHandler.Update)
+		coupons.DELETE("/:id", audit("coupon.delete", "coupon", "id"), couponHandler.Delete)
+		coupons.GET("/:id/redemptions", couponHandler.ListRedemptions)
+	}
+
+	// PAYMENT PROVIDER CALLBACKS (verified by signature, not by token)
+	payments := v1.Group("/payments")
+	{
+		payments.POST("/webhook", orderHandler.Webhook)
+		if cfg.Payment.Provider == "fake" {
+			payments.POST("/fake/:reference/complete", orderHandler.FakeComplete)
+			payments.POST("/fake/subscriptions/:reference/complete", subscriptionHandler.FakeComplete)
+			payments.POST("/fake/subscriptions/:reference/renew", subscriptionHandler.FakeRenew)
+		}
+	}
+
 	// DASHBOARD ROUTES
 	dashboard := v1.Group("/dashboard")
-	dashboard.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist))
+	dashboard.Use(middleware.APIScope("dashboard"))
+	dashboard.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
 	{
 		// Student dashboard
 		dashboard.GET("/student",
-			middleware.RequireRole(domain.RoleStudent),
+			middleware.RequirePermission(authz, domain.PermDashboardStudent),
 			dashboardHandler.GetStudentDashboard,
 		)
 
 		// Teacher dashboard
 		dashboard.GET("/teacher",
-			middleware.RequireRole(domain.RoleTeacher),
+			middleware.RequirePermission(authz, domain.PermDashboardTeacher),
 			dashboardHandler.GetTeacherDashboard,
 		)
 
 		// Admin dashboard
 		dashboard.GET("/admin",
-			middleware.RequireRole(domain.RoleAdmin),
+			middleware.RequirePermission(authz, domain.PermDashboardAdmin),
 			dashboardHandler.GetAdminDashboard,
 		)
 	}
 
 	// ADMIN ROUTES
 	admin := v1.Group("/admin")
-	admin.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist))
-	admin.Use(middleware.RequireRole(domain.RoleAdmin))
+	admin.Use(middleware.APIScope("admin"))
+	admin.Use(middleware.AuthMiddleware(tokenMaker, tokenBlacklist, sessionService, accountStatus, apiKeyService))
+	admin.Use(middleware.NoImpersonation())
 	{
 		// USER MANAGEMENT
-		admin.GET("/users", adminHandler.GetAllUsers)
-		admin.POST("/users", adminHandler.CreateUser)
-		admin.PUT("/users/:user_id", adminHandler.UpdateUser)
-		admin.DELETE("/users/:user_id", adminHandler.DeleteUser)
-
-		admin.GET("/reports/overview", reportsHandler.GetOverviewReport)
-		admin.GET("/reports/enrollments", reportsHandler.GetEnrollmentReport)
-		admin.GET("/reports/users", reportsHandler.GetUserReport)
-		admin.GET("/reports/courses", reportsHandler.GetCourseReport)
-		admin.GET("/reports/revenue", reportsHandler.GetRevenueReport)
+		manageUsers := middleware.RequirePermission(authz, domain.PermUserManage)
+		admin.GET("/users", manageUsers, adminHandler.GetAllUsers)
+		admin.POST("/users", manageUsers, audit("user.create", "user", ""), adminHandler.CreateUser)
+		admin.PUT("/users/:user_id", manageUsers, audit("user.update", "user", "user_id"), adminHandler.UpdateUser)
+		admin.DELETE("/users/:user_id", manageUsers, audit("user.delete", "user", "user_id"), adminHandler.DeleteUser)
+		admin.POST("/users/:user_id/logout", manageUsers, audit("user.force_logout", "user", "user_id"), sessionHandler.ForceLogout)
+		admin.POST("/users/:user_id/suspend", manageUsers, audit("user.suspend", "user", "user_id"), accountStatusHandler.Suspend)
+		admin.POST("/users/:user_id/deactivate", manageUsers, audit("user.deactivate", "user", "user_id"), accountStatusHandler.Deactivate)
+		admin.POST("/users/:user_id/reinstate", manageUsers, audit("user.reinstate", "user", "user_id"), accountStatusHandler.Reinstate)
+
+		// Bulk import and export (CSV)
+		admin.POST("/users/import/validate", manageUsers, userImportHandler.Validate)
+		admin.POST("/users/import", manageUsers, audit("user.import", "user_import", ""), userImportHandler.Start)
+		admin.GET("/users/imports", manageUsers, userImportHandler.List)
+		admin.GET("/users/imports/:id", manageUsers, userImportHandler.Get)
+		admin.GET("/users/imports/:id/rows", manageUsers, userImportHandler.ListRows)
+		admin.GET("/users/export", manageUsers, auditAccess("user.export", "user", ""), userImportHandler.Export)
+
+		readReports := middleware.RequirePermission(authz, domain.PermReportRead)
+		admin.GET("/reports/overview", readReports, reportsHandler.GetOverviewReport)
+		admin.GET("/reports/enrollments", readReports, reportsHandler.GetEnrollmentReport)
+		admin.GET("/reports/users", readReports, reportsHandler.GetUserReport)
+		admin.GET("/reports/courses", readReports, reportsHandler.GetCourseReport)
+		admin.GET("/reports/revenue", readReports, reportsHandler.GetRevenueReport)
+
+		// ORDERS
+		admin.GET("/orders", middleware.RequirePermission(authz, domain.PermOrderManage), orderHandler.List)
+
+		// REFUND REVIEW
+		manageRefunds := middleware.RequirePermission(authz, domain.PermRefundManage)
+		admin.GET("/refunds", manageRefunds, refundHandler.List)
+		admin.POST("/refunds/:id/approve", manageRefunds, audit("refund.approve", "refund", "id"), refundHandler.Approve)
+		admin.POST("/refunds/:id/reject", manageRefunds, audit("refund.reject", "refund", "id"), refundHandler.Reject)
+
+		// SUBSCRIPTION PLANS
+		managePlans := middleware.RequirePermission(authz, domain.PermPlanManage)
+		admin.GET("/subscription-plans", managePlans, subscriptionHandler.ListAllPlans)
+		admin.POST("/subscription-plans", managePlans, audit("plan.create", "subscription_plan", ""), subscriptionHandler.CreatePlan)
+		admin.PUT("/subscription-plans/:id", managePlans, audit("plan.update", "subscription_plan", "id"), subscriptionHandler.UpdatePlan)
+		admin.GET("/subscriptions", managePlans, subscriptionHandler.List)
+
+		// TEACHER PAYOUTS
+		managePayouts := middleware.RequirePermission(authz, domain.PermPayoutManage)
+		admin.GET("/payout-balances", managePayouts, payoutHandler.Balances)
+		admin.GET("/payout-batches", managePayouts, payoutHandler.List)
+		admin.POST("/payout-batches", managePayouts, audit("payout.generate", "payout_batch", ""), payoutHandler.Generate)
+		admin.GET("/payout-batches/:id", managePayouts, payoutHandler.Get)
+		admin.GET("/payout-batches/:id/export", managePayouts, auditAccess("payout.export", "payout_batch", "id"), payoutHandler.Export)
+		admin.PUT("/payouts/:id/status", managePayouts, audit("payout.settle", "payout", "id"), payoutHandler.Settle)
+
+		// OUTBOUND WEBHOOKS
+		manageWebhooks := middleware.RequirePermission(authz, domain.PermWebhookManage)
+		admin.GET("/webhooks", manageWebhooks, webhookHandler.List)
+		admin.POST("/webhooks", manageWebhooks, audit("webhook.create", "webhook", ""), webhookHandler.Create)
+		admin.GET("/webhooks/:webhook_id", manageWebhooks, webhookHandler.Get)
+		admin.PUT("/webhooks/:webhook_id", manageWebhooks, audit("webhook.update", "webhook", "webhook_id"), webhookHandler.Update)
+		admin.DELETE("/webhooks/:webhook_id", manageWebhooks, audit("webhook.delete", "webhook", "webhook_id"), webhookHandler.Delete)
+		admin.GET("/webhooks/:webhook_id/deliveries", manageWebhooks, webhookHandler.ListDeliveries)
+		admin.POST("/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", manageWebhooks, webhookHandler.Redeliver)
+
+		// LOGIN LOCKOUTS
+		manageLockouts := middleware.RequirePermission(authz, domain.PermLockoutManage)
+		admin.GET("/lockouts", manageLockouts, lockoutHandler.List)
+		admin.DELETE("/lockouts/:lockout_id", manageLockouts, audit("lockout.clear", "lockout", "lockout_id"), lockoutHandler.Clear)
+
+		// API KEYS (e.g. for service accounts)
+		manageAPIKeys := middleware.RequirePermission(authz, domain.PermAPIKeyManage)
+		admin.GET("/api-keys", middleware.DenyAPIKeys(), manageAPIKeys, apiKeyHandler.ListAll)
+		admin.POST("/users/:user_id/api-keys", middleware.DenyAPIKeys(), manageAPIKeys, audit("api_key.create", "api_key", ""), apiKeyHandler.CreateForUser)
+		admin.DELETE("/api-keys/:id", middleware.DenyAPIKeys(), manageAPIKeys, audit("api_key.revoke", "api_key", "id"), apiKeyHandler.RevokeAny)
+
+		// ROLES AND PERMISSIONS
+		manageRoles := middleware.RequirePermission(authz, domain.PermRoleManage)
+		admin.GET("/permissions", manageRoles, roleHandler.ListPermissions)
+		admin.GET("/roles", manageRoles, roleHandler.List)
+		admin.POST("/roles", manageRoles, audit("role.create", "role", ""), roleHandler.Create)
+		admin.GET("/roles/:role_id", manageRoles, roleHandler.Get)
+		admin.PUT("/roles/:role_id", manageRoles, audit("role.update", "role", "role_id"), roleHandler.Update)
+		admin.DELETE("/roles/:role_id", manageRoles, audit("role.delete", "role", "role_id"), roleHandler.Delete)
+
+		// IMPERSONATION
+		impersonate := middleware.RequirePermission(authz, domain.PermUserImpersonate)
+		admin.POST("/users/:user_id/impersonate", middleware.DenyAPIKeys(), impersonate, audit("user.impersonate", "user", "user_id"), impersonationHandler.Start)
+		admin.GET("/impersonations", impersonate, impersonationHandler.List)
+		admin.GET("/impersonations/:id/requests", impersonate, impersonationHandler.ListRequests)
+
+		// AUDIT LOG
+		readAudit := middleware.RequirePermission(authz, domain.PermAuditRead)
+		admin.GET("/audit-logs", readAudit, auditLogHandler.List)
+		admin.GET("/audit-logs/export", readAudit, auditLogHandler.Export)
+		admin.GET("/audit-logs/verify", readAudit, auditLogHandler.Verify)
+
+		// TRASH (deleted users, courses and lessons)
+		manageTrash := middleware.RequirePermission(authz, domain.PermTrashManage)
+		admin.GET("/trash/:type", manageTrash, trashHandler.List)
+		admin.POST("/trash/:type/:id/restore", manageTrash, audit("trash.restore", ":type", "id"), trashHandler.Restore)
+		admin.DELETE("/trash/:type/:id", manageTrash, audit("trash.purge", ":type", "id"), trashHandler.Purge)
+
+		// ACCOUNT ERASURE REVIEW
+		managePrivacy := middleware.RequirePermission(authz, domain.PermPrivacyManage)
+		admin.GET("/erasure-requests", managePrivacy, privacyHandler.ListErasures)
+		admin.POST("/erasure-requests/:id/approve", managePrivacy, audit("erasure.approve", "erasure_request", "id"), privacyHandler.ApproveErasure)
+		admin.POST("/erasure-requests/:id/reject", managePrivacy, audit("erasure.reject", "erasure_request", "id"), privacyHandler.RejectErasure)
 	}
 
 	return r
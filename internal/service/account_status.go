@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/grpcclient"
+	"elearning/pkg/mailer"
+)
+
+var (
+	ErrAccountNotFound    = errors.New("account no longer exists")
+	ErrAccountUnavailable = errors.New("account status could not be checked")
+	ErrOwnAccountStatus   = errors.New("admins cannot change the status of their own account")
+	ErrAccountNotBlocked  = errors.New("account is not suspended or deactivated")
+	ErrSuspensionInPast   = errors.New("suspension end must be in the future")
+	ErrAlreadyDeactivated = errors.New("account is already deactivated")
+)
+
+// accountCacheTTL bounds how long an account status is trusted without
+// re-reading it, i.e. how late a suspension made by another instance can
+// take effect here. Suspending also revokes the user's sessions.
+const accountCacheTTL = 30 * time.Second
+
+// AccountBlockedError is returned when a suspended or deactivated account
+// signs in or uses the API
+type AccountBlockedError struct {
+	Status domain.AccountStatus
+	Reason string
+	Until  *time.Time
+}
+
+func (e *AccountBlockedError) Error() string {
+	msg := "account is " + string(e.Status)
+	if e.Until != nil {
+		msg += " until " + e.Until.UTC().Format(time.RFC3339)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// accountBlocked returns an AccountBlockedError if the user may not sign
+// in or use the API
+func accountBlocked(user *domain.User) error {
+	if !user.IsBlocked(time.Now()) {
+		return nil
+	}
+	return &AccountBlockedError{Status: user.Status, Reason: user.StatusReason, Until: user.StatusUntil}
+}
+
+// SuspendRequest represents an admin suspending an account. Without Until
+// the suspension lasts until the account is reinstated.
+type SuspendRequest struct {
+	Reason string     `json:"reason" binding:"required,max=500"`
+	Until  *time.Time `json:"until"`
+}
+
+// DeactivateRequest represents an admin deactivating an account
+type DeactivateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type cachedAccount struct {
+	user     domain.User
+	cachedAt time.Time
+}
+
+// AccountStatusService suspends, deactivates and reinstates accounts and
+// checks account status on every authenticated request
+type AccountStatusService struct {
+	userRepo    repository.UserRepository
+	sessions    *SessionService
+	notifClient *grpcclient.NotificationClient
+	mailer      mailer.Mailer
+
+	mu    sync.Mutex
+	cache map[uint]cachedAccount
+}
+
+// NewAccountStatusService creates a new account status service
+func NewAccountStatusService(
+	userRepo repository.UserRepository,
+	sessions *SessionService,
+	notifClient *grpcclient.NotificationClient,
+	mailer mailer.Mailer,
+) *AccountStatusService {
+	return &AccountStatusService{
+		userRepo:    userRepo,
+		sessions:    sessions,
+		notifClient: notifClient,
+		mailer:      mailer,
+		cache:       make(map[uint]cachedAccount),
+	}
+}
+
+// CheckAccount returns an error unless the user exists and may use the API
+func (s *AccountStatusService) CheckAccount(userID uint) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[userID]
+	s.mu.Unlock()
+
+	if !ok || now.Sub(cached.cachedAt) > accountCacheTTL {
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				return ErrAccountNotFound
+			}
+			log.Printf("failed to check status of user %d: %v", userID, err)
+			return ErrAccountUnavailable
+		}
+		cached = cachedAccount{user: *user, cachedAt: now}
+		s.mu.Lock()
+		s.cache[userID] = cached
+		s.mu.Unlock()
+	}
+
+	return accountBlocked(&cached.user)
+}
+
+// Suspend locks the user out, optionally until a given time, and logs them
+// out everywhere
+func (s *AccountStatusService) Suspend(ctx context.Context, adminID, userID uint, req SuspendRequest) (*domain.User, error) {
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return nil, ErrSuspensionInPast
+	}
+
+	message := "Your account has been suspended: " + req.Reason
+	if req.Until != nil {
+		message = fmt.Sprintf("Your account has been suspended until %s: %s", req.Until.UTC().Format("2006-01-02 15:04 MST"), req.Reason)
+	}
+	return s.block(ctx, adminID, userID, domain.AccountSuspended, req.Reason, req.Until, "Account suspended", message)
+}
+
+// Deactivate locks the user out until they are reinstated and logs them
+// out everywhere
+func (s *AccountStatusService) Deactivate(ctx context.Context, adminID, userID uint, req DeactivateRequest) (*domain.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == domain.AccountDeactivated {
+		return nil, ErrAlreadyDeactivated
+	}
+	return s.block(ctx, adminID, userID, domain.AccountDeactivated, req.Reason, nil,
+		"Account deactivated", "Your account has been deactivated: "+req.Reason)
+}
+
+func (s *AccountStatusService) block(ctx context.Context, adminID, userID uint, status domain.AccountStatus, reason string, until *time.Time, title, message string) (*domain.User, error) {
+	if adminID == userID {
+		return nil, ErrOwnAccountStatus
+	}
+	if err := s.userRepo.UpdateStatus(userID, status, reason, until); err != nil {
+		return nil, err
+	}
+	s.evict(userID)
+
+	if err := s.sessions.RevokeAll(userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Account %s: user=%d by=%d", status, userID, adminID)
+	s.notify(ctx, user, title, message)
+	return user, nil
+}
+
+// Reinstate lifts a suspension or deactivation
+func (s *AccountStatusService) Reinstate(ctx context.Context, adminID, userID uint) (*domain.User, error) {
+	if adminID == userID {
+		return nil, ErrOwnAccountStatus
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != domain.AccountSuspended && user.Status != domain.AccountDeactivated {
+		return nil, ErrAccountNotBlocked
+	}
+	return s.reinstate(ctx, user)
+}
+
+func (s *AccountStatusService) reinstate(ctx context.Context, user *domain.User) (*domain.User, error) {
+	status := domain.AccountActive
+	if !user.IsVerified() {
+		status = domain.AccountPendingVerification
+	}
+	if err := s.userRepo.UpdateStatus(user.ID, status, "", nil); err != nil {
+		return nil, err
+	}
+	s.evict(user.ID)
+
+	user.Status = status
+	user.StatusReason = ""
+	user.StatusUntil = nil
+	log.Printf("Account reinstated: user=%d", user.ID)
+	s.notify(ctx, user, "Account reinstated", "Your account has been reinstated. You can sign in again.")
+	return user, nil
+}
+
+// Run lifts expired suspensions and prunes stale cache entries every
+// interval until ctx is cancelled
+func (s *AccountStatusService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.pruneCache(time.Now())
+
+			users, err := s.userRepo.ExpiredSuspensions(time.Now())
+			if err != nil {
+				log.Printf("failed to load expired suspensions: %v", err)
+				continue
+			}
+			for i := range users {
+				if _, err := s.reinstate(ctx, &users[i]); err != nil {
+					log.Printf("failed to lift suspension of user %d: %v", users[i].ID, err)
+				}
+			}
+		}
+	}
+}
+
+// pruneCache drops cached statuses that would be re-read anyway, so the
+// cache only holds recently active users
+func (s *AccountStatusService) pruneCache(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for userID, cached := range s.cache {
+		if now.Sub(cached.cachedAt) > accountCacheTTL {
+			delete(s.cache, userID)
+		}
+	}
+}
+
+func (s *AccountStatusService) evict(userID uint) {
+	s.mu.Lock()
+	delete(s.cache, userID)
+	s.mu.Unlock()
+}
+
+// notify tells the user about a status change by notification and email,
+// since a blocked user cannot read their notifications. Both are
+// best-effort.
+func (s *AccountStatusService) notify(ctx context.Context, user *domain.User, title, message string) {
+	notifCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := s.notifClient.SendNotification(notifCtx, int64(user.ID), string(domain.NotificationTypeAccount), title, message); err != nil {
+		log.Printf("failed to notify user %d: %v", user.ID, err)
+	}
+
+	err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: title,
+		Body:    fmt.Sprintf("Hi %s,\n\n%s\n", user.Name, message),
+	})
+	if err != nil {
+		log.Printf("failed to email user %d: %v", user.ID, err)
+	}
+}
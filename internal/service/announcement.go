@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/grpcclient"
+)
+
+var ErrNotCourseMember = errors.New("you are not a member of this course")
+
+// announcementBatchSize caps the number of recipients per bulk RPC
+const announcementBatchSize = 500
+
+// AnnouncementService handles course announcements
+type AnnouncementService struct {
+	announcementRepo repository.AnnouncementRepository
+	enrollmentRepo   repository.EnrollmentRepository
+	courseRepo       repository.CourseRepository
+	staff            *CourseStaffService
+	notifClient      *grpcclient.NotificationClient
+	events           *eventbus.Bus
+}
+
+// NewAnnouncementService creates a new announcement service
+func NewAnnouncementService(
+	announcementRepo repository.AnnouncementRepository,
+	enrollmentRepo repository.EnrollmentRepository,
+	courseRepo repository.CourseRepository,
+	staff *CourseStaffService,
+	notifClient *grpcclient.NotificationClient,
+	events *eventbus.Bus,
+) *AnnouncementService {
+	return &AnnouncementService{
+		announcementRepo: announcementRepo,
+		enrollmentRepo:   enrollmentRepo,
+		courseRepo:       courseRepo,
+		staff:            staff,
+		notifClient:      notifClient,
+		events:           events,
+	}
+}
+
+// CreateAnnouncementRequest represents a new announcement
+type CreateAnnouncementRequest struct {
+	Title   string `json:"title" binding:"required,max=200"`
+	Message string `json:"message" binding:"required"`
+}
+
+// Create stores an announcement. Its students are notified in the
+// background, so a large course does not hold up the request.
+func (s *AnnouncementService) Create(course *domain.Course, authorID uint, req CreateAnnouncementRequest) (*domain.Announcement, error) {
+	announcement := &domain.Announcement{
+		CourseID: uint(course.ID),
+		AuthorID: authorID,
+		Title:    req.Title,
+		Message:  req.Message,
+	}
+
+	if err := s.announcementRepo.Create(announcement); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(context.Background(), domain.AnnouncementPosted{
+		AnnouncementID: announcement.ID,
+		CourseID:       announcement.CourseID,
+		CourseTitle:    course.Title,
+		Title:          announcement.Title,
+		Message:        announcement.Message,
+		OccurredAt:     announcement.CreatedAt,
+	})
+	log.Printf("Announcement %d posted to course %d", announcement.ID, course.ID)
+	return announcement, nil
+}
+
+// Subscribe notifies students of new announcements as they are posted
+func (s *AnnouncementService) Subscribe(bus *eventbus.Bus) {
+	eventbus.Subscribe(bus, "announcements", eventbus.Async, s.onAnnouncementPosted)
+}
+
+// onAnnouncementPosted notifies every active student of the course in
+// batches, counting the students reached as it goes
+func (s *AnnouncementService) onAnnouncementPosted(ctx context.Context, e domain.AnnouncementPosted) error {
+	enrollments, err := s.enrollmentRepo.FindByCourse(e.CourseID)
+	if err != nil {
+		return fmt.Errorf("load enrollments of course %d: %w", e.CourseID, err)
+	}
+
+	recipients := make([]int64, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		if enrollment.Status == domain.EnrollmentStatusActive {
+			recipients = append(recipients, int64(enrollment.UserID))
+		}
+	}
+
+	title := fmt.Sprintf("[%s] %s", e.CourseTitle, e.Title)
+	sent := 0
+	var failed []string
+	for start := 0; start < len(recipients); start += announcementBatchSize {
+		end := min(start+announcementBatchSize, len(recipients))
+
+		batchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		count, err := s.notifClient.SendBulkNotification(batchCtx,
+			recipients[start:end],
+			string(domain.NotificationTypeAnnouncement),
+			title,
+			e.Message,
+		)
+		cancel()
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("batch at %d: %v", start, err))
+			continue
+		}
+		sent += int(count)
+		if err := s.announcementRepo.AddRecipients(e.AnnouncementID, int(count)); err != nil {
+			log.Printf("failed to update recipient count for announcement %d: %v", e.AnnouncementID, err)
+		}
+	}
+
+	log.Printf("Announcement %d sent to %d/%d students", e.AnnouncementID, sent, len(recipients))
+	if len(failed) > 0 {
+		return fmt.Errorf("notify announcement %d: %s", e.AnnouncementID, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// List returns the announcements of a course for its staff or an enrolled
+// student. anyCourse lets the user read every course's feed.
+func (s *AnnouncementService) List(courseID uint, userID uint, anyCourse bool, page, limit int) ([]domain.Announcement, int64, error) {
+	course, err := s.courseRepo.FindByID(int64(courseID))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !anyCourse {
+		staffRole, err := s.staff.StaffRole(course, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !staffRole.Allows(domain.StaffAccessRead) {
+			enrolled, err := s.enrollmentRepo.IsEnrolled(userID, courseID)
+			if err != nil {
+				return nil, 0, err
+			}
+			if !enrolled {
+				return nil, 0, ErrNotCourseMember
+			}
+		}
+	}
+
+	return s.announcementRepo.FindByCourse(courseID, page, limit)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/hash"
+	"elearning/pkg/token"
+)
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid or expired api key")
+	ErrInvalidAPIScope    = errors.New("invalid api key scope")
+	ErrAdminScopeDenied   = errors.New("admin scopes can only be granted to admin accounts")
+	ErrTooManyAPIKeys     = errors.New("too many active api keys")
+	ErrAPIKeyExpiryTooFar = errors.New("api key expiry exceeds the maximum lifetime")
+)
+
+const (
+	// apiKeySize is the number of random bytes in an API key
+	apiKeySize = 32
+	// apiKeyVisibleChars is how much of the random part is kept as prefix
+	apiKeyVisibleChars = 8
+	// apiKeyTouchInterval limits last-used writes to one per key per interval
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyOptions configures API keys
+type APIKeyOptions struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	MaxPerUser int
+}
+
+// CreateAPIKeyRequest represents a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1"`
+}
+
+// CreateAPIKeyResponse returns the new key. The key itself is only shown
+// here.
+type CreateAPIKeyResponse struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyService manages API keys and authenticates requests made with them
+type APIKeyService struct {
+	repo     repository.APIKeyRepository
+	userRepo repository.UserRepository
+	opts     APIKeyOptions
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(repo repository.APIKeyRepository, userRepo repository.UserRepository, opts APIKeyOptions) *APIKeyService {
+	return &APIKeyService{
+		repo:     repo,
+		userRepo: userRepo,
+		opts:     opts,
+	}
+}
+
+// Create issues a key owned by ownerID. creatorID differs from the owner
+// when an admin creates a key for a service account.
+func (s *APIKeyService) Create(ownerID, creatorID uint, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	owner, err := s.userRepo.FindByID(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := validateAPIScopes(req.Scopes, owner.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.opts.DefaultTTL
+	if req.ExpiresInDays > 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl > s.opts.MaxTTL {
+		return nil, ErrAPIKeyExpiryTooFar
+	}
+
+	now := time.Now()
+	active, err := s.repo.CountActive(ownerID, now)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.MaxPerUser > 0 && active >= int64(s.opts.MaxPerUser) {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	raw, _, err := hash.GenerateToken(apiKeySize)
+	if err != nil {
+		return nil, err
+	}
+	key := domain.APIKeyPrefix + raw
+
+	apiKey := domain.APIKey{
+		UserID:    ownerID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    key[:len(domain.APIKeyPrefix)+apiKeyVisibleChars],
+		KeyHash:   hash.HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: now.Add(ttl),
+		CreatedBy: creatorID,
+	}
+	if err := s.repo.Create(&apiKey); err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %d created api key %d (%s) for user %d", creatorID, apiKey.ID, apiKey.Prefix, ownerID)
+	return &CreateAPIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+// List returns the user's keys
+func (s *APIKeyService) List(userID uint) ([]domain.APIKey, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// ListAll returns every key, for admins
+func (s *APIKeyService) ListAll() ([]domain.APIKey, error) {
+	return s.repo.ListAll()
+}
+
+// Revoke revokes one of the user's keys
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	return s.repo.Revoke(userID, id, time.Now())
+}
+
+// RevokeAny revokes any key, for admins
+func (s *APIKeyService) RevokeAny(id uint) error {
+	return s.repo.Revoke(0, id, time.Now())
+}
+
+// RevokeAll revokes every key of the user, for when their account may be
+// compromised
+func (s *APIKeyService) RevokeAll(userID uint) error {
+	revoked, err := s.repo.RevokeAllForUser(userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if revoked > 0 {
+		log.Printf("Revoked %d api keys of user %d", revoked, userID)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves an API key to the claims of its owner,
+// restricted to the key's scopes
+func (s *APIKeyService) AuthenticateAPIKey(key, ip string) (*token.Claims, error) {
+	apiKey, err := s.repo.FindByHash(hash.HashToken(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !apiKey.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.FindByID(apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if err := s.repo.TouchUsed(apiKey.ID, ip, now, apiKeyTouchInterval); err != nil {
+		log.Printf("failed to record api key %d use: %v", apiKey.ID, err)
+	}
+
+	return &token.Claims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     string(user.Role),
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
+
+// validateAPIScopes checks requested scopes and removes duplicates
+func validateAPIScopes(requested []string, role domain.UserRole) ([]string, error) {
+	seen := make(map[string]bool, len(requested))
+	scopes := make([]string, 0, len(requested))
+	for _, s := range requested {
+		scope := domain.APIScope(strings.TrimSpace(s))
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIScope, s)
+		}
+		if scope.IsAdmin() && role != domain.RoleAdmin {
+			return nil, ErrAdminScopeDenied
+		}
+		if !seen[string(scope)] {
+			seen[string(scope)] = true
+			scopes = append(scopes, string(scope))
+		}
+	}
+	return scopes, nil
+}
@@ -1,53 +1,82 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"log"
 	"time"
 
 	"elearning/internal/domain"
 	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
 	"elearning/pkg/hash"
 	"elearning/pkg/token"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrInvalidRole        = errors.New("invalid role")
 )
 
-// RegisterRequest represents registration request
+// RegisterRequest represents registration request. Self-registered
+// accounts are always students; teachers and admins are made by an admin.
 type RegisterRequest struct {
-	Name     string          `json:"name" binding:"required"`
-	Email    string          `json:"email" binding:"required,email"`
-	Password string          `json:"password" binding:"required,min=6"`
-	Role     domain.UserRole `json:"role" binding:"required"`
+	Name     string     `json:"name" binding:"required"`
+	Email    string     `json:"email" binding:"required,email"`
+	Password string     `json:"password" binding:"required,min=6"`
+	Client   ClientInfo `json:"-"`
 }
 
-// LoginRequest represents login request
+// LoginRequest represents login request. Client is set by the handler.
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email    string     `json:"email" binding:"required,email"`
+	Password string     `json:"password" binding:"required"`
+	Client   ClientInfo `json:"-"`
 }
 
-// AuthResponse represents authentication response
+// AuthResponse represents authentication response. AccessToken is empty
+// when the account must verify its email before logging in, or when a
+// second factor is required (MFARequired with an MFAToken to answer).
+// With MFASetupRequired the access token may only be used to set up
+// two-factor authentication.
 type AuthResponse struct {
-	AccessToken string      `json:"access_token"`
-	User        UserProfile `json:"user"`
+	AccessToken          string      `json:"access_token,omitempty"`
+	VerificationRequired bool        `json:"verification_required,omitempty"`
+	MFARequired          bool        `json:"mfa_required,omitempty"`
+	MFAToken             string      `json:"mfa_token,omitempty"`
+	MFAExpiresAt         *time.Time  `json:"mfa_expires_at,omitempty"`
+	MFASetupRequired     bool        `json:"mfa_setup_required,omitempty"`
+	User                 UserProfile `json:"user"`
+}
+
+// EnableMFAResponse returns the recovery codes and a new access token that
+// carries the two-factor claim
+type EnableMFAResponse struct {
+	AccessToken   string   `json:"access_token"`
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 // UserProfile represents user profile
 type UserProfile struct {
-	ID    uint            `json:"id"`
-	Name  string          `json:"name"`
-	Email string          `json:"email"`
-	Role  domain.UserRole `json:"role"`
+	ID            uint                 `json:"id"`
+	Name          string               `json:"name"`
+	Email         string               `json:"email"`
+	Role          domain.UserRole      `json:"role"`
+	Status        domain.AccountStatus `json:"status"`
+	EmailVerified bool                 `json:"email_verified"`
+	MFAEnabled    bool                 `json:"mfa_enabled"`
 }
 
+// mfaSetupTokenTTL bounds restricted tokens issued to users who must set up
+// two-factor authentication before they can do anything else
+const mfaSetupTokenTTL = 15 * time.Minute
+
 // AuthService handles authentication business logic
 type AuthService interface {
 	Register(req RegisterRequest) (*AuthResponse, error)
 	Login(req LoginRequest) (*AuthResponse, error)
+	LoginExternal(userID uint, client ClientInfo) (*AuthResponse, error)
+	VerifyMFA(req MFAVerifyRequest) (*AuthResponse, error)
+	EnableMFA(userID uint, code string, client ClientInfo) (*EnableMFAResponse, error)
 	GetProfile(userID uint) (*UserProfile, error)
 	Logout(userID uint, token string) error
 }
@@ -57,6 +86,11 @@ type authService struct {
 	tokenMaker token.TokenMaker
 	blacklist  token.TokenBlacklist
 	jwtExpiry  time.Duration
+	events     *eventbus.Bus
+	policy     VerificationPolicy
+	mfa        *MFAService
+	guard      *LoginGuard
+	sessions   *SessionService
 }
 
 // NewAuthService creates a new auth service
@@ -65,22 +99,27 @@ func NewAuthService(
 	tokenMaker token.TokenMaker,
 	blacklist token.TokenBlacklist,
 	jwtExpiry time.Duration,
+	events *eventbus.Bus,
+	policy VerificationPolicy,
+	mfa *MFAService,
+	guard *LoginGuard,
+	sessions *SessionService,
 ) AuthService {
 	return &authService{
 		userRepo:   userRepo,
 		tokenMaker: tokenMaker,
 		blacklist:  blacklist,
 		jwtExpiry:  jwtExpiry,
+		events:     events,
+		policy:     policy,
+		mfa:        mfa,
+		guard:      guard,
+		sessions:   sessions,
 	}
 }
 
 // Register registers a new user
 func (s *authService) Register(req RegisterRequest) (*AuthResponse, error) {
-	// Validate role
-	if !req.Role.IsValid() {
-		return nil, ErrInvalidRole
-	}
-
 	// Hash password
 	hashedPassword, err := hash.HashPassword(req.Password)
 	if err != nil {
@@ -92,41 +131,37 @@ func (s *authService) Register(req RegisterRequest) (*AuthResponse, error) {
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: hashedPassword,
-		Role:     req.Role,
+		Role:     domain.RoleStudent,
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, err
 	}
 
-	// Generate token
-	accessToken, err := s.tokenMaker.CreateToken(
-		user.ID,
-		user.Email,
-		string(user.Role),
-		s.jwtExpiry,
-	)
-	if err != nil {
-		return nil, err
+	s.events.Publish(context.Background(), userCreatedEvent(user, "registration"))
+
+	if s.policy.BlocksLogin() {
+		return &AuthResponse{
+			VerificationRequired: true,
+			User:                 newUserProfile(user),
+		}, nil
 	}
 
-	return &AuthResponse{
-		AccessToken: accessToken,
-		User: UserProfile{
-			ID:    user.ID,
-			Name:  user.Name,
-			Email: user.Email,
-			Role:  user.Role,
-		},
-	}, nil
+	return s.issueTokens(user, false, req.Client)
 }
 
 // Login authenticates a user
 func (s *authService) Login(req LoginRequest) (*AuthResponse, error) {
+	// Refuse attempts while the account or IP is delayed or locked out
+	if err := s.guard.Check(req.Email, req.Client.IP); err != nil {
+		return nil, err
+	}
+
 	// Find user by email
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
+			s.guard.RecordFailure(req.Email, req.Client.IP, 0)
 			return nil, ErrInvalidCredentials
 		}
 		return nil, err
@@ -134,28 +169,162 @@ func (s *authService) Login(req LoginRequest) (*AuthResponse, error) {
 
 	// Check password
 	if err := hash.CheckPassword(user.Password, req.Password); err != nil {
+		s.guard.RecordFailure(req.Email, req.Client.IP, user.ID)
 		return nil, ErrInvalidCredentials
 	}
 
+	if s.policy.BlocksLogin() && !user.IsVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	resp, err := s.completeLogin(user, req.Client)
+	if err != nil {
+		return nil, err
+	}
+	// With two-factor enabled the failures are only reset once the second
+	// factor is verified
+	if !resp.MFARequired {
+		s.guard.RecordSuccess(req.Email)
+	}
+	return resp, nil
+}
+
+// LoginExternal signs in a user already authenticated by an external
+// identity provider. Local two-factor authentication still applies.
+func (s *authService) LoginExternal(userID uint, client ClientInfo) (*AuthResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.completeLogin(user, client)
+}
+
+// completeLogin finishes a login once the first factor has been checked
+func (s *authService) completeLogin(user *domain.User, client ClientInfo) (*AuthResponse, error) {
+	if err := accountBlocked(user); err != nil {
+		return nil, err
+	}
+
+	// Two-step login: answer with a challenge instead of an access token
+	mfaEnabled, err := s.mfa.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		challenge, expiresAt, err := s.mfa.CreateChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		profile := newUserProfile(user)
+		profile.MFAEnabled = true
+		return &AuthResponse{
+			MFARequired:  true,
+			MFAToken:     challenge,
+			MFAExpiresAt: &expiresAt,
+			User:         profile,
+		}, nil
+	}
+
+	return s.issueTokens(user, false, client)
+}
+
+// VerifyMFA completes a two-step login
+func (s *authService) VerifyMFA(req MFAVerifyRequest) (*AuthResponse, error) {
+	if (req.Code == "") == (req.RecoveryCode == "") {
+		return nil, ErrInvalidMFACode
+	}
+
+	userID, verifyErr := s.mfa.VerifyChallenge(req)
+	if userID == 0 {
+		return nil, verifyErr
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if verifyErr != nil {
+		// Wrong codes count toward the same lockout as wrong passwords
+		if errors.Is(verifyErr, ErrInvalidMFACode) || errors.Is(verifyErr, ErrInvalidMFAChallenge) {
+			s.guard.RecordFailure(user.Email, req.Client.IP, user.ID)
+		}
+		return nil, verifyErr
+	}
+	s.guard.RecordSuccess(user.Email)
+
+	return s.issueTokens(user, true, req.Client)
+}
+
+// EnableMFA confirms two-factor setup. The returned access token carries the
+// two-factor claim, replacing a restricted setup token.
+func (s *authService) EnableMFA(userID uint, code string, client ClientInfo) (*EnableMFAResponse, error) {
+	codes, err := s.mfa.Enable(userID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.issueTokens(user, true, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EnableMFAResponse{
+		AccessToken:   resp.AccessToken,
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// issueTokens creates the access token for an authenticated user, backed by
+// a new session. Users whose role enforces two-factor but who have not set
+// it up only get a restricted, short-lived setup token.
+func (s *authService) issueTokens(user *domain.User, mfaVerified bool, client ClientInfo) (*AuthResponse, error) {
+	// Also covers accounts suspended during a two-step login
+	if err := accountBlocked(user); err != nil {
+		return nil, err
+	}
+
+	profile := newUserProfile(user)
+	profile.MFAEnabled = mfaVerified
+
+	setupOnly := !mfaVerified && s.mfa.IsEnforced(user.Role)
+	ttl := s.jwtExpiry
+	if setupOnly {
+		ttl = mfaSetupTokenTTL
+	}
+
+	sessionID, err := s.sessions.Start(user.ID, client, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []token.ClaimOption{token.WithID(sessionID)}
+	switch {
+	case setupOnly:
+		opts = append(opts, token.WithMFASetupOnly())
+	case mfaVerified:
+		opts = append(opts, token.WithMFA())
+	}
+
 	// Generate token
 	accessToken, err := s.tokenMaker.CreateToken(
 		user.ID,
 		user.Email,
 		string(user.Role),
-		s.jwtExpiry,
+		ttl,
+		opts...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	return &AuthResponse{
-		AccessToken: accessToken,
-		User: UserProfile{
-			ID:    user.ID,
-			Name:  user.Name,
-			Email: user.Email,
-			Role:  user.Role,
-		},
+		AccessToken:      accessToken,
+		MFASetupRequired: setupOnly,
+		User:             profile,
 	}, nil
 }
 
@@ -166,12 +335,22 @@ func (s *authService) GetProfile(userID uint) (*UserProfile, error) {
 		return nil, err
 	}
 
-	return &UserProfile{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-		Role:  user.Role,
-	}, nil
+	profile := newUserProfile(user)
+	if profile.MFAEnabled, err = s.mfa.IsEnabled(userID); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func newUserProfile(user *domain.User) UserProfile {
+	return UserProfile{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Role:          user.Role,
+		Status:        user.Status,
+		EmailVerified: user.IsVerified(),
+	}
 }
 
 // Logout handles user logout
@@ -191,6 +370,11 @@ func (s *authService) Logout(userID uint, tokenString string) error {
 
 	log.Printf("User logged out: ID=%d, Email=%s", user.ID, user.Email)
 
+	if err := s.sessions.RevokeToken(claims.ID); err != nil {
+		log.Printf("Failed to revoke session: %v", err)
+		return err
+	}
+
 	// Add token to blacklist with its expiration time
 	if s.blacklist != nil {
 		expiresAt := claims.ExpiresAt.Time
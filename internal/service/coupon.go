@@ -0,0 +1,312 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInvalidCoupon         = errors.New("invalid coupon")
+	ErrCouponNotApplicable   = errors.New("coupon cannot be used for this course")
+	ErrCouponExpired         = errors.New("coupon has expired or is not yet valid")
+	ErrCouponUsedUp          = errors.New("coupon has reached its usage limit")
+	ErrCouponRedeemed        = errors.New("coupon has been redeemed; deactivate it instead")
+	ErrCouponCoursesNotOwned = errors.New("coupons can only be restricted to courses you own")
+)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{2,39}$`)
+
+// CouponRequest creates or replaces a coupon
+type CouponRequest struct {
+	Code           string              `json:"code" binding:"required"`
+	Description    string              `json:"description" binding:"max=255"`
+	Type           domain.DiscountType `json:"type" binding:"required"`
+	PercentOff     int                 `json:"percent_off"`
+	AmountOff      int64               `json:"amount_off"`
+	Currency       string              `json:"currency"`
+	CourseIDs      []int64             `json:"course_ids"`
+	CategoryIDs    []int64             `json:"category_ids"`
+	StartsAt       *time.Time          `json:"starts_at"`
+	ExpiresAt      *time.Time          `json:"expires_at"`
+	MaxRedemptions int                 `json:"max_redemptions"`
+	MaxPerUser     *int                `json:"max_per_user"`
+	Active         *bool               `json:"active"`
+}
+
+// CouponQuote is the price of a course with a coupon applied
+type CouponQuote struct {
+	Coupon   *domain.Coupon `json:"-"`
+	Code     string         `json:"code"`
+	Subtotal int64          `json:"subtotal"`
+	Discount int64          `json:"discount"`
+	Amount   int64          `json:"amount"`
+	Currency string         `json:"currency"`
+}
+
+// CouponService manages promo codes. Admins manage any coupon; teachers
+// manage their own coupons, which must be restricted to courses they own.
+type CouponService struct {
+	repo       repository.CouponRepository
+	courseRepo repository.CourseRepository
+	staff      *CourseStaffService
+}
+
+// NewCouponService creates a new coupon service
+func NewCouponService(repo repository.CouponRepository, courseRepo repository.CourseRepository, staff *CourseStaffService) *CouponService {
+	return &CouponService{
+		repo:       repo,
+		courseRepo: courseRepo,
+		staff:      staff,
+	}
+}
+
+// Create adds a coupon. With anyCoupon false the coupon is limited to the
+// author's own courses.
+func (s *CouponService) Create(userID uint, anyCoupon bool, req CouponRequest) (*domain.Coupon, error) {
+	coupon := &domain.Coupon{CreatedBy: userID, Active: true, MaxPerUser: 1}
+	if err := s.apply(coupon, userID, anyCoupon, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(coupon); err != nil {
+		return nil, err
+	}
+	log.Printf("Coupon %s created by user %d", coupon.Code, userID)
+	return coupon, nil
+}
+
+// Update replaces a coupon's settings. Its redemption count is kept.
+func (s *CouponService) Update(id, userID uint, anyCoupon bool, req CouponRequest) (*domain.Coupon, error) {
+	coupon, err := s.Get(id, userID, anyCoupon)
+	if err != nil {
+		return nil, err
+	}
+	code := coupon.Code
+	if err := s.apply(coupon, userID, anyCoupon, req); err != nil {
+		return nil, err
+	}
+	if coupon.Code != code {
+		if _, err := s.repo.FindByCode(coupon.Code); err == nil {
+			return nil, repository.ErrCouponCodeExists
+		} else if !errors.Is(err, repository.ErrCouponNotFound) {
+			return nil, err
+		}
+	}
+	if err := s.repo.Update(coupon); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// Delete removes a coupon that was never redeemed
+func (s *CouponService) Delete(id, userID uint, anyCoupon bool) (*domain.Coupon, error) {
+	coupon, err := s.Get(id, userID, anyCoupon)
+	if err != nil {
+		return nil, err
+	}
+	if coupon.Redemptions > 0 {
+		return nil, ErrCouponRedeemed
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// Get returns a coupon the user may manage
+func (s *CouponService) Get(id, userID uint, anyCoupon bool) (*domain.Coupon, error) {
+	coupon, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !anyCoupon && coupon.CreatedBy != userID {
+		return nil, repository.ErrCouponNotFound
+	}
+	return coupon, nil
+}
+
+// List returns the coupons the user may manage
+func (s *CouponService) List(userID uint, anyCoupon bool, filter repository.CouponFilter, page, limit int) ([]domain.Coupon, int64, error) {
+	if !anyCoupon {
+		filter.CreatedBy = userID
+	}
+	return s.repo.List(filter, page, limit)
+}
+
+// ListRedemptions returns the paid orders a coupon was used on
+func (s *CouponService) ListRedemptions(id, userID uint, anyCoupon bool, page, limit int) ([]domain.CouponRedemption, int64, error) {
+	if _, err := s.Get(id, userID, anyCoupon); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListRedemptions(id, page, limit)
+}
+
+// Quote checks that a customer can use a coupon for a course and returns
+// the discounted price
+func (s *CouponService) Quote(userID uint, course *domain.Course, code string) (*CouponQuote, error) {
+	coupon, err := s.repo.FindByCode(strings.TrimSpace(code))
+	if errors.Is(err, repository.ErrCouponNotFound) {
+		return nil, ErrInvalidCoupon
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case !coupon.Active:
+		return nil, ErrInvalidCoupon
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt),
+		coupon.ExpiresAt != nil && !now.Before(*coupon.ExpiresAt):
+		return nil, ErrCouponExpired
+	case coupon.MaxRedemptions > 0 && coupon.Redemptions >= coupon.MaxRedemptions:
+		return nil, ErrCouponUsedUp
+	case !coupon.AppliesTo(course):
+		return nil, ErrCouponNotApplicable
+	}
+	if coupon.MaxPerUser > 0 {
+		used, err := s.repo.CountUserRedemptions(coupon.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(coupon.MaxPerUser) {
+			return nil, ErrCouponUsedUp
+		}
+	}
+
+	discount := coupon.Discount(course.Price, course.Currency)
+	if discount == 0 {
+		// e.g. a fixed discount in another currency
+		return nil, ErrCouponNotApplicable
+	}
+	return &CouponQuote{
+		Coupon:   coupon,
+		Code:     coupon.Code,
+		Subtotal: course.Price,
+		Discount: discount,
+		Amount:   course.Price - discount,
+		Currency: course.Currency,
+	}, nil
+}
+
+// Reserve holds a use of the order's coupon until the order is paid or
+// released. Quote's limit checks are advisory; this is where concurrent
+// checkouts are held to the coupon's limits.
+func (s *CouponService) Reserve(order *domain.Order, coupon *domain.Coupon) error {
+	err := s.repo.Reserve(redemptionOf(order), coupon.MaxPerUser)
+	if errors.Is(err, repository.ErrCouponExhausted) {
+		return ErrCouponUsedUp
+	}
+	return err
+}
+
+// Release gives back the coupon use reserved for an order that will not
+// be paid
+func (s *CouponService) Release(order *domain.Order) error {
+	if order.CouponID == nil {
+		return nil
+	}
+	return s.repo.Release(order.ID)
+}
+
+// Redeem records the coupon of a paid order
+func (s *CouponService) Redeem(order *domain.Order) error {
+	if order.CouponID == nil {
+		return nil
+	}
+	return s.repo.Redeem(redemptionOf(order))
+}
+
+func redemptionOf(order *domain.Order) *domain.CouponRedemption {
+	return &domain.CouponRedemption{
+		CouponID: *order.CouponID,
+		UserID:   order.UserID,
+		OrderID:  order.ID,
+		CourseID: order.CourseID,
+		Discount: order.Discount,
+		Currency: order.Currency,
+	}
+}
+
+// apply validates a request and copies it onto the coupon
+func (s *CouponService) apply(coupon *domain.Coupon, userID uint, anyCoupon bool, req CouponRequest) error {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if !couponCodePattern.MatchString(code) {
+		return fmt.Errorf("%w: code must be 3-40 letters, digits, '-' or '_'", ErrInvalidCoupon)
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	switch req.Type {
+	case domain.DiscountPercent:
+		if req.PercentOff < 1 || req.PercentOff > 100 {
+			return fmt.Errorf("%w: percent_off must be between 1 and 100", ErrInvalidCoupon)
+		}
+		req.AmountOff = 0
+		currency = ""
+	case domain.DiscountFixed:
+		if req.AmountOff <= 0 || !isCurrencyCode(currency) {
+			return fmt.Errorf("%w: fixed discounts need a positive amount_off and a currency", ErrInvalidCoupon)
+		}
+		req.PercentOff = 0
+	default:
+		return fmt.Errorf("%w: type must be percent or fixed", ErrInvalidCoupon)
+	}
+
+	if req.StartsAt != nil && req.ExpiresAt != nil && !req.ExpiresAt.After(*req.StartsAt) {
+		return fmt.Errorf("%w: expires_at must be after starts_at", ErrInvalidCoupon)
+	}
+	if req.MaxRedemptions < 0 || (req.MaxPerUser != nil && *req.MaxPerUser < 0) {
+		return fmt.Errorf("%w: usage limits cannot be negative", ErrInvalidCoupon)
+	}
+
+	if !anyCoupon && (len(req.CourseIDs) == 0 || len(req.CategoryIDs) > 0) {
+		return ErrCouponCoursesNotOwned
+	}
+	for _, id := range req.CourseIDs {
+		course, err := s.courseRepo.FindByID(id)
+		if err != nil {
+			return fmt.Errorf("%w: course %d not found", ErrInvalidCoupon, id)
+		}
+		if !anyCoupon {
+			role, err := s.staff.StaffRole(course, userID)
+			if err != nil {
+				return err
+			}
+			if !role.Allows(domain.StaffAccessOwner) {
+				return ErrCouponCoursesNotOwned
+			}
+		}
+	}
+	for _, id := range req.CategoryIDs {
+		if id <= 0 {
+			return fmt.Errorf("%w: invalid category %d", ErrInvalidCoupon, id)
+		}
+	}
+
+	coupon.Code = code
+	coupon.Description = strings.TrimSpace(req.Description)
+	coupon.Type = req.Type
+	coupon.PercentOff = req.PercentOff
+	coupon.AmountOff = req.AmountOff
+	coupon.Currency = currency
+	coupon.CourseIDs = pq.Int64Array(req.CourseIDs)
+	coupon.CategoryIDs = pq.Int64Array(req.CategoryIDs)
+	coupon.StartsAt = req.StartsAt
+	coupon.ExpiresAt = req.ExpiresAt
+	coupon.MaxRedemptions = req.MaxRedemptions
+	if req.MaxPerUser != nil {
+		coupon.MaxPerUser = *req.MaxPerUser
+	}
+	if req.Active != nil {
+		coupon.Active = *req.Active
+	}
+	return nil
+}
@@ -1,13 +1,28 @@
 package service
 
 import (
+	"context"
 	"elearning/internal/domain"
 	"elearning/internal/repository"
-	"log"
-	"os"
+	"elearning/pkg/eventbus"
+	"errors"
 	"strings"
+	"time"
 )
 
+var (
+	ErrInvalidPricing   = errors.New("paid courses need a positive price and a three-letter currency code")
+	ErrInvalidThumbnail = errors.New("thumbnail must be an uploaded file or an http(s) URL")
+)
+
+// CoursePricing is the price of a course. Price is in the currency's minor
+// unit, e.g. cents.
+type CoursePricing struct {
+	IsFree   bool   `json:"is_free"`
+	Price    int64  `json:"price"`
+	Currency string `json:"currency"`
+}
+
 type CourseService interface {
 	Create(c *domain.Course) error
 	GetByID(id int64) (*domain.Course, error)
@@ -15,21 +30,27 @@ type CourseService interface {
 	Update(c *domain.Course) error
 	Delete(id int64) error
 	Publish(id int64, state bool) error
+	SetPricing(id int64, pricing CoursePricing) (*domain.Course, error)
 }
 
 type courseService struct {
 	repo       repository.CourseRepository
 	lessonRepo repository.LessonRepository
+	events     *eventbus.Bus
 }
 
-func NewCourseService(repo repository.CourseRepository, lessonRepo repository.LessonRepository) CourseService {
+func NewCourseService(repo repository.CourseRepository, lessonRepo repository.LessonRepository, events *eventbus.Bus) CourseService {
 	return &courseService{
 		repo:       repo,
 		lessonRepo: lessonRepo,
+		events:     events,
 	}
 }
 
 func (s *courseService) Create(c *domain.Course) error {
+	if c.Thumbnail != "" && !IsMediaURL(c.Thumbnail) {
+		return ErrInvalidThumbnail
+	}
 	return s.repo.Create(c)
 }
 
@@ -42,58 +63,75 @@ func (s *courseService) GetList(filter map[string]interface{}) ([]domain.Course,
 }
 
 func (s *courseService) Update(c *domain.Course) error {
+	if c.Thumbnail != "" && !IsMediaURL(c.Thumbnail) {
+		return ErrInvalidThumbnail
+	}
 	return s.repo.Update(c)
 }
 
+// Delete moves a course to the trash. Its lessons, enrollments and media
+// are kept until the course is purged.
 func (s *courseService) Delete(id int64) error {
-	// Get course to access thumbnail
-	course, err := s.repo.FindByID(id)
-	if err != nil {
+	if _, err := s.repo.FindByID(id); err != nil {
 		return err
 	}
+	return s.repo.Delete(id)
+}
 
-	// Get all lessons for this course
-	lessons, err := s.lessonRepo.GetByCourse(id)
+func (s *courseService) Publish(id int64, state bool) error {
+	course, err := s.repo.FindByID(id)
 	if err != nil {
-		log.Printf("failed to get lessons for course %d: %v", id, err)
+		return err
 	}
-
-	// Delete all lesson files
-	for _, lesson := range lessons {
-		// Delete video file
-		if lesson.VideoURL != "" {
-			videoPath := strings.TrimPrefix(lesson.VideoURL, "/")
-			if err := os.Remove(videoPath); err != nil && !os.IsNotExist(err) {
-				log.Printf("failed to delete video file %s: %v", videoPath, err)
-			}
-		}
-
-		// Delete lesson file (PDF, etc)
-		if lesson.FileURL != "" {
-			filePath := strings.TrimPrefix(lesson.FileURL, "/")
-			if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-				log.Printf("failed to delete lesson file %s: %v", filePath, err)
-			}
-		}
+	wasPublished := course.IsPublished
+	course.IsPublished = state
+	if err := s.repo.Update(course); err != nil {
+		return err
 	}
 
-	// Delete course thumbnail
-	if course.Thumbnail != "" {
-		thumbnailPath := strings.TrimPrefix(course.Thumbnail, "/")
-		if err := os.Remove(thumbnailPath); err != nil && !os.IsNotExist(err) {
-			log.Printf("failed to delete thumbnail %s: %v", thumbnailPath, err)
-		}
+	if state && !wasPublished {
+		s.events.Publish(context.Background(), domain.CoursePublished{
+			CourseID:   uint(course.ID),
+			TeacherID:  uint(course.TeacherID),
+			Title:      course.Title,
+			OccurredAt: time.Now(),
+		})
 	}
-
-	// Delete course from database (lessons will be cascade deleted if foreign key is set)
-	return s.repo.Delete(id)
+	return nil
 }
 
-func (s *courseService) Publish(id int64, state bool) error {
+// SetPricing changes the price of a course. Existing orders keep the price
+// they were placed at.
+func (s *courseService) SetPricing(id int64, pricing CoursePricing) (*domain.Course, error) {
+	currency := strings.ToUpper(strings.TrimSpace(pricing.Currency))
+	if currency == "" {
+		currency = "USD"
+	}
+	if !isCurrencyCode(currency) || pricing.Price < 0 || (!pricing.IsFree && pricing.Price == 0) {
+		return nil, ErrInvalidPricing
+	}
+
 	course, err := s.repo.FindByID(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	course.IsPublished = state
-	return s.repo.Update(course)
+	course.IsFree = pricing.IsFree
+	course.Price = pricing.Price
+	course.Currency = currency
+	if err := s.repo.Update(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+)
+
+var (
+	ErrInvalidStaffRole = errors.New("staff role must be co_instructor or ta")
+	ErrAlreadyStaff     = errors.New("user is already on the course staff or invited")
+	ErrInviteOwner      = errors.New("the course owner is already on the staff")
+)
+
+// InviteStaffRequest invites an existing user to a course's staff
+type InviteStaffRequest struct {
+	Email string                 `json:"email" binding:"required,email"`
+	Role  domain.CourseStaffRole `json:"role" binding:"required"`
+}
+
+// UpdateStaffRoleRequest changes a staff member's role
+type UpdateStaffRoleRequest struct {
+	Role domain.CourseStaffRole `json:"role" binding:"required"`
+}
+
+// CourseStaffService manages the co-instructors and teaching assistants of
+// courses and resolves what each user may do on a course
+type CourseStaffService struct {
+	staffRepo  repository.CourseStaffRepository
+	courseRepo repository.CourseRepository
+	userRepo   repository.UserRepository
+	events     *eventbus.Bus
+}
+
+// NewCourseStaffService creates a new course staff service
+func NewCourseStaffService(
+	staffRepo repository.CourseStaffRepository,
+	courseRepo repository.CourseRepository,
+	userRepo repository.UserRepository,
+	events *eventbus.Bus,
+) *CourseStaffService {
+	return &CourseStaffService{
+		staffRepo:  staffRepo,
+		courseRepo: courseRepo,
+		userRepo:   userRepo,
+		events:     events,
+	}
+}
+
+// StaffRole returns the user's role on the course's staff, or "" when the
+// user is not on it. Pending invitations grant nothing.
+func (s *CourseStaffService) StaffRole(course *domain.Course, userID uint) (domain.CourseStaffRole, error) {
+	if course.TeacherID == int64(userID) {
+		return domain.StaffOwner, nil
+	}
+
+	staff, err := s.staffRepo.Find(uint(course.ID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrStaffNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !staff.IsAccepted() {
+		return "", nil
+	}
+	return staff.Role, nil
+}
+
+// HasAccess reports whether the user's staff role on the course grants
+// the access level
+func (s *CourseStaffService) HasAccess(courseID, userID uint, access domain.StaffAccess) (bool, error) {
+	course, err := s.courseRepo.FindByID(int64(courseID))
+	if err != nil {
+		return false, err
+	}
+	role, err := s.StaffRole(course, userID)
+	if err != nil {
+		return false, err
+	}
+	return role.Allows(access), nil
+}
+
+// List returns the course's owner followed by its staff and pending
+// invitations
+func (s *CourseStaffService) List(course *domain.Course) ([]domain.CourseStaff, error) {
+	staff, err := s.staffRepo.ListByCourse(uint(course.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	owner := domain.CourseStaff{
+		CourseID:   uint(course.ID),
+		UserID:     uint(course.TeacherID),
+		Role:       domain.StaffOwner,
+		CreatedAt:  course.CreatedAt,
+		AcceptedAt: &course.CreatedAt,
+	}
+	if user, err := s.userRepo.FindByID(uint(course.TeacherID)); err == nil {
+		owner.User = user
+	}
+
+	return append([]domain.CourseStaff{owner}, staff...), nil
+}
+
+// Invite invites an existing user to the course's staff. The invitation
+// grants nothing until the user accepts it.
+func (s *CourseStaffService) Invite(course *domain.Course, inviterID uint, req InviteStaffRequest) (*domain.CourseStaff, error) {
+	if !req.Role.IsInvitable() {
+		return nil, ErrInvalidStaffRole
+	}
+
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(req.Email))
+	if err != nil {
+		return nil, err
+	}
+	if course.TeacherID == int64(user.ID) {
+		return nil, ErrInviteOwner
+	}
+
+	if _, err := s.staffRepo.Find(uint(course.ID), user.ID); err == nil {
+		return nil, ErrAlreadyStaff
+	} else if !errors.Is(err, repository.ErrStaffNotFound) {
+		return nil, err
+	}
+
+	staff := &domain.CourseStaff{
+		CourseID:  uint(course.ID),
+		UserID:    user.ID,
+		Role:      req.Role,
+		InvitedBy: inviterID,
+	}
+	if err := s.staffRepo.Create(staff); err != nil {
+		return nil, err
+	}
+	staff.User = user
+
+	log.Printf("User %d invited user %d to course %d as %s", inviterID, user.ID, course.ID, req.Role)
+
+	s.events.Publish(context.Background(), domain.CourseStaffInvited{
+		CourseID:    uint(course.ID),
+		CourseTitle: course.Title,
+		UserID:      user.ID,
+		Role:        req.Role,
+		InvitedBy:   inviterID,
+		OccurredAt:  staff.CreatedAt,
+	})
+	return staff, nil
+}
+
+// UpdateRole changes a staff member's role
+func (s *CourseStaffService) UpdateRole(course *domain.Course, userID uint, role domain.CourseStaffRole) error {
+	if !role.IsInvitable() {
+		return ErrInvalidStaffRole
+	}
+	if err := s.staffRepo.UpdateRole(uint(course.ID), userID, role); err != nil {
+		return err
+	}
+	log.Printf("User %d is now %s on course %d", userID, role, course.ID)
+	return nil
+}
+
+// Remove removes a staff member or withdraws an invitation
+func (s *CourseStaffService) Remove(course *domain.Course, userID uint) error {
+	if err := s.staffRepo.Delete(uint(course.ID), userID); err != nil {
+		return err
+	}
+	log.Printf("User %d removed from the staff of course %d", userID, course.ID)
+	return nil
+}
+
+// ListInvitations returns the user's pending invitations
+func (s *CourseStaffService) ListInvitations(userID uint) ([]domain.CourseStaff, error) {
+	return s.staffRepo.ListInvitations(userID)
+}
+
+// Accept accepts the user's invitation to the course's staff
+func (s *CourseStaffService) Accept(courseID, userID uint) error {
+	if err := s.staffRepo.Accept(courseID, userID, time.Now()); err != nil {
+		return err
+	}
+	log.Printf("User %d joined the staff of course %d", userID, courseID)
+	return nil
+}
+
+// Leave declines the user's invitation to the course or leaves its staff
+func (s *CourseStaffService) Leave(courseID, userID uint) error {
+	if err := s.staffRepo.Delete(courseID, userID); err != nil {
+		return err
+	}
+	log.Printf("User %d left the staff of course %d", userID, courseID)
+	return nil
+}
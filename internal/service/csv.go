@@ -0,0 +1,13 @@
+package service
+
+import "strings"
+
+// csvCell makes a user-supplied value safe to open in a spreadsheet.
+// Values starting with a character that spreadsheets read as a formula are
+// prefixed with a quote so they are shown as text.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
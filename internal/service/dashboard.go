@@ -34,15 +34,10 @@ func NewDashboardService(
 }
 
 func (s *dashboardService) GetStudentDashboard(ctx context.Context, userID uint) (*domain.StudentDashboard, error) {
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	if user.Role != domain.RoleStudent {
-		return nil, fmt.Errorf("user is not a student")
-	}
-
 	enrolledCourses, err := s.dashboardRepo.GetStudentEnrolledCourses(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get enrolled courses: %w", err)
@@ -99,15 +94,10 @@ func (s *dashboardService) GetStudentDashboard(ctx context.Context, userID uint)
 }
 
 func (s *dashboardService) GetTeacherDashboard(ctx context.Context, teacherID int64) (*domain.TeacherDashboard, error) {
-	user, err := s.userRepo.FindByID(uint(teacherID))
-	if err != nil {
+	if _, err := s.userRepo.FindByID(uint(teacherID)); err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	if user.Role != domain.RoleTeacher {
-		return nil, fmt.Errorf("user is not a teacher")
-	}
-
 	courses, err := s.dashboardRepo.GetTeacherCourses(ctx, teacherID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get teacher courses: %w", err)
@@ -123,11 +113,17 @@ func (s *dashboardService) GetTeacherDashboard(ctx context.Context, teacherID in
 		return nil, fmt.Errorf("failed to get recent enrollments: %w", err)
 	}
 
+	earnings, err := s.dashboardRepo.GetTeacherEarnings(ctx, teacherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teacher earnings: %w", err)
+	}
+
 	return &domain.TeacherDashboard{
 		MyCourses:         courses,
 		TotalStudents:     stats.TotalStudents,
 		RecentEnrollments: recentEnrollments,
 		Stats:             *stats,
+		Earnings:          earnings,
 	}, nil
 }
 
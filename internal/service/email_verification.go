@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/hash"
+	"elearning/pkg/mailer"
+)
+
+var (
+	ErrEmailNotVerified   = errors.New("email address is not verified")
+	ErrInvalidVerifyToken = errors.New("verification link is invalid or has expired")
+)
+
+// VerificationPolicy decides what unverified users are blocked from
+type VerificationPolicy string
+
+const (
+	VerificationPolicyNone   VerificationPolicy = "none"
+	VerificationPolicyEnroll VerificationPolicy = "enroll"
+	VerificationPolicyLogin  VerificationPolicy = "login"
+)
+
+// BlocksLogin reports whether unverified users may not log in
+func (p VerificationPolicy) BlocksLogin() bool {
+	return p == VerificationPolicyLogin
+}
+
+// BlocksEnroll reports whether unverified users may not enroll. Blocking
+// login implies blocking enrollment.
+func (p VerificationPolicy) BlocksEnroll() bool {
+	return p == VerificationPolicyEnroll || p == VerificationPolicyLogin
+}
+
+// verificationTokenSize is the number of random bytes in a token
+const verificationTokenSize = 32
+
+// EmailVerificationOptions configures the verification flow
+type EmailVerificationOptions struct {
+	TokenTTL       time.Duration
+	ResendLimit    int
+	ResendInterval time.Duration
+	AppBaseURL     string
+}
+
+// EmailVerificationService issues and redeems email verification tokens
+type EmailVerificationService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.UserTokenRepository
+	mailer    mailer.Mailer
+	opts      EmailVerificationOptions
+}
+
+// NewEmailVerificationService creates a new email verification service
+func NewEmailVerificationService(
+	userRepo repository.UserRepository,
+	tokenRepo repository.UserTokenRepository,
+	mailer mailer.Mailer,
+	opts EmailVerificationOptions,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		mailer:    mailer,
+		opts:      opts,
+	}
+}
+
+// VerifyEmailRequest represents an email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest represents a request for a new verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Subscribe sends a verification email to every self-registered user
+func (s *EmailVerificationService) Subscribe(bus *eventbus.Bus) {
+	eventbus.Subscribe(bus, "email-verification", eventbus.Async, func(ctx context.Context, e domain.UserCreated) error {
+		if e.Source != "registration" {
+			return nil
+		}
+		user, err := s.userRepo.FindByID(e.UserID)
+		if err != nil {
+			return err
+		}
+		if user.IsVerified() {
+			return nil
+		}
+		return s.Send(ctx, user)
+	})
+}
+
+// Send issues a new verification token, invalidating earlier ones, and
+// emails the verification link to the user
+func (s *EmailVerificationService) Send(ctx context.Context, user *domain.User) error {
+	raw, hashed, err := hash.GenerateToken(verificationTokenSize)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.InvalidateForUser(user.ID, domain.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token := &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPurposeEmailVerification,
+		TokenHash: hashed,
+		ExpiresAt: time.Now().Add(s.opts.TokenTTL),
+	}
+	if err := s.tokenRepo.Create(token); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.opts.AppBaseURL, url.QueryEscape(raw))
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not create an account, you can ignore this email.\n",
+			user.Name, link, s.opts.TokenTTL,
+		),
+	})
+}
+
+// Verify redeems a verification token and marks the user's email verified
+func (s *EmailVerificationService) Verify(rawToken string) error {
+	token, err := s.tokenRepo.Consume(domain.TokenPurposeEmailVerification, hash.HashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenInvalid) {
+			return ErrInvalidVerifyToken
+		}
+		return err
+	}
+
+	if err := s.userRepo.MarkVerified(token.UserID, time.Now()); err != nil {
+		return err
+	}
+
+	log.Printf("User %d verified their email", token.UserID)
+	return nil
+}
+
+// Resend emails a new verification link. Unknown addresses, verified
+// addresses and throttled requests are all silently ignored so the endpoint
+// cannot be used to find out which emails are registered.
+func (s *EmailVerificationService) Resend(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.IsVerified() {
+		return nil
+	}
+
+	throttled, err := tokenRequestThrottled(s.tokenRepo, user.ID, domain.TokenPurposeEmailVerification, s.opts.ResendInterval, s.opts.ResendLimit)
+	if err != nil {
+		return err
+	}
+	if throttled {
+		log.Printf("Verification resend throttled for user %d", user.ID)
+		return nil
+	}
+
+	return s.Send(ctx, user)
+}
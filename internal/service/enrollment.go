@@ -3,18 +3,21 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"time"
 
 	"elearning/internal/domain"
 	"elearning/internal/repository"
-	"elearning/pkg/grpcclient"
+	"elearning/pkg/eventbus"
+
+	"gorm.io/gorm"
 )
 
 var (
-	ErrCannotEnrollInOwnCourse = errors.New("teacher cannot enroll in their own course")
+	ErrCannotEnrollInOwnCourse = errors.New("course staff cannot enroll in their own course")
 	ErrCourseNotPublished      = errors.New("course is not published")
+	ErrPaymentRequired         = errors.New("course must be purchased or covered by a subscription before enrolling")
+	ErrEnrollmentNotActive     = errors.New("enrollment is no longer active")
 )
 
 // EnrollmentService handles enrollment business logic
@@ -22,7 +25,11 @@ type EnrollmentService struct {
 	enrollmentRepo repository.EnrollmentRepository
 	courseRepo     repository.CourseRepository
 	userRepo       repository.UserRepository
-	notifClient    *grpcclient.NotificationClient
+	orders         repository.OrderRepository
+	subscriptions  *SubscriptionService
+	staff          *CourseStaffService
+	events         *eventbus.Bus
+	policy         VerificationPolicy
 }
 
 // NewEnrollmentService creates a new enrollment service
@@ -30,13 +37,21 @@ func NewEnrollmentService(
 	enrollmentRepo repository.EnrollmentRepository,
 	courseRepo repository.CourseRepository,
 	userRepo repository.UserRepository,
-	notifClient *grpcclient.NotificationClient,
+	orders repository.OrderRepository,
+	subscriptions *SubscriptionService,
+	staff *CourseStaffService,
+	events *eventbus.Bus,
+	policy VerificationPolicy,
 ) *EnrollmentService {
 	return &EnrollmentService{
 		enrollmentRepo: enrollmentRepo,
 		courseRepo:     courseRepo,
 		userRepo:       userRepo,
-		notifClient:    notifClient,
+		orders:         orders,
+		subscriptions:  subscriptions,
+		staff:          staff,
+		events:         events,
+		policy:         policy,
 	}
 }
 
@@ -45,32 +60,62 @@ type EnrollRequest struct {
 	CourseID uint `json:"course_id" binding:"required"`
 }
 
-// Enroll enrolls a student in a course
+// Enroll enrolls a student in a course. Priced courses need a paid order
+// or a subscription covering them.
 func (s *EnrollmentService) Enroll(userID uint, courseID uint) (*domain.Enrollment, error) {
-	// Get course
-	course, err := s.courseRepo.FindByID(int64(courseID))
+	course, err := s.CheckEligible(userID, courseID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if course is published
-	if !course.IsPublished {
-		return nil, ErrCourseNotPublished
+	if course.RequiresPayment() {
+		paid, err := s.hasPaidAccess(userID, course)
+		if err != nil {
+			return nil, err
+		}
+		if !paid {
+			return nil, ErrPaymentRequired
+		}
 	}
 
-	// Get user to check role
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return nil, err
+	return s.create(course, userID)
+}
+
+// HasContentAccess reports whether a student may view the lessons of a
+// course. Priced courses need an enrollment and, like enrolling, a paid
+// order or a running subscription, so access ends with the subscription.
+func (s *EnrollmentService) HasContentAccess(userID uint, course *domain.Course) (bool, error) {
+	if !course.RequiresPayment() {
+		return true, nil
+	}
+	enrolled, err := s.enrollmentRepo.IsEnrolled(userID, uint(course.ID))
+	if err != nil || !enrolled {
+		return false, err
 	}
+	return s.hasPaidAccess(userID, course)
+}
 
-	// Students and admins can enroll
-	// Teachers can enroll in other teacher's courses
-	if user.Role == domain.RoleTeacher && course.TeacherID == int64(userID) {
-		return nil, ErrCannotEnrollInOwnCourse
+func (s *EnrollmentService) hasPaidAccess(userID uint, course *domain.Course) (bool, error) {
+	paid, err := s.orders.HasPaid(userID, uint(course.ID))
+	if err != nil || paid {
+		return paid, err
 	}
+	return s.subscriptions.HasAccess(userID, course)
+}
 
-	// Create enrollment
+// EnrollPurchased enrolls the customer of a paid order. Eligibility was
+// checked at checkout, so it is not checked again: a course unpublished
+// while the payment was processed is still delivered.
+func (s *EnrollmentService) EnrollPurchased(userID uint, courseID uint) (*domain.Enrollment, error) {
+	course, err := s.courseRepo.FindByID(int64(courseID))
+	if err != nil {
+		return nil, err
+	}
+	return s.create(course, userID)
+}
+
+func (s *EnrollmentService) create(course *domain.Course, userID uint) (*domain.Enrollment, error) {
+	courseID := uint(course.ID)
 	enrollment := &domain.Enrollment{
 		UserID:   userID,
 		CourseID: courseID,
@@ -83,31 +128,65 @@ func (s *EnrollmentService) Enroll(userID uint, courseID uint) (*domain.Enrollme
 
 	log.Printf("User %d enrolled in course %d", userID, courseID)
 
-	// Send notification to teacher
-	if s.notifClient != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
-
-		if err := s.notifClient.SendNotification(ctx,
-			course.TeacherID,
-			"enrollment",
-			"New Student Enrolled",
-			fmt.Sprintf("A student has enrolled in your course: %s", course.Title),
-		); err != nil {
-			log.Printf("failed to send enrollment notification: %v", err)
-		}
-	}
+	s.events.Publish(context.Background(), domain.UserEnrolled{
+		EnrollmentID: enrollment.ID,
+		UserID:       enrollment.UserID,
+		CourseID:     enrollment.CourseID,
+		TeacherID:    uint(course.TeacherID),
+		CourseTitle:  course.Title,
+		Status:       enrollment.Status,
+		EnrolledAt:   enrollment.EnrolledAt,
+	})
 
 	// Reload with relationships
 	return s.enrollmentRepo.FindByID(enrollment.ID)
 }
 
+// CheckEligible checks that a user may enroll in a course, apart from
+// paying for it, and returns the course
+func (s *EnrollmentService) CheckEligible(userID uint, courseID uint) (*domain.Course, error) {
+	// Get course
+	course, err := s.courseRepo.FindByID(int64(courseID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if course is published
+	if !course.IsPublished {
+		return nil, ErrCourseNotPublished
+	}
+
+	// Get user to check role
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.policy.BlocksEnroll() && !user.IsVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	// Teachers can enroll in other teachers' courses, but not in courses
+	// they are on the staff of
+	staffRole, err := s.staff.StaffRole(course, userID)
+	if err != nil {
+		return nil, err
+	}
+	if staffRole != "" {
+		return nil, ErrCannotEnrollInOwnCourse
+	}
+	return course, nil
+}
+
 // Unenroll removes a student from a course
 func (s *EnrollmentService) Unenroll(userID uint, courseID uint) error {
 	enrollment, err := s.enrollmentRepo.FindByUserAndCourse(userID, courseID)
 	if err != nil {
 		return err
 	}
+	if enrollment.Status == domain.EnrollmentStatusRefunded {
+		return repository.ErrNotEnrolled
+	}
 
 	// Update status to dropped
 	enrollment.Status = domain.EnrollmentStatusDropped
@@ -119,6 +198,26 @@ func (s *EnrollmentService) Unenroll(userID uint, courseID uint) error {
 	return nil
 }
 
+// Revoke ends the customer's access to a refunded course. Progress is
+// kept in case they buy the course again.
+func (s *EnrollmentService) Revoke(userID uint, courseID uint) error {
+	enrollment, err := s.enrollmentRepo.FindByUserAndCourse(userID, courseID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	enrollment.Status = domain.EnrollmentStatusRefunded
+	if err := s.enrollmentRepo.Update(enrollment); err != nil {
+		return err
+	}
+
+	log.Printf("User %d lost access to refunded course %d", userID, courseID)
+	return nil
+}
+
 // GetMyEnrollments returns all enrollments for a user
 func (s *EnrollmentService) GetMyEnrollments(userID uint, status string) ([]domain.Enrollment, error) {
 	enrollments, err := s.enrollmentRepo.FindByUser(userID)
@@ -140,21 +239,17 @@ func (s *EnrollmentService) GetMyEnrollments(userID uint, status string) ([]doma
 	return enrollments, nil
 }
 
-// GetCourseEnrollments returns all enrollments for a course (teacher view)
-func (s *EnrollmentService) GetCourseEnrollments(courseID uint, teacherID uint) ([]domain.Enrollment, error) {
-	// Verify teacher owns the course
-	course, err := s.courseRepo.FindByID(int64(courseID))
-	if err != nil {
-		return nil, err
-	}
-
-	if course.TeacherID != int64(teacherID) {
-		return nil, errors.New("not authorized to view enrollments for this course")
-	}
-
+// GetCourseEnrollments returns all enrollments for a course (teacher view).
+// Access is checked by the enrollment.read permission on the route.
+func (s *EnrollmentService) GetCourseEnrollments(courseID uint) ([]domain.Enrollment, error) {
 	return s.enrollmentRepo.FindByCourse(courseID)
 }
 
+// GetEnrollment returns an enrollment by ID
+func (s *EnrollmentService) GetEnrollment(id uint) (*domain.Enrollment, error) {
+	return s.enrollmentRepo.FindByID(id)
+}
+
 // GetEnrollmentStatus gets enrollment status for a specific course
 func (s *EnrollmentService) GetEnrollmentStatus(userID uint, courseID uint) (*domain.Enrollment, error) {
 	return s.enrollmentRepo.FindByUserAndCourse(userID, courseID)
@@ -165,7 +260,8 @@ func (s *EnrollmentService) IsEnrolled(userID uint, courseID uint) (bool, error)
 	return s.enrollmentRepo.IsEnrolled(userID, courseID)
 }
 
-// UpdateProgress updates enrollment progress
+// UpdateProgress updates enrollment progress. Dropped and refunded
+// enrollments cannot be updated, so they cannot be completed again.
 func (s *EnrollmentService) UpdateProgress(enrollmentID uint, progress float64) error {
 	if progress < 0 {
 		progress = 0
@@ -174,19 +270,33 @@ func (s *EnrollmentService) UpdateProgress(enrollmentID uint, progress float64)
 		progress = 100
 	}
 
-	err := s.enrollmentRepo.UpdateProgress(enrollmentID, progress)
+	updated, err := s.enrollmentRepo.UpdateProgress(enrollmentID, progress)
 	if err != nil {
 		return err
 	}
+	if !updated {
+		if _, err := s.enrollmentRepo.FindByID(enrollmentID); err != nil {
+			return err
+		}
+		return ErrEnrollmentNotActive
+	}
 
 	// If progress is 100%, mark as completed
 	if progress >= 100 {
+		completed, err := s.enrollmentRepo.MarkCompleted(enrollmentID)
+		if err != nil || !completed {
+			return err
+		}
 		enrollment, err := s.enrollmentRepo.FindByID(enrollmentID)
 		if err != nil {
 			return err
 		}
-		enrollment.Status = domain.EnrollmentStatusCompleted
-		return s.enrollmentRepo.Update(enrollment)
+		s.events.Publish(context.Background(), domain.CourseCompleted{
+			EnrollmentID: enrollment.ID,
+			UserID:       enrollment.UserID,
+			CourseID:     enrollment.CourseID,
+			OccurredAt:   time.Now(),
+		})
 	}
 
 	return nil
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+)
+
+// fakeEnrollmentRepo keeps enrollments in memory. Methods the tests do not
+// use are left to the embedded nil interface.
+type fakeEnrollmentRepo struct {
+	repository.EnrollmentRepository
+	enrollments map[uint]*domain.Enrollment
+}
+
+func (r *fakeEnrollmentRepo) FindByID(id uint) (*domain.Enrollment, error) {
+	e, ok := r.enrollments[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *e
+	return &copied, nil
+}
+
+func (r *fakeEnrollmentRepo) UpdateProgress(id uint, progress float64) (bool, error) {
+	e, ok := r.enrollments[id]
+	if !ok || (e.Status != domain.EnrollmentStatusActive && e.Status != domain.EnrollmentStatusCompleted) {
+		return false, nil
+	}
+	e.MaxProgress = max(e.MaxProgress, progress)
+	return true, nil
+}
+
+func (r *fakeEnrollmentRepo) MarkCompleted(id uint) (bool, error) {
+	e, ok := r.enrollments[id]
+	if !ok || e.Status != domain.EnrollmentStatusActive {
+		return false, nil
+	}
+	e.Status = domain.EnrollmentStatusCompleted
+	return true, nil
+}
+
+func TestEnrollmentUpdateProgress(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        domain.EnrollmentStatus
+		progress      float64
+		wantErr       error
+		wantStatus    domain.EnrollmentStatus
+		wantProgress  float64
+		wantCompleted int
+	}{
+		{"active in progress", domain.EnrollmentStatusActive, 40, nil, domain.EnrollmentStatusActive, 40, 0},
+		{"active completes", domain.EnrollmentStatusActive, 100, nil, domain.EnrollmentStatusCompleted, 100, 1},
+		{"completed again", domain.EnrollmentStatusCompleted, 100, nil, domain.EnrollmentStatusCompleted, 100, 0},
+		{"refunded stays refunded", domain.EnrollmentStatusRefunded, 100, ErrEnrollmentNotActive, domain.EnrollmentStatusRefunded, 10, 0},
+		{"dropped stays dropped", domain.EnrollmentStatusDropped, 100, ErrEnrollmentNotActive, domain.EnrollmentStatusDropped, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeEnrollmentRepo{enrollments: map[uint]*domain.Enrollment{
+				1: {ID: 1, UserID: 2, CourseID: 3, Status: tt.status, MaxProgress: 10},
+			}}
+			bus := eventbus.New(zap.NewNop(), 1, 1)
+			completed := 0
+			eventbus.Subscribe(bus, "test", eventbus.Sync, func(ctx context.Context, e domain.CourseCompleted) error {
+				completed++
+				return nil
+			})
+			svc := &EnrollmentService{enrollmentRepo: repo, events: bus}
+
+			err := svc.UpdateProgress(1, tt.progress)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateProgress error = %v, want %v", err, tt.wantErr)
+			}
+
+			got := repo.enrollments[1]
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if got.MaxProgress != tt.wantProgress {
+				t.Errorf("progress = %v, want %v", got.MaxProgress, tt.wantProgress)
+			}
+			if completed != tt.wantCompleted {
+				t.Errorf("CourseCompleted published %d times, want %d", completed, tt.wantCompleted)
+			}
+		})
+	}
+}
+
+func TestEnrollmentUpdateProgressNotFound(t *testing.T) {
+	repo := &fakeEnrollmentRepo{enrollments: map[uint]*domain.Enrollment{}}
+	svc := &EnrollmentService{enrollmentRepo: repo, events: eventbus.New(zap.NewNop(), 1, 1)}
+
+	if err := svc.UpdateProgress(1, 50); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("UpdateProgress error = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
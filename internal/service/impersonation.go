@@ -0,0 +1,180 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/token"
+)
+
+var (
+	ErrSelfImpersonation   = errors.New("cannot impersonate yourself")
+	ErrNestedImpersonation = errors.New("cannot impersonate while impersonating")
+	ErrImpersonationDenied = errors.New("users with administrative permissions cannot be impersonated")
+	ErrNotImpersonating    = errors.New("token is not an impersonation token")
+)
+
+// defaultImpersonationTTL applies when no lifetime is configured
+const defaultImpersonationTTL = 15 * time.Minute
+
+// ImpersonationOptions configures impersonation
+type ImpersonationOptions struct {
+	TTL time.Duration
+}
+
+// ImpersonateRequest represents a request to sign in as another user
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,min=5,max=500"`
+}
+
+// ImpersonationResponse returns the short-lived token acting as the user
+type ImpersonationResponse struct {
+	AccessToken   string               `json:"access_token"`
+	ExpiresAt     time.Time            `json:"expires_at"`
+	Impersonation domain.Impersonation `json:"impersonation"`
+}
+
+// ImpersonationService lets admins act as another user. Every token it
+// issues carries the admin in the act claim and every request made with
+// one is recorded.
+type ImpersonationService struct {
+	repo       repository.ImpersonationRepository
+	userRepo   repository.UserRepository
+	tokenMaker token.TokenMaker
+	sessions   *SessionService
+	roles      *RBACService
+	opts       ImpersonationOptions
+}
+
+// NewImpersonationService creates a new impersonation service
+func NewImpersonationService(
+	repo repository.ImpersonationRepository,
+	userRepo repository.UserRepository,
+	tokenMaker token.TokenMaker,
+	sessions *SessionService,
+	roles *RBACService,
+	opts ImpersonationOptions,
+) *ImpersonationService {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultImpersonationTTL
+	}
+	return &ImpersonationService{
+		repo:       repo,
+		userRepo:   userRepo,
+		tokenMaker: tokenMaker,
+		sessions:   sessions,
+		roles:      roles,
+		opts:       opts,
+	}
+}
+
+// Start issues a token for targetID on behalf of the admin in claims
+func (s *ImpersonationService) Start(admin *token.Claims, targetID uint, req ImpersonateRequest, client ClientInfo) (*ImpersonationResponse, error) {
+	if admin.Act != nil {
+		return nil, ErrNestedImpersonation
+	}
+	if admin.UserID == targetID {
+		return nil, ErrSelfImpersonation
+	}
+
+	target, err := s.userRepo.FindByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Acting as another admin would let the impersonator escalate
+	// privileges through their role management permissions
+	for _, perm := range []domain.Permission{domain.PermUserImpersonate, domain.PermRoleManage, domain.PermUserManage} {
+		if _, ok := s.roles.PermissionScope(string(target.Role), perm); ok {
+			return nil, ErrImpersonationDenied
+		}
+	}
+
+	tokenID, err := s.sessions.Start(target.ID, client, s.opts.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	impersonation := &domain.Impersonation{
+		AdminID:   admin.UserID,
+		UserID:    target.ID,
+		Reason:    req.Reason,
+		TokenID:   tokenID,
+		IP:        client.IP,
+		ExpiresAt: now.Add(s.opts.TTL),
+	}
+	if err := s.repo.Create(impersonation); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.tokenMaker.CreateToken(
+		target.ID,
+		target.Email,
+		string(target.Role),
+		s.opts.TTL,
+		token.WithID(tokenID),
+		token.WithActor(admin.UserID, admin.Email),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Impersonation started: admin=%d user=%d reason=%q", admin.UserID, target.ID, req.Reason)
+
+	impersonation.User = target
+	return &ImpersonationResponse{
+		AccessToken:   accessToken,
+		ExpiresAt:     impersonation.ExpiresAt,
+		Impersonation: *impersonation,
+	}, nil
+}
+
+// End revokes the impersonation token in claims before it expires
+func (s *ImpersonationService) End(claims *token.Claims) error {
+	if claims.Act == nil {
+		return ErrNotImpersonating
+	}
+
+	impersonation, err := s.repo.FindByTokenID(claims.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeToken(claims.ID); err != nil {
+		return err
+	}
+	return s.repo.End(impersonation.ID, time.Now())
+}
+
+// RecordRequest adds a request made with an impersonation token to the
+// audit trail
+func (s *ImpersonationService) RecordRequest(tokenID, method, path, route string, status int, ip string) error {
+	impersonation, err := s.repo.FindByTokenID(tokenID)
+	if err != nil {
+		return err
+	}
+	return s.repo.CreateRequest(&domain.ImpersonationRequest{
+		ImpersonationID: impersonation.ID,
+		Method:          method,
+		Path:            truncate(path, 500),
+		Route:           route,
+		Status:          status,
+		IP:              ip,
+	})
+}
+
+// List returns impersonations, newest first
+func (s *ImpersonationService) List(filter repository.ImpersonationFilter, page, limit int) ([]domain.Impersonation, int64, error) {
+	return s.repo.List(filter, page, limit)
+}
+
+// ListRequests returns the requests made during an impersonation
+func (s *ImpersonationService) ListRequests(id uint, page, limit int) ([]domain.ImpersonationRequest, int64, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListRequests(id, page, limit)
+}
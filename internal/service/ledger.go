@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+)
+
+var (
+	ErrInvalidPayoutRequest = errors.New("invalid payout request")
+	ErrNoPayoutsDue         = errors.New("no teacher balances are due for payout")
+)
+
+// reconcileBatchSize caps the sales and refunds posted per worker run
+const reconcileBatchSize = 500
+
+// LedgerOptions is the revenue share policy
+type LedgerOptions struct {
+	// TeacherSharePercent is the part of each sale credited to the
+	// course's teacher; the platform keeps the rest
+	TeacherSharePercent int
+}
+
+// GeneratePayoutsRequest selects the balances to pay out
+type GeneratePayoutsRequest struct {
+	// Currency limits the batch to one currency; empty pays out all
+	Currency string `json:"currency"`
+	// MinAmount leaves smaller balances for a later batch
+	MinAmount int64 `json:"min_amount" binding:"min=0"`
+}
+
+// SettlePayoutRequest records the outcome of a payout
+type SettlePayoutRequest struct {
+	Status domain.PayoutStatus `json:"status" binding:"required,oneof=sent failed"`
+	// Reference identifies the transfer, such as a bank reference
+	Reference string `json:"reference" binding:"max=255"`
+	// Note explains a failure
+	Note string `json:"note" binding:"max=255"`
+}
+
+// LedgerService keeps the revenue ledger. Each sale is split between the
+// platform and the course's teacher, refunds reverse the split, and
+// payouts settle what teachers are owed. Subscription revenue is not
+// tied to a course and stays with the platform.
+type LedgerService struct {
+	repo          repository.LedgerRepository
+	orders        repository.OrderRepository
+	refunds       repository.RefundRepository
+	subscriptions repository.SubscriptionRepository
+	opts          LedgerOptions
+}
+
+// NewLedgerService creates a new ledger service
+func NewLedgerService(
+	repo repository.LedgerRepository,
+	orders repository.OrderRepository,
+	refunds repository.RefundRepository,
+	subscriptions repository.SubscriptionRepository,
+	opts LedgerOptions,
+) *LedgerService {
+	return &LedgerService{
+		repo:          repo,
+		orders:        orders,
+		refunds:       refunds,
+		subscriptions: subscriptions,
+		opts:          opts,
+	}
+}
+
+// Subscribe posts sales, refunds and subscription payments as they happen
+func (s *LedgerService) Subscribe(bus *eventbus.Bus) {
+	eventbus.Subscribe(bus, "ledger", eventbus.Async, func(ctx context.Context, e domain.OrderPaid) error {
+		order, err := s.orders.FindByID(e.OrderID)
+		if err != nil {
+			return err
+		}
+		return s.postSale(order)
+	})
+	eventbus.Subscribe(bus, "ledger", eventbus.Async, func(ctx context.Context, e domain.OrderRefunded) error {
+		refund, err := s.refunds.FindByID(e.RefundID)
+		if err != nil {
+			return err
+		}
+		return s.postRefund(refund)
+	})
+	eventbus.Subscribe(bus, "ledger", eventbus.Async, func(ctx context.Context, e domain.SubscriptionInvoicePaid) error {
+		invoice, err := s.subscriptions.FindInvoice(e.InvoiceID)
+		if err != nil {
+			return err
+		}
+		return s.postInvoice(invoice)
+	})
+}
+
+// postSale splits a paid order by the current revenue share
+func (s *LedgerService) postSale(order *domain.Order) error {
+	if order.Amount <= 0 {
+		return nil
+	}
+	teacherID, err := s.repo.CourseTeacher(order.CourseID)
+	if err != nil {
+		return err
+	}
+	var teacher *uint
+	if teacherID != 0 {
+		teacher = &teacherID
+	}
+
+	_, err = s.repo.Post(domain.SaleTransaction(order, teacher, s.opts.TeacherSharePercent))
+	return err
+}
+
+// postRefund reverses the refunded part of a sale, posting the sale first
+// if that was missed
+func (s *LedgerService) postRefund(refund *domain.Refund) error {
+	if refund.Amount <= 0 {
+		return nil
+	}
+	sale, err := s.repo.FindSale(refund.OrderID)
+	if errors.Is(err, repository.ErrLedgerTransactionNotFound) {
+		order, findErr := s.orders.FindByID(refund.OrderID)
+		if findErr != nil {
+			return findErr
+		}
+		if err := s.postSale(order); err != nil {
+			return err
+		}
+		sale, err = s.repo.FindSale(refund.OrderID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.Post(domain.RefundTransaction(refund, sale))
+	return err
+}
+
+// postInvoice records a subscription payment as platform revenue
+func (s *LedgerService) postInvoice(invoice *domain.SubscriptionInvoice) error {
+	if invoice.Amount <= 0 {
+		return nil
+	}
+	_, err := s.repo.Post(domain.SubscriptionTransaction(invoice))
+	return err
+}
+
+// Run posts sales, refunds and subscription payments the subscribers
+// missed, such as those from before the ledger existed or still queued at
+// a restart, until ctx is done
+func (s *LedgerService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.reconcile()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *LedgerService) reconcile() {
+	orders, err := s.repo.UnpostedSales(reconcileBatchSize)
+	if err != nil {
+		log.Printf("failed to load unposted sales: %v", err)
+		return
+	}
+	for i := range orders {
+		if err := s.postSale(&orders[i]); err != nil {
+			log.Printf("failed to post sale of order %d: %v", orders[i].ID, err)
+		}
+	}
+
+	refunds, err := s.repo.UnpostedRefunds(reconcileBatchSize)
+	if err != nil {
+		log.Printf("failed to load unposted refunds: %v", err)
+		return
+	}
+	for i := range refunds {
+		if err := s.postRefund(&refunds[i]); err != nil {
+			log.Printf("failed to post refund %d: %v", refunds[i].ID, err)
+		}
+	}
+
+	invoices, err := s.repo.UnpostedInvoices(reconcileBatchSize)
+	if err != nil {
+		log.Printf("failed to load unposted subscription invoices: %v", err)
+		return
+	}
+	for i := range invoices {
+		if err := s.postInvoice(&invoices[i]); err != nil {
+			log.Printf("failed to post subscription invoice %d: %v", invoices[i].ID, err)
+		}
+	}
+
+	if len(orders) > 0 || len(refunds) > 0 || len(invoices) > 0 {
+		log.Printf("Ledger caught up on %d sales, %d refunds and %d subscription invoices", len(orders), len(refunds), len(invoices))
+	}
+}
+
+// Balances returns what is owed to each teacher
+func (s *LedgerService) Balances(currency string) ([]domain.TeacherBalance, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency != "" && !isCurrencyCode(currency) {
+		return nil, fmt.Errorf("%w: currency must be a three-letter code", ErrInvalidPayoutRequest)
+	}
+	return s.repo.Balances(currency, 0)
+}
+
+// GeneratePayouts creates a pending payout of every teacher balance due,
+// in one batch. Pending payouts are held back from balances, so a balance
+// is only ever included in one batch; they are posted to the ledger once
+// marked sent.
+func (s *LedgerService) GeneratePayouts(adminID uint, req GeneratePayoutsRequest) (*domain.PayoutBatch, error) {
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if currency != "" && !isCurrencyCode(currency) {
+		return nil, fmt.Errorf("%w: currency must be a three-letter code", ErrInvalidPayoutRequest)
+	}
+	if req.MinAmount < 0 {
+		return nil, fmt.Errorf("%w: min_amount cannot be negative", ErrInvalidPayoutRequest)
+	}
+
+	batch, err := s.repo.CreateBatch(adminID, currency, req.MinAmount)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, ErrNoPayoutsDue
+	}
+	log.Printf("Payout batch %d with %d payouts generated by user %d", batch.ID, batch.PayoutCount, adminID)
+	return batch, nil
+}
+
+// SettlePayout records whether a pending payout was paid. A sent payout
+// settles the teacher's balance in the ledger; a failed one makes the
+// amount due again for a later batch.
+func (s *LedgerService) SettlePayout(id, adminID uint, req SettlePayoutRequest) (*domain.Payout, error) {
+	if req.Status != domain.PayoutSent && req.Status != domain.PayoutFailed {
+		return nil, fmt.Errorf("%w: status must be sent or failed", ErrInvalidPayoutRequest)
+	}
+
+	payout, err := s.repo.SettlePayout(id, req.Status, adminID, strings.TrimSpace(req.Reference), strings.TrimSpace(req.Note))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Payout %d to teacher %d marked %s by user %d", payout.ID, payout.TeacherID, payout.Status, adminID)
+	return payout, nil
+}
+
+// ListBatches returns payout batches, newest first
+func (s *LedgerService) ListBatches(page, limit int) ([]domain.PayoutBatch, int64, error) {
+	return s.repo.ListBatches(page, limit)
+}
+
+// GetBatch returns a payout batch with its payouts
+func (s *LedgerService) GetBatch(id uint) (*domain.PayoutBatch, error) {
+	return s.repo.FindBatch(id)
+}
+
+// ExportBatch writes a batch's payouts as CSV. Amounts are in the
+// currency's minor unit, as everywhere else. Names and emails are escaped
+// so that a spreadsheet does not run them as formulas.
+func (s *LedgerService) ExportBatch(batch *domain.PayoutBatch, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"payout_id", "teacher_id", "teacher_name", "teacher_email", "amount", "currency", "status", "created_at"}); err != nil {
+		return err
+	}
+	for _, p := range batch.Payouts {
+		record := []string{
+			strconv.FormatUint(uint64(p.ID), 10),
+			strconv.FormatUint(uint64(p.TeacherID), 10),
+			csvCell(p.TeacherName),
+			csvCell(p.TeacherEmail),
+			strconv.FormatInt(p.Amount, 10),
+			p.Currency,
+			string(p.Status),
+			p.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -5,9 +5,6 @@ import (
 	"elearning/internal/repository"
 	"errors"
 	"fmt"
-	"log"
-	"os"
-	"strings"
 
 	"gorm.io/gorm"
 )
@@ -67,31 +64,13 @@ func (s *LessonService) UpdateLesson(l *domain.Lesson) error {
 	return nil
 }
 
-// DeleteLesson deletes a lesson by id.
+// DeleteLesson moves a lesson to the trash. Its files are kept until the
+// lesson is purged.
 func (s *LessonService) DeleteLesson(id int64) error {
-	// Get lesson to access file paths
-	lesson, err := s.repo.GetByID(id)
-	if err != nil {
+	if _, err := s.repo.GetByID(id); err != nil {
 		return fmt.Errorf("get lesson %d: %w", id, err)
 	}
 
-	// Delete video file if exists
-	if lesson.VideoURL != "" {
-		videoPath := strings.TrimPrefix(lesson.VideoURL, "/")
-		if err := os.Remove(videoPath); err != nil && !os.IsNotExist(err) {
-			log.Printf("failed to delete video file %s: %v", videoPath, err)
-		}
-	}
-
-	// Delete lesson file if exists
-	if lesson.FileURL != "" {
-		filePath := strings.TrimPrefix(lesson.FileURL, "/")
-		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-			log.Printf("failed to delete lesson file %s: %v", filePath, err)
-		}
-	}
-
-	// Delete lesson from database
 	if err := s.repo.Delete(id); err != nil {
 		return fmt.Errorf("delete lesson %d: %w", id, err)
 	}
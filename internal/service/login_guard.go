@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/mailer"
+	"elearning/pkg/metrics"
+)
+
+// LoginBlockedError is returned when failed logins for the account or the
+// client IP require the caller to wait before trying again
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	// Locked is true for a lockout, false for a progressive delay
+	Locked bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts; try again later"
+	}
+	return "please wait before trying to log in again"
+}
+
+// LoginThrottlePolicy sets the failure counts at which attempts start to be
+// delayed and at which the key is locked out
+type LoginThrottlePolicy struct {
+	DelayAfter int
+	LockAfter  int
+}
+
+// LoginGuardOptions configures brute-force protection on login
+type LoginGuardOptions struct {
+	Account LoginThrottlePolicy
+	IP      LoginThrottlePolicy
+	// BaseDelay doubles with every failure past DelayAfter, up to MaxDelay
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	// FailureWindow is how long failures keep counting after the last one
+	FailureWindow time.Duration
+}
+
+// LoginGuard counts failed logins per account and per client IP and blocks
+// further attempts with progressive delays, then temporary lockouts.
+// Accounts are keyed by normalized email whether or not they exist, so the
+// behaviour does not reveal which emails are registered.
+type LoginGuard struct {
+	repo     repository.LoginThrottleRepository
+	userRepo repository.UserRepository
+	events   *eventbus.Bus
+	opts     LoginGuardOptions
+}
+
+// NewLoginGuard creates a new login guard
+func NewLoginGuard(
+	repo repository.LoginThrottleRepository,
+	userRepo repository.UserRepository,
+	events *eventbus.Bus,
+	opts LoginGuardOptions,
+) *LoginGuard {
+	return &LoginGuard{
+		repo:     repo,
+		userRepo: userRepo,
+		events:   events,
+		opts:     opts,
+	}
+}
+
+// Check returns a *LoginBlockedError if the account or IP may not attempt
+// a login right now
+func (g *LoginGuard) Check(email, ip string) error {
+	now := time.Now()
+	throttles, err := g.repo.FindBlocking(normalizeLoginEmail(email), ip, now)
+	if err != nil {
+		return err
+	}
+
+	var blocked *LoginBlockedError
+	for _, t := range throttles {
+		retryAfter := t.BlockedUntil.Sub(now)
+		if blocked == nil || retryAfter > blocked.RetryAfter {
+			blocked = &LoginBlockedError{RetryAfter: retryAfter, Locked: t.IsLocked(now)}
+		}
+	}
+	if blocked != nil {
+		metrics.LoginAttempts.WithLabelValues("blocked").Inc()
+		return blocked
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and the IP.
+// userID is zero when no account exists for the email.
+func (g *LoginGuard) RecordFailure(email, ip string, userID uint) {
+	metrics.LoginAttempts.WithLabelValues("failure").Inc()
+
+	g.recordFailure(domain.LoginThrottleAccount, normalizeLoginEmail(email), ip, userID, g.opts.Account)
+	if ip != "" {
+		g.recordFailure(domain.LoginThrottleIP, ip, ip, 0, g.opts.IP)
+	}
+}
+
+// RecordSuccess clears the account's failure streak. The IP streak is kept
+// so that one valid account cannot be used to reset an IP's counter.
+func (g *LoginGuard) RecordSuccess(email string) {
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
+
+	if err := g.repo.Reset(domain.LoginThrottleAccount, normalizeLoginEmail(email)); err != nil {
+		log.Printf("failed to reset login throttle: %v", err)
+	}
+}
+
+// ClearAccount lifts any lockout on the account, e.g. after a password reset
+func (g *LoginGuard) ClearAccount(email string) error {
+	return g.repo.Reset(domain.LoginThrottleAccount, normalizeLoginEmail(email))
+}
+
+// ListLockouts returns throttles that currently block logins, optionally
+// filtered by scope
+func (g *LoginGuard) ListLockouts(scope domain.LoginThrottleScope) ([]domain.LoginThrottle, error) {
+	return g.repo.ListActive(scope, time.Now())
+}
+
+// ClearLockout removes a throttle so the account or IP can log in again
+func (g *LoginGuard) ClearLockout(id uint, adminID uint) error {
+	throttle, err := g.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := g.repo.Delete(id); err != nil {
+		return err
+	}
+	log.Printf("Admin %d cleared %s login lockout for %q", adminID, throttle.Scope, throttle.Key)
+	return nil
+}
+
+// Run prunes expired throttles until ctx is cancelled
+func (g *LoginGuard) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			before := time.Now().Add(-g.opts.FailureWindow)
+			if _, err := g.repo.DeleteStale(before); err != nil {
+				log.Printf("failed to prune login throttles: %v", err)
+			}
+		}
+	}
+}
+
+func (g *LoginGuard) recordFailure(scope domain.LoginThrottleScope, key, ip string, userID uint, policy LoginThrottlePolicy) {
+	now := time.Now()
+	throttle, err := g.repo.RecordFailure(scope, key, ip, now, now.Add(-g.opts.FailureWindow))
+	if err != nil {
+		log.Printf("failed to record failed login (%s): %v", scope, err)
+		return
+	}
+
+	n := throttle.FailedCount
+	switch {
+	case policy.LockAfter > 0 && n >= policy.LockAfter:
+		until := now.Add(g.opts.LockoutDuration)
+		if err := g.repo.Block(throttle.ID, until, true); err != nil {
+			log.Printf("failed to lock out login (%s): %v", scope, err)
+			return
+		}
+		// Only the failure that starts the lockout raises the alarm
+		if n == policy.LockAfter {
+			metrics.LoginLockouts.WithLabelValues(string(scope)).Inc()
+			log.Printf("Login %s %q locked until %s after %d failures", scope, key, until.Format(time.RFC3339), n)
+			if userID != 0 {
+				g.publishLocked(userID, ip, n, until)
+			}
+		}
+	case policy.DelayAfter > 0 && n >= policy.DelayAfter:
+		if err := g.repo.Block(throttle.ID, now.Add(g.delay(n-policy.DelayAfter)), false); err != nil {
+			log.Printf("failed to delay login (%s): %v", scope, err)
+		}
+	}
+}
+
+// delay returns BaseDelay doubled excess times, capped at MaxDelay
+func (g *LoginGuard) delay(excess int) time.Duration {
+	d := g.opts.BaseDelay
+	for i := 0; i < excess && d < g.opts.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > g.opts.MaxDelay {
+		d = g.opts.MaxDelay
+	}
+	return d
+}
+
+func (g *LoginGuard) publishLocked(userID uint, ip string, failures int, until time.Time) {
+	user, err := g.userRepo.FindByID(userID)
+	if err != nil {
+		log.Printf("failed to look up locked account %d: %v", userID, err)
+		return
+	}
+	g.events.Publish(context.Background(), domain.AccountLocked{
+		UserID:      user.ID,
+		Email:       user.Email,
+		IP:          ip,
+		Failures:    failures,
+		LockedUntil: until,
+		OccurredAt:  time.Now(),
+	})
+}
+
+// SubscribeAlerts emails users when their account is locked out
+func (g *LoginGuard) SubscribeAlerts(bus *eventbus.Bus, mail mailer.Mailer) {
+	eventbus.Subscribe(bus, "login-lockout-alert", eventbus.Async, func(ctx context.Context, e domain.AccountLocked) error {
+		return mail.Send(ctx, mailer.Message{
+			To:      e.Email,
+			Subject: "Suspicious sign-in activity on your account",
+			Body: fmt.Sprintf(
+				"We blocked sign-ins to your account until %s after %d failed login attempts (last from IP %s).\n\nIf this was you, wait and try again or reset your password. If it wasn't, we recommend resetting your password and enabling two-factor authentication.\n",
+				e.LockedUntil.Format(time.RFC1123), e.Failures, e.IP,
+			),
+		})
+	})
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
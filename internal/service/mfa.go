@@ -0,0 +1,373 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/hash"
+	"elearning/pkg/secretbox"
+	"elearning/pkg/totp"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMFANotSetUp         = errors.New("start two-factor setup first")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrInvalidMFAChallenge = errors.New("two-factor challenge is invalid or has expired")
+	ErrMFARequiredForRole  = errors.New("two-factor authentication is required for your role")
+)
+
+const (
+	// mfaCodeSkew accepts codes from one step before or after the current one
+	mfaCodeSkew = 1
+	// recoveryCodeCount is the number of recovery codes issued at a time
+	recoveryCodeCount = 10
+	// mfaChallengeSize is the number of random bytes in a challenge token
+	mfaChallengeSize = 32
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAOptions configures two-factor authentication
+type MFAOptions struct {
+	Issuer string
+	// EnforcedRoles must use two-factor in addition to the privileged roles
+	EnforcedRoles []domain.UserRole
+	ChallengeTTL  time.Duration
+	// MaxAttempts is the number of wrong codes allowed per challenge
+	MaxAttempts int
+}
+
+// MFAService manages TOTP two-factor authentication
+type MFAService struct {
+	userRepo  repository.UserRepository
+	mfaRepo   repository.MFARepository
+	tokenRepo repository.UserTokenRepository
+	roles     *RBACService
+	box       *secretbox.Box
+	opts      MFAOptions
+}
+
+// NewMFAService creates a new MFA service
+func NewMFAService(
+	userRepo repository.UserRepository,
+	mfaRepo repository.MFARepository,
+	tokenRepo repository.UserTokenRepository,
+	roles *RBACService,
+	box *secretbox.Box,
+	opts MFAOptions,
+) *MFAService {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 5
+	}
+	return &MFAService{
+		userRepo:  userRepo,
+		mfaRepo:   mfaRepo,
+		tokenRepo: tokenRepo,
+		roles:     roles,
+		box:       box,
+		opts:      opts,
+	}
+}
+
+// MFASetupResponse is returned when two-factor setup starts
+type MFASetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFACodeRequest carries a code from the user's authenticator app
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableMFARequest requires both factors to turn two-factor off
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest completes a two-step login with either a TOTP code or
+// a recovery code
+type MFAVerifyRequest struct {
+	MFAToken     string     `json:"mfa_token" binding:"required"`
+	Code         string     `json:"code"`
+	RecoveryCode string     `json:"recovery_code"`
+	Client       ClientInfo `json:"-"`
+}
+
+// RecoveryCodesResponse lists freshly generated recovery codes. They are
+// only shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// IsEnforced reports whether users of the role must use two-factor: roles
+// holding a privileged permission, such as any administrative permission or
+// a permission in the "any" scope, and the roles listed in EnforcedRoles
+func (s *MFAService) IsEnforced(role domain.UserRole) bool {
+	for _, r := range s.opts.EnforcedRoles {
+		if r == role {
+			return true
+		}
+	}
+	return s.roles.IsPrivileged(string(role))
+}
+
+// IsEnabled reports whether the user has two-factor enabled
+func (s *MFAService) IsEnabled(userID uint) (bool, error) {
+	mfa, err := s.mfaRepo.FindByUser(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.Enabled, nil
+}
+
+// BeginSetup generates a new secret for the user. Any earlier, unconfirmed
+// secret is replaced; an enabled configuration is left untouched.
+func (s *MFAService) BeginSetup(userID uint) (*MFASetupResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.Save(&domain.UserMFA{UserID: userID, Secret: sealed}); err != nil {
+		return nil, err
+	}
+
+	return &MFASetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.opts.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Enable confirms setup with a code from the authenticator and returns the
+// user's recovery codes
+func (s *MFAService) Enable(userID uint, code string) ([]string, error) {
+	mfa, err := s.mfaRepo.FindByUser(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return nil, ErrMFANotSetUp
+		}
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.verifyCode(mfa, code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	mfa.Enabled = true
+	mfa.EnabledAt = &now
+	if err := s.mfaRepo.Save(mfa); err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %d enabled two-factor authentication", userID)
+	return s.issueRecoveryCodes(userID)
+}
+
+// Disable turns two-factor off after checking the password and a code.
+// Users whose role enforces two-factor cannot disable it.
+func (s *MFAService) Disable(userID uint, password, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if s.IsEnforced(user.Role) {
+		return ErrMFARequiredForRole
+	}
+	if err := hash.CheckPassword(user.Password, password); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	mfa, err := s.enabledConfig(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyCode(mfa, code); err != nil {
+		return err
+	}
+
+	log.Printf("User %d disabled two-factor authentication", userID)
+	return s.mfaRepo.Delete(userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a code
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	mfa, err := s.enabledConfig(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(mfa, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+// CreateChallenge issues the short-lived token returned by the first login
+// step, to be exchanged for an access token together with a code
+func (s *MFAService) CreateChallenge(userID uint) (string, time.Time, error) {
+	raw, hashed, err := hash.GenerateToken(mfaChallengeSize)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(s.opts.ChallengeTTL)
+	if err := s.tokenRepo.Create(&domain.UserToken{
+		UserID:    userID,
+		Purpose:   domain.TokenPurposeMFAChallenge,
+		TokenHash: hashed,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", time.Time{}, err
+	}
+	return raw, expiresAt, nil
+}
+
+// VerifyChallenge checks the second factor for a login challenge and
+// returns the user it belongs to, also alongside a wrong code so that the
+// failure can be counted against the account. A challenge is consumed on
+// success and invalidated after too many wrong codes.
+func (s *MFAService) VerifyChallenge(req MFAVerifyRequest) (uint, error) {
+	challenge, err := s.tokenRepo.FindValid(domain.TokenPurposeMFAChallenge, hash.HashToken(req.MFAToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenInvalid) {
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, err
+	}
+
+	if err := s.checkSecondFactor(challenge.UserID, req); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return 0, err
+		}
+		attempts, incErr := s.tokenRepo.IncrementAttempts(challenge.ID)
+		if incErr == nil && attempts >= s.opts.MaxAttempts {
+			_ = s.tokenRepo.MarkUsed(challenge.ID)
+			return challenge.UserID, ErrInvalidMFAChallenge
+		}
+		return challenge.UserID, err
+	}
+
+	if err := s.tokenRepo.MarkUsed(challenge.ID); err != nil {
+		if errors.Is(err, repository.ErrTokenInvalid) {
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, err
+	}
+	return challenge.UserID, nil
+}
+
+func (s *MFAService) checkSecondFactor(userID uint, req MFAVerifyRequest) error {
+	mfa, err := s.enabledConfig(userID)
+	if err != nil {
+		return err
+	}
+
+	if req.RecoveryCode != "" {
+		used, err := s.mfaRepo.UseRecoveryCode(userID, hash.HashToken(normalizeRecoveryCode(req.RecoveryCode)))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		log.Printf("User %d signed in with a recovery code", userID)
+		return nil
+	}
+
+	return s.verifyCode(mfa, req.Code)
+}
+
+// verifyCode validates a TOTP code and burns its time step
+func (s *MFAService) verifyCode(mfa *domain.UserMFA, code string) error {
+	secret, err := s.box.Open(mfa.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), mfaCodeSkew)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	advanced, err := s.mfaRepo.AdvanceStep(mfa.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		// Code already used
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *MFAService) enabledConfig(userID uint) (*domain.UserMFA, error) {
+	mfa, err := s.mfaRepo.FindByUser(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, err
+	}
+	if !mfa.Enabled {
+		return nil, ErrMFANotEnabled
+	}
+	return mfa, nil
+}
+
+func (s *MFAService) issueRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hash.HashToken(raw)
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts codes with or without the dash and in any case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
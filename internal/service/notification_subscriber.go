@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/grpcclient"
+)
+
+// NotificationSubscriber turns domain events into user notifications
+type NotificationSubscriber struct {
+	notifClient *grpcclient.NotificationClient
+	courseRepo  repository.CourseRepository
+	staffRepo   repository.CourseStaffRepository
+}
+
+// NewNotificationSubscriber creates a new notification subscriber
+func NewNotificationSubscriber(notifClient *grpcclient.NotificationClient, courseRepo repository.CourseRepository, staffRepo repository.CourseStaffRepository) *NotificationSubscriber {
+	return &NotificationSubscriber{
+		notifClient: notifClient,
+		courseRepo:  courseRepo,
+		staffRepo:   staffRepo,
+	}
+}
+
+// Subscribe registers the subscriber's handlers on the bus
+func (n *NotificationSubscriber) Subscribe(bus *eventbus.Bus) {
+	eventbus.Subscribe(bus, "notifications", eventbus.Async, n.onUserEnrolled)
+	eventbus.Subscribe(bus, "notifications", eventbus.Async, n.onCourseCompleted)
+	eventbus.Subscribe(bus, "notifications", eventbus.Async, n.onStaffInvited)
+}
+
+// onUserEnrolled tells the course's teacher and staff a student joined
+func (n *NotificationSubscriber) onUserEnrolled(ctx context.Context, e domain.UserEnrolled) error {
+	recipients := []uint{e.TeacherID}
+	staff, err := n.staffRepo.ListAcceptedUserIDs(e.CourseID)
+	if err != nil {
+		log.Printf("failed to load staff of course %d: %v", e.CourseID, err)
+	}
+	recipients = append(recipients, staff...)
+
+	var failed []string
+	for _, userID := range recipients {
+		if err := n.notifClient.SendNotification(ctx,
+			int64(userID),
+			string(domain.NotificationTypeEnrollment),
+			"New Student Enrolled",
+			fmt.Sprintf("A student has enrolled in your course: %s", e.CourseTitle),
+		); err != nil {
+			failed = append(failed, fmt.Sprintf("user %d: %v", userID, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("notify enrollment: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// onStaffInvited tells a user they were invited to teach a course
+func (n *NotificationSubscriber) onStaffInvited(ctx context.Context, e domain.CourseStaffInvited) error {
+	role := "a co-instructor"
+	if e.Role == domain.StaffTA {
+		role = "a teaching assistant"
+	}
+	return n.notifClient.SendNotification(ctx,
+		int64(e.UserID),
+		string(domain.NotificationTypeStaffInvite),
+		"Course Staff Invitation",
+		fmt.Sprintf("You have been invited to join %s as %s", e.CourseTitle, role),
+	)
+}
+
+// onCourseCompleted congratulates the student
+func (n *NotificationSubscriber) onCourseCompleted(ctx context.Context, e domain.CourseCompleted) error {
+	course, err := n.courseRepo.FindByID(int64(e.CourseID))
+	if err != nil {
+		return fmt.Errorf("load course %d: %w", e.CourseID, err)
+	}
+
+	return n.notifClient.SendNotification(ctx,
+		int64(e.UserID),
+		string(domain.NotificationTypeCompleted),
+		"Course Completed",
+		fmt.Sprintf("Congratulations! You have completed the course: %s", course.Title),
+	)
+}
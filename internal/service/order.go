@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/payment"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCourseIsFree        = errors.New("course is free; enroll directly")
+	ErrAlreadyPurchased    = errors.New("course has already been purchased")
+	ErrFakePaymentDisabled = errors.New("fake payments are only available with the fake provider")
+)
+
+// CheckoutRequest optionally applies a coupon to a checkout
+type CheckoutRequest struct {
+	CouponCode string `json:"coupon_code" binding:"max=40"`
+}
+
+// OrderOptions configures checkout
+type OrderOptions struct {
+	// SuccessURL and CancelURL are where the provider sends the customer
+	// after checkout; {ORDER_ID} is replaced with the order ID
+	SuccessURL string
+	CancelURL  string
+	// CheckoutTTL is how long a pending order's checkout is reused and
+	// accepts payment
+	CheckoutTTL time.Duration
+}
+
+// expiryGrace lets payments made just before a checkout expired arrive
+// before the order is cancelled
+const expiryGrace = 10 * time.Minute
+
+// couponProvider names orders that a coupon paid for in full
+const couponProvider = "coupon"
+
+// OrderService sells courses through a payment provider. Checkout creates
+// a pending order and a checkout at the provider; the provider's webhook
+// marks the order paid or failed, and a paid order enrolls the customer.
+// Orders a coupon pays for in full are paid at checkout. The provider's
+// subscription events arrive on the same webhook and are passed on to the
+// subscription service.
+type OrderService struct {
+	repo          repository.OrderRepository
+	courseRepo    repository.CourseRepository
+	userRepo      repository.UserRepository
+	enrollments   *EnrollmentService
+	coupons       *CouponService
+	subscriptions *SubscriptionService
+	provider      payment.Provider
+	events        *eventbus.Bus
+	opts          OrderOptions
+}
+
+// NewOrderService creates a new order service
+func NewOrderService(
+	repo repository.OrderRepository,
+	courseRepo repository.CourseRepository,
+	userRepo repository.UserRepository,
+	enrollments *EnrollmentService,
+	coupons *CouponService,
+	subscriptions *SubscriptionService,
+	provider payment.Provider,
+	events *eventbus.Bus,
+	opts OrderOptions,
+) *OrderService {
+	return &OrderService{
+		repo:          repo,
+		courseRepo:    courseRepo,
+		userRepo:      userRepo,
+		enrollments:   enrollments,
+		coupons:       coupons,
+		subscriptions: subscriptions,
+		provider:      provider,
+		events:        events,
+		opts:          opts,
+	}
+}
+
+// Quote returns the price of a course for a customer with a coupon applied
+func (s *OrderService) Quote(userID, courseID uint, couponCode string) (*CouponQuote, error) {
+	course, err := s.checkPurchasable(userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return s.coupons.Quote(userID, course, couponCode)
+}
+
+// Checkout starts paying for a course, optionally with a coupon. A recent
+// pending order for the same price is returned instead of creating another
+// one.
+func (s *OrderService) Checkout(ctx context.Context, userID, courseID uint, couponCode string) (*domain.Order, error) {
+	course, err := s.checkPurchasable(userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &CouponQuote{Subtotal: course.Price, Amount: course.Price, Currency: course.Currency}
+	if couponCode != "" {
+		if quote, err = s.coupons.Quote(userID, course, couponCode); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := s.repo.FindPending(userID, courseID, time.Now().Add(-s.opts.CheckoutTTL))
+	switch {
+	case err == nil && order.Provider == s.provider.Name() &&
+		order.Amount == quote.Amount && order.Currency == quote.Currency && order.CouponCode == quote.Code:
+		return order, nil
+	case err == nil:
+		// The price or coupon changed since; the old checkout is abandoned
+		if err := s.cancel(ctx, order, "replaced by a new checkout"); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, repository.ErrOrderNotFound):
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	order = &domain.Order{
+		UserID:      userID,
+		CourseID:    courseID,
+		CourseTitle: course.Title,
+		Subtotal:    quote.Subtotal,
+		Discount:    quote.Discount,
+		Amount:      quote.Amount,
+		Currency:    quote.Currency,
+		CouponCode:  quote.Code,
+		Status:      domain.OrderStatusPending,
+		Provider:    s.provider.Name(),
+	}
+	if quote.Coupon != nil {
+		order.CouponID = &quote.Coupon.ID
+	}
+	if order.Amount == 0 {
+		order.Provider = couponProvider
+	}
+	if err := s.repo.Create(order); err != nil {
+		return nil, err
+	}
+	if quote.Coupon != nil {
+		if err := s.coupons.Reserve(order, quote.Coupon); err != nil {
+			if cancelErr := s.cancel(ctx, order, "coupon could not be reserved"); cancelErr != nil {
+				log.Printf("failed to cancel order %d: %v", order.ID, cancelErr)
+			}
+			return nil, err
+		}
+	}
+
+	if order.Amount == 0 {
+		if err := s.markPaid(ctx, order, ""); err != nil {
+			return nil, err
+		}
+		return s.repo.FindByID(order.ID)
+	}
+
+	checkout, err := s.provider.CreateCheckout(ctx, payment.CheckoutRequest{
+		OrderID:       order.ID,
+		Amount:        order.Amount,
+		Currency:      order.Currency,
+		Description:   course.Title,
+		CustomerEmail: user.Email,
+		SuccessURL:    orderURL(s.opts.SuccessURL, order.ID),
+		CancelURL:     orderURL(s.opts.CancelURL, order.ID),
+		ExpiresAt:     order.CreatedAt.Add(s.opts.CheckoutTTL),
+	})
+	if err != nil {
+		if _, failErr := s.repo.MarkFailed(order.ID, "checkout could not be created"); failErr != nil {
+			log.Printf("failed to mark order %d failed: %v", order.ID, failErr)
+		} else if releaseErr := s.coupons.Release(order); releaseErr != nil {
+			log.Printf("failed to release coupon of order %d: %v", order.ID, releaseErr)
+		}
+		return nil, fmt.Errorf("create checkout: %w", err)
+	}
+
+	order.ProviderRef = checkout.Reference
+	order.CheckoutURL = checkout.URL
+	if err := s.repo.Update(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// HandleWebhook verifies and applies a payment provider event. Events are
+// applied once; redeliveries are acknowledged without effect.
+func (s *OrderService) HandleWebhook(ctx context.Context, payload []byte, header http.Header) error {
+	event, err := s.provider.ParseWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+	if event.Type == payment.EventIgnored {
+		return nil
+	}
+
+	processed, err := s.repo.EventProcessed(s.provider.Name(), event.ID)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+	if event.Subscription != nil {
+		return s.handleSubscriptionEvent(ctx, event)
+	}
+
+	order, err := s.findOrder(event)
+	if errors.Is(err, repository.ErrOrderNotFound) {
+		// Checkouts created outside this platform share the account
+		log.Printf("Ignoring %s payment event %s for unknown checkout %s", s.provider.Name(), event.ID, event.Reference)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case payment.EventPaymentSucceeded:
+		if err := s.markPaid(ctx, order, event.PaymentReference); err != nil {
+			return err
+		}
+	case payment.EventPaymentFailed:
+		failed, err := s.repo.MarkFailed(order.ID, event.FailureReason)
+		if err != nil {
+			return err
+		}
+		if failed {
+			if err := s.coupons.Release(order); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.repo.RecordEvent(&domain.PaymentEvent{
+		Provider: s.provider.Name(),
+		EventID:  event.ID,
+		Type:     string(event.Type),
+		OrderID:  &order.ID,
+	})
+}
+
+func (s *OrderService) handleSubscriptionEvent(ctx context.Context, event *payment.Event) error {
+	sub, err := s.subscriptions.HandleEvent(ctx, event)
+	if errors.Is(err, repository.ErrSubscriptionNotFound) {
+		log.Printf("Ignoring %s payment event %s for unknown subscription %s", s.provider.Name(), event.ID, event.Reference)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RecordEvent(&domain.PaymentEvent{
+		Provider:       s.provider.Name(),
+		EventID:        event.ID,
+		Type:           string(event.Type),
+		SubscriptionID: &sub.ID,
+	})
+}
+
+// checkPurchasable checks that the user may buy the course and returns it
+func (s *OrderService) checkPurchasable(userID, courseID uint) (*domain.Course, error) {
+	course, err := s.enrollments.CheckEligible(userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if !course.RequiresPayment() {
+		return nil, ErrCourseIsFree
+	}
+	paid, err := s.repo.HasPaid(userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if paid {
+		return nil, ErrAlreadyPurchased
+	}
+	enrolled, err := s.enrollments.IsEnrolled(userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if enrolled {
+		return nil, repository.ErrAlreadyEnrolled
+	}
+	return course, nil
+}
+
+func (s *OrderService) findOrder(event *payment.Event) (*domain.Order, error) {
+	order, err := s.repo.FindByProviderRef(s.provider.Name(), event.Reference)
+	if errors.Is(err, repository.ErrOrderNotFound) && event.OrderID != 0 {
+		// The checkout reference is saved after the checkout is created,
+		// so a fast webhook can arrive first
+		order, err = s.repo.FindByID(event.OrderID)
+		if err == nil && order.Provider != s.provider.Name() {
+			return nil, repository.ErrOrderNotFound
+		}
+	}
+	return order, err
+}
+
+// markPaid records the payment and enrolls the customer. Enrollment is
+// retried on redelivery if it failed after the order was marked paid.
+// Payments for cancelled orders and second payments for a course are
+// refunded.
+func (s *OrderService) markPaid(ctx context.Context, order *domain.Order, paymentRef string) error {
+	if order.Status == domain.OrderStatusRefunded {
+		// A late redelivery must not restore access to a refunded course
+		log.Printf("Ignoring payment for refunded order %d", order.ID)
+		return nil
+	}
+
+	if order.Status == domain.OrderStatusCancelled {
+		// The checkout was replaced or expired while the customer paid
+		return s.refundUnwanted(ctx, order, paymentRef, "paid after the checkout was cancelled")
+	}
+
+	now := time.Now()
+	changed, err := s.repo.MarkPaid(order.ID, paymentRef, now)
+	if err != nil {
+		return err
+	}
+	if !changed && order.Status != domain.OrderStatusPaid {
+		// Either a concurrent delivery settled the order, or the customer
+		// paid for the course with another order
+		current, err := s.repo.FindByID(order.ID)
+		if err != nil {
+			return err
+		}
+		switch current.Status {
+		case domain.OrderStatusPaid:
+		case domain.OrderStatusRefunded:
+			return nil
+		case domain.OrderStatusCancelled:
+			return s.refundUnwanted(ctx, current, paymentRef, "paid after the checkout was cancelled")
+		default:
+			return s.refundUnwanted(ctx, current, paymentRef, "course was already paid with another order")
+		}
+	}
+
+	_, err = s.enrollments.EnrollPurchased(order.UserID, order.CourseID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// The course was purged; the payment is still recorded
+		log.Printf("Order %d paid for missing course %d", order.ID, order.CourseID)
+	case err != nil && !errors.Is(err, repository.ErrAlreadyEnrolled):
+		return fmt.Errorf("enroll after payment for order %d: %w", order.ID, err)
+	}
+
+	if changed {
+		// Confirms the use reserved at checkout. A late payment for an
+		// order whose reservation was released still counts, as the
+		// customer paid the discounted price.
+		if err := s.coupons.Redeem(order); err != nil {
+			log.Printf("failed to record coupon redemption for order %d: %v", order.ID, err)
+		}
+		log.Printf("Order %d paid by user %d for course %d", order.ID, order.UserID, order.CourseID)
+		s.events.Publish(ctx, domain.OrderPaid{
+			OrderID:    order.ID,
+			UserID:     order.UserID,
+			CourseID:   order.CourseID,
+			Amount:     order.Amount,
+			Currency:   order.Currency,
+			OccurredAt: now,
+		})
+	}
+	return nil
+}
+
+// refundUnwanted refunds a payment for an order that must not be paid: its
+// checkout was cancelled, or the customer already paid for the course with
+// another order. The event is acknowledged afterwards, as retrying it could
+// never pay the order.
+func (s *OrderService) refundUnwanted(ctx context.Context, order *domain.Order, paymentRef, reason string) error {
+	if order.Amount > 0 {
+		if paymentRef == "" {
+			log.Printf("Order %d needs a refund (%s) but has no payment reference; refund it at %s", order.ID, reason, order.Provider)
+			return nil
+		}
+		_, err := s.provider.Refund(ctx, payment.RefundRequest{
+			OrderID:          order.ID,
+			PaymentReference: paymentRef,
+			Amount:           order.Amount,
+			Currency:         order.Currency,
+			IdempotencyKey:   fmt.Sprintf("order-%d-unwanted", order.ID),
+		})
+		if err != nil {
+			return fmt.Errorf("refund unwanted payment for order %d: %w", order.ID, err)
+		}
+	}
+
+	returned, err := s.repo.MarkReturned(order.ID, paymentRef, reason, time.Now())
+	if err != nil || !returned {
+		return err
+	}
+	log.Printf("Refunded payment for order %d: %s", order.ID, reason)
+	return s.coupons.Release(order)
+}
+
+// cancel abandons a pending order, releases its coupon and expires its
+// checkout at the provider. A payment that still gets through is refunded
+// by markPaid.
+func (s *OrderService) cancel(ctx context.Context, order *domain.Order, reason string) error {
+	cancelled, err := s.repo.MarkCancelled(order.ID, reason)
+	if err != nil || !cancelled {
+		return err
+	}
+	order.Status = domain.OrderStatusCancelled
+	order.FailureReason = reason
+
+	if order.ProviderRef != "" && order.Provider == s.provider.Name() {
+		if err := s.provider.ExpireCheckout(ctx, order.ProviderRef); err != nil {
+			log.Printf("failed to expire checkout of order %d: %v", order.ID, err)
+		}
+	}
+	return s.coupons.Release(order)
+}
+
+// Run cancels pending orders whose checkout expired, releasing their
+// coupons, until ctx is done
+func (s *OrderService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			orders, err := s.repo.ListExpired(time.Now().Add(-s.opts.CheckoutTTL-expiryGrace), 500)
+			if err != nil {
+				log.Printf("failed to load expired orders: %v", err)
+				continue
+			}
+			for i := range orders {
+				if err := s.cancel(ctx, &orders[i], "checkout expired"); err != nil {
+					log.Printf("failed to cancel expired order %d: %v", orders[i].ID, err)
+				}
+			}
+		}
+	}
+}
+
+// FakeComplete pays or abandons a checkout of the fake provider by sending
+// the webhook it would send, for local development
+func (s *OrderService) FakeComplete(ctx context.Context, reference string, succeeded bool) (*domain.Order, error) {
+	fake, ok := s.provider.(*payment.Fake)
+	if !ok {
+		return nil, ErrFakePaymentDisabled
+	}
+	order, err := s.repo.FindByProviderRef(fake.Name(), reference)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, header, err := fake.Complete(reference, succeeded)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.HandleWebhook(ctx, payload, header); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(order.ID)
+}
+
+// GetForUser returns one of the user's orders
+func (s *OrderService) GetForUser(userID, orderID uint) (*domain.Order, error) {
+	order, err := s.repo.FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, repository.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// ListMine returns a user's orders, newest first
+func (s *OrderService) ListMine(userID uint, page, limit int) ([]domain.Order, int64, error) {
+	return s.repo.List(repository.OrderFilter{UserID: userID}, page, limit)
+}
+
+// List returns orders matching the filter, newest first
+func (s *OrderService) List(filter repository.OrderFilter, page, limit int) ([]domain.Order, int64, error) {
+	return s.repo.List(filter, page, limit)
+}
+
+func orderURL(template string, orderID uint) string {
+	return strings.ReplaceAll(template, "{ORDER_ID}", strconv.FormatUint(uint64(orderID), 10))
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/payment"
+)
+
+// fakeOrderRepo keeps orders in memory and applies the same conditions as
+// the SQL of the real repository
+type fakeOrderRepo struct {
+	repository.OrderRepository
+	orders map[uint]*domain.Order
+}
+
+func (r *fakeOrderRepo) FindByID(id uint) (*domain.Order, error) {
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, repository.ErrOrderNotFound
+	}
+	copied := *o
+	return &copied, nil
+}
+
+func (r *fakeOrderRepo) MarkPaid(id uint, paymentRef string, at time.Time) (bool, error) {
+	o := r.orders[id]
+	if o.Status != domain.OrderStatusPending && o.Status != domain.OrderStatusFailed {
+		return false, nil
+	}
+	for _, other := range r.orders {
+		if other.UserID == o.UserID && other.CourseID == o.CourseID && other.Status == domain.OrderStatusPaid {
+			return false, nil
+		}
+	}
+	o.Status, o.PaymentRef, o.PaidAt = domain.OrderStatusPaid, paymentRef, &at
+	return true, nil
+}
+
+func (r *fakeOrderRepo) MarkReturned(id uint, paymentRef, reason string, at time.Time) (bool, error) {
+	o := r.orders[id]
+	if o.Status == domain.OrderStatusPaid || o.Status == domain.OrderStatusRefunded {
+		return false, nil
+	}
+	o.Status, o.PaymentRef, o.FailureReason, o.RefundedAt = domain.OrderStatusRefunded, paymentRef, reason, &at
+	return true, nil
+}
+
+func (r *fakeOrderRepo) MarkCancelled(id uint, reason string) (bool, error) {
+	o := r.orders[id]
+	if o.Status != domain.OrderStatusPending {
+		return false, nil
+	}
+	o.Status, o.FailureReason = domain.OrderStatusCancelled, reason
+	return true, nil
+}
+
+// recordingProvider records refunds and expired checkouts
+type recordingProvider struct {
+	payment.Provider
+	refunds []payment.RefundRequest
+	expired []string
+}
+
+func (p *recordingProvider) Name() string { return "stripe" }
+
+func (p *recordingProvider) Refund(ctx context.Context, req payment.RefundRequest) (*payment.Refund, error) {
+	p.refunds = append(p.refunds, req)
+	return &payment.Refund{Reference: "re_test"}, nil
+}
+
+func (p *recordingProvider) ExpireCheckout(ctx context.Context, reference string) error {
+	p.expired = append(p.expired, reference)
+	return nil
+}
+
+func TestOrderMarkPaidRefundsUnwantedPayments(t *testing.T) {
+	tests := []struct {
+		name   string
+		orders []domain.Order
+	}{
+		{
+			name: "cancelled checkout",
+			orders: []domain.Order{
+				{ID: 1, UserID: 7, CourseID: 3, Amount: 4900, Currency: "usd", Status: domain.OrderStatusCancelled},
+			},
+		},
+		{
+			name: "course already paid",
+			orders: []domain.Order{
+				{ID: 1, UserID: 7, CourseID: 3, Amount: 4900, Currency: "usd", Status: domain.OrderStatusPending},
+				{ID: 2, UserID: 7, CourseID: 3, Amount: 4900, Currency: "usd", Status: domain.OrderStatusPaid},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeOrderRepo{orders: make(map[uint]*domain.Order)}
+			for i := range tt.orders {
+				repo.orders[tt.orders[i].ID] = &tt.orders[i]
+			}
+			provider := &recordingProvider{}
+			s := &OrderService{repo: repo, coupons: &CouponService{}, provider: provider}
+
+			// The provider redelivers the event; the payment is refunded once
+			for range 2 {
+				order, _ := repo.FindByID(1)
+				if err := s.markPaid(context.Background(), order, "pi_late"); err != nil {
+					t.Fatalf("markPaid: %v", err)
+				}
+			}
+
+			if len(provider.refunds) != 1 {
+				t.Fatalf("refunds = %d, want 1", len(provider.refunds))
+			}
+			if r := provider.refunds[0]; r.PaymentReference != "pi_late" || r.Amount != 4900 || r.OrderID != 1 {
+				t.Errorf("refund = %+v", r)
+			}
+			if got := repo.orders[1].Status; got != domain.OrderStatusRefunded {
+				t.Errorf("status = %s, want %s", got, domain.OrderStatusRefunded)
+			}
+		})
+	}
+}
+
+func TestOrderCancelExpiresCheckout(t *testing.T) {
+	repo := &fakeOrderRepo{orders: map[uint]*domain.Order{
+		1: {ID: 1, Status: domain.OrderStatusPending, Provider: "stripe", ProviderRef: "cs_1"},
+	}}
+	provider := &recordingProvider{}
+	s := &OrderService{repo: repo, coupons: &CouponService{}, provider: provider}
+
+	order, _ := repo.FindByID(1)
+	if err := s.cancel(context.Background(), order, "checkout expired"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if len(provider.expired) != 1 || provider.expired[0] != "cs_1" {
+		t.Errorf("expired = %v, want [cs_1]", provider.expired)
+	}
+	if got := repo.orders[1].Status; got != domain.OrderStatusCancelled {
+		t.Errorf("status = %s, want %s", got, domain.OrderStatusCancelled)
+	}
+}
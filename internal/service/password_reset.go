@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/hash"
+	"elearning/pkg/mailer"
+)
+
+var ErrInvalidResetToken = errors.New("reset link is invalid or has expired")
+
+// resetTokenSize is the number of random bytes in a reset token
+const resetTokenSize = 32
+
+// resetQueueSize caps the reset requests waiting for the worker; requests
+// beyond it are dropped
+const resetQueueSize = 256
+
+// PasswordResetOptions configures the password reset flow
+type PasswordResetOptions struct {
+	TokenTTL        time.Duration
+	RequestLimit    int
+	RequestInterval time.Duration
+	AppBaseURL      string
+}
+
+// PasswordResetService handles forgotten passwords
+type PasswordResetService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.UserTokenRepository
+	sessions  *SessionService
+	apiKeys   *APIKeyService
+	mailer    mailer.Mailer
+	guard     *LoginGuard
+	opts      PasswordResetOptions
+	// requests holds the addresses queued by Enqueue for Run
+	requests chan string
+}
+
+// NewPasswordResetService creates a new password reset service
+func NewPasswordResetService(
+	userRepo repository.UserRepository,
+	tokenRepo repository.UserTokenRepository,
+	sessions *SessionService,
+	apiKeys *APIKeyService,
+	mailer mailer.Mailer,
+	guard *LoginGuard,
+	opts PasswordResetOptions,
+) *PasswordResetService {
+	return &PasswordResetService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		sessions:  sessions,
+		apiKeys:   apiKeys,
+		mailer:    mailer,
+		guard:     guard,
+		opts:      opts,
+		requests:  make(chan string, resetQueueSize),
+	}
+}
+
+// Enqueue queues a reset request for Run without blocking, so the caller's
+// response time does not reveal whether the address belongs to an account.
+// When the queue is full the request is dropped.
+func (s *PasswordResetService) Enqueue(email string) {
+	select {
+	case s.requests <- email:
+	default:
+		log.Printf("Password reset queue full, dropping request")
+	}
+}
+
+// Run handles queued reset requests one at a time until ctx is done
+func (s *PasswordResetService) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case email := <-s.requests:
+			reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if err := s.RequestReset(reqCtx, email); err != nil {
+				log.Printf("failed to process password reset request: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// ForgotPasswordRequest represents a password reset request
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents a new password set with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// RequestReset emails a password reset link. Unknown addresses and
+// throttled requests are silently ignored so the response never reveals
+// whether an account exists.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	throttled, err := tokenRequestThrottled(s.tokenRepo, user.ID, domain.TokenPurposePasswordReset, s.opts.RequestInterval, s.opts.RequestLimit)
+	if err != nil {
+		return err
+	}
+	if throttled {
+		log.Printf("Password reset request throttled for user %d", user.ID)
+		return nil
+	}
+
+	link, err := s.issueLink(user.ID)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %s. If you did not ask for this, you can ignore this email; your password will not change.\n",
+			user.Name, link, s.opts.TokenTTL,
+		),
+	})
+}
+
+// SendWelcome emails a newly created account a link to choose its first
+// password
+func (s *PasswordResetService) SendWelcome(ctx context.Context, user *domain.User) error {
+	link, err := s.issueLink(user.ID)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Welcome! Set up your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nAn account has been created for you. Open the link below to choose your password:\n\n%s\n\nThe link expires in %s. Afterwards you can request a new one with \"Forgot password\" on the login page.\n",
+			user.Name, link, s.opts.TokenTTL,
+		),
+	})
+}
+
+// issueLink creates a reset token for the user and returns the link that
+// redeems it. Only the most recent link works.
+func (s *PasswordResetService) issueLink(userID uint) (string, error) {
+	raw, hashed, err := hash.GenerateToken(resetTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.tokenRepo.InvalidateForUser(userID, domain.TokenPurposePasswordReset); err != nil {
+		return "", err
+	}
+
+	if err := s.tokenRepo.Create(&domain.UserToken{
+		UserID:    userID,
+		Purpose:   domain.TokenPurposePasswordReset,
+		TokenHash: hashed,
+		ExpiresAt: time.Now().Add(s.opts.TokenTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/reset-password?token=%s", s.opts.AppBaseURL, url.QueryEscape(raw)), nil
+}
+
+// ResetPassword sets a new password using a reset token, signs the user
+// out of every session and revokes their API keys
+func (s *PasswordResetService) ResetPassword(ctx context.Context, rawToken, newPassword string) error {
+	if len(newPassword) < 6 {
+		return ErrPasswordTooShort
+	}
+
+	resetToken, err := s.tokenRepo.Consume(domain.TokenPurposePasswordReset, hash.HashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenInvalid) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(resetToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := hash.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	if err := s.sessions.RevokeAll(user.ID); err != nil {
+		return err
+	}
+	if err := s.apiKeys.RevokeAll(user.ID); err != nil {
+		return err
+	}
+	if err := s.tokenRepo.InvalidateForUser(user.ID, domain.TokenPurposePasswordReset); err != nil {
+		log.Printf("failed to invalidate reset tokens for user %d: %v", user.ID, err)
+	}
+
+	// Proving control of the mailbox lifts a lockout on the account
+	if err := s.guard.ClearAccount(user.Email); err != nil {
+		log.Printf("failed to clear login lockout for user %d: %v", user.ID, err)
+	}
+
+	// The reset link was delivered to the address, which proves ownership
+	if !user.IsVerified() {
+		if err := s.userRepo.MarkVerified(user.ID, time.Now()); err != nil {
+			log.Printf("failed to mark user %d verified: %v", user.ID, err)
+		}
+	}
+
+	log.Printf("User %d reset their password", user.ID)
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour password was just reset, all your sessions were signed out and your API keys were revoked. If this wasn't you, contact support immediately.\n",
+			user.Name,
+		),
+	}); err != nil {
+		log.Printf("failed to send password change notice to user %d: %v", user.ID, err)
+	}
+
+	return nil
+}
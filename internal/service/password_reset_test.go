@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/hash"
+	"elearning/pkg/mailer"
+	"elearning/pkg/token"
+)
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[uint]*domain.User
+}
+
+func (r *fakeUserRepo) FindByID(id uint) (*domain.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) UpdatePassword(id uint, hashedPassword string) error {
+	r.users[id].Password = hashedPassword
+	return nil
+}
+
+type fakeUserTokenRepo struct {
+	repository.UserTokenRepository
+	tokens map[string]*domain.UserToken
+}
+
+func (r *fakeUserTokenRepo) Consume(purpose domain.TokenPurpose, tokenHash string) (*domain.UserToken, error) {
+	t, ok := r.tokens[tokenHash]
+	if !ok || t.Purpose != purpose || t.UsedAt != nil {
+		return nil, repository.ErrTokenInvalid
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return t, nil
+}
+
+func (r *fakeUserTokenRepo) InvalidateForUser(userID uint, purpose domain.TokenPurpose) error {
+	return nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	revoked []uint
+}
+
+func (r *fakeSessionRepo) RevokeAll(userID uint, at time.Time) ([]string, error) {
+	r.revoked = append(r.revoked, userID)
+	return nil, nil
+}
+
+type fakeLoginThrottleRepo struct {
+	repository.LoginThrottleRepository
+}
+
+func (r *fakeLoginThrottleRepo) Reset(scope domain.LoginThrottleScope, key string) error {
+	return nil
+}
+
+type fakeAPIKeyRepo struct {
+	repository.APIKeyRepository
+	keys []domain.APIKey
+}
+
+func (r *fakeAPIKeyRepo) RevokeAllForUser(userID uint, at time.Time) (int64, error) {
+	var revoked int64
+	for i := range r.keys {
+		if r.keys[i].UserID == userID && r.keys[i].RevokedAt == nil {
+			r.keys[i].RevokedAt = &at
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func TestResetPasswordRevokesAPIKeys(t *testing.T) {
+	verified := time.Now()
+	users := &fakeUserRepo{users: map[uint]*domain.User{
+		7: {ID: 7, Email: "learner@example.com", Name: "Learner", VerifiedAt: &verified},
+	}}
+	tokens := &fakeUserTokenRepo{tokens: map[string]*domain.UserToken{
+		hash.HashToken("reset-token"): {UserID: 7, Purpose: domain.TokenPurposePasswordReset, ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	sessionRepo := &fakeSessionRepo{}
+	apiKeyRepo := &fakeAPIKeyRepo{keys: []domain.APIKey{
+		{ID: 1, UserID: 7},
+		{ID: 2, UserID: 8},
+	}}
+
+	s := NewPasswordResetService(
+		users,
+		tokens,
+		NewSessionService(sessionRepo, token.NewInMemoryBlacklist(time.Hour), time.Hour),
+		NewAPIKeyService(apiKeyRepo, users, APIKeyOptions{}),
+		mailer.NewLogMailer(zap.NewNop()),
+		&LoginGuard{repo: &fakeLoginThrottleRepo{}},
+		PasswordResetOptions{},
+	)
+
+	if err := s.ResetPassword(context.Background(), "reset-token", "new-password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	if len(sessionRepo.revoked) != 1 || sessionRepo.revoked[0] != 7 {
+		t.Errorf("sessions revoked for %v, want [7]", sessionRepo.revoked)
+	}
+	if apiKeyRepo.keys[0].RevokedAt == nil {
+		t.Error("the user's api key was not revoked")
+	}
+	if apiKeyRepo.keys[1].RevokedAt != nil {
+		t.Error("another user's api key was revoked")
+	}
+}
@@ -0,0 +1,481 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/grpcclient"
+	"elearning/pkg/hash"
+)
+
+var (
+	ErrExportInProgress  = errors.New("a data export is already being prepared")
+	ErrExportTooSoon     = errors.New("a data export was requested recently; try again later")
+	ErrExportNotReady    = errors.New("data export is not ready for download")
+	ErrErasureInProgress = errors.New("an erasure request is already open")
+	ErrErasureNotPending = errors.New("erasure request has already been reviewed")
+	ErrErasureSelfReview = errors.New("admins cannot review their own erasure request")
+	ErrErasureNotOpen    = errors.New("no open erasure request")
+)
+
+const (
+	erasedUserName = "Deleted user"
+	// exportBatchSize caps the exports built per worker cycle
+	exportBatchSize = 5
+)
+
+// PrivacyOptions configures data exports and account erasure
+type PrivacyOptions struct {
+	// ExportDir holds export archives; it must not be publicly served
+	ExportDir string
+	// ExportTTL is how long an archive can be downloaded
+	ExportTTL time.Duration
+	// ExportInterval is the minimum time between export requests
+	ExportInterval time.Duration
+	// CoolingOff is how long after the request an erasure is carried out
+	// at the earliest; the user can cancel until then
+	CoolingOff time.Duration
+}
+
+// ErasureRequestBody represents a user's request to erase their account
+type ErasureRequestBody struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ErasureReviewRequest represents an admin decision on an erasure request
+type ErasureReviewRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// PrivacyService builds personal data exports and carries out approved
+// account erasures
+type PrivacyService struct {
+	repo          repository.PrivacyRepository
+	sessions      *SessionService
+	subscriptions *SubscriptionService
+	avatars       AvatarStore
+	notifClient   *grpcclient.NotificationClient
+	opts          PrivacyOptions
+}
+
+// NewPrivacyService creates a new privacy service. avatars is nil when
+// avatars are stored locally.
+func NewPrivacyService(
+	repo repository.PrivacyRepository,
+	sessions *SessionService,
+	subscriptions *SubscriptionService,
+	avatars AvatarStore,
+	notifClient *grpcclient.NotificationClient,
+	opts PrivacyOptions,
+) *PrivacyService {
+	return &PrivacyService{
+		repo:          repo,
+		sessions:      sessions,
+		subscriptions: subscriptions,
+		avatars:       avatars,
+		notifClient:   notifClient,
+		opts:          opts,
+	}
+}
+
+// RequestExport queues an export of everything stored about the user
+func (s *PrivacyService) RequestExport(userID uint) (*domain.DataExport, error) {
+	latest, err := s.repo.LatestExport(userID)
+	switch {
+	case errors.Is(err, repository.ErrDataExportNotFound):
+	case err != nil:
+		return nil, err
+	case latest.Status == domain.DataExportPending || latest.Status == domain.DataExportProcessing:
+		return nil, ErrExportInProgress
+	case time.Since(latest.CreatedAt) < s.opts.ExportInterval:
+		return nil, ErrExportTooSoon
+	}
+
+	export := &domain.DataExport{UserID: userID, Status: domain.DataExportPending}
+	if err := s.repo.CreateExport(export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// ListExports returns the user's exports, newest first
+func (s *PrivacyService) ListExports(userID uint) ([]domain.DataExport, error) {
+	return s.repo.ListExports(userID)
+}
+
+// ExportFile returns the archive path of one of the user's ready exports
+func (s *PrivacyService) ExportFile(userID, exportID uint) (string, error) {
+	export, err := s.repo.FindExport(exportID)
+	if err != nil {
+		return "", err
+	}
+	if export.UserID != userID {
+		return "", repository.ErrDataExportNotFound
+	}
+	if export.Status != domain.DataExportReady || (export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt)) {
+		return "", ErrExportNotReady
+	}
+	return export.FilePath, nil
+}
+
+// RequestErasure opens an erasure request. It is carried out once an admin
+// has approved it and the cooling-off period has passed.
+func (s *PrivacyService) RequestErasure(userID uint, req ErasureRequestBody) (*domain.ErasureRequest, error) {
+	if _, err := s.repo.OpenErasure(userID); err == nil {
+		return nil, ErrErasureInProgress
+	} else if !errors.Is(err, repository.ErrErasureNotFound) {
+		return nil, err
+	}
+
+	request := &domain.ErasureRequest{
+		UserID:          userID,
+		Reason:          req.Reason,
+		Status:          domain.ErasurePending,
+		CoolingOffUntil: time.Now().Add(s.opts.CoolingOff),
+	}
+	if err := s.repo.CreateErasure(request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// GetErasure returns the user's open erasure request
+func (s *PrivacyService) GetErasure(userID uint) (*domain.ErasureRequest, error) {
+	request, err := s.repo.OpenErasure(userID)
+	if errors.Is(err, repository.ErrErasureNotFound) {
+		return nil, ErrErasureNotOpen
+	}
+	return request, err
+}
+
+// CancelErasure withdraws the user's open erasure request
+func (s *PrivacyService) CancelErasure(userID uint) error {
+	request, err := s.GetErasure(userID)
+	if err != nil {
+		return err
+	}
+	request.Status = domain.ErasureCancelled
+	return s.repo.UpdateErasure(request)
+}
+
+// ListErasures returns erasure requests for review
+func (s *PrivacyService) ListErasures(status domain.ErasureStatus, page, limit int) ([]domain.ErasureRequest, int64, error) {
+	return s.repo.ListErasures(status, page, limit)
+}
+
+// ApproveErasure schedules the erasure for the end of the cooling-off
+// period
+func (s *PrivacyService) ApproveErasure(id, adminID uint, req ErasureReviewRequest) (*domain.ErasureRequest, error) {
+	return s.review(id, adminID, domain.ErasureApproved, req.Note)
+}
+
+// RejectErasure closes the request without erasing the account
+func (s *PrivacyService) RejectErasure(id, adminID uint, req ErasureReviewRequest) (*domain.ErasureRequest, error) {
+	return s.review(id, adminID, domain.ErasureRejected, req.Note)
+}
+
+func (s *PrivacyService) review(id, adminID uint, status domain.ErasureStatus, note string) (*domain.ErasureRequest, error) {
+	request, err := s.repo.FindErasure(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != domain.ErasurePending {
+		return nil, ErrErasureNotPending
+	}
+	if request.UserID == adminID {
+		return nil, ErrErasureSelfReview
+	}
+
+	now := time.Now()
+	request.Status = status
+	request.ReviewedBy = &adminID
+	request.ReviewedAt = &now
+	request.ReviewNote = note
+	if err := s.repo.UpdateErasure(request); err != nil {
+		return nil, err
+	}
+
+	message := "Your account erasure request was rejected."
+	if status == domain.ErasureApproved {
+		message = fmt.Sprintf("Your account erasure request was approved. Your account will be erased after %s unless you cancel.",
+			request.CoolingOffUntil.Format("2006-01-02 15:04 MST"))
+	}
+	s.notify(request.UserID, "Account erasure", message)
+	return request, nil
+}
+
+// Run builds pending exports, removes expired ones and carries out due
+// erasures every interval until ctx is cancelled
+func (s *PrivacyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processExports()
+			s.expireExports()
+			s.processErasures()
+		}
+	}
+}
+
+func (s *PrivacyService) processExports() {
+	exports, err := s.repo.PendingExports(exportBatchSize)
+	if err != nil {
+		log.Printf("failed to load pending data exports: %v", err)
+		return
+	}
+
+	for i := range exports {
+		export := &exports[i]
+		export.Status = domain.DataExportProcessing
+		if err := s.repo.UpdateExport(export); err != nil {
+			log.Printf("failed to start data export %d: %v", export.ID, err)
+			continue
+		}
+
+		now := time.Now()
+		size, path, err := s.buildExport(export.UserID)
+		if err != nil {
+			log.Printf("failed to build data export %d: %v", export.ID, err)
+			export.Status = domain.DataExportFailed
+			export.Error = "failed to build export"
+		} else {
+			expiresAt := now.Add(s.opts.ExportTTL)
+			export.Status = domain.DataExportReady
+			export.FilePath = path
+			export.Size = size
+			export.ExpiresAt = &expiresAt
+		}
+		export.CompletedAt = &now
+		if err := s.repo.UpdateExport(export); err != nil {
+			log.Printf("failed to save data export %d: %v", export.ID, err)
+			continue
+		}
+
+		if export.Status == domain.DataExportReady {
+			s.notify(export.UserID, "Your data export is ready", "Download it from your account settings within "+s.opts.ExportTTL.String()+".")
+		}
+	}
+}
+
+// buildExport writes data.json and the user's uploaded files to a zip
+// archive and returns its size and path
+func (s *PrivacyService) buildExport(userID uint) (int64, string, error) {
+	data, err := s.repo.CollectPersonalData(userID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if err := os.MkdirAll(s.opts.ExportDir, 0o700); err != nil {
+		return 0, "", err
+	}
+	suffix, err := randomHex(8)
+	if err != nil {
+		return 0, "", err
+	}
+	path := filepath.Join(s.opts.ExportDir, fmt.Sprintf("user-%d-%s.zip", userID, suffix))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := writeExportArchive(f, data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return 0, "", err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return 0, "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return 0, "", err
+	}
+	return info.Size(), path, nil
+}
+
+func writeExportArchive(w io.Writer, data *domain.PersonalData) error {
+	zw := zip.NewWriter(w)
+
+	jsonFile, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(jsonFile)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	// Uploaded files served from local storage; files in cloud storage are
+	// referenced by URL in data.json
+	files := map[string]string{}
+	if data.Profile.Avatar != nil {
+		files["files/avatar"+filepath.Ext(*data.Profile.Avatar)] = *data.Profile.Avatar
+	}
+	for _, course := range data.TaughtCourses {
+		if course.Thumbnail != "" {
+			files[fmt.Sprintf("files/courses/%d/thumbnail%s", course.ID, filepath.Ext(course.Thumbnail))] = course.Thumbnail
+		}
+	}
+	for name, url := range files {
+		if err := addUploadToArchive(zw, name, url); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func addUploadToArchive(zw *zip.Writer, name, url string) error {
+	path, ok := LocalUploadPath(url)
+	if !ok {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func (s *PrivacyService) expireExports() {
+	exports, err := s.repo.ExpiredExports(time.Now())
+	if err != nil {
+		log.Printf("failed to load expired data exports: %v", err)
+		return
+	}
+	for i := range exports {
+		export := &exports[i]
+		removeExportFile(export.FilePath)
+		export.Status = domain.DataExportExpired
+		export.FilePath = ""
+		if err := s.repo.UpdateExport(export); err != nil {
+			log.Printf("failed to expire data export %d: %v", export.ID, err)
+		}
+	}
+}
+
+func (s *PrivacyService) processErasures() {
+	requests, err := s.repo.DueErasures(time.Now())
+	if err != nil {
+		log.Printf("failed to load due erasure requests: %v", err)
+		return
+	}
+	for i := range requests {
+		if err := s.erase(&requests[i]); err != nil {
+			log.Printf("failed to carry out erasure request %d: %v", requests[i].ID, err)
+		}
+	}
+}
+
+// erase anonymizes the account. Subscriptions are cancelled with the
+// payment provider first so nobody is billed for an erased account.
+// Enrollments and progress stay, attached to the anonymized account, so
+// course statistics are unchanged. Entries in the append-only audit log
+// are kept as records of security-relevant actions.
+func (s *PrivacyService) erase(request *domain.ErasureRequest) error {
+	data, err := s.repo.CollectPersonalData(request.UserID)
+	if err != nil {
+		return err
+	}
+	user := data.Profile
+
+	if err := s.subscriptions.EndAll(context.Background(), user.ID); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(user.ID); err != nil {
+		return err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+	passwordHash, err := hash.HashPassword(secret)
+	if err != nil {
+		return err
+	}
+
+	exports, err := s.repo.ListExports(user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Anonymize(user.ID, repository.AnonymizedUser{
+		Name:         erasedUserName,
+		Email:        fmt.Sprintf("erased-%d@erased.invalid", user.ID),
+		PasswordHash: passwordHash,
+		LoginKey:     normalizeLoginEmail(user.Email),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		removeExportFile(export.FilePath)
+	}
+	if user.Avatar != nil && *user.Avatar != "" {
+		if s.avatars != nil {
+			if err := s.avatars.Delete(context.Background(), filepath.Base(*user.Avatar)); err != nil {
+				log.Printf("failed to delete avatar of user %d: %v", user.ID, err)
+			}
+		} else {
+			removeUpload(*user.Avatar)
+		}
+	}
+
+	now := time.Now()
+	request.Status = domain.ErasureCompleted
+	request.CompletedAt = &now
+	if err := s.repo.UpdateErasure(request); err != nil {
+		return err
+	}
+	log.Printf("Account erased: user=%d request=%d", user.ID, request.ID)
+	return nil
+}
+
+// notify sends a best-effort account notification
+func (s *PrivacyService) notify(userID uint, title, message string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.notifClient.SendNotification(ctx, int64(userID), string(domain.NotificationTypePrivacy), title, message); err != nil {
+		log.Printf("failed to notify user %d: %v", userID, err)
+	}
+}
+
+func removeExportFile(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to delete data export %s: %v", path, err)
+	}
+}
@@ -3,36 +3,33 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
-	"log"
 	"time"
 
 	"elearning/internal/domain"
 	"elearning/internal/repository"
-	"elearning/pkg/grpcclient"
+	"elearning/pkg/eventbus"
 )
 
+// ProgressService tracks lesson progress. Reactions to progress (such as
+// completion notifications) subscribe to the events it publishes.
 type ProgressService struct {
 	progressRepo   repository.ProgressRepository
 	enrollmentRepo repository.EnrollmentRepository
 	lessonRepo     repository.LessonRepository
-	courseRepo     repository.CourseRepository
-	notifClient    *grpcclient.NotificationClient
+	events         *eventbus.Bus
 }
 
 func NewProgressService(
 	progressRepo repository.ProgressRepository,
 	enrollmentRepo repository.EnrollmentRepository,
 	lessonRepo repository.LessonRepository,
-	courseRepo repository.CourseRepository,
-	notifClient *grpcclient.NotificationClient,
+	events *eventbus.Bus,
 ) *ProgressService {
 	return &ProgressService{
 		progressRepo:   progressRepo,
 		enrollmentRepo: enrollmentRepo,
 		lessonRepo:     lessonRepo,
-		courseRepo:     courseRepo,
-		notifClient:    notifClient,
+		events:         events,
 	}
 }
 
@@ -55,11 +52,22 @@ func (s *ProgressService) MarkLessonCompleted(userID, lessonID uint) error {
 		return err
 	}
 
+	ctx := context.Background()
+	s.events.Publish(ctx, domain.LessonCompleted{
+		UserID:     userID,
+		CourseID:   lesson.CourseID,
+		LessonID:   lessonID,
+		OccurredAt: time.Now(),
+	})
+
 	// Check if course is now complete
 	progress, err := s.progressRepo.GetCourseProgress(userID, lesson.CourseID)
 	if err != nil {
 		return err
 	}
+	if err := s.enrollmentRepo.RaiseMaxProgress(userID, lesson.CourseID, progress.ProgressPercentage); err != nil {
+		return err
+	}
 
 	// If course is completed, update enrollment status
 	if progress.IsCompleted {
@@ -68,30 +76,19 @@ func (s *ProgressService) MarkLessonCompleted(userID, lessonID uint) error {
 			return err
 		}
 
-		enrollment.Status = domain.EnrollmentStatusCompleted
-		// Note: CompletedAt field doesn't exist in database, using status only
-
-		if err := s.enrollmentRepo.Update(enrollment); err != nil {
+		// Only an active enrollment is completed; a dropped or refunded one
+		// keeps its status
+		completed, err := s.enrollmentRepo.MarkCompleted(enrollment.ID)
+		if err != nil || !completed {
 			return err
 		}
 
-		// Send course completion notification
-		if s.notifClient != nil && s.courseRepo != nil {
-			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-			defer cancel()
-
-			course, err := s.courseRepo.FindByID(int64(lesson.CourseID))
-			if err == nil {
-				if err := s.notifClient.SendNotification(ctx,
-					int64(userID),
-					"completed",
-					"Course Completed",
-					fmt.Sprintf("Congratulations! You have completed the course: %s", course.Title),
-				); err != nil {
-					log.Printf("failed to send completion notification: %v", err)
-				}
-			}
-		}
+		s.events.Publish(ctx, domain.CourseCompleted{
+			EnrollmentID: enrollment.ID,
+			UserID:       userID,
+			CourseID:     lesson.CourseID,
+			OccurredAt:   time.Now(),
+		})
 	}
 
 	return nil
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+)
+
+var (
+	ErrUnknownRole       = errors.New("role does not exist")
+	ErrInvalidRoleName   = errors.New("role name must be 2-50 lowercase letters, digits, dashes or underscores")
+	ErrRoleExists        = errors.New("a role with this name already exists")
+	ErrBuiltInRole       = errors.New("built-in roles cannot be deleted")
+	ErrAdminRoleLocked   = errors.New("the admin role always holds every permission")
+	ErrRoleInUse         = errors.New("role is still assigned to users")
+	ErrInvalidPermission = errors.New("invalid permission")
+)
+
+// rbacCacheTTL bounds how long role permissions are cached, i.e. how late
+// a change made on another instance takes effect here
+const rbacCacheTTL = 30 * time.Second
+
+// CreateRoleRequest defines a custom role
+type CreateRoleRequest struct {
+	Name        string                  `json:"name" binding:"required"`
+	Description string                  `json:"description" binding:"max=255"`
+	Permissions []domain.RolePermission `json:"permissions"`
+}
+
+// UpdateRoleRequest replaces a role's description and permissions
+type UpdateRoleRequest struct {
+	Description string                  `json:"description" binding:"max=255"`
+	Permissions []domain.RolePermission `json:"permissions"`
+}
+
+// RBACService resolves what each role is allowed to do and lets admins
+// define custom roles
+type RBACService struct {
+	repo repository.RoleRepository
+
+	mu       sync.RWMutex
+	grants   map[string]map[domain.Permission]domain.PermissionScope
+	loadedAt time.Time
+}
+
+// NewRBACService creates a new RBAC service
+func NewRBACService(repo repository.RoleRepository) *RBACService {
+	return &RBACService{repo: repo}
+}
+
+// EnsureDefaults creates the built-in roles that do not exist yet and
+// grants the admin role every permission, including ones added since it was
+// created. Default permissions added to the student and teacher roles since
+// they were created are granted once; permissions an admin removed stay
+// removed.
+func (s *RBACService) EnsureDefaults() error {
+	for _, name := range []domain.UserRole{domain.RoleStudent, domain.RoleTeacher, domain.RoleAdmin} {
+		role, err := s.repo.FindByName(string(name))
+		if err != nil && !errors.Is(err, repository.ErrRoleNotFound) {
+			return err
+		}
+
+		if role == nil {
+			role = &domain.Role{Name: string(name), BuiltIn: true}
+			if err := s.repo.Create(role); err != nil {
+				return err
+			}
+			log.Printf("Created built-in role %q", name)
+		}
+
+		if name == domain.RoleAdmin {
+			if len(role.Permissions) != len(domain.Permissions) {
+				if err := s.repo.ReplacePermissions(role, allPermissions()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := s.grantNewDefaults(role, domain.DefaultRolePermissions[name]); err != nil {
+			return err
+		}
+	}
+
+	s.invalidate()
+	return nil
+}
+
+func (s *RBACService) grantNewDefaults(role *domain.Role, defaults []domain.RolePermission) error {
+	seeded := make(map[string]bool, len(role.Seeded))
+	for _, p := range role.Seeded {
+		seeded[p] = true
+	}
+
+	var grants []domain.RolePermission
+	names := make([]string, 0, len(defaults))
+	for _, p := range defaults {
+		names = append(names, string(p.Permission))
+		if !seeded[string(p.Permission)] {
+			grants = append(grants, p)
+		}
+	}
+	if len(grants) == 0 && len(role.Seeded) == len(names) {
+		return nil
+	}
+
+	if err := s.repo.GrantDefaults(role, grants, names); err != nil {
+		return err
+	}
+	if len(grants) > 0 {
+		log.Printf("Granted %d default permissions to role %q", len(grants), role.Name)
+	}
+	return nil
+}
+
+// PermissionScope returns the scope in which the role holds the permission
+func (s *RBACService) PermissionScope(role string, perm domain.Permission) (domain.PermissionScope, bool) {
+	grants := s.loadGrants()
+	scope, ok := grants[role][perm]
+	return scope, ok
+}
+
+// IsPrivileged reports whether the role holds any privileged permission
+func (s *RBACService) IsPrivileged(role string) bool {
+	for perm, scope := range s.loadGrants()[role] {
+		if (domain.RolePermission{Permission: perm, Scope: scope}).IsPrivileged() {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleExists checks that users can be assigned the role
+func (s *RBACService) RoleExists(name string) error {
+	if _, ok := s.loadGrants()[name]; ok {
+		return nil
+	}
+	if _, err := s.repo.FindByName(name); err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			return ErrUnknownRole
+		}
+		return err
+	}
+	return nil
+}
+
+// Permissions lists every permission that can be granted
+func (s *RBACService) Permissions() []domain.PermissionInfo {
+	return domain.Permissions
+}
+
+// ListRoles returns every role with its permissions
+func (s *RBACService) ListRoles() ([]domain.Role, error) {
+	return s.repo.FindAll()
+}
+
+// GetRole returns a role with its permissions
+func (s *RBACService) GetRole(id uint) (*domain.Role, error) {
+	return s.repo.FindByID(id)
+}
+
+// CreateRole defines a custom role
+func (s *RBACService) CreateRole(req CreateRoleRequest) (*domain.Role, error) {
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !domain.IsValidRoleName(name) {
+		return nil, ErrInvalidRoleName
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.FindByName(name); err == nil {
+		return nil, ErrRoleExists
+	} else if !errors.Is(err, repository.ErrRoleNotFound) {
+		return nil, err
+	}
+
+	role := &domain.Role{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Permissions: permissions,
+	}
+	if err := s.repo.Create(role); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Role %q created with %d permissions", role.Name, len(permissions))
+	return role, nil
+}
+
+// UpdateRole replaces a role's description and permissions. The admin
+// role cannot be changed so there is always a role that can manage roles.
+func (s *RBACService) UpdateRole(id uint, req UpdateRoleRequest) (*domain.Role, error) {
+	role, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == string(domain.RoleAdmin) {
+		return nil, ErrAdminRoleLocked
+	}
+
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = strings.TrimSpace(req.Description)
+	if err := s.repo.ReplacePermissions(role, permissions); err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	log.Printf("Role %q updated with %d permissions", role.Name, len(permissions))
+	return role, nil
+}
+
+// DeleteRole removes a custom role that no user holds
+func (s *RBACService) DeleteRole(id uint) error {
+	role, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrBuiltInRole
+	}
+
+	users, err := s.repo.CountUsers(role.Name)
+	if err != nil {
+		return err
+	}
+	if users > 0 {
+		return fmt.Errorf("%w (%d users)", ErrRoleInUse, users)
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.invalidate()
+	log.Printf("Role %q deleted", role.Name)
+	return nil
+}
+
+// loadGrants returns the cached permissions of every role, reloading them
+// once the cache is stale. When reloading fails the stale grants are kept.
+func (s *RBACService) loadGrants() map[string]map[domain.Permission]domain.PermissionScope {
+	s.mu.RLock()
+	grants, loadedAt := s.grants, s.loadedAt
+	s.mu.RUnlock()
+	if grants != nil && time.Since(loadedAt) < rbacCacheTTL {
+		return grants
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.grants != nil && time.Since(s.loadedAt) < rbacCacheTTL {
+		return s.grants
+	}
+
+	roles, err := s.repo.FindAll()
+	if err != nil {
+		log.Printf("failed to load role permissions: %v", err)
+		return s.grants
+	}
+
+	grants = make(map[string]map[domain.Permission]domain.PermissionScope, len(roles))
+	for _, role := range roles {
+		perms := make(map[domain.Permission]domain.PermissionScope, len(role.Permissions))
+		for _, p := range role.Permissions {
+			perms[p.Permission] = p.Scope
+		}
+		grants[role.Name] = perms
+	}
+	s.grants = grants
+	s.loadedAt = time.Now()
+	return grants
+}
+
+func (s *RBACService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// normalizePermissions validates requested permissions, defaults their
+// scope to "any" and removes duplicates
+func normalizePermissions(requested []domain.RolePermission) ([]domain.RolePermission, error) {
+	seen := make(map[domain.Permission]bool, len(requested))
+	permissions := make([]domain.RolePermission, 0, len(requested))
+	for _, p := range requested {
+		info, ok := p.Permission.Info()
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPermission, p.Permission)
+		}
+		if p.Scope == "" {
+			p.Scope = domain.ScopeAny
+		}
+		if !p.Scope.IsValid() {
+			return nil, fmt.Errorf("%w: scope %q", ErrInvalidPermission, p.Scope)
+		}
+		if p.Scope == domain.ScopeOwn && !info.Scoped {
+			return nil, fmt.Errorf("%w: %q cannot be limited to own resources", ErrInvalidPermission, p.Permission)
+		}
+		if seen[p.Permission] {
+			continue
+		}
+		seen[p.Permission] = true
+		permissions = append(permissions, domain.RolePermission{Permission: p.Permission, Scope: p.Scope})
+	}
+	return permissions, nil
+}
+
+func allPermissions() []domain.RolePermission {
+	permissions := make([]domain.RolePermission, len(domain.Permissions))
+	for i, info := range domain.Permissions {
+		permissions[i] = domain.RolePermission{Permission: info.Permission, Scope: domain.ScopeAny}
+	}
+	return permissions
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/grpcclient"
+	"elearning/pkg/payment"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderNotRefundable  = errors.New("only paid orders can be refunded")
+	ErrRefundInProgress    = errors.New("a refund request for this order is already open")
+	ErrRefundWindowClosed  = errors.New("the refund period for this order has ended")
+	ErrRefundTooMuchViewed = errors.New("too much of the course has been completed for a refund")
+	ErrRefundNotPending    = errors.New("refund request has already been reviewed")
+	ErrRefundSelfReview    = errors.New("admins cannot review their own refund request")
+	ErrRefundFailed        = errors.New("the payment provider could not issue the refund")
+)
+
+// RefundOptions is the refund policy
+type RefundOptions struct {
+	// Window is how long after payment a refund can be requested
+	Window time.Duration
+	// MaxProgress is the course progress, in percent, above which a
+	// refund can no longer be requested
+	MaxProgress float64
+}
+
+// RefundRequestBody represents a customer's refund request
+type RefundRequestBody struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// RefundReviewRequest represents an admin decision on a refund request
+type RefundReviewRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// RefundService handles refund requests. Customers ask for a refund within
+// the policy; once an admin approves it, the payment is refunded at the
+// provider, the order is marked refunded and course access is revoked.
+type RefundService struct {
+	repo        repository.RefundRepository
+	orders      repository.OrderRepository
+	progress    repository.ProgressRepository
+	enrollments *EnrollmentService
+	provider    payment.Provider
+	events      *eventbus.Bus
+	notifClient *grpcclient.NotificationClient
+	opts        RefundOptions
+}
+
+// NewRefundService creates a new refund service
+func NewRefundService(
+	repo repository.RefundRepository,
+	orders repository.OrderRepository,
+	progress repository.ProgressRepository,
+	enrollments *EnrollmentService,
+	provider payment.Provider,
+	events *eventbus.Bus,
+	notifClient *grpcclient.NotificationClient,
+	opts RefundOptions,
+) *RefundService {
+	return &RefundService{
+		repo:        repo,
+		orders:      orders,
+		progress:    progress,
+		enrollments: enrollments,
+		provider:    provider,
+		events:      events,
+		notifClient: notifClient,
+		opts:        opts,
+	}
+}
+
+// Request opens a refund request for one of the user's paid orders
+func (s *RefundService) Request(userID, orderID uint, req RefundRequestBody) (*domain.Refund, error) {
+	order, err := s.orders.FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, repository.ErrOrderNotFound
+	}
+	if order.Status != domain.OrderStatusPaid || order.PaidAt == nil {
+		return nil, ErrOrderNotRefundable
+	}
+	if _, err := s.repo.FindOpen(order.ID); err == nil {
+		return nil, ErrRefundInProgress
+	} else if !errors.Is(err, repository.ErrRefundNotFound) {
+		return nil, err
+	}
+
+	if time.Since(*order.PaidAt) > s.opts.Window {
+		return nil, ErrRefundWindowClosed
+	}
+	watched, err := s.watched(userID, order.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if watched > s.opts.MaxProgress {
+		return nil, ErrRefundTooMuchViewed
+	}
+
+	refund := &domain.Refund{
+		OrderID:         order.ID,
+		UserID:          userID,
+		CourseID:        order.CourseID,
+		Amount:          order.Amount,
+		Currency:        order.Currency,
+		Reason:          req.Reason,
+		ProgressPercent: watched,
+		Status:          domain.RefundPending,
+	}
+	if err := s.repo.Create(refund); err != nil {
+		return nil, err
+	}
+	log.Printf("Refund %d requested by user %d for order %d", refund.ID, userID, order.ID)
+	return refund, nil
+}
+
+// watched returns the most of a course the user has seen: their current
+// progress or, if higher, the enrollment's high-water mark, since lessons
+// can be marked incomplete again
+func (s *RefundService) watched(userID, courseID uint) (float64, error) {
+	progress, err := s.progress.GetCourseProgress(userID, courseID)
+	if err != nil {
+		return 0, err
+	}
+	watched := progress.ProgressPercentage
+
+	enrollment, err := s.enrollments.GetEnrollmentStatus(userID, courseID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	if enrollment != nil && enrollment.MaxProgress > watched {
+		watched = enrollment.MaxProgress
+	}
+	return watched, nil
+}
+
+// ListMine returns the user's refund requests
+func (s *RefundService) ListMine(userID uint, page, limit int) ([]domain.Refund, int64, error) {
+	return s.repo.List(repository.RefundFilter{UserID: userID}, page, limit)
+}
+
+// Cancel withdraws one of the user's pending refund requests
+func (s *RefundService) Cancel(userID, id uint) (*domain.Refund, error) {
+	refund, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.UserID != userID {
+		return nil, repository.ErrRefundNotFound
+	}
+	if refund.Status != domain.RefundPending {
+		return nil, ErrRefundNotPending
+	}
+
+	refund.Status = domain.RefundCancelled
+	if err := s.repo.Update(refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// List returns refund requests for review
+func (s *RefundService) List(filter repository.RefundFilter, page, limit int) ([]domain.Refund, int64, error) {
+	return s.repo.List(filter, page, limit)
+}
+
+// Approve refunds the payment and revokes the customer's access to the
+// course. A request the provider refused is left failed and can be
+// approved again; retries reuse the provider's idempotency key.
+func (s *RefundService) Approve(ctx context.Context, id, adminID uint, req RefundReviewRequest) (*domain.Refund, error) {
+	refund, order, err := s.reviewable(id, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refund.ReviewedBy = &adminID
+	refund.ReviewedAt = &now
+	refund.ReviewNote = req.Note
+
+	ref, err := s.issue(ctx, refund, order)
+	if err != nil {
+		log.Printf("Refund %d for order %d failed: %v", refund.ID, order.ID, err)
+		refund.Status = domain.RefundFailed
+		refund.FailureReason = truncate(err.Error(), 255)
+		if err := s.repo.Update(refund); err != nil {
+			return nil, err
+		}
+		return refund, fmt.Errorf("%w: %v", ErrRefundFailed, err)
+	}
+	refund.ProviderRef = ref
+
+	// The order is updated first so a failure here leaves the request open
+	// to be approved again. A concurrent approval that got there first
+	// revokes access and notifies the customer itself.
+	changed, err := s.orders.MarkRefunded(order.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, ErrRefundNotPending
+	}
+	if err := s.enrollments.Revoke(order.UserID, order.CourseID); err != nil {
+		return nil, err
+	}
+
+	refund.Status = domain.RefundCompleted
+	refund.FailureReason = ""
+	refund.RefundedAt = &now
+	if err := s.repo.Update(refund); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Refund %d completed for order %d", refund.ID, order.ID)
+	s.events.Publish(ctx, domain.OrderRefunded{
+		OrderID:    order.ID,
+		RefundID:   refund.ID,
+		UserID:     order.UserID,
+		CourseID:   order.CourseID,
+		Amount:     refund.Amount,
+		Currency:   refund.Currency,
+		OccurredAt: now,
+	})
+	s.notify(refund.UserID, "Refund approved",
+		fmt.Sprintf("Your refund for %q was approved. Your access to the course has ended.", order.CourseTitle))
+	return refund, nil
+}
+
+// Reject closes the request without refunding
+func (s *RefundService) Reject(ctx context.Context, id, adminID uint, req RefundReviewRequest) (*domain.Refund, error) {
+	refund, order, err := s.reviewable(id, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refund.Status = domain.RefundRejected
+	refund.ReviewedBy = &adminID
+	refund.ReviewedAt = &now
+	refund.ReviewNote = req.Note
+	if err := s.repo.Update(refund); err != nil {
+		return nil, err
+	}
+
+	s.notify(refund.UserID, "Refund rejected", fmt.Sprintf("Your refund request for %q was rejected.", order.CourseTitle))
+	return refund, nil
+}
+
+// reviewable loads an open request and its order for review
+func (s *RefundService) reviewable(id, adminID uint) (*domain.Refund, *domain.Order, error) {
+	refund, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !refund.Status.IsOpen() {
+		return nil, nil, ErrRefundNotPending
+	}
+	if refund.UserID == adminID {
+		return nil, nil, ErrRefundSelfReview
+	}
+
+	order, err := s.orders.FindByID(refund.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Status != domain.OrderStatusPaid {
+		return nil, nil, ErrOrderNotRefundable
+	}
+	return refund, order, nil
+}
+
+// issue refunds the payment at the provider and returns its reference.
+// Orders a coupon paid for in full have nothing to refund.
+func (s *RefundService) issue(ctx context.Context, refund *domain.Refund, order *domain.Order) (string, error) {
+	if refund.Amount == 0 {
+		return "", nil
+	}
+	if order.Provider != s.provider.Name() {
+		return "", fmt.Errorf("order was paid with %s, but %s is configured", order.Provider, s.provider.Name())
+	}
+
+	result, err := s.provider.Refund(ctx, payment.RefundRequest{
+		OrderID:          order.ID,
+		PaymentReference: order.PaymentRef,
+		Amount:           refund.Amount,
+		Currency:         refund.Currency,
+		IdempotencyKey:   fmt.Sprintf("refund-%d", refund.ID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Reference, nil
+}
+
+// notify sends a best-effort refund notification
+func (s *RefundService) notify(userID uint, title, message string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.notifClient.SendNotification(ctx, int64(userID), string(domain.NotificationTypeRefund), title, message); err != nil {
+		log.Printf("failed to notify user %d: %v", userID, err)
+	}
+}
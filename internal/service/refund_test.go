@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+)
+
+type fakeRefundRepo struct {
+	repository.RefundRepository
+	refunds map[uint]*domain.Refund
+}
+
+func (r *fakeRefundRepo) FindByID(id uint) (*domain.Refund, error) {
+	refund, ok := r.refunds[id]
+	if !ok {
+		return nil, repository.ErrRefundNotFound
+	}
+	copied := *refund
+	return &copied, nil
+}
+
+func (r *fakeRefundRepo) Update(refund *domain.Refund) error {
+	copied := *refund
+	r.refunds[refund.ID] = &copied
+	return nil
+}
+
+// lostRaceOrderRepo refunds the order behind the caller's back, as a
+// concurrent approval of the same request would
+type lostRaceOrderRepo struct {
+	*fakeOrderRepo
+}
+
+func (r lostRaceOrderRepo) MarkRefunded(id uint, at time.Time) (bool, error) {
+	r.orders[id].Status = domain.OrderStatusRefunded
+	return false, nil
+}
+
+func TestRefundApproveConcurrentApproval(t *testing.T) {
+	orders := &fakeOrderRepo{orders: map[uint]*domain.Order{
+		1: {ID: 1, UserID: 7, CourseID: 3, Amount: 4900, Currency: "usd", Status: domain.OrderStatusPaid, Provider: "stripe", PaymentRef: "pi_1"},
+	}}
+	refunds := &fakeRefundRepo{refunds: map[uint]*domain.Refund{
+		5: {ID: 5, OrderID: 1, UserID: 7, Amount: 4900, Currency: "usd", Status: domain.RefundPending},
+	}}
+	bus := eventbus.New(zap.NewNop(), 1, 1)
+	published := 0
+	eventbus.Subscribe(bus, "test", eventbus.Sync, func(ctx context.Context, e domain.OrderRefunded) error {
+		published++
+		return nil
+	})
+	s := &RefundService{repo: refunds, orders: lostRaceOrderRepo{orders}, provider: &recordingProvider{}, events: bus}
+
+	_, err := s.Approve(context.Background(), 5, 1, RefundReviewRequest{})
+	if !errors.Is(err, ErrRefundNotPending) {
+		t.Fatalf("Approve error = %v, want %v", err, ErrRefundNotPending)
+	}
+	if published != 0 {
+		t.Errorf("OrderRefunded published %d times, want 0", published)
+	}
+	if got := refunds.refunds[5].Status; got != domain.RefundPending {
+		t.Errorf("refund status = %s, want it left to the other approval", got)
+	}
+}
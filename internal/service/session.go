@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/token"
+)
+
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+const (
+	// sessionCacheTTL bounds how long a validated session is trusted without
+	// re-reading it. Revocations take effect at once on the instance that
+	// made them and within this long on the others.
+	sessionCacheTTL = 5 * time.Second
+	// sessionTouchInterval limits last-seen writes to one per session per
+	// interval
+	sessionTouchInterval = time.Minute
+	// sessionIDSize is the number of random bytes in a token ID
+	sessionIDSize = 16
+)
+
+// ClientInfo describes the client a token is issued to
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionView is a session as shown to its owner
+type SessionView struct {
+	domain.UserSession
+	Current bool `json:"current"`
+}
+
+type cachedSession struct {
+	id        uint
+	userID    uint
+	expiresAt time.Time
+	lastSeen  time.Time
+	ip        string
+	cachedAt  time.Time
+}
+
+// SessionService keeps a server-side record for every issued access token
+// so users can see where they are logged in and revoke sessions
+type SessionService struct {
+	repo      repository.SessionRepository
+	blacklist token.TokenBlacklist
+	jwtExpiry time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedSession
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(repo repository.SessionRepository, blacklist token.TokenBlacklist, jwtExpiry time.Duration) *SessionService {
+	return &SessionService{
+		repo:      repo,
+		blacklist: blacklist,
+		jwtExpiry: jwtExpiry,
+		cache:     make(map[string]cachedSession),
+	}
+}
+
+// Start records a new session and returns the token ID to put in the jti
+// claim of its access token
+func (s *SessionService) Start(userID uint, client ClientInfo, ttl time.Duration) (string, error) {
+	tokenID, err := randomHex(sessionIDSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session := &domain.UserSession{
+		UserID:     userID,
+		TokenID:    tokenID,
+		Device:     describeDevice(client.UserAgent),
+		UserAgent:  truncate(client.UserAgent, 512),
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if err := s.repo.Create(session); err != nil {
+		return "", err
+	}
+	return tokenID, nil
+}
+
+// ValidateSession checks that the session behind a token is still active
+// and records activity on it. Every access token is issued with a session,
+// so a token without an ID is refused.
+func (s *SessionService) ValidateSession(userID uint, tokenID, ip string) error {
+	if tokenID == "" {
+		return ErrSessionRevoked
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[tokenID]
+	s.mu.Unlock()
+
+	if !ok || now.Sub(cached.cachedAt) > sessionCacheTTL {
+		session, err := s.repo.FindByTokenID(tokenID)
+		if err != nil {
+			if errors.Is(err, repository.ErrSessionNotFound) {
+				return ErrSessionRevoked
+			}
+			return err
+		}
+		if !session.IsActive(now) {
+			s.evict(tokenID)
+			return ErrSessionRevoked
+		}
+		cached = cachedSession{
+			id:        session.ID,
+			userID:    session.UserID,
+			expiresAt: session.ExpiresAt,
+			lastSeen:  session.LastSeenAt,
+			ip:        session.IP,
+			cachedAt:  now,
+		}
+	}
+
+	if cached.userID != userID || !cached.expiresAt.After(now) {
+		return ErrSessionRevoked
+	}
+
+	if now.Sub(cached.lastSeen) > sessionTouchInterval || cached.ip != ip {
+		if err := s.repo.Touch(cached.id, ip, now); err != nil {
+			log.Printf("failed to update session %d: %v", cached.id, err)
+		}
+		cached.lastSeen = now
+		cached.ip = ip
+	}
+
+	s.mu.Lock()
+	s.cache[tokenID] = cached
+	s.mu.Unlock()
+	return nil
+}
+
+// List returns the user's active sessions, flagging the one making the
+// request
+func (s *SessionService) List(userID uint, currentTokenID string) ([]SessionView, error) {
+	sessions, err := s.repo.ListActive(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]SessionView, len(sessions))
+	for i, session := range sessions {
+		views[i] = SessionView{
+			UserSession: session,
+			Current:     session.TokenID == currentTokenID,
+		}
+	}
+	return views, nil
+}
+
+// Revoke logs out one of the user's sessions
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	tokenID, err := s.repo.Revoke(userID, sessionID, time.Now())
+	if err != nil {
+		return err
+	}
+	s.evict(tokenID)
+	return nil
+}
+
+// RevokeToken logs out the session of a single token
+func (s *SessionService) RevokeToken(tokenID string) error {
+	if tokenID == "" {
+		return nil
+	}
+	s.evict(tokenID)
+	return s.repo.RevokeByTokenID(tokenID, time.Now())
+}
+
+// RevokeAll logs the user out everywhere. The user's tokens are also
+// revoked on the blacklist, which needs no database read.
+func (s *SessionService) RevokeAll(userID uint) error {
+	now := time.Now()
+	tokenIDs, err := s.repo.RevokeAll(userID, now)
+	if err != nil {
+		return err
+	}
+	for _, tokenID := range tokenIDs {
+		s.evict(tokenID)
+	}
+
+	if err := s.blacklist.RevokeUser(userID, now, now.Add(s.jwtExpiry)); err != nil {
+		return err
+	}
+	log.Printf("Revoked %d sessions of user %d", len(tokenIDs), userID)
+	return nil
+}
+
+// Run prunes expired sessions and stale cache entries until ctx is
+// cancelled
+func (s *SessionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if _, err := s.repo.DeleteExpired(now); err != nil {
+				log.Printf("failed to prune expired sessions: %v", err)
+			}
+
+			s.mu.Lock()
+			for tokenID, cached := range s.cache {
+				if now.Sub(cached.cachedAt) > sessionCacheTTL {
+					delete(s.cache, tokenID)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *SessionService) evict(tokenID string) {
+	s.mu.Lock()
+	delete(s.cache, tokenID)
+	s.mu.Unlock()
+}
+
+// describeDevice summarizes a user agent as "Browser on OS"
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(userAgent, "curl/"):
+		browser = "curl"
+	case strings.Contains(userAgent, "PostmanRuntime/"):
+		browser = "Postman"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/hash"
+	"elearning/pkg/oidc"
+)
+
+var (
+	ErrSSOProviderNotFound = errors.New("sso provider not found")
+	ErrSSOInvalidState     = errors.New("sso login is invalid or has expired; please start again")
+	ErrSSOEmailNotVerified = errors.New("the identity provider did not confirm your email address")
+	ErrSSODomainNotAllowed = errors.New("your email domain is not allowed to sign in with this provider")
+	ErrSSOSignupDisabled   = errors.New("no account exists for this email")
+	ErrInvalidSSOLoginCode = errors.New("sso login code is invalid or has expired")
+)
+
+const (
+	// ssoStateSize is the number of random bytes in the state parameter
+	ssoStateSize = 32
+	// ssoLoginCodeSize is the number of random bytes in a login code
+	ssoLoginCodeSize = 32
+	// ssoWildcardDomain in a domain→role mapping matches any email domain
+	ssoWildcardDomain = "*"
+)
+
+// SSOProvider is a configured OpenID Connect identity provider
+type SSOProvider struct {
+	Name        string
+	DisplayName string
+	Client      *oidc.Provider
+	// DomainRoles maps email domains to the role given to accounts created
+	// on first sign-in. Only listed domains may sign in; "*" matches any.
+	DomainRoles map[string]domain.UserRole
+	// AllowSignup creates accounts for unknown emails; otherwise only
+	// existing users can sign in
+	AllowSignup bool
+}
+
+// SSOProviderInfo describes a provider to clients
+type SSOProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// SSOOptions configures single sign-on
+type SSOOptions struct {
+	StateTTL     time.Duration
+	LoginCodeTTL time.Duration
+	// FrontendCallbackURL receives the one-time login code (or an error)
+	// after the provider redirects back
+	FrontendCallbackURL string
+}
+
+// SSOExchangeRequest redeems the one-time code handed to the frontend
+type SSOExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// SSOService implements OpenID Connect sign-in
+type SSOService struct {
+	providers    map[string]SSOProvider
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	tokenRepo    repository.UserTokenRepository
+	authService  AuthService
+	events       *eventbus.Bus
+	opts         SSOOptions
+}
+
+// NewSSOService creates a new SSO service
+func NewSSOService(
+	providers []SSOProvider,
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	tokenRepo repository.UserTokenRepository,
+	authService AuthService,
+	events *eventbus.Bus,
+	opts SSOOptions,
+) *SSOService {
+	byName := make(map[string]SSOProvider, len(providers))
+	for _, p := range providers {
+		byName[p.Name] = p
+	}
+	return &SSOService{
+		providers:    byName,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		tokenRepo:    tokenRepo,
+		authService:  authService,
+		events:       events,
+		opts:         opts,
+	}
+}
+
+// FrontendCallbackURL is where the browser is sent after the callback
+func (s *SSOService) FrontendCallbackURL() string {
+	return s.opts.FrontendCallbackURL
+}
+
+// StateTTL is how long a started login stays valid
+func (s *SSOService) StateTTL() time.Duration {
+	return s.opts.StateTTL
+}
+
+// ListProviders returns the configured providers sorted by name
+func (s *SSOService) ListProviders() []SSOProviderInfo {
+	infos := make([]SSOProviderInfo, 0, len(s.providers))
+	for _, p := range s.providers {
+		infos = append(infos, SSOProviderInfo{
+			Name:        p.Name,
+			DisplayName: p.DisplayName,
+			LoginURL:    "/api/v1/auth/sso/" + p.Name + "/login",
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Begin starts a login with a provider. It returns the authorization URL
+// to redirect to and the raw state, which the caller should also bind to
+// the browser (e.g. in a cookie).
+func (s *SSOService) Begin(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrSSOProviderNotFound
+	}
+
+	state, stateHash, err := hash.GenerateToken(ssoStateSize)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, _, err := hash.GenerateToken(ssoStateSize)
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oidc.GenerateVerifier()
+
+	authURL, err := provider.Client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	if err := s.identityRepo.DeleteExpiredStates(now); err != nil {
+		log.Printf("failed to prune expired sso states: %v", err)
+	}
+	if err := s.identityRepo.CreateState(&domain.SSOLoginState{
+		StateHash:    stateHash,
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(s.opts.StateTTL),
+	}); err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// Complete handles the provider's redirect: it exchanges the code, validates
+// the ID token, finds or creates the user and returns a short-lived,
+// single-use login code for the frontend to redeem with Exchange
+func (s *SSOService) Complete(ctx context.Context, providerName, code, state string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrSSOProviderNotFound
+	}
+
+	loginState, err := s.identityRepo.ConsumeState(providerName, hash.HashToken(state))
+	if err != nil {
+		if errors.Is(err, repository.ErrSSOStateInvalid) {
+			return "", ErrSSOInvalidState
+		}
+		return "", err
+	}
+
+	claims, err := provider.Client.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.resolveUser(provider, claims)
+	if err != nil {
+		return "", err
+	}
+
+	raw, hashed, err := hash.GenerateToken(ssoLoginCodeSize)
+	if err != nil {
+		return "", err
+	}
+	if err := s.tokenRepo.Create(&domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPurposeSSOLogin,
+		TokenHash: hashed,
+		ExpiresAt: time.Now().Add(s.opts.LoginCodeTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	log.Printf("User %d signed in with %s", user.ID, providerName)
+	return raw, nil
+}
+
+// Exchange redeems a login code for our normal tokens
+func (s *SSOService) Exchange(code string, client ClientInfo) (*AuthResponse, error) {
+	loginCode, err := s.tokenRepo.Consume(domain.TokenPurposeSSOLogin, hash.HashToken(code))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenInvalid) {
+			return nil, ErrInvalidSSOLoginCode
+		}
+		return nil, err
+	}
+	return s.authService.LoginExternal(loginCode.UserID, client)
+}
+
+// resolveUser finds the user for a validated identity: by an existing link
+// first, then by email, creating the account if the provider allows it
+func (s *SSOService) resolveUser(provider SSOProvider, claims *oidc.Claims) (*domain.User, error) {
+	now := time.Now()
+
+	identity, err := s.identityRepo.FindByProviderSubject(provider.Name, claims.Subject)
+	switch {
+	case err == nil:
+		if err := s.identityRepo.TouchLogin(identity.ID, claims.Email, now); err != nil {
+			log.Printf("failed to update identity %d: %v", identity.ID, err)
+		}
+		return s.userRepo.FindByID(identity.UserID)
+	case !errors.Is(err, repository.ErrIdentityNotFound):
+		return nil, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the
+	// address and the domain is one we trust this provider for
+	if claims.Email == "" || !claims.IsEmailVerified() {
+		return nil, ErrSSOEmailNotVerified
+	}
+	role, ok := provider.roleForEmail(claims.Email)
+	if !ok {
+		return nil, ErrSSODomainNotAllowed
+	}
+
+	user, err := s.userRepo.FindByEmail(claims.Email)
+	switch {
+	case err == nil:
+		if !user.IsVerified() {
+			if err := s.userRepo.MarkVerified(user.ID, now); err != nil {
+				log.Printf("failed to mark user %d verified: %v", user.ID, err)
+			}
+		}
+	case errors.Is(err, repository.ErrUserNotFound):
+		if !provider.AllowSignup {
+			return nil, ErrSSOSignupDisabled
+		}
+		if user, err = s.createUser(claims, role, now); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.identityRepo.Create(&domain.UserIdentity{
+		UserID:      user.ID,
+		Provider:    provider.Name,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+	log.Printf("Linked user %d to %s identity", user.ID, provider.Name)
+
+	return user, nil
+}
+
+// createUser creates an account for a first-time SSO user. The password is
+// random and never disclosed; the user can set one with a password reset.
+func (s *SSOService) createUser(claims *oidc.Claims, role domain.UserRole, now time.Time) (*domain.User, error) {
+	randomPassword, _, err := hash.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := hash.HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	user := &domain.User{
+		Name:       name,
+		Email:      claims.Email,
+		Password:   hashedPassword,
+		Role:       role,
+		VerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(context.Background(), userCreatedEvent(user, "sso"))
+	return user, nil
+}
+
+// roleForEmail returns the role mapped to the email's domain
+func (p SSOProvider) roleForEmail(email string) (domain.UserRole, bool) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return "", false
+	}
+	emailDomain := strings.ToLower(email[at+1:])
+
+	if role, ok := p.DomainRoles[emailDomain]; ok {
+		return role, true
+	}
+	role, ok := p.DomainRoles[ssoWildcardDomain]
+	return role, ok
+}
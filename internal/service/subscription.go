@@ -0,0 +1,646 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/grpcclient"
+	"elearning/pkg/payment"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInvalidPlan         = errors.New("invalid subscription plan")
+	ErrPlanInactive        = errors.New("subscription plan is not available")
+	ErrAlreadySubscribed   = errors.New("already subscribed to this plan")
+	ErrSubscriptionNotLive = errors.New("subscription is not running")
+)
+
+// PlanRequest creates or replaces a subscription plan
+type PlanRequest struct {
+	Name        string                 `json:"name" binding:"required,max=100"`
+	Description string                 `json:"description" binding:"max=500"`
+	Interval    domain.BillingInterval `json:"interval" binding:"required"`
+	Price       int64                  `json:"price"`
+	Currency    string                 `json:"currency" binding:"required"`
+	TrialDays   int                    `json:"trial_days"`
+	CategoryIDs []int64                `json:"category_ids"`
+	Active      *bool                  `json:"active"`
+}
+
+// SubscribeRequest starts a subscription
+type SubscribeRequest struct {
+	PlanID uint `json:"plan_id" binding:"required"`
+}
+
+// SubscriptionOptions configures subscription checkout and access
+type SubscriptionOptions struct {
+	// SuccessURL and CancelURL are where the provider sends the customer
+	// after checkout; {SUBSCRIPTION_ID} is replaced with the subscription ID
+	SuccessURL string
+	CancelURL  string
+	// CheckoutTTL is how long an unfinished checkout is reused
+	CheckoutTTL time.Duration
+	// Grace is how long access outlasts a period that was not renewed
+	Grace time.Duration
+}
+
+// SubscriptionService sells subscription plans that unlock whole parts of
+// the catalog. Subscribe creates an incomplete subscription and a checkout
+// at the provider; the provider then owns billing and its webhooks start,
+// renew and end the subscription. A trial is offered once per customer.
+type SubscriptionService struct {
+	repo        repository.SubscriptionRepository
+	userRepo    repository.UserRepository
+	provider    payment.Provider
+	events      *eventbus.Bus
+	notifClient *grpcclient.NotificationClient
+	opts        SubscriptionOptions
+}
+
+// NewSubscriptionService creates a new subscription service
+func NewSubscriptionService(
+	repo repository.SubscriptionRepository,
+	userRepo repository.UserRepository,
+	provider payment.Provider,
+	events *eventbus.Bus,
+	notifClient *grpcclient.NotificationClient,
+	opts SubscriptionOptions,
+) *SubscriptionService {
+	return &SubscriptionService{
+		repo:        repo,
+		userRepo:    userRepo,
+		provider:    provider,
+		events:      events,
+		notifClient: notifClient,
+		opts:        opts,
+	}
+}
+
+// CreatePlan adds a subscription plan
+func (s *SubscriptionService) CreatePlan(req PlanRequest) (*domain.Plan, error) {
+	plan := &domain.Plan{Active: true}
+	if err := applyPlan(plan, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreatePlan(plan); err != nil {
+		return nil, err
+	}
+	log.Printf("Subscription plan %d (%s) created", plan.ID, plan.Name)
+	return plan, nil
+}
+
+// UpdatePlan replaces a plan's settings. Price changes apply to new
+// subscribers; running subscriptions keep the price they were sold at.
+func (s *SubscriptionService) UpdatePlan(id uint, req PlanRequest) (*domain.Plan, error) {
+	plan, err := s.repo.FindPlan(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPlan(plan, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetPlan returns a plan
+func (s *SubscriptionService) GetPlan(id uint) (*domain.Plan, error) {
+	return s.repo.FindPlan(id)
+}
+
+// ListPlans returns plans, cheapest first
+func (s *SubscriptionService) ListPlans(activeOnly bool) ([]domain.Plan, error) {
+	return s.repo.ListPlans(activeOnly)
+}
+
+// Subscribe starts a checkout for a plan. A recent unfinished checkout for
+// the same plan is returned instead of creating another one.
+func (s *SubscriptionService) Subscribe(ctx context.Context, userID uint, req SubscribeRequest) (*domain.Subscription, error) {
+	plan, err := s.repo.FindPlan(req.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.Active {
+		return nil, ErrPlanInactive
+	}
+
+	live, err := s.repo.ListLive(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range live {
+		if sub.PlanID == plan.ID {
+			return nil, ErrAlreadySubscribed
+		}
+	}
+
+	sub, err := s.repo.FindIncomplete(userID, plan.ID, time.Now().Add(-s.opts.CheckoutTTL))
+	switch {
+	case err == nil && sub.Provider == s.provider.Name():
+		return sub, nil
+	case err != nil && !errors.Is(err, repository.ErrSubscriptionNotFound):
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	trialDays, err := s.trialDays(userID, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	sub = &domain.Subscription{
+		UserID:   userID,
+		PlanID:   plan.ID,
+		Status:   domain.SubscriptionIncomplete,
+		Provider: s.provider.Name(),
+	}
+	if err := s.repo.Create(sub); err != nil {
+		return nil, err
+	}
+	sub.Plan = plan
+
+	checkout, err := s.provider.CreateSubscriptionCheckout(ctx, payment.SubscriptionCheckoutRequest{
+		SubscriptionID: sub.ID,
+		Amount:         plan.Price,
+		Currency:       plan.Currency,
+		Interval:       string(plan.Interval),
+		TrialDays:      trialDays,
+		Description:    plan.Name,
+		CustomerEmail:  user.Email,
+		SuccessURL:     subscriptionURL(s.opts.SuccessURL, sub.ID),
+		CancelURL:      subscriptionURL(s.opts.CancelURL, sub.ID),
+	})
+	if err != nil {
+		s.end(sub)
+		if updateErr := s.repo.Update(sub); updateErr != nil {
+			log.Printf("failed to cancel subscription %d: %v", sub.ID, updateErr)
+		}
+		return nil, fmt.Errorf("create checkout: %w", err)
+	}
+
+	sub.CheckoutRef = checkout.Reference
+	sub.CheckoutURL = checkout.URL
+	if err := s.repo.Update(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetForUser returns one of the user's subscriptions
+func (s *SubscriptionService) GetForUser(userID, id uint) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, repository.ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+// ListMine returns a user's subscriptions, newest first
+func (s *SubscriptionService) ListMine(userID uint, page, limit int) ([]domain.Subscription, int64, error) {
+	return s.repo.List(repository.SubscriptionFilter{UserID: userID}, page, limit)
+}
+
+// List returns subscriptions matching the filter, newest first
+func (s *SubscriptionService) List(filter repository.SubscriptionFilter, page, limit int) ([]domain.Subscription, int64, error) {
+	return s.repo.List(filter, page, limit)
+}
+
+// Cancel stops one of the user's subscriptions from renewing. Access lasts
+// until the end of the current period. An unfinished checkout is abandoned.
+func (s *SubscriptionService) Cancel(ctx context.Context, userID, id uint) (*domain.Subscription, error) {
+	sub, err := s.GetForUser(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case sub.Status == domain.SubscriptionIncomplete:
+		s.end(sub)
+	case !sub.Status.IsLive():
+		return nil, ErrSubscriptionNotLive
+	case sub.CancelAtPeriodEnd:
+		return sub, nil
+	default:
+		if sub.Provider != s.provider.Name() {
+			return nil, fmt.Errorf("subscription was started with %s, but %s is configured", sub.Provider, s.provider.Name())
+		}
+		if err := s.provider.CancelSubscription(ctx, sub.ProviderRef); err != nil {
+			return nil, fmt.Errorf("cancel subscription: %w", err)
+		}
+		sub.CancelAtPeriodEnd = true
+	}
+
+	if err := s.repo.Update(sub); err != nil {
+		return nil, err
+	}
+	log.Printf("Subscription %d canceled by user %d", sub.ID, userID)
+	return sub, nil
+}
+
+// EndAll ends every running or unfinished subscription of the user, for
+// an account that is being erased. Renewals are cancelled with the
+// provider first, so the customer is not charged again; an error leaves
+// the remaining subscriptions for a retry.
+func (s *SubscriptionService) EndAll(ctx context.Context, userID uint) error {
+	live, err := s.repo.ListLive(userID)
+	if err != nil {
+		return err
+	}
+	incomplete, _, err := s.repo.List(repository.SubscriptionFilter{UserID: userID, Status: domain.SubscriptionIncomplete}, 1, 100)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range append(live, incomplete...) {
+		if sub.Status.IsLive() && !sub.CancelAtPeriodEnd {
+			if sub.Provider != s.provider.Name() {
+				return fmt.Errorf("subscription %d was started with %s, but %s is configured", sub.ID, sub.Provider, s.provider.Name())
+			}
+			if err := s.provider.CancelSubscription(ctx, sub.ProviderRef); err != nil {
+				return fmt.Errorf("cancel subscription %d: %w", sub.ID, err)
+			}
+		}
+
+		s.end(&sub)
+		if err := s.repo.Update(&sub); err != nil {
+			return err
+		}
+		log.Printf("Subscription %d of user %d ended for account erasure", sub.ID, userID)
+		s.events.Publish(ctx, domain.SubscriptionChanged{
+			SubscriptionID:   sub.ID,
+			UserID:           sub.UserID,
+			PlanID:           sub.PlanID,
+			Status:           sub.Status,
+			CurrentPeriodEnd: sub.CurrentPeriodEnd,
+			OccurredAt:       time.Now(),
+		})
+	}
+	return nil
+}
+
+// HasAccess reports whether one of the user's subscriptions covers the
+// course
+func (s *SubscriptionService) HasAccess(userID uint, course *domain.Course) (bool, error) {
+	subs, err := s.repo.ListLive(userID)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	for _, sub := range subs {
+		if sub.Plan != nil && sub.GrantsAccess(now, s.opts.Grace) && sub.Plan.Covers(course) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HandleEvent applies a subscription event from the payment provider and
+// returns the subscription it applied to. Events for subscriptions created
+// outside this platform return repository.ErrSubscriptionNotFound.
+func (s *SubscriptionService) HandleEvent(ctx context.Context, event *payment.Event) (*domain.Subscription, error) {
+	update := event.Subscription
+	sub, err := s.repo.FindByProviderRef(s.provider.Name(), update.Reference)
+	if errors.Is(err, repository.ErrSubscriptionNotFound) && update.SubscriptionID != 0 {
+		// The first event of a subscription only carries our ID
+		sub, err = s.repo.FindByID(update.SubscriptionID)
+		if err == nil && sub.Provider != s.provider.Name() {
+			return nil, repository.ErrSubscriptionNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sub.ProviderRef == "" {
+		sub.ProviderRef = update.Reference
+	}
+	// Money collected is recorded even for a subscription that has ended
+	if event.Type == payment.EventSubscriptionRenewed {
+		if err := s.recordInvoice(ctx, sub, update); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := sub.Status
+	if previous == domain.SubscriptionCanceled {
+		// Providers may deliver events out of order; an ended subscription
+		// is not restarted
+		log.Printf("Ignoring %s for canceled subscription %d", event.Type, sub.ID)
+		return sub, nil
+	}
+
+	switch event.Type {
+	case payment.EventSubscriptionUpdated:
+		sub.Status = domain.SubscriptionStatus(update.Status)
+		sub.CancelAtPeriodEnd = update.CancelAtPeriodEnd
+		if !update.PeriodEnd.IsZero() {
+			end := update.PeriodEnd
+			sub.CurrentPeriodEnd = &end
+			if sub.Status == domain.SubscriptionTrial {
+				sub.TrialEndsAt = &end
+			}
+		}
+		if sub.Status == domain.SubscriptionCanceled {
+			s.end(sub)
+		}
+	case payment.EventSubscriptionRenewed:
+		sub.Status = domain.SubscriptionActive
+		if sub.CurrentPeriodEnd == nil || update.PeriodEnd.After(*sub.CurrentPeriodEnd) {
+			end := update.PeriodEnd
+			sub.CurrentPeriodEnd = &end
+		}
+	case payment.EventSubscriptionPaymentFailed:
+		if sub.Status == domain.SubscriptionTrial || sub.Status == domain.SubscriptionActive {
+			sub.Status = domain.SubscriptionPastDue
+		}
+	}
+
+	if err := s.repo.Update(sub); err != nil {
+		return nil, err
+	}
+
+	if sub.Status != previous || event.Type == payment.EventSubscriptionRenewed {
+		log.Printf("Subscription %d of user %d is %s", sub.ID, sub.UserID, sub.Status)
+		s.events.Publish(ctx, domain.SubscriptionChanged{
+			SubscriptionID:   sub.ID,
+			UserID:           sub.UserID,
+			PlanID:           sub.PlanID,
+			Status:           sub.Status,
+			CurrentPeriodEnd: sub.CurrentPeriodEnd,
+			OccurredAt:       time.Now(),
+		})
+	}
+	if sub.Status != previous {
+		s.notifyStatus(sub, previous)
+	}
+	return sub, nil
+}
+
+// recordInvoice stores a paid invoice of the subscription; free invoices,
+// such as the one starting a trial, are skipped
+func (s *SubscriptionService) recordInvoice(ctx context.Context, sub *domain.Subscription, update *payment.SubscriptionEvent) error {
+	if update.Amount <= 0 || update.InvoiceReference == "" {
+		return nil
+	}
+	invoice := &domain.SubscriptionInvoice{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		PlanID:         sub.PlanID,
+		Provider:       s.provider.Name(),
+		ProviderRef:    update.InvoiceReference,
+		Amount:         update.Amount,
+		Currency:       update.Currency,
+		PaidAt:         time.Now(),
+	}
+	if !update.PeriodEnd.IsZero() {
+		end := update.PeriodEnd
+		invoice.PeriodEnd = &end
+	}
+	recorded, err := s.repo.RecordInvoice(invoice)
+	if err != nil || !recorded {
+		return err
+	}
+	log.Printf("Subscription %d of user %d paid %d %s", sub.ID, sub.UserID, invoice.Amount, invoice.Currency)
+	s.events.Publish(ctx, domain.SubscriptionInvoicePaid{
+		InvoiceID:      invoice.ID,
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Amount:         invoice.Amount,
+		Currency:       invoice.Currency,
+		OccurredAt:     invoice.PaidAt,
+	})
+	return nil
+}
+
+// FakeComplete starts or abandons a subscription checkout of the fake
+// provider by applying the webhook it would send, for local development
+func (s *SubscriptionService) FakeComplete(ctx context.Context, reference string, succeeded bool) (*domain.Subscription, error) {
+	fake, ok := s.provider.(*payment.Fake)
+	if !ok {
+		return nil, ErrFakePaymentDisabled
+	}
+	sub, err := s.repo.FindByCheckoutRef(fake.Name(), reference)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status != domain.SubscriptionIncomplete {
+		return sub, nil
+	}
+	if !succeeded {
+		s.end(sub)
+		if err := s.repo.Update(sub); err != nil {
+			return nil, err
+		}
+		return sub, nil
+	}
+
+	trialDays, err := s.trialDays(sub.UserID, sub.Plan)
+	if err != nil {
+		return nil, err
+	}
+	status, periodEnd := payment.SubscriptionActive, sub.Plan.Interval.Next(time.Now())
+	if trialDays > 0 {
+		status, periodEnd = payment.SubscriptionTrial, time.Now().AddDate(0, 0, trialDays)
+	}
+
+	_, payload, header, err := fake.UpdateSubscription("", sub.ID, status, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyFake(ctx, fake, payload, header)
+}
+
+// FakeRenew charges a running subscription of the fake provider, found by
+// its checkout reference, for its next period, or fails the charge, by
+// applying the webhook the provider would send. Subscriptions set to
+// cancel end instead.
+func (s *SubscriptionService) FakeRenew(ctx context.Context, reference string, succeeded bool) (*domain.Subscription, error) {
+	fake, ok := s.provider.(*payment.Fake)
+	if !ok {
+		return nil, ErrFakePaymentDisabled
+	}
+	sub, err := s.repo.FindByCheckoutRef(fake.Name(), reference)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.Status.IsLive() {
+		return nil, ErrSubscriptionNotLive
+	}
+
+	start := time.Now()
+	if sub.CurrentPeriodEnd != nil {
+		start = *sub.CurrentPeriodEnd
+	}
+
+	var payload []byte
+	var header http.Header
+	if sub.CancelAtPeriodEnd {
+		_, payload, header, err = fake.UpdateSubscription(sub.ProviderRef, sub.ID, payment.SubscriptionCanceled, start)
+	} else {
+		payload, header, err = fake.Renew(sub.ProviderRef, sub.ID, sub.Plan.Price, sub.Plan.Currency, sub.Plan.Interval.Next(start), succeeded)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.applyFake(ctx, fake, payload, header)
+}
+
+func (s *SubscriptionService) applyFake(ctx context.Context, fake *payment.Fake, payload []byte, header http.Header) (*domain.Subscription, error) {
+	event, err := fake.ParseWebhook(payload, header)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.HandleEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(sub.ID)
+}
+
+// Run ends subscriptions that were set to cancel once their period is
+// over, in case the provider's final event was missed
+func (s *SubscriptionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			subs, err := s.repo.ListEnded(time.Now())
+			if err != nil {
+				log.Printf("failed to load ended subscriptions: %v", err)
+				continue
+			}
+			for i := range subs {
+				sub := &subs[i]
+				previous := sub.Status
+				s.end(sub)
+				if err := s.repo.Update(sub); err != nil {
+					log.Printf("failed to end subscription %d: %v", sub.ID, err)
+					continue
+				}
+				s.events.Publish(ctx, domain.SubscriptionChanged{
+					SubscriptionID:   sub.ID,
+					UserID:           sub.UserID,
+					PlanID:           sub.PlanID,
+					Status:           sub.Status,
+					CurrentPeriodEnd: sub.CurrentPeriodEnd,
+					OccurredAt:       *sub.CanceledAt,
+				})
+				s.notifyStatus(sub, previous)
+			}
+		}
+	}
+}
+
+// trialDays returns the trial the user gets on the plan. Only customers
+// who never had a subscription get one.
+func (s *SubscriptionService) trialDays(userID uint, plan *domain.Plan) (int, error) {
+	if plan.TrialDays == 0 {
+		return 0, nil
+	}
+	started, err := s.repo.HasStarted(userID)
+	if err != nil {
+		return 0, err
+	}
+	if started {
+		return 0, nil
+	}
+	return plan.TrialDays, nil
+}
+
+// end marks a subscription canceled
+func (s *SubscriptionService) end(sub *domain.Subscription) {
+	sub.Status = domain.SubscriptionCanceled
+	if sub.CanceledAt == nil {
+		now := time.Now()
+		sub.CanceledAt = &now
+	}
+}
+
+// notifyStatus tells the subscriber about a status change. Checkouts that
+// were never finished are not worth a notification.
+func (s *SubscriptionService) notifyStatus(sub *domain.Subscription, previous domain.SubscriptionStatus) {
+	plan := "subscription"
+	if sub.Plan != nil {
+		plan = fmt.Sprintf("%q subscription", sub.Plan.Name)
+	}
+
+	switch {
+	case previous == domain.SubscriptionIncomplete && sub.Status.IsLive():
+		s.notify(sub.UserID, "Subscription started", fmt.Sprintf("Thanks for subscribing! The courses in your %s are now open to you.", plan))
+	case sub.Status == domain.SubscriptionPastDue:
+		s.notify(sub.UserID, "Subscription payment failed",
+			fmt.Sprintf("We could not renew your %s. Please update your payment details to keep your access.", plan))
+	case sub.Status == domain.SubscriptionCanceled && previous != domain.SubscriptionIncomplete:
+		s.notify(sub.UserID, "Subscription ended", fmt.Sprintf("Your %s has ended.", plan))
+	}
+}
+
+// notify sends a best-effort subscription notification
+func (s *SubscriptionService) notify(userID uint, title, message string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.notifClient.SendNotification(ctx, int64(userID), string(domain.NotificationTypeSubscription), title, message); err != nil {
+		log.Printf("failed to notify user %d: %v", userID, err)
+	}
+}
+
+// applyPlan validates a request and copies it onto the plan
+func applyPlan(plan *domain.Plan, req PlanRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPlan)
+	}
+	if !req.Interval.IsValid() {
+		return fmt.Errorf("%w: interval must be month or year", ErrInvalidPlan)
+	}
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if req.Price <= 0 || !isCurrencyCode(currency) {
+		return fmt.Errorf("%w: plans need a positive price and a currency", ErrInvalidPlan)
+	}
+	if req.TrialDays < 0 || req.TrialDays > 365 {
+		return fmt.Errorf("%w: trial_days must be between 0 and 365", ErrInvalidPlan)
+	}
+	for _, id := range req.CategoryIDs {
+		if id <= 0 {
+			return fmt.Errorf("%w: invalid category %d", ErrInvalidPlan, id)
+		}
+	}
+
+	plan.Name = name
+	plan.Description = strings.TrimSpace(req.Description)
+	plan.Interval = req.Interval
+	plan.Price = req.Price
+	plan.Currency = currency
+	plan.TrialDays = req.TrialDays
+	plan.CategoryIDs = pq.Int64Array(req.CategoryIDs)
+	if req.Active != nil {
+		plan.Active = *req.Active
+	}
+	return nil
+}
+
+func subscriptionURL(template string, id uint) string {
+	return strings.ReplaceAll(template, "{SUBSCRIPTION_ID}", strconv.FormatUint(uint64(id), 10))
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+)
+
+var (
+	ErrUnknownTrashType = errors.New("trash type must be users, courses or lessons")
+	ErrCourseInTrash    = errors.New("the lesson's course is in the trash; restore the course first")
+	ErrNotInTrash       = errors.New("item is not in the trash")
+)
+
+// Trash types, as used in the trash routes
+const (
+	TrashUsers   = "users"
+	TrashCourses = "courses"
+	TrashLessons = "lessons"
+)
+
+// TrashItem is a soft-deleted user, course or lesson
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CourseID  uint      `json:"course_id,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashOptions configures the trash
+type TrashOptions struct {
+	// Retention is how long deleted items can be restored before they and
+	// their media are removed for good
+	Retention time.Duration
+}
+
+// AvatarStore deletes avatars kept in cloud storage
+type AvatarStore interface {
+	Delete(ctx context.Context, filename string) error
+}
+
+// TrashService restores soft-deleted users, courses and lessons and purges
+// them, with their uploaded media, once the retention period has passed
+type TrashService struct {
+	repo       repository.TrashRepository
+	courseRepo repository.CourseRepository
+	avatars    AvatarStore
+	opts       TrashOptions
+}
+
+// NewTrashService creates a new trash service. avatars is nil when avatars
+// are stored locally.
+func NewTrashService(repo repository.TrashRepository, courseRepo repository.CourseRepository, avatars AvatarStore, opts TrashOptions) *TrashService {
+	return &TrashService{
+		repo:       repo,
+		courseRepo: courseRepo,
+		avatars:    avatars,
+		opts:       opts,
+	}
+}
+
+// List returns the trashed items of one type, most recently deleted first
+func (s *TrashService) List(kind string, page, limit int) ([]TrashItem, int64, error) {
+	var items []TrashItem
+	switch kind {
+	case TrashUsers:
+		users, total, err := s.repo.ListUsers(page, limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, u := range users {
+			items = append(items, s.item(TrashUsers, u.ID, u.Name, u.DeletedAt, func(i *TrashItem) { i.Email = u.Email }))
+		}
+		return items, total, nil
+	case TrashCourses:
+		courses, total, err := s.repo.ListCourses(page, limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, c := range courses {
+			items = append(items, s.item(TrashCourses, uint(c.ID), c.Title, c.DeletedAt, nil))
+		}
+		return items, total, nil
+	case TrashLessons:
+		lessons, total, err := s.repo.ListLessons(page, limit)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, l := range lessons {
+			items = append(items, s.item(TrashLessons, l.ID, l.Title, l.DeletedAt, func(i *TrashItem) { i.CourseID = l.CourseID }))
+		}
+		return items, total, nil
+	default:
+		return nil, 0, ErrUnknownTrashType
+	}
+}
+
+func (s *TrashService) item(kind string, id uint, name string, deletedAt gorm.DeletedAt, fill func(*TrashItem)) TrashItem {
+	item := TrashItem{
+		Type:      kind,
+		ID:        id,
+		Name:      name,
+		DeletedAt: deletedAt.Time,
+		PurgeAt:   deletedAt.Time.Add(s.opts.Retention),
+	}
+	if fill != nil {
+		fill(&item)
+	}
+	return item
+}
+
+// Restore takes an item out of the trash. A lesson can only be restored
+// while its course is not in the trash.
+func (s *TrashService) Restore(kind string, id uint) error {
+	var err error
+	switch kind {
+	case TrashUsers:
+		err = s.repo.RestoreUser(id)
+	case TrashCourses:
+		err = s.repo.RestoreCourse(id)
+	case TrashLessons:
+		var lesson *domain.Lesson
+		if lesson, err = s.repo.FindLesson(id); err != nil {
+			break
+		}
+		if _, err := s.courseRepo.FindByID(int64(lesson.CourseID)); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCourseInTrash
+			}
+			return err
+		}
+		err = s.repo.RestoreLesson(id)
+	default:
+		return ErrUnknownTrashType
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotInTrash
+	}
+	return err
+}
+
+// Purge permanently deletes an item that is in the trash, without waiting
+// for the retention period
+func (s *TrashService) Purge(kind string, id uint) error {
+	var err error
+	switch kind {
+	case TrashUsers:
+		var user *domain.User
+		if user, err = s.repo.FindUser(id); err == nil {
+			err = s.purgeUser(*user)
+		}
+	case TrashCourses:
+		var course *domain.Course
+		if course, err = s.repo.FindCourse(id); err == nil {
+			err = s.purgeCourse(*course)
+		}
+	case TrashLessons:
+		var lesson *domain.Lesson
+		if lesson, err = s.repo.FindLesson(id); err == nil {
+			err = s.purgeLesson(*lesson)
+		}
+	default:
+		return ErrUnknownTrashType
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotInTrash
+	}
+	return err
+}
+
+// PurgeExpired permanently deletes everything trashed longer than the
+// retention period
+func (s *TrashService) PurgeExpired() error {
+	before := time.Now().Add(-s.opts.Retention)
+
+	lessons, err := s.repo.ExpiredLessons(before)
+	if err != nil {
+		return err
+	}
+	for _, lesson := range lessons {
+		if err := s.purgeLesson(lesson); err != nil {
+			log.Printf("failed to purge lesson %d: %v", lesson.ID, err)
+		}
+	}
+
+	courses, err := s.repo.ExpiredCourses(before)
+	if err != nil {
+		return err
+	}
+	for _, course := range courses {
+		if err := s.purgeCourse(course); err != nil {
+			log.Printf("failed to purge course %d: %v", course.ID, err)
+		}
+	}
+
+	users, err := s.repo.ExpiredUsers(before)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := s.purgeUser(user); err != nil {
+			log.Printf("failed to purge user %d: %v", user.ID, err)
+		}
+	}
+	return nil
+}
+
+// Run purges expired items every interval until ctx is cancelled
+func (s *TrashService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.PurgeExpired(); err != nil {
+				log.Printf("failed to purge trash: %v", err)
+			}
+		}
+	}
+}
+
+// Media is only removed once the rows referencing it are gone
+
+func (s *TrashService) purgeLesson(lesson domain.Lesson) error {
+	if err := s.repo.PurgeLesson(lesson.ID); err != nil {
+		return err
+	}
+	removeUpload(lesson.VideoURL)
+	removeUpload(lesson.FileURL)
+	return nil
+}
+
+func (s *TrashService) purgeCourse(course domain.Course) error {
+	media, err := s.courseMedia(course)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.PurgeCourse(uint(course.ID)); err != nil {
+		return err
+	}
+	for _, path := range media {
+		removeUpload(path)
+	}
+	return nil
+}
+
+// purgeUser also removes the courses the user teaches, which the database
+// deletes along with the account
+func (s *TrashService) purgeUser(user domain.User) error {
+	courses, err := s.repo.CoursesByTeacher(user.ID)
+	if err != nil {
+		return err
+	}
+	var media []string
+	for _, course := range courses {
+		paths, err := s.courseMedia(course)
+		if err != nil {
+			return err
+		}
+		media = append(media, paths...)
+	}
+
+	if err := s.repo.PurgeUser(user.ID); err != nil {
+		return err
+	}
+
+	for _, path := range media {
+		removeUpload(path)
+	}
+	if user.Avatar != nil && *user.Avatar != "" {
+		if s.avatars != nil {
+			if err := s.avatars.Delete(context.Background(), filepath.Base(*user.Avatar)); err != nil {
+				log.Printf("failed to delete avatar of user %d: %v", user.ID, err)
+			}
+		} else {
+			removeUpload(*user.Avatar)
+		}
+	}
+	return nil
+}
+
+// courseMedia lists the thumbnail and the files of every lesson, including
+// lessons in the trash
+func (s *TrashService) courseMedia(course domain.Course) ([]string, error) {
+	lessons, err := s.repo.LessonsByCourse(uint(course.ID))
+	if err != nil {
+		return nil, err
+	}
+	media := []string{course.Thumbnail}
+	for _, lesson := range lessons {
+		media = append(media, lesson.VideoURL, lesson.FileURL)
+	}
+	return media, nil
+}
+
+// removeUpload deletes a file served from the local uploads directory.
+// Anything else, such as an external URL or a path leading out of the
+// directory, is left alone.
+func removeUpload(url string) {
+	path, ok := LocalUploadPath(url)
+	if !ok {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to delete file %s: %v", path, err)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// uploadsDir is the local directory served under /uploads
+const uploadsDir = "uploads"
+
+// LocalUploadPath returns the file behind a /uploads URL. It reports false
+// for any other value, including paths that climb out of the uploads
+// directory, so client supplied URLs can never name other files.
+func LocalUploadPath(rawURL string) (string, bool) {
+	if !strings.HasPrefix(rawURL, "/"+uploadsDir+"/") {
+		return "", false
+	}
+	path := filepath.Clean(strings.TrimPrefix(rawURL, "/"))
+	if !strings.HasPrefix(path, uploadsDir+string(filepath.Separator)) {
+		return "", false
+	}
+	return path, true
+}
+
+// IsMediaURL reports whether a client supplied media URL is a local upload
+// or a file in cloud storage
+func IsMediaURL(rawURL string) bool {
+	if _, ok := LocalUploadPath(rawURL); ok {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// NewUploadName returns an unguessable file name in a subdirectory of the
+// uploads directory, such as "videos/3f2c...9a.mp4"
+func NewUploadName(dir, ext string) (string, error) {
+	name, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	return dir + "/" + name + ext, nil
+}
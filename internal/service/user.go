@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
 	"elearning/internal/domain"
 	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
 	"elearning/pkg/hash"
 	"errors"
+	"time"
 )
 
 var (
@@ -27,10 +30,13 @@ type UserService interface {
 
 type userService struct {
 	userRepo repository.UserRepository
+	sessions *SessionService
+	events   *eventbus.Bus
+	roles    *RBACService
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, sessions *SessionService, events *eventbus.Bus, roles *RBACService) UserService {
+	return &userService{userRepo: userRepo, sessions: sessions, events: events, roles: roles}
 }
 
 func (s *userService) GetProfile(userID uint) (*domain.User, error) {
@@ -90,16 +96,23 @@ func (s *userService) CreateUser(name, email, password, role string) (*domain.Us
 		return nil, ErrPasswordTooShort
 	}
 
+	if err := s.roles.RoleExists(role); err != nil {
+		return nil, err
+	}
+
 	hashedPassword, err := hash.HashPassword(password)
 	if err != nil {
 		return nil, err
 	}
 
+	// Accounts created by an admin are trusted and start out verified
+	now := time.Now()
 	user := &domain.User{
-		Name:     name,
-		Email:    email,
-		Password: hashedPassword,
-		Role:     domain.UserRole(role),
+		Name:       name,
+		Email:      email,
+		Password:   hashedPassword,
+		Role:       domain.UserRole(role),
+		VerifiedAt: &now,
 	}
 
 	err = s.userRepo.Create(user)
@@ -107,6 +120,8 @@ func (s *userService) CreateUser(name, email, password, role string) (*domain.Us
 		return nil, err
 	}
 
+	s.events.Publish(context.Background(), userCreatedEvent(user, "admin"))
+
 	user.Password = ""
 	return user, nil
 }
@@ -125,6 +140,10 @@ func (s *userService) UpdateUser(userID uint, name, email, role string) error {
 		}
 	}
 
+	if err := s.roles.RoleExists(role); err != nil {
+		return err
+	}
+
 	user.Name = name
 	user.Email = email
 	user.Role = domain.UserRole(role)
@@ -132,6 +151,22 @@ func (s *userService) UpdateUser(userID uint, name, email, role string) error {
 	return s.userRepo.Update(user)
 }
 
+// DeleteUser moves the account to the trash and logs it out everywhere
 func (s *userService) DeleteUser(userID uint) error {
-	return s.userRepo.Delete(userID)
+	if err := s.userRepo.Delete(userID); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(userID)
+}
+
+// userCreatedEvent builds the UserCreated event for a newly stored user
+func userCreatedEvent(user *domain.User, source string) domain.UserCreated {
+	return domain.UserCreated{
+		UserID:     user.ID,
+		Name:       user.Name,
+		Email:      user.Email,
+		Role:       user.Role,
+		Source:     source,
+		OccurredAt: time.Now(),
+	}
 }
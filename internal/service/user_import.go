@@ -0,0 +1,533 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/grpcclient"
+	"elearning/pkg/hash"
+)
+
+var (
+	ErrImportEmpty     = errors.New("the file has no rows to import")
+	ErrImportTooLarge  = errors.New("the file has too many rows")
+	ErrImportHeader    = errors.New("the first line must be a header with name and email columns")
+	ErrImportMalformed = errors.New("the file is not valid CSV")
+	ErrImportInvalid   = errors.New("the file has invalid rows; fix them and upload it again")
+)
+
+const (
+	// importRowBatch is how many rows the worker loads at a time
+	importRowBatch = 100
+	// importCourseSeparator separates course IDs in the courses column
+	importCourseSeparator = ";"
+)
+
+// UserImportOptions configures bulk user imports
+type UserImportOptions struct {
+	MaxRows int
+}
+
+// UserImportReport is the result of validating an import file
+type UserImportReport struct {
+	Total          int                  `json:"total"`
+	Valid          int                  `json:"valid"`
+	Invalid        int                  `json:"invalid"`
+	IgnoredColumns []string             `json:"ignored_columns,omitempty"`
+	Errors         []UserImportRowError `json:"errors"`
+}
+
+// UserImportRowError lists the problems with one row of an import file
+type UserImportRowError struct {
+	Line   int      `json:"line"`
+	Email  string   `json:"email,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+// UserImportService creates accounts in bulk from CSV files and exports
+// accounts to CSV.
+//
+// Import files have a header line with the columns name, email and
+// optionally role (student, teacher or admin; default student) and courses
+// (course IDs separated by ";" to enroll the account in). Imported accounts
+// start out verified with a random password; they choose their own through
+// the welcome email or "forgot password".
+type UserImportService struct {
+	repo           repository.UserImportRepository
+	userRepo       repository.UserRepository
+	courseRepo     repository.CourseRepository
+	rbac           *RBACService
+	enrollments    *EnrollmentService
+	passwordResets *PasswordResetService
+	notifClient    *grpcclient.NotificationClient
+	events         *eventbus.Bus
+	opts           UserImportOptions
+}
+
+// NewUserImportService creates a new user import service
+func NewUserImportService(
+	repo repository.UserImportRepository,
+	userRepo repository.UserRepository,
+	courseRepo repository.CourseRepository,
+	rbac *RBACService,
+	enrollments *EnrollmentService,
+	passwordResets *PasswordResetService,
+	notifClient *grpcclient.NotificationClient,
+	events *eventbus.Bus,
+	opts UserImportOptions,
+) *UserImportService {
+	return &UserImportService{
+		repo:           repo,
+		userRepo:       userRepo,
+		courseRepo:     courseRepo,
+		rbac:           rbac,
+		enrollments:    enrollments,
+		passwordResets: passwordResets,
+		notifClient:    notifClient,
+		events:         events,
+		opts:           opts,
+	}
+}
+
+// Validate checks an import file without creating anything
+func (s *UserImportService) Validate(r io.Reader) (*UserImportReport, error) {
+	_, report, err := s.parse(r)
+	return report, err
+}
+
+// Start validates an import file and, when every row is valid, queues the
+// import. Otherwise it returns ErrImportInvalid with the report.
+func (s *UserImportService) Start(adminID uint, fileName string, r io.Reader, sendWelcome bool) (*domain.UserImport, *UserImportReport, error) {
+	rows, report, err := s.parse(r)
+	if err != nil {
+		return nil, report, err
+	}
+	if report.Invalid > 0 {
+		return nil, report, ErrImportInvalid
+	}
+
+	userImport := &domain.UserImport{
+		CreatedBy:   adminID,
+		FileName:    fileName,
+		Status:      domain.UserImportPending,
+		SendWelcome: sendWelcome,
+		Total:       len(rows),
+		Rows:        rows,
+	}
+	if err := s.repo.Create(userImport); err != nil {
+		return nil, report, err
+	}
+	userImport.Rows = nil
+	return userImport, report, nil
+}
+
+// Get returns an import with its progress
+func (s *UserImportService) Get(id uint) (*domain.UserImport, error) {
+	return s.repo.FindByID(id)
+}
+
+// List returns imports, newest first
+func (s *UserImportService) List(page, limit int) ([]domain.UserImport, int64, error) {
+	return s.repo.List(page, limit)
+}
+
+// ListRows returns the rows of an import, optionally only those with status
+func (s *UserImportService) ListRows(id uint, status domain.UserImportRowStatus, page, limit int) ([]domain.UserImportRow, int64, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListRows(id, status, page, limit)
+}
+
+// parse reads and validates an import file. Rows are returned pending,
+// ready to be stored.
+func (s *UserImportService) parse(r io.Reader) ([]domain.UserImportRow, *UserImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, ErrImportEmpty
+		}
+		return nil, nil, ErrImportMalformed
+	}
+
+	report := &UserImportReport{Errors: []UserImportRowError{}}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheet programs often start UTF-8 files with a BOM
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "name", "email", "role", "courses":
+			columns[name] = i
+		default:
+			report.IgnoredColumns = append(report.IgnoredColumns, name)
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, nil, ErrImportHeader
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, nil, ErrImportHeader
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []domain.UserImportRow
+	var rowErrors [][]string
+	firstLine := map[string]int{}
+	courseErrors := map[uint]string{}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrImportMalformed, err)
+		}
+		if len(rows) == s.opts.MaxRows {
+			return nil, nil, fmt.Errorf("%w: at most %d rows per file", ErrImportTooLarge, s.opts.MaxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := domain.UserImportRow{
+			Line:   line,
+			Name:   field(record, "name"),
+			Email:  field(record, "email"),
+			Role:   domain.UserRole(strings.ToLower(field(record, "role"))),
+			Status: domain.UserImportRowPending,
+		}
+		var problems []string
+
+		if row.Name == "" {
+			problems = append(problems, "name is required")
+		} else if len(row.Name) > 100 {
+			problems = append(problems, "name is longer than 100 characters")
+		}
+
+		switch {
+		case row.Email == "":
+			problems = append(problems, "email is required")
+		case len(row.Email) > 100:
+			problems = append(problems, "email is longer than 100 characters")
+		case !validEmail(row.Email):
+			problems = append(problems, "email is not a valid address")
+		default:
+			key := strings.ToLower(row.Email)
+			if first, ok := firstLine[key]; ok {
+				problems = append(problems, fmt.Sprintf("duplicate email, first used on line %d", first))
+			} else {
+				firstLine[key] = line
+			}
+		}
+
+		if row.Role == "" {
+			row.Role = domain.RoleStudent
+		}
+		// Custom roles can be assigned as well as the built-in ones
+		if err := s.rbac.RoleExists(string(row.Role)); errors.Is(err, ErrUnknownRole) {
+			problems = append(problems, fmt.Sprintf("unknown role %q", row.Role))
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		courseIDs, courseProblems := s.parseCourses(field(record, "courses"), courseErrors)
+		row.CourseIDs = courseIDs
+		problems = append(problems, courseProblems...)
+
+		rows = append(rows, row)
+		rowErrors = append(rowErrors, problems)
+	}
+	if len(rows) == 0 {
+		return nil, nil, ErrImportEmpty
+	}
+
+	emails := make([]string, 0, len(firstLine))
+	for email := range firstLine {
+		emails = append(emails, email)
+	}
+	existing, err := s.userRepo.ExistingEmails(emails)
+	if err != nil {
+		return nil, nil, err
+	}
+	registered := make(map[string]bool, len(existing))
+	for _, email := range existing {
+		registered[email] = true
+	}
+
+	for i, row := range rows {
+		problems := rowErrors[i]
+		if registered[strings.ToLower(row.Email)] {
+			problems = append(problems, "email is already registered")
+		}
+		if len(problems) > 0 {
+			report.Errors = append(report.Errors, UserImportRowError{Line: row.Line, Email: row.Email, Errors: problems})
+		}
+	}
+	report.Total = len(rows)
+	report.Invalid = len(report.Errors)
+	report.Valid = report.Total - report.Invalid
+	return rows, report, nil
+}
+
+// parseCourses checks the courses column and returns it normalized. Course
+// lookups are cached in checked, keyed by ID, with "" for usable courses.
+func (s *UserImportService) parseCourses(value string, checked map[uint]string) (string, []string) {
+	if value == "" {
+		return "", nil
+	}
+
+	var ids []string
+	var problems []string
+	seen := map[uint]bool{}
+	for _, part := range strings.Split(value, importCourseSeparator) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || id == 0 {
+			problems = append(problems, fmt.Sprintf("invalid course ID %q", part))
+			continue
+		}
+		courseID := uint(id)
+		if seen[courseID] {
+			continue
+		}
+		seen[courseID] = true
+
+		problem, ok := checked[courseID]
+		if !ok {
+			course, err := s.courseRepo.FindByID(int64(courseID))
+			switch {
+			case err != nil:
+				problem = fmt.Sprintf("course %d not found", courseID)
+			case !course.IsPublished:
+				problem = fmt.Sprintf("course %d is not published", courseID)
+			case course.RequiresPayment():
+				problem = fmt.Sprintf("course %d is a paid course", courseID)
+			}
+			checked[courseID] = problem
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+			continue
+		}
+		ids = append(ids, part)
+	}
+
+	normalized := strings.Join(ids, importCourseSeparator)
+	if len(normalized) > 255 {
+		problems = append(problems, "too many courses")
+	}
+	return normalized, problems
+}
+
+// validEmail reports whether s is a bare email address
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// Run processes queued imports every interval until ctx is cancelled.
+// Imports interrupted by a restart resume with their remaining rows.
+func (s *UserImportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processImports(ctx)
+		}
+	}
+}
+
+func (s *UserImportService) processImports(ctx context.Context) {
+	for ctx.Err() == nil {
+		userImport, err := s.repo.NextUnfinished()
+		if errors.Is(err, repository.ErrUserImportNotFound) {
+			return
+		}
+		if err != nil {
+			log.Printf("failed to load user import: %v", err)
+			return
+		}
+		if err := s.process(ctx, userImport); err != nil {
+			log.Printf("failed to process user import %d: %v", userImport.ID, err)
+			return
+		}
+	}
+}
+
+func (s *UserImportService) process(ctx context.Context, userImport *domain.UserImport) error {
+	if userImport.Status == domain.UserImportPending {
+		now := time.Now()
+		userImport.Status = domain.UserImportProcessing
+		userImport.StartedAt = &now
+		if err := s.repo.Update(userImport); err != nil {
+			return err
+		}
+	}
+
+	for {
+		rows, err := s.repo.PendingRows(userImport.ID, importRowBatch)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for i := range rows {
+			if ctx.Err() != nil {
+				return s.repo.Update(userImport)
+			}
+			if err := s.importRow(ctx, userImport, &rows[i]); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.Update(userImport); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	userImport.Status = domain.UserImportCompleted
+	userImport.CompletedAt = &now
+	if err := s.repo.Update(userImport); err != nil {
+		return err
+	}
+	log.Printf("User import %d completed: %d created, %d failed", userImport.ID, userImport.Created, userImport.Failed)
+	return nil
+}
+
+// importRow creates one account. Failures specific to the row are recorded
+// on it; only storage errors are returned.
+func (s *UserImportService) importRow(ctx context.Context, userImport *domain.UserImport, row *domain.UserImportRow) error {
+	secret, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+	passwordHash, err := hash.HashPassword(secret)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		Name:       row.Name,
+		Email:      row.Email,
+		Password:   passwordHash,
+		Role:       row.Role,
+		VerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		// The email may have been registered since the file was validated
+		row.Status = domain.UserImportRowFailed
+		row.Error = err.Error()
+		userImport.Failed++
+		return s.repo.UpdateRow(row)
+	}
+	s.events.Publish(context.Background(), userCreatedEvent(user, "import"))
+
+	if userImport.SendWelcome {
+		s.welcome(ctx, user)
+	}
+
+	var enrollErrors []string
+	for _, part := range strings.Split(row.CourseIDs, importCourseSeparator) {
+		if part == "" {
+			continue
+		}
+		courseID, _ := strconv.ParseUint(part, 10, 32)
+		if _, err := s.enrollments.Enroll(user.ID, uint(courseID)); err != nil {
+			enrollErrors = append(enrollErrors, fmt.Sprintf("course %d: %v", courseID, err))
+			continue
+		}
+		userImport.Enrolled++
+	}
+
+	row.Status = domain.UserImportRowCreated
+	row.UserID = &user.ID
+	if len(enrollErrors) > 0 {
+		row.Error = "enrollment failed: " + strings.Join(enrollErrors, "; ")
+	}
+	userImport.Created++
+	return s.repo.UpdateRow(row)
+}
+
+// welcome sends a best-effort welcome notification and an email to choose
+// a password
+func (s *UserImportService) welcome(ctx context.Context, user *domain.User) {
+	notifCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := s.notifClient.SendNotification(notifCtx, int64(user.ID), string(domain.NotificationTypeAccount), "Welcome", "Your account has been created. Check your email to set your password."); err != nil {
+		log.Printf("failed to send welcome notification to user %d: %v", user.ID, err)
+	}
+	if err := s.passwordResets.SendWelcome(ctx, user); err != nil {
+		log.Printf("failed to send welcome email to user %d: %v", user.ID, err)
+	}
+}
+
+// Export writes the users matching filter as CSV. The name, email and role
+// columns can be imported again; values a spreadsheet would run as a
+// formula are escaped.
+func (s *UserImportService) Export(filter repository.UserFilter, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "name", "email", "role", "status", "verified_at", "created_at"}); err != nil {
+		return err
+	}
+
+	err := s.userRepo.Each(filter, func(users []domain.User) error {
+		for _, u := range users {
+			verifiedAt := ""
+			if u.VerifiedAt != nil {
+				verifiedAt = u.VerifiedAt.UTC().Format(time.RFC3339)
+			}
+			record := []string{
+				strconv.FormatUint(uint64(u.ID), 10),
+				csvCell(u.Name),
+				csvCell(u.Email),
+				csvCell(string(u.Role)),
+				string(u.Status),
+				verifiedAt,
+				u.CreatedAt.UTC().Format(time.RFC3339),
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+)
+
+// tokenRequestThrottled reports whether a user has asked for too many tokens
+// of a purpose: at most one per interval and at most limit per hour
+func tokenRequestThrottled(repo repository.UserTokenRepository, userID uint, purpose domain.TokenPurpose, interval time.Duration, limit int) (bool, error) {
+	now := time.Now()
+
+	recent, err := repo.CountSince(userID, purpose, now.Add(-interval))
+	if err != nil {
+		return false, err
+	}
+	if recent > 0 {
+		return true, nil
+	}
+
+	if limit <= 0 {
+		return false, nil
+	}
+	hourly, err := repo.CountSince(userID, purpose, now.Add(-time.Hour))
+	if err != nil {
+		return false, err
+	}
+	return hourly >= int64(limit), nil
+}
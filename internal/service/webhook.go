@@ -0,0 +1,542 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"elearning/internal/domain"
+	"elearning/internal/repository"
+	"elearning/pkg/eventbus"
+	"elearning/pkg/webhook"
+)
+
+var (
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidWebhookEvent = errors.New("unknown webhook event")
+	ErrNoWebhookEvents     = errors.New("at least one event is required")
+)
+
+const (
+	// webhookResponseLimit caps how much of a receiver's response body is logged
+	webhookResponseLimit = 2048
+	// webhookBatchSize caps the number of deliveries processed per poll
+	webhookBatchSize = 50
+	// webhookConcurrency caps the deliveries sent at once, so a slow
+	// receiver holds up one sender rather than the whole batch
+	webhookConcurrency = 8
+	// webhookClaimMargin is added to the request timeout to lease claimed
+	// deliveries; a delivery left unrecorded, e.g. by a crash, is retried
+	// once the lease runs out
+	webhookClaimMargin = time.Minute
+	// webhookMaxBackoff caps the delay between two attempts
+	webhookMaxBackoff = 6 * time.Hour
+)
+
+// WebhookOptions configures delivery behaviour
+type WebhookOptions struct {
+	RequestTimeout time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	PollInterval   time.Duration
+}
+
+// WebhookService manages webhook subscriptions and delivers events to them.
+//
+// Dispatch records one delivery per matching subscription; a background
+// worker started with Run sends due deliveries and reschedules failures with
+// exponential backoff. Because pending deliveries live in the database they
+// survive restarts. Each instance claims the deliveries it sends, so
+// several instances can run the worker without sending one twice.
+type WebhookService struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	opts   WebhookOptions
+	wake   chan struct{}
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo repository.WebhookRepository, opts WebhookOptions) *WebhookService {
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = 10 * time.Second
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = 30 * time.Second
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+
+	return &WebhookService{
+		repo: repo,
+		client: &http.Client{
+			Timeout: opts.RequestTimeout,
+			// Receivers must answer directly; following redirects would let a
+			// subscription point somewhere other than the configured URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// CreateWebhookRequest represents a new webhook subscription
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=500"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description" binding:"max=255"`
+	// Secret is generated when omitted
+	Secret string `json:"secret" binding:"omitempty,min=16,max=100"`
+}
+
+// UpdateWebhookRequest represents changes to a webhook subscription
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,max=500"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// CreateWebhookResponse includes the signing secret, which is only shown once
+type CreateWebhookResponse struct {
+	*domain.Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookPayload is the JSON body sent to receivers
+type WebhookPayload struct {
+	ID        string              `json:"id"`
+	Event     domain.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      interface{}         `json:"data"`
+}
+
+// Create registers a new webhook subscription
+func (s *WebhookService) Create(createdBy uint, req CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := validateWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = randomHex(24); err != nil {
+			return nil, err
+		}
+		secret = "whsec_" + secret
+	}
+
+	hook := &domain.Webhook{
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      events,
+		Description: req.Description,
+		IsActive:    true,
+		CreatedBy:   createdBy,
+	}
+	if err := s.repo.Create(hook); err != nil {
+		return nil, err
+	}
+
+	return &CreateWebhookResponse{Webhook: hook, Secret: secret}, nil
+}
+
+// List returns all webhook subscriptions
+func (s *WebhookService) List() ([]domain.Webhook, error) {
+	return s.repo.FindAll()
+}
+
+// Get returns a webhook subscription
+func (s *WebhookService) Get(id uint) (*domain.Webhook, error) {
+	return s.repo.FindByID(id)
+}
+
+// Update changes a webhook subscription
+func (s *WebhookService) Update(id uint, req UpdateWebhookRequest) (*domain.Webhook, error) {
+	hook, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		events, err := validateWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		hook.Events = events
+	}
+	if req.Description != nil {
+		hook.Description = *req.Description
+	}
+	if req.IsActive != nil {
+		hook.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Update(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Delete removes a webhook subscription and its delivery log
+func (s *WebhookService) Delete(id uint) error {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// ListDeliveries returns the delivery log of a webhook
+func (s *WebhookService) ListDeliveries(webhookID uint, status string, page, limit int) ([]domain.WebhookDelivery, int64, error) {
+	if _, err := s.repo.FindByID(webhookID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.FindDeliveries(webhookID, status, page, limit)
+}
+
+// Redeliver queues a fresh delivery of a previously sent payload. The
+// original delivery is kept in the log; the new one references it.
+func (s *WebhookService) Redeliver(webhookID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	original, err := s.repo.FindDelivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &domain.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        domain.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+		RedeliveryOf:  &original.ID,
+	}
+	if err := s.repo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	s.notify()
+	return delivery, nil
+}
+
+// Subscribe maps domain events on the bus to outbound webhook events
+func (s *WebhookService) Subscribe(bus *eventbus.Bus) {
+	eventbus.Subscribe(bus, "webhooks", eventbus.Async, func(ctx context.Context, e domain.UserEnrolled) error {
+		s.Dispatch(domain.WebhookEventEnrollmentCreated, map[string]interface{}{
+			"enrollment_id": e.EnrollmentID,
+			"user_id":       e.UserID,
+			"course_id":     e.CourseID,
+			"status":        e.Status,
+			"enrolled_at":   e.EnrolledAt,
+		})
+		return nil
+	})
+	eventbus.Subscribe(bus, "webhooks", eventbus.Async, func(ctx context.Context, e domain.CourseCompleted) error {
+		s.Dispatch(domain.WebhookEventEnrollmentCompleted, map[string]interface{}{
+			"enrollment_id": e.EnrollmentID,
+			"user_id":       e.UserID,
+			"course_id":     e.CourseID,
+			"completed_at":  e.OccurredAt.UTC(),
+		})
+		return nil
+	})
+	eventbus.Subscribe(bus, "webhooks", eventbus.Async, func(ctx context.Context, e domain.CoursePublished) error {
+		s.Dispatch(domain.WebhookEventCoursePublished, map[string]interface{}{
+			"course_id":    e.CourseID,
+			"title":        e.Title,
+			"teacher_id":   e.TeacherID,
+			"published_at": e.OccurredAt.UTC(),
+		})
+		return nil
+	})
+	eventbus.Subscribe(bus, "webhooks", eventbus.Async, func(ctx context.Context, e domain.UserCreated) error {
+		s.Dispatch(domain.WebhookEventUserCreated, map[string]interface{}{
+			"user_id":    e.UserID,
+			"name":       e.Name,
+			"email":      e.Email,
+			"role":       e.Role,
+			"source":     e.Source,
+			"created_at": e.OccurredAt.UTC(),
+		})
+		return nil
+	})
+	eventbus.Subscribe(bus, "webhooks", eventbus.Async, func(ctx context.Context, e domain.OrderPaid) error {
+		s.Dispatch(domain.WebhookEventOrderPaid, map[string]interface{}{
+			"order_id":  e.OrderID,
+			"user_id":   e.UserID,
+			"course_id": e.CourseID,
+			"amount":    e.Amount,
+			"currency":  e.Currency,
+			"paid_at":   e.OccurredAt.UTC(),
+		})
+		return nil
+	})
+	eventbus.Subscribe(bus, "webhooks", eventbus.Async, func(ctx context.Context, e domain.OrderRefunded) error {
+		s.Dispatch(domain.WebhookEventOrderRefunded, map[string]interface{}{
+			"order_id":    e.OrderID,
+			"refund_id":   e.RefundID,
+			"user_id":     e.UserID,
+			"course_id":   e.CourseID,
+			"amount":      e.Amount,
+			"currency":    e.Currency,
+			"refunded_at": e.OccurredAt.UTC(),
+		})
+		return nil
+	})
+	eventbus.Subscribe(bus, "webhooks", eventbus.Async, func(ctx context.Context, e domain.SubscriptionChanged) error {
+		s.Dispatch(domain.WebhookEventSubscriptionChanged, map[string]interface{}{
+			"subscription_id":    e.SubscriptionID,
+			"user_id":            e.UserID,
+			"plan_id":            e.PlanID,
+			"status":             e.Status,
+			"current_period_end": e.CurrentPeriodEnd,
+			"changed_at":         e.OccurredAt.UTC(),
+		})
+		return nil
+	})
+}
+
+// Dispatch queues an event for every active webhook subscribed to it.
+// Failures are logged; dispatching never fails the caller's operation.
+func (s *WebhookService) Dispatch(event domain.WebhookEvent, data interface{}) {
+	hooks, err := s.repo.FindActiveByEvent(event)
+	if err != nil {
+		log.Printf("failed to load webhooks for %s: %v", event, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	eventID, err := randomHex(16)
+	if err != nil {
+		log.Printf("failed to generate webhook event id: %v", err)
+		return
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		ID:        "evt_" + eventID,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("failed to encode webhook payload for %s: %v", event, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		delivery := &domain.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventID:       "evt_" + eventID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        domain.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := s.repo.CreateDelivery(delivery); err != nil {
+			log.Printf("failed to queue %s delivery for webhook %d: %v", event, hook.ID, err)
+		}
+	}
+
+	s.notify()
+}
+
+// Run processes due deliveries until ctx is cancelled
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.processDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// notify wakes the worker without blocking
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// processDue claims due deliveries and sends them, at most
+// webhookConcurrency at a time. Each request is bounded by the client's
+// RequestTimeout.
+func (s *WebhookService) processDue(ctx context.Context) {
+	deliveries, err := s.repo.ClaimDueDeliveries(time.Now(), s.opts.RequestTimeout+webhookClaimMargin, webhookBatchSize)
+	if err != nil {
+		log.Printf("failed to claim due webhook deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, webhookConcurrency)
+	hooks := make(map[uint]*domain.Webhook)
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		delivery := &deliveries[i]
+		hook, ok := hooks[delivery.WebhookID]
+		if !ok {
+			if hook, err = s.repo.FindByID(delivery.WebhookID); err != nil {
+				log.Printf("failed to load webhook %d: %v", delivery.WebhookID, err)
+				continue
+			}
+			hooks[delivery.WebhookID] = hook
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s.attempt(ctx, hook, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt sends one delivery and records the outcome
+func (s *WebhookService) attempt(ctx context.Context, hook *domain.Webhook, delivery *domain.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	code, body, err := s.send(ctx, hook, delivery)
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+	delivery.Error = ""
+
+	switch {
+	case err == nil:
+		delivery.Status = domain.WebhookDeliverySucceeded
+	case !hook.IsActive:
+		delivery.Status = domain.WebhookDeliveryFailed
+		delivery.Error = "webhook is disabled"
+	default:
+		delivery.Error = err.Error()
+		if delivery.Attempts >= s.opts.MaxAttempts {
+			delivery.Status = domain.WebhookDeliveryFailed
+		} else {
+			delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		}
+	}
+
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		log.Printf("failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send posts the signed payload; any non-2xx response is an error
+func (s *WebhookService) send(ctx context.Context, hook *domain.Webhook, delivery *domain.WebhookDelivery) (int, string, error) {
+	if !hook.IsActive {
+		return 0, "", errors.New("webhook is disabled")
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "elearning-webhooks/1.0")
+	req.Header.Set(webhook.HeaderEvent, string(delivery.Event))
+	req.Header.Set(webhook.HeaderDelivery, delivery.EventID)
+	timestamp := time.Now()
+	req.Header.Set(webhook.HeaderTimestamp, fmt.Sprintf("%d", timestamp.Unix()))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(hook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(respBody), nil
+}
+
+// backoff returns the delay before the next attempt: base * 2^(attempts-1)
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.opts.RetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+func validateWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, ErrNoWebhookEvents
+	}
+
+	seen := make(map[string]bool, len(events))
+	result := make([]string, 0, len(events))
+	for _, e := range events {
+		if !domain.WebhookEvent(e).IsValid() {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidWebhookEvent, e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
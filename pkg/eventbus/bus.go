@@ -0,0 +1,239 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"elearning/pkg/metrics"
+)
+
+// Event is a message published on the bus. The name identifies the event
+// type and is used to route it to subscribers.
+type Event interface {
+	EventName() string
+}
+
+// Mode selects how a subscriber receives events
+type Mode int
+
+const (
+	// Sync handlers run in the publisher's goroutine before Publish returns
+	Sync Mode = iota
+	// Async handlers run on the bus worker pool after Publish returns
+	Async
+)
+
+// enqueueTimeout is how long Publish waits for room in a full queue before
+// dropping an async delivery
+const enqueueTimeout = time.Second
+
+// Handler reacts to an event
+type Handler func(ctx context.Context, event Event) error
+
+type subscription struct {
+	name    string
+	mode    Mode
+	handler Handler
+}
+
+type job struct {
+	ctx   context.Context
+	event Event
+	sub   subscription
+}
+
+// Bus is an in-process publish/subscribe event bus.
+//
+// Each handler is isolated: an error or panic in one subscriber is logged
+// and counted but never reaches the publisher or other subscribers.
+type Bus struct {
+	mu    sync.RWMutex
+	subs  map[string][]subscription
+	queue chan job
+	// stop is closed by Close; the queue itself is never closed since
+	// publishers send on it without holding the lock
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	closed bool
+	logger *zap.Logger
+}
+
+// New creates a bus with the given number of async workers and queue size
+func New(logger *zap.Logger, workers, queueSize int) *Bus {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	b := &Bus{
+		subs:   make(map[string][]subscription),
+		queue:  make(chan job, queueSize),
+		stop:   make(chan struct{}),
+		logger: logger,
+	}
+
+	b.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go b.worker()
+	}
+	return b
+}
+
+// Subscribe registers a handler for the named event. The subscriber name is
+// used in logs and metrics.
+func (b *Bus) Subscribe(event, subscriber string, mode Mode, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[event] = append(b.subs[event], subscription{name: subscriber, mode: mode, handler: handler})
+}
+
+// Subscribe registers a handler for events of type T
+func Subscribe[T Event](b *Bus, subscriber string, mode Mode, handler func(ctx context.Context, event T) error) {
+	var zero T
+	b.Subscribe(zero.EventName(), subscriber, mode, func(ctx context.Context, event Event) error {
+		typed, ok := event.(T)
+		if !ok {
+			return fmt.Errorf("unexpected event type %T for %s", event, event.EventName())
+		}
+		return handler(ctx, typed)
+	})
+}
+
+// Publish delivers an event to its subscribers. Publish should be called only
+// after the change the event describes has been committed, so subscribers
+// never observe state that is later rolled back.
+//
+// Sync handlers run before Publish returns. Async handlers are queued; the
+// context passed to them is detached from ctx's cancellation so a finished
+// HTTP request does not cancel its side effects. When the queue stays full
+// for enqueueTimeout the delivery is dropped, logged and counted rather
+// than stalling the publisher.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		b.logger.Warn("event published after bus was closed", zap.String("event", event.EventName()))
+		return
+	}
+	subs := append([]subscription(nil), b.subs[event.EventName()]...)
+	b.mu.RUnlock()
+
+	metrics.EventsPublished.WithLabelValues(event.EventName()).Inc()
+
+	// Handlers run and jobs are queued without the lock, so handlers may
+	// publish further events and a full queue never blocks Subscribe or
+	// Close
+	for _, sub := range subs {
+		if sub.mode == Async {
+			b.enqueue(job{ctx: context.WithoutCancel(ctx), event: event, sub: sub})
+		}
+	}
+	for _, sub := range subs {
+		if sub.mode == Sync {
+			b.dispatch(ctx, event, sub)
+		}
+	}
+}
+
+func (b *Bus) enqueue(j job) {
+	select {
+	case b.queue <- j:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(enqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case b.queue <- j:
+	case <-b.stop:
+		b.drop(j, "closed")
+	case <-timer.C:
+		b.drop(j, "queue_full")
+	}
+}
+
+func (b *Bus) drop(j job, reason string) {
+	metrics.EventsDropped.WithLabelValues(j.event.EventName(), j.sub.name, reason).Inc()
+	b.logger.Error("event delivery dropped",
+		zap.String("event", j.event.EventName()),
+		zap.String("subscriber", j.sub.name),
+		zap.String("reason", reason),
+	)
+}
+
+// Close stops accepting events and waits for queued async handlers to finish
+// or for ctx to expire
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.stop)
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) worker() {
+	defer b.wg.Done()
+	for {
+		select {
+		case j := <-b.queue:
+			b.dispatch(j.ctx, j.event, j.sub)
+		case <-b.stop:
+			// Finish what was queued before closing
+			for {
+				select {
+				case j := <-b.queue:
+					b.dispatch(j.ctx, j.event, j.sub)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// dispatch runs one handler, recovering from panics
+func (b *Bus) dispatch(ctx context.Context, event Event, sub subscription) {
+	defer func() {
+		if r := recover(); r != nil {
+			metrics.EventHandlerFailures.WithLabelValues(event.EventName(), sub.name, "panic").Inc()
+			b.logger.Error("event handler panicked",
+				zap.String("event", event.EventName()),
+				zap.String("subscriber", sub.name),
+				zap.Any("panic", r),
+				zap.ByteString("stack", debug.Stack()),
+			)
+		}
+	}()
+
+	if err := sub.handler(ctx, event); err != nil {
+		metrics.EventHandlerFailures.WithLabelValues(event.EventName(), sub.name, "error").Inc()
+		b.logger.Warn("event handler failed",
+			zap.String("event", event.EventName()),
+			zap.String("subscriber", sub.name),
+			zap.Error(err),
+		)
+	}
+}
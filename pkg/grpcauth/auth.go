@@ -0,0 +1,139 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys used for service-to-service authentication
+const (
+	MetadataAuthorization = "authorization"
+	MetadataServiceName   = "x-service-name"
+	MetadataUserID        = "x-user-id"
+)
+
+// ServiceTokenCredentials attaches a shared service token to every RPC
+type ServiceTokenCredentials struct {
+	ServiceName string
+	Token       string
+	// RequireTLS refuses to send the token over an insecure connection
+	RequireTLS bool
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (c ServiceTokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		MetadataAuthorization: "Bearer " + c.Token,
+		MetadataServiceName:   c.ServiceName,
+	}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials
+func (c ServiceTokenCredentials) RequireTransportSecurity() bool {
+	return c.RequireTLS
+}
+
+// WithUserID records the end user an RPC is made on behalf of
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataUserID, strconv.FormatInt(userID, 10))
+}
+
+// ServerAuthConfig configures the server-side service authentication
+type ServerAuthConfig struct {
+	// Tokens maps a shared secret to the name of the service that owns it
+	Tokens map[string]string
+	// Privileged maps full method names (e.g. "/notification.NotificationService/SendNotification")
+	// to the services allowed to call them
+	Privileged map[string][]string
+}
+
+type callerKey struct{}
+
+// CallerFromContext returns the authenticated calling service
+func CallerFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(callerKey{}).(string)
+	return name, ok
+}
+
+// userScoped is implemented by requests that carry a user_id field
+type userScoped interface {
+	GetUserId() int64
+}
+
+// UnaryServerInterceptor authenticates the calling service and authorizes the RPC.
+//
+// Every call must carry a known service token. Privileged methods are limited
+// to the services listed for them. All other methods that carry a user_id must
+// also carry a matching x-user-id header. That check only catches callers that
+// build the request and the metadata inconsistently: both come from the same
+// token holder, so any authenticated service can act for any user. Services
+// holding a token are trusted to pass the user they authenticated.
+func UnaryServerInterceptor(cfg ServerAuthConfig) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		caller, ok := authenticate(cfg.Tokens, first(md, MetadataAuthorization))
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid service credentials")
+		}
+
+		if allowed, privileged := cfg.Privileged[info.FullMethod]; privileged {
+			if !contains(allowed, caller) {
+				return nil, status.Errorf(codes.PermissionDenied, "service %q may not call %s", caller, info.FullMethod)
+			}
+		} else if scoped, ok := req.(userScoped); ok {
+			actingUser, err := strconv.ParseInt(first(md, MetadataUserID), 10, 64)
+			if err != nil || actingUser != scoped.GetUserId() {
+				return nil, status.Error(codes.PermissionDenied, "user_id does not match the acting user")
+			}
+		}
+
+		return handler(context.WithValue(ctx, callerKey{}, caller), req)
+	}
+}
+
+// authenticate resolves a bearer token to a service name in constant time
+func authenticate(tokens map[string]string, header string) (string, bool) {
+	presented, found := strings.CutPrefix(header, "Bearer ")
+	if !found || presented == "" {
+		return "", false
+	}
+
+	var caller string
+	matched := false
+	for token, name := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(presented)) == 1 {
+			caller = name
+			matched = true
+		}
+	}
+	return caller, matched
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"elearning/pkg/metrics"
+)
+
+// BreakerState represents the state of a circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// ErrCircuitOpen is returned when a call is rejected by an open breaker
+var ErrCircuitOpen = status.Error(codes.Unavailable, "circuit breaker is open")
+
+// CircuitBreaker stops calling a failing upstream for a cooldown period.
+// After threshold consecutive failures the breaker opens; once the cooldown
+// elapses a single probe call is let through (half-open) and its result
+// decides whether the breaker closes again or re-opens.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	target    string
+	state     BreakerState
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker creates a new circuit breaker for the given target
+func NewCircuitBreaker(target string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	b := &CircuitBreaker{
+		target:    target,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+	b.setState(BreakerClosed)
+	return b
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow reports whether a call may proceed
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		// Only one probe at a time
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Record reports the outcome of a call that was allowed through. A call
+// the caller cancelled says nothing about the upstream: it frees the probe
+// slot without changing the state, so a half-open breaker lets the next
+// call probe instead.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if isCanceled(err) {
+		return
+	}
+
+	if !isBreakerFailure(err) {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// setState updates the state and the exported gauge; caller holds the lock
+func (b *CircuitBreaker) setState(s BreakerState) {
+	b.state = s
+	metrics.GrpcCircuitBreakerState.WithLabelValues(b.target).Set(float64(s))
+}
+
+// UnaryClientInterceptor rejects calls while the breaker is open
+func (b *CircuitBreaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if !b.Allow() {
+			metrics.GrpcCircuitBreakerRejections.WithLabelValues(b.target, method).Inc()
+			return ErrCircuitOpen
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.Record(err)
+		return err
+	}
+}
+
+// isCanceled reports whether the call ended because the caller gave up
+func isCanceled(err error) bool {
+	return status.Code(err) == codes.Canceled || errors.Is(err, context.Canceled)
+}
+
+// isBreakerFailure reports whether an error indicates the upstream is unhealthy.
+// Application errors such as NotFound or InvalidArgument do not trip the breaker.
+func isBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package grpcclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// halfOpenBreaker returns a breaker whose cooldown has elapsed, with the
+// probe call already allowed through
+func halfOpenBreaker(t *testing.T) *CircuitBreaker {
+	t.Helper()
+	b := NewCircuitBreaker("test", 1, time.Millisecond)
+	b.Record(status.Error(codes.Unavailable, "down"))
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("probe not allowed after cooldown")
+	}
+	return b
+}
+
+func TestBreakerProbeOutcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantState BreakerState
+		// wantProbe is whether another call is let through afterwards
+		wantProbe bool
+	}{
+		{"success closes", nil, BreakerClosed, true},
+		{"application error closes", status.Error(codes.NotFound, "missing"), BreakerClosed, true},
+		{"failure re-opens", status.Error(codes.Unavailable, "down"), BreakerOpen, false},
+		{"cancelled stays half-open", status.Error(codes.Canceled, "context canceled"), BreakerHalfOpen, true},
+		{"cancelled context stays half-open", context.Canceled, BreakerHalfOpen, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := halfOpenBreaker(t)
+			b.Record(tt.err)
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("state = %s, want %s", got, tt.wantState)
+			}
+			if got := b.Allow(); got != tt.wantProbe {
+				t.Errorf("Allow() = %v, want %v", got, tt.wantProbe)
+			}
+		})
+	}
+}
+
+func TestBreakerCancelledCallsDoNotResetFailures(t *testing.T) {
+	b := NewCircuitBreaker("test", 2, time.Minute)
+	b.Record(status.Error(codes.Unavailable, "down"))
+	b.Record(status.Error(codes.Canceled, "context canceled"))
+	b.Record(status.Error(codes.Unavailable, "down"))
+	if got := b.State(); got != BreakerOpen {
+		t.Errorf("state = %s, want open", got)
+	}
+}
@@ -6,43 +6,164 @@ import (
 	"log"
 	"time"
 
+	"elearning/pkg/grpcauth"
 	pb "elearning/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+const notificationServiceName = "notification.NotificationService"
+
+// NotificationClientConfig configures the notification service client
+type NotificationClientConfig struct {
+	Address          string
+	RequestTimeout   time.Duration
+	MaxRetries       int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// TLS enables (m)TLS when non-nil; otherwise the connection is plaintext
+	TLS *grpcauth.TLSConfig
+	// ServiceName and ServiceToken identify this service to the server
+	ServiceName  string
+	ServiceToken string
+}
+
+// NotificationClient talks to the notification service.
+//
+// The underlying connection is established lazily on first use and is
+// re-established automatically by gRPC if the service goes away, so the
+// client can be created at startup even when the service is down.
 type NotificationClient struct {
-	client pb.NotificationServiceClient
-	conn   *grpc.ClientConn
+	client  pb.NotificationServiceClient
+	conn    *grpc.ClientConn
+	breaker *CircuitBreaker
+	timeout time.Duration
 }
 
-func NewNotificationClient(address string) (*NotificationClient, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// NewNotificationClient creates a notification client. It does not dial;
+// an error is only returned for an invalid configuration.
+func NewNotificationClient(cfg NotificationClientConfig) (*NotificationClient, error) {
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = 3 * time.Second
+	}
+
+	transportCreds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		creds, err := grpcauth.ClientCredentials(*cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load notification client TLS: %w", err)
+		}
+		transportCreds = creds
+	}
+
+	breaker := NewCircuitBreaker(cfg.Address, cfg.BreakerThreshold, cfg.BreakerCooldown)
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithDefaultServiceConfig(serviceConfig(cfg)),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: 5 * time.Second,
+		}),
+		grpc.WithChainUnaryInterceptor(
+			UnaryClientInterceptor(),
+			breaker.UnaryClientInterceptor(),
+		),
+	}
+
+	if cfg.ServiceToken != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(grpcauth.ServiceTokenCredentials{
+			ServiceName: cfg.ServiceName,
+			Token:       cfg.ServiceToken,
+			RequireTLS:  cfg.TLS != nil,
+		}))
+	} else {
+		log.Printf("warning: notification client has no service token; calls are unauthenticated")
+	}
 
-	conn, err := grpc.DialContext(ctx, address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-	)
+	conn, err := grpc.NewClient(cfg.Address, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to notification service: %w", err)
+		return nil, fmt.Errorf("failed to create notification client: %w", err)
 	}
 
-	client := pb.NewNotificationServiceClient(conn)
-	log.Printf("connected to notification service at %s", address)
+	log.Printf("notification client configured for %s (lazy connect)", cfg.Address)
 
 	return &NotificationClient{
-		client: client,
-		conn:   conn,
+		client:  pb.NewNotificationServiceClient(conn),
+		conn:    conn,
+		breaker: breaker,
+		timeout: cfg.RequestTimeout,
 	}, nil
 }
 
+// serviceConfig builds the gRPC service config with per-method deadlines.
+// Only idempotent reads and read-marking RPCs are retried; SendNotification
+// is not, since a retry after a lost response would deliver twice.
+func serviceConfig(cfg NotificationClientConfig) string {
+	timeout := fmt.Sprintf("%.3fs", cfg.RequestTimeout.Seconds())
+
+	retryPolicy := ""
+	if cfg.MaxRetries > 0 {
+		// MaxAttempts includes the original call and is capped at 5 by gRPC
+		attempts := cfg.MaxRetries + 1
+		if attempts > 5 {
+			attempts = 5
+		}
+		retryPolicy = fmt.Sprintf(`,
+			"retryPolicy": {
+				"maxAttempts": %d,
+				"initialBackoff": "0.1s",
+				"maxBackoff": "1s",
+				"backoffMultiplier": 2,
+				"retryableStatusCodes": ["UNAVAILABLE", "RESOURCE_EXHAUSTED"]
+			}`, attempts)
+	}
+
+	return fmt.Sprintf(`{
+		"methodConfig": [
+			{
+				"name": [
+					{"service": %[1]q, "method": "GetNotifications"},
+					{"service": %[1]q, "method": "GetUnreadCount"},
+					{"service": %[1]q, "method": "MarkAsRead"},
+					{"service": %[1]q, "method": "MarkAllAsRead"}
+				],
+				"timeout": %[2]q%[3]s
+			},
+			{
+				"name": [
+					{"service": %[1]q, "method": "SendNotification"},
+					{"service": %[1]q, "method": "SendBulkNotification"}
+				],
+				"timeout": %[2]q
+			}
+		]
+	}`, notificationServiceName, timeout, retryPolicy)
+}
+
+// withTimeout applies the default per-RPC deadline when the caller has none
+func (c *NotificationClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// BreakerState returns the current circuit breaker state
+func (c *NotificationClient) BreakerState() BreakerState {
+	return c.breaker.State()
+}
+
 func (c *NotificationClient) Close() error {
 	return c.conn.Close()
 }
 
 func (c *NotificationClient) SendNotification(ctx context.Context, userID int64, notifType, title, message string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	_, err := c.client.SendNotification(ctx, &pb.SendNotificationRequest{
 		UserId:  userID,
 		Type:    notifType,
@@ -52,7 +173,27 @@ func (c *NotificationClient) SendNotification(ctx context.Context, userID int64,
 	return err
 }
 
+// SendBulkNotification sends the same notification to many users in one call
+func (c *NotificationClient) SendBulkNotification(ctx context.Context, userIDs []int64, notifType, title, message string) (int32, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.SendBulkNotification(ctx, &pb.SendBulkNotificationRequest{
+		UserIds: userIDs,
+		Type:    notifType,
+		Title:   title,
+		Message: message,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
 func (c *NotificationClient) GetNotifications(ctx context.Context, userID int64, page, limit int32, unreadOnly bool) (*pb.GetNotificationsResponse, error) {
+	ctx, cancel := c.withTimeout(grpcauth.WithUserID(ctx, userID))
+	defer cancel()
+
 	return c.client.GetNotifications(ctx, &pb.GetNotificationsRequest{
 		UserId:     userID,
 		Page:       page,
@@ -62,6 +203,9 @@ func (c *NotificationClient) GetNotifications(ctx context.Context, userID int64,
 }
 
 func (c *NotificationClient) MarkAsRead(ctx context.Context, notificationID, userID int64) error {
+	ctx, cancel := c.withTimeout(grpcauth.WithUserID(ctx, userID))
+	defer cancel()
+
 	_, err := c.client.MarkAsRead(ctx, &pb.MarkAsReadRequest{
 		NotificationId: notificationID,
 		UserId:         userID,
@@ -70,6 +214,9 @@ func (c *NotificationClient) MarkAsRead(ctx context.Context, notificationID, use
 }
 
 func (c *NotificationClient) GetUnreadCount(ctx context.Context, userID int64) (int64, error) {
+	ctx, cancel := c.withTimeout(grpcauth.WithUserID(ctx, userID))
+	defer cancel()
+
 	resp, err := c.client.GetUnreadCount(ctx, &pb.GetUnreadCountRequest{
 		UserId: userID,
 	})
@@ -80,6 +227,9 @@ func (c *NotificationClient) GetUnreadCount(ctx context.Context, userID int64) (
 }
 
 func (c *NotificationClient) MarkAllAsRead(ctx context.Context, userID int64) (int32, error) {
+	ctx, cancel := c.withTimeout(grpcauth.WithUserID(ctx, userID))
+	defer cancel()
+
 	resp, err := c.client.MarkAllAsRead(ctx, &pb.MarkAllAsReadRequest{
 		UserId: userID,
 	})
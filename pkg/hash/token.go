@@ -0,0 +1,26 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateToken returns a random URL-safe token and its SHA-256 hash.
+// Only the hash should be stored; the raw token is handed to the user.
+func GenerateToken(size int) (raw string, hashed string, err error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, HashToken(raw), nil
+}
+
+// HashToken hashes a high-entropy token for storage and lookup. Unlike
+// passwords these tokens are random, so a fast hash is sufficient.
+func HashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -106,6 +106,48 @@ var (
 		[]string{"method", "status"},
 	)
 
+	// GrpcCircuitBreakerState reports 0 = closed, 1 = half-open, 2 = open
+	GrpcCircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "grpc_circuit_breaker_state",
+			Help: "Current state of the gRPC client circuit breaker (0=closed, 1=half-open, 2=open)",
+		},
+		[]string{"target"},
+	)
+
+	GrpcCircuitBreakerRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_circuit_breaker_rejections_total",
+			Help: "Total number of gRPC calls rejected by an open circuit breaker",
+		},
+		[]string{"target", "method"},
+	)
+
+	// EventsPublished Event Bus Metrics
+	EventsPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "domain_events_published_total",
+			Help: "Total number of domain events published on the in-process event bus",
+		},
+		[]string{"event"},
+	)
+
+	EventsDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "domain_events_dropped_total",
+			Help: "Total number of async event deliveries dropped because the queue was full or the bus closed",
+		},
+		[]string{"event", "subscriber", "reason"},
+	)
+
+	EventHandlerFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "domain_event_handler_failures_total",
+			Help: "Total number of event handler errors and recovered panics",
+		},
+		[]string{"event", "subscriber", "reason"},
+	)
+
 	// LoginAttempts Auth Metrics
 	LoginAttempts = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -115,6 +157,14 @@ var (
 		[]string{"status"},
 	)
 
+	LoginLockouts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "login_lockouts_total",
+			Help: "Total number of login lockouts started, by scope (account or ip)",
+		},
+		[]string{"scope"},
+	)
+
 	TokensIssued = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "tokens_issued_total",
@@ -122,6 +172,15 @@ var (
 		},
 	)
 
+	// AuditWriteFailures Audit Metrics
+	AuditWriteFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "audit_write_failures_total",
+			Help: "Total number of completed actions whose audit log entry could not be written",
+		},
+		[]string{"action"},
+	)
+
 	// ErrorsTotal Error Metrics
 	ErrorsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -0,0 +1,204 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fake is a provider for local development. Its checkout URL points back
+// at this API: POSTing to it completes (or, with ?status=failed, fails) the
+// payment, and the outcome is delivered as a signed Stripe-style webhook.
+// Subscription checkouts point at "subscriptions/<reference>/complete"
+// instead; renewals are triggered the same way.
+type Fake struct {
+	// CheckoutBaseURL is prefixed to "<reference>/complete" to build the
+	// checkout URL
+	CheckoutBaseURL string
+	WebhookSecret   string
+}
+
+// NewFake creates a fake provider
+func NewFake(checkoutBaseURL, webhookSecret string) *Fake {
+	return &Fake{CheckoutBaseURL: checkoutBaseURL, WebhookSecret: webhookSecret}
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	reference := "cs_fake_" + id
+	return &Checkout{
+		Reference: reference,
+		URL:       strings.TrimSuffix(f.CheckoutBaseURL, "/") + "/" + reference + "/complete",
+	}, nil
+}
+
+func (f *Fake) CreateSubscriptionCheckout(ctx context.Context, req SubscriptionCheckoutRequest) (*Checkout, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	reference := "cs_fake_" + id
+	return &Checkout{
+		Reference: reference,
+		URL:       strings.TrimSuffix(f.CheckoutBaseURL, "/") + "/subscriptions/" + reference + "/complete",
+	}, nil
+}
+
+// ExpireCheckout always succeeds; fake checkouts are only paid on request
+func (f *Fake) ExpireCheckout(ctx context.Context, reference string) error {
+	return nil
+}
+
+// CancelSubscription always succeeds; the fake never renews on its own
+func (f *Fake) CancelSubscription(ctx context.Context, reference string) error {
+	return nil
+}
+
+func (f *Fake) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if err := VerifySignature(f.WebhookSecret, header.Get(SignatureHeader), payload, 5*time.Minute); err != nil {
+		return nil, err
+	}
+	return parseStripeEvent(payload)
+}
+
+// Refund always succeeds
+func (f *Fake) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	return &Refund{Reference: "re_fake_" + id}, nil
+}
+
+// Complete builds the signed webhook a real provider would send once the
+// checkout is paid, or abandoned when succeeded is false
+func (f *Fake) Complete(reference string, succeeded bool) ([]byte, http.Header, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event := map[string]interface{}{
+		"id":   "evt_fake_" + id,
+		"type": "checkout.session.expired",
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{
+				"id":             reference,
+				"payment_status": "unpaid",
+			},
+		},
+	}
+	if succeeded {
+		event["type"] = "checkout.session.completed"
+		event["data"] = map[string]interface{}{
+			"object": map[string]interface{}{
+				"id":             reference,
+				"payment_status": "paid",
+				"payment_intent": "pi_fake_" + id,
+			},
+		}
+	}
+
+	return f.sign(event)
+}
+
+// fakeSubscriptionStatuses maps our subscription states to Stripe's
+var fakeSubscriptionStatuses = map[string]string{
+	SubscriptionTrial:    "trialing",
+	SubscriptionActive:   "active",
+	SubscriptionPastDue:  "past_due",
+	SubscriptionCanceled: "canceled",
+}
+
+// UpdateSubscription builds the signed webhook a real provider would send
+// when a subscription starts or changes state. reference identifies the
+// subscription at the provider; an empty one starts a new subscription and
+// is returned with the webhook.
+func (f *Fake) UpdateSubscription(reference string, subscriptionID uint, status string, periodEnd time.Time) (string, []byte, http.Header, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	eventType := "customer.subscription.updated"
+	if reference == "" {
+		eventType = "customer.subscription.created"
+		reference = "sub_fake_" + id
+	}
+
+	payload, header, err := f.sign(map[string]interface{}{
+		"id":   "evt_fake_" + id,
+		"type": eventType,
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{
+				"id":                 reference,
+				"status":             fakeSubscriptionStatuses[status],
+				"current_period_end": periodEnd.Unix(),
+				"metadata":           map[string]string{"subscription_id": strconv.FormatUint(uint64(subscriptionID), 10)},
+			},
+		},
+	})
+	return reference, payload, header, err
+}
+
+// Renew builds the signed invoice webhook a real provider would send when
+// it charges a subscription for the period ending at periodEnd
+func (f *Fake) Renew(reference string, subscriptionID uint, amount int64, currency string, periodEnd time.Time, succeeded bool) ([]byte, http.Header, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, nil, err
+	}
+	eventType, paid := "invoice.payment_failed", int64(0)
+	if succeeded {
+		eventType, paid = "invoice.paid", amount
+	}
+
+	return f.sign(map[string]interface{}{
+		"id":   "evt_fake_" + id,
+		"type": eventType,
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{
+				"id":           "in_fake_" + id,
+				"subscription": reference,
+				"amount_paid":  paid,
+				"amount_due":   amount,
+				"currency":     strings.ToLower(currency),
+				"subscription_details": map[string]interface{}{
+					"metadata": map[string]string{"subscription_id": strconv.FormatUint(uint64(subscriptionID), 10)},
+				},
+				"lines": map[string]interface{}{
+					"data": []map[string]interface{}{
+						{"period": map[string]interface{}{"end": periodEnd.Unix()}},
+					},
+				},
+			},
+		},
+	})
+}
+
+func (f *Fake) sign(event map[string]interface{}) ([]byte, http.Header, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(SignatureHeader, Sign(f.WebhookSecret, time.Now(), payload))
+	return payload, header, nil
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
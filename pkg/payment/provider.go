@@ -0,0 +1,145 @@
+// Package payment defines the interface to payment providers and provides
+// a Stripe client and a fake provider for local development. Both use
+// Stripe's checkout sessions and signed webhook format.
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid payment webhook signature")
+	ErrStaleTimestamp   = errors.New("payment webhook timestamp outside tolerance")
+	ErrInvalidEvent     = errors.New("invalid payment webhook event")
+)
+
+// CheckoutRequest describes a one-off payment for an order
+type CheckoutRequest struct {
+	OrderID uint
+	// Amount is in the currency's minor unit, e.g. cents
+	Amount        int64
+	Currency      string
+	Description   string
+	CustomerEmail string
+	SuccessURL    string
+	CancelURL     string
+	// ExpiresAt is when the checkout stops accepting payment
+	ExpiresAt time.Time
+}
+
+// SubscriptionCheckoutRequest describes a recurring payment for a
+// subscription
+type SubscriptionCheckoutRequest struct {
+	SubscriptionID uint
+	// Amount is charged every Interval ("month" or "year")
+	Amount   int64
+	Currency string
+	Interval string
+	// TrialDays delays the first charge
+	TrialDays     int
+	Description   string
+	CustomerEmail string
+	SuccessURL    string
+	CancelURL     string
+}
+
+// Checkout is a payment page created by the provider
+type Checkout struct {
+	// Reference identifies the checkout in later webhook events
+	Reference string
+	URL       string
+}
+
+// RefundRequest returns a captured payment to the customer
+type RefundRequest struct {
+	OrderID uint
+	// PaymentReference is the Event.PaymentReference of the payment
+	PaymentReference string
+	// Amount is in the currency's minor unit; it may be less than was paid
+	Amount   int64
+	Currency string
+	// IdempotencyKey makes retries of the same refund safe
+	IdempotencyKey string
+}
+
+// Refund is a refund issued by the provider
+type Refund struct {
+	Reference string
+}
+
+// EventType is the outcome reported by a webhook event
+type EventType string
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+	// EventSubscriptionUpdated reports the state of a subscription
+	EventSubscriptionUpdated EventType = "subscription.updated"
+	// EventSubscriptionRenewed reports a paid subscription invoice
+	EventSubscriptionRenewed       EventType = "subscription.renewed"
+	EventSubscriptionPaymentFailed EventType = "subscription.payment_failed"
+	// EventIgnored is any event type that does not affect orders or
+	// subscriptions
+	EventIgnored EventType = "ignored"
+)
+
+// Subscription states reported by providers
+const (
+	SubscriptionTrial    = "trial"
+	SubscriptionActive   = "active"
+	SubscriptionPastDue  = "past_due"
+	SubscriptionCanceled = "canceled"
+)
+
+// SubscriptionEvent is the subscription part of a subscription event
+type SubscriptionEvent struct {
+	// Reference identifies the subscription at the provider
+	Reference string
+	// SubscriptionID is the subscription the checkout was created for, if
+	// the provider echoes it back
+	SubscriptionID uint
+	// Status is one of the Subscription* states; empty for invoices
+	Status            string
+	PeriodEnd         time.Time
+	CancelAtPeriodEnd bool
+	// InvoiceReference, Amount and Currency are set for invoices
+	InvoiceReference string
+	Amount           int64
+	Currency         string
+}
+
+// Event is a verified webhook event
+type Event struct {
+	// ID is unique per provider; events can be delivered more than once
+	ID        string
+	Type      EventType
+	Reference string
+	// OrderID is the order the checkout was created for, if the provider
+	// echoes it back
+	OrderID uint
+	// PaymentReference identifies the captured payment, e.g. for refunds
+	PaymentReference string
+	FailureReason    string
+	// Subscription is set for subscription events
+	Subscription *SubscriptionEvent
+}
+
+// Provider creates checkouts for one-off payments and subscriptions,
+// verifies the webhooks that report their outcome and renewals, and
+// refunds captured payments
+type Provider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error)
+	// ExpireCheckout closes an unpaid checkout so it no longer accepts
+	// payment
+	ExpireCheckout(ctx context.Context, reference string) error
+	CreateSubscriptionCheckout(ctx context.Context, req SubscriptionCheckoutRequest) (*Checkout, error)
+	// CancelSubscription stops renewals; the subscription runs until the
+	// end of the paid period
+	CancelSubscription(ctx context.Context, reference string) error
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+}
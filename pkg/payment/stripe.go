@@ -0,0 +1,394 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries Stripe-style webhook signatures:
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">"
+const SignatureHeader = "Stripe-Signature"
+
+const defaultStripeAPIURL = "https://api.stripe.com"
+
+// StripeConfig holds Stripe credentials
+type StripeConfig struct {
+	SecretKey     string
+	WebhookSecret string
+	// APIURL defaults to Stripe's; set it to use a compatible server
+	APIURL string
+	// Tolerance bounds how old a webhook may be
+	Tolerance time.Duration
+}
+
+// Stripe creates Stripe Checkout sessions and verifies Stripe webhooks
+type Stripe struct {
+	cfg    StripeConfig
+	client *http.Client
+}
+
+// NewStripe creates a Stripe provider. A nil client uses a default with a
+// 10 second timeout.
+func NewStripe(cfg StripeConfig, client *http.Client) *Stripe {
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultStripeAPIURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Stripe{cfg: cfg, client: client}
+}
+
+func (s *Stripe) Name() string { return "stripe" }
+
+// CreateCheckout creates a Checkout session in payment mode
+func (s *Stripe) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	orderID := strconv.FormatUint(uint64(req.OrderID), 10)
+	form := url.Values{
+		"mode":                                   {"payment"},
+		"success_url":                            {req.SuccessURL},
+		"cancel_url":                             {req.CancelURL},
+		"client_reference_id":                    {orderID},
+		"metadata[order_id]":                     {orderID},
+		"line_items[0][quantity]":                {"1"},
+		"line_items[0][price_data][currency]":    {strings.ToLower(req.Currency)},
+		"line_items[0][price_data][unit_amount]": {strconv.FormatInt(req.Amount, 10)},
+		"line_items[0][price_data][product_data][name]": {req.Description},
+		"payment_intent_data[metadata][order_id]":       {orderID},
+	}
+	if req.CustomerEmail != "" {
+		form.Set("customer_email", req.CustomerEmail)
+	}
+	if !req.ExpiresAt.IsZero() {
+		// Stripe refuses sessions expiring in under 30 minutes; the margin
+		// covers the request's own latency
+		expiresAt := req.ExpiresAt
+		if earliest := time.Now().Add(31 * time.Minute); expiresAt.Before(earliest) {
+			expiresAt = earliest
+		}
+		form.Set("expires_at", strconv.FormatInt(expiresAt.Unix(), 10))
+	}
+
+	var session struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := s.post(ctx, "/v1/checkout/sessions", form, "", &session); err != nil {
+		return nil, err
+	}
+	return &Checkout{Reference: session.ID, URL: session.URL}, nil
+}
+
+// CreateSubscriptionCheckout creates a Checkout session in subscription
+// mode. The subscription ID is stored in the Stripe subscription's
+// metadata so its events can be matched.
+func (s *Stripe) CreateSubscriptionCheckout(ctx context.Context, req SubscriptionCheckoutRequest) (*Checkout, error) {
+	subscriptionID := strconv.FormatUint(uint64(req.SubscriptionID), 10)
+	form := url.Values{
+		"mode":                                   {"subscription"},
+		"success_url":                            {req.SuccessURL},
+		"cancel_url":                             {req.CancelURL},
+		"metadata[subscription_id]":              {subscriptionID},
+		"line_items[0][quantity]":                {"1"},
+		"line_items[0][price_data][currency]":    {strings.ToLower(req.Currency)},
+		"line_items[0][price_data][unit_amount]": {strconv.FormatInt(req.Amount, 10)},
+		"line_items[0][price_data][recurring][interval]": {req.Interval},
+		"line_items[0][price_data][product_data][name]":  {req.Description},
+		"subscription_data[metadata][subscription_id]":   {subscriptionID},
+	}
+	if req.TrialDays > 0 {
+		form.Set("subscription_data[trial_period_days]", strconv.Itoa(req.TrialDays))
+	}
+	if req.CustomerEmail != "" {
+		form.Set("customer_email", req.CustomerEmail)
+	}
+
+	var session struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := s.post(ctx, "/v1/checkout/sessions", form, "", &session); err != nil {
+		return nil, err
+	}
+	return &Checkout{Reference: session.ID, URL: session.URL}, nil
+}
+
+// ExpireCheckout expires an open checkout session
+func (s *Stripe) ExpireCheckout(ctx context.Context, reference string) error {
+	var out struct {
+		ID string `json:"id"`
+	}
+	return s.post(ctx, "/v1/checkout/sessions/"+url.PathEscape(reference)+"/expire", url.Values{}, "", &out)
+}
+
+// CancelSubscription sets a subscription to cancel at the end of its period
+func (s *Stripe) CancelSubscription(ctx context.Context, reference string) error {
+	form := url.Values{"cancel_at_period_end": {"true"}}
+	var out struct {
+		ID string `json:"id"`
+	}
+	return s.post(ctx, "/v1/subscriptions/"+url.PathEscape(reference), form, "", &out)
+}
+
+// Refund refunds a payment intent
+func (s *Stripe) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	form := url.Values{
+		"payment_intent":     {req.PaymentReference},
+		"amount":             {strconv.FormatInt(req.Amount, 10)},
+		"metadata[order_id]": {strconv.FormatUint(uint64(req.OrderID), 10)},
+	}
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := s.post(ctx, "/v1/refunds", form, req.IdempotencyKey, &refund); err != nil {
+		return nil, err
+	}
+	if refund.Status == "failed" || refund.Status == "canceled" {
+		return nil, fmt.Errorf("stripe refund %s: %s", refund.ID, refund.Status)
+	}
+	return &Refund{Reference: refund.ID}, nil
+}
+
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.cfg.APIURL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.SecretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		return fmt.Errorf("stripe %s: %s: %s", path, resp.Status, apiErr.Error.Message)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// ParseWebhook verifies the signature and extracts the order outcome
+func (s *Stripe) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if err := VerifySignature(s.cfg.WebhookSecret, header.Get(SignatureHeader), payload, s.cfg.Tolerance); err != nil {
+		return nil, err
+	}
+	return parseStripeEvent(payload)
+}
+
+// Sign returns a signature header value for a payload sent at timestamp
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + computeSignature(secret, ts, payload)
+}
+
+func computeSignature(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a Stripe-style signature header. Any of several
+// v1 signatures may match, as Stripe sends one per active secret during
+// rotation.
+func VerifySignature(secret, header string, payload []byte, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return ErrStaleTimestamp
+		}
+	}
+
+	expected := computeSignature(secret, timestamp, payload)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(expected), []byte(sig)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// stripeEvent is the part of a Stripe event used here. Checkout session,
+// subscription and subscription invoice events are handled.
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID                string            `json:"id"`
+			ClientReferenceID string            `json:"client_reference_id"`
+			Mode              string            `json:"mode"`
+			PaymentStatus     string            `json:"payment_status"`
+			PaymentIntent     string            `json:"payment_intent"`
+			Metadata          map[string]string `json:"metadata"`
+			// Subscription fields
+			Status            string `json:"status"`
+			CurrentPeriodEnd  int64  `json:"current_period_end"`
+			CancelAtPeriodEnd bool   `json:"cancel_at_period_end"`
+			// Invoice fields
+			Subscription        string `json:"subscription"`
+			AmountPaid          int64  `json:"amount_paid"`
+			AmountDue           int64  `json:"amount_due"`
+			Currency            string `json:"currency"`
+			SubscriptionDetails struct {
+				Metadata map[string]string `json:"metadata"`
+			} `json:"subscription_details"`
+			Lines struct {
+				Data []struct {
+					Period struct {
+						End int64 `json:"end"`
+					} `json:"period"`
+				} `json:"data"`
+			} `json:"lines"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// stripeSubscriptionStatuses maps Stripe subscription statuses to ours.
+// Incomplete subscriptions have not been paid yet and are ignored.
+var stripeSubscriptionStatuses = map[string]string{
+	"trialing":           SubscriptionTrial,
+	"active":             SubscriptionActive,
+	"past_due":           SubscriptionPastDue,
+	"unpaid":             SubscriptionPastDue,
+	"canceled":           SubscriptionCanceled,
+	"incomplete_expired": SubscriptionCanceled,
+}
+
+func parseStripeEvent(payload []byte) (*Event, error) {
+	var e stripeEvent
+	if err := json.Unmarshal(payload, &e); err != nil || e.ID == "" {
+		return nil, ErrInvalidEvent
+	}
+
+	obj := e.Data.Object
+	event := &Event{
+		ID:               e.ID,
+		Type:             EventIgnored,
+		Reference:        obj.ID,
+		PaymentReference: obj.PaymentIntent,
+	}
+	orderRef := obj.ClientReferenceID
+	if orderRef == "" {
+		orderRef = obj.Metadata["order_id"]
+	}
+	event.OrderID = parseID(orderRef)
+
+	// Subscription checkouts are followed by subscription events
+	if strings.HasPrefix(e.Type, "checkout.session.") && obj.Mode == "subscription" {
+		return event, nil
+	}
+
+	switch e.Type {
+	case "checkout.session.completed":
+		// Delayed payment methods complete the session before the money
+		// arrives and report the outcome in an async_payment event
+		if obj.PaymentStatus == "paid" || obj.PaymentStatus == "no_payment_required" {
+			event.Type = EventPaymentSucceeded
+		}
+	case "checkout.session.async_payment_succeeded":
+		event.Type = EventPaymentSucceeded
+	case "checkout.session.async_payment_failed":
+		event.Type = EventPaymentFailed
+		event.FailureReason = "payment failed"
+	case "checkout.session.expired":
+		event.Type = EventPaymentFailed
+		event.FailureReason = "checkout expired"
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		status, ok := stripeSubscriptionStatuses[obj.Status]
+		if e.Type == "customer.subscription.deleted" {
+			status, ok = SubscriptionCanceled, true
+		}
+		if ok {
+			event.Type = EventSubscriptionUpdated
+			event.Subscription = &SubscriptionEvent{
+				Reference:         obj.ID,
+				SubscriptionID:    parseID(obj.Metadata["subscription_id"]),
+				Status:            status,
+				PeriodEnd:         time.Unix(obj.CurrentPeriodEnd, 0),
+				CancelAtPeriodEnd: obj.CancelAtPeriodEnd,
+			}
+		}
+	case "invoice.paid", "invoice.payment_failed":
+		if obj.Subscription == "" {
+			break
+		}
+		event.Type = EventSubscriptionRenewed
+		amount := obj.AmountPaid
+		if e.Type == "invoice.payment_failed" {
+			event.Type = EventSubscriptionPaymentFailed
+			event.FailureReason = "renewal payment failed"
+			amount = obj.AmountDue
+		}
+		event.Reference = obj.Subscription
+		event.Subscription = &SubscriptionEvent{
+			Reference:        obj.Subscription,
+			SubscriptionID:   parseID(obj.SubscriptionDetails.Metadata["subscription_id"]),
+			InvoiceReference: obj.ID,
+			Amount:           amount,
+			Currency:         strings.ToUpper(obj.Currency),
+		}
+		if len(obj.Lines.Data) > 0 {
+			event.Subscription.PeriodEnd = time.Unix(obj.Lines.Data[0].Period.End, 0)
+		}
+	}
+	return event, nil
+}
+
+// parseID parses an ID echoed back in metadata, returning 0 if absent
+func parseID(s string) uint {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
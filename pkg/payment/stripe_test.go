@@ -0,0 +1,61 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"evt_1","type":"checkout.session.completed"}`)
+	now := time.Now()
+	valid := Sign(secret, now, payload)
+	rotated := Sign("whsec_old", now, payload)
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		payload   []byte
+		tolerance time.Duration
+		want      error
+	}{
+		{"valid", secret, valid, payload, 5 * time.Minute, nil},
+		{"one of several signatures matches", secret, valid + ",v1=" + signatureOf(rotated), payload, 5 * time.Minute, nil},
+		{"tampered payload", secret, valid, []byte(`{"id":"evt_1","type":"charge.refunded"}`), 5 * time.Minute, ErrInvalidSignature},
+		{"wrong secret", "whsec_other", valid, payload, 5 * time.Minute, ErrInvalidSignature},
+		{"tampered signature", secret, valid[:len(valid)-1] + flipHex(valid[len(valid)-1]), payload, 5 * time.Minute, ErrInvalidSignature},
+		{"expired", secret, Sign(secret, now.Add(-10*time.Minute), payload), payload, 5 * time.Minute, ErrStaleTimestamp},
+		{"from the future", secret, Sign(secret, now.Add(10*time.Minute), payload), payload, 5 * time.Minute, ErrStaleTimestamp},
+		{"old but no tolerance", secret, Sign(secret, now.Add(-24*time.Hour), payload), payload, 0, nil},
+		{"missing timestamp", secret, "v1=" + signatureOf(valid), payload, 5 * time.Minute, ErrInvalidSignature},
+		{"missing signature", secret, "t=1234567890", payload, 5 * time.Minute, ErrInvalidSignature},
+		{"malformed timestamp", secret, "t=soon,v1=abc", payload, 5 * time.Minute, ErrInvalidSignature},
+		{"empty header", secret, "", payload, 5 * time.Minute, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, tt.payload, tt.tolerance)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifySignature error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// signatureOf returns the v1 signature of a header made by Sign
+func signatureOf(header string) string {
+	_, sig, _ := strings.Cut(header, ",v1=")
+	return sig
+}
+
+// flipHex returns a different hex digit than c
+func flipHex(c byte) string {
+	if c == '0' {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,110 @@
+package secretbox
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestNewRequiresKey(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Error("New with an empty key succeeded")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	box, err := New("test key")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"empty", ""},
+		{"totp secret", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{"unicode", "rahasia ✓"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := box.Seal(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+			if tt.plaintext != "" && sealed == tt.plaintext {
+				t.Fatal("Seal returned the plaintext")
+			}
+
+			got, err := box.Open(sealed)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if got != tt.plaintext {
+				t.Errorf("Open = %q, want %q", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshNonces(t *testing.T) {
+	box, err := New("test key")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	first, err := box.Seal("secret")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	second, err := box.Seal("secret")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if first == second {
+		t.Error("sealing the same plaintext twice gave the same result")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	box, err := New("test key")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	other, err := New("other key")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	sealed, err := box.Seal("secret")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	raw[len(raw)-1] ^= 0x01
+	tampered := base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name   string
+		box    *Box
+		sealed string
+	}{
+		{"wrong key", other, sealed},
+		{"tampered ciphertext", box, tampered},
+		{"not base64", box, "not base64!"},
+		{"shorter than a nonce", box, base64.StdEncoding.EncodeToString([]byte("short"))},
+		{"empty", box, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.box.Open(tt.sealed); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("Open error = %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
@@ -15,13 +15,25 @@ type BlacklistedToken struct {
 type TokenBlacklist interface {
 	Add(token string, expiresAt time.Time) error
 	IsBlacklisted(token string) bool
+	// RevokeUser revokes every token of a user issued at or before
+	// issuedBefore. The revocation is kept until the given time, after which
+	// all such tokens have expired anyway.
+	RevokeUser(userID uint, issuedBefore, until time.Time) error
+	IsUserRevoked(userID uint, issuedAt time.Time) bool
 	Cleanup() // Remove expired tokens
 }
 
+// userRevocation is a per-user cutoff for token issue times
+type userRevocation struct {
+	issuedBefore time.Time
+	until        time.Time
+}
+
 // InMemoryBlacklist is an in-memory implementation of TokenBlacklist
 type InMemoryBlacklist struct {
 	mu         sync.RWMutex
 	tokens     map[string]time.Time
+	users      map[uint]userRevocation
 	cleanupTTL time.Duration
 }
 
@@ -29,6 +41,7 @@ type InMemoryBlacklist struct {
 func NewInMemoryBlacklist(cleanupInterval time.Duration) *InMemoryBlacklist {
 	blacklist := &InMemoryBlacklist{
 		tokens:     make(map[string]time.Time),
+		users:      make(map[uint]userRevocation),
 		cleanupTTL: cleanupInterval,
 	}
 
@@ -65,6 +78,36 @@ func (b *InMemoryBlacklist) IsBlacklisted(token string) bool {
 	return true
 }
 
+// RevokeUser revokes all tokens of a user issued at or before issuedBefore
+func (b *InMemoryBlacklist) RevokeUser(userID uint, issuedBefore, until time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, exists := b.users[userID]
+	if exists && current.issuedBefore.After(issuedBefore) {
+		issuedBefore = current.issuedBefore
+	}
+	if exists && current.until.After(until) {
+		until = current.until
+	}
+	b.users[userID] = userRevocation{issuedBefore: issuedBefore, until: until}
+	return nil
+}
+
+// IsUserRevoked checks if a token issued at issuedAt was revoked for the user.
+// Issue times are truncated to IssuedAtPrecision, so a token issued within
+// the same millisecond as the revocation counts as revoked.
+func (b *InMemoryBlacklist) IsUserRevoked(userID uint, issuedAt time.Time) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	revocation, exists := b.users[userID]
+	if !exists || time.Now().After(revocation.until) {
+		return false
+	}
+	return !issuedAt.After(revocation.issuedBefore.Truncate(IssuedAtPrecision))
+}
+
 // Cleanup removes expired tokens from the blacklist
 func (b *InMemoryBlacklist) Cleanup() {
 	b.mu.Lock()
@@ -76,6 +119,11 @@ func (b *InMemoryBlacklist) Cleanup() {
 			delete(b.tokens, token)
 		}
 	}
+	for userID, revocation := range b.users {
+		if now.After(revocation.until) {
+			delete(b.users, userID)
+		}
+	}
 }
 
 // startCleanup runs periodic cleanup of expired tokens
@@ -13,17 +13,79 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// IssuedAtPrecision is the precision of token issue times. It is finer than
+// the second of the standard iat claim so that revoking a user's tokens does
+// not also reject the tokens they get later in the same second; the finer
+// time is carried in the iat_ms claim.
+const IssuedAtPrecision = time.Millisecond
+
 // Claims represents JWT claims
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// MFA is set when the user completed a second authentication factor
+	MFA bool `json:"mfa,omitempty"`
+	// MFASetup marks a restricted token that may only be used to enroll in
+	// two-factor authentication
+	MFASetup bool `json:"mfa_setup,omitempty"`
+	// APIKeyID and Scopes are set when the request was authenticated with
+	// an API key instead of a JWT; they are never part of a token
+	APIKeyID uint     `json:"-"`
+	Scopes   []string `json:"-"`
+	// Act identifies an admin acting as the user (impersonation)
+	Act *Actor `json:"act,omitempty"`
+	// IssuedAtMilli is the issue time in Unix milliseconds
+	IssuedAtMilli int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IssuedAtTime returns when the token was issued, to IssuedAtPrecision for
+// tokens that carry iat_ms. The zero time means the token has no issue time.
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAtMilli != 0 {
+		return time.UnixMilli(c.IssuedAtMilli)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// Actor is the party acting on behalf of the token's subject, as in the
+// "act" claim of RFC 8693
+type Actor struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// ClaimOption customizes the claims of a new token
+type ClaimOption func(*Claims)
+
+// WithMFA marks the token as issued after two-factor authentication
+func WithMFA() ClaimOption {
+	return func(c *Claims) { c.MFA = true }
+}
+
+// WithMFASetupOnly restricts the token to two-factor enrollment
+func WithMFASetupOnly() ClaimOption {
+	return func(c *Claims) { c.MFASetup = true }
+}
+
+// WithActor marks the token as used by another user acting as its subject
+func WithActor(userID uint, email string) ClaimOption {
+	return func(c *Claims) { c.Act = &Actor{UserID: userID, Email: email} }
+}
+
+// WithID sets the token ID (jti), which ties the token to a server-side
+// session
+func WithID(id string) ClaimOption {
+	return func(c *Claims) { c.ID = id }
+}
+
 // TokenMaker is an interface for managing tokens
 type TokenMaker interface {
-	CreateToken(userID uint, email, role string, duration time.Duration) (string, error)
+	CreateToken(userID uint, email, role string, duration time.Duration, opts ...ClaimOption) (string, error)
 	VerifyToken(token string) (*Claims, error)
 }
 
@@ -41,16 +103,21 @@ func NewJWTMaker(secretKey string) (TokenMaker, error) {
 }
 
 // CreateToken creates a new JWT token
-func (maker *JWTMaker) CreateToken(userID uint, email, role string, duration time.Duration) (string, error) {
+func (maker *JWTMaker) CreateToken(userID uint, email, role string, duration time.Duration, opts ...ClaimOption) (string, error) {
+	now := time.Now().Truncate(IssuedAtPrecision)
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:        userID,
+		Email:         email,
+		Role:          role,
+		IssuedAtMilli: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
+	for _, opt := range opts {
+		opt(claims)
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(maker.secretKey))
@@ -0,0 +1,62 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestIssuedAtMillisecondsSurviveSigning(t *testing.T) {
+	maker, err := NewJWTMaker("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Truncate(IssuedAtPrecision)
+	signed, err := maker.CreateToken(7, "learner@example.com", "student", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := maker.VerifyToken(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jwt.TimePrecision != time.Second {
+		t.Errorf("jwt.TimePrecision = %v, want the library default", jwt.TimePrecision)
+	}
+	if got := claims.IssuedAtTime(); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("IssuedAtTime = %v, want between %v and now", got, before)
+	}
+	if claims.IssuedAt.Time.Nanosecond() != 0 {
+		t.Errorf("iat = %v, want whole seconds", claims.IssuedAt.Time)
+	}
+}
+
+func TestUserRevocationSparesLaterTokensInTheSameSecond(t *testing.T) {
+	blacklist := NewInMemoryBlacklist(time.Hour)
+	second := time.Now().Truncate(time.Second)
+	revokedAt := second.Add(200 * time.Millisecond)
+	if err := blacklist.RevokeUser(7, revokedAt, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"earlier in the second", second.Add(100 * time.Millisecond), true},
+		{"same millisecond", revokedAt, true},
+		{"later in the second", second.Add(300 * time.Millisecond), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &Claims{IssuedAtMilli: tt.issuedAt.UnixMilli()}
+			if got := blacklist.IsUserRevoked(7, claims.IssuedAtTime()); got != tt.want {
+				t.Errorf("IsUserRevoked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
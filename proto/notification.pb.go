@@ -149,6 +149,126 @@ func (x *SendNotificationResponse) GetNotificationId() int64 {
 	return 0
 }
 
+type SendBulkNotificationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserIds       []int64                `protobuf:"varint,1,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendBulkNotificationRequest) Reset() {
+	*x = SendBulkNotificationRequest{}
+	mi := &file_proto_notification_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendBulkNotificationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendBulkNotificationRequest) ProtoMessage() {}
+
+func (x *SendBulkNotificationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_notification_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendBulkNotificationRequest.ProtoReflect.Descriptor instead.
+func (*SendBulkNotificationRequest) Descriptor() ([]byte, []int) {
+	return file_proto_notification_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SendBulkNotificationRequest) GetUserIds() []int64 {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+func (x *SendBulkNotificationRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *SendBulkNotificationRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *SendBulkNotificationRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type SendBulkNotificationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendBulkNotificationResponse) Reset() {
+	*x = SendBulkNotificationResponse{}
+	mi := &file_proto_notification_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendBulkNotificationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendBulkNotificationResponse) ProtoMessage() {}
+
+func (x *SendBulkNotificationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_notification_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendBulkNotificationResponse.ProtoReflect.Descriptor instead.
+func (*SendBulkNotificationResponse) Descriptor() ([]byte, []int) {
+	return file_proto_notification_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SendBulkNotificationResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SendBulkNotificationResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
 type GetNotificationsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -161,7 +281,7 @@ type GetNotificationsRequest struct {
 
 func (x *GetNotificationsRequest) Reset() {
 	*x = GetNotificationsRequest{}
-	mi := &file_proto_notification_proto_msgTypes[2]
+	mi := &file_proto_notification_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -173,7 +293,7 @@ func (x *GetNotificationsRequest) String() string {
 func (*GetNotificationsRequest) ProtoMessage() {}
 
 func (x *GetNotificationsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[2]
+	mi := &file_proto_notification_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -186,7 +306,7 @@ func (x *GetNotificationsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetNotificationsRequest.ProtoReflect.Descriptor instead.
 func (*GetNotificationsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{2}
+	return file_proto_notification_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *GetNotificationsRequest) GetUserId() int64 {
@@ -232,7 +352,7 @@ type Notification struct {
 
 func (x *Notification) Reset() {
 	*x = Notification{}
-	mi := &file_proto_notification_proto_msgTypes[3]
+	mi := &file_proto_notification_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -244,7 +364,7 @@ func (x *Notification) String() string {
 func (*Notification) ProtoMessage() {}
 
 func (x *Notification) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[3]
+	mi := &file_proto_notification_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -257,7 +377,7 @@ func (x *Notification) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Notification.ProtoReflect.Descriptor instead.
 func (*Notification) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{3}
+	return file_proto_notification_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Notification) GetId() int64 {
@@ -321,7 +441,7 @@ type GetNotificationsResponse struct {
 
 func (x *GetNotificationsResponse) Reset() {
 	*x = GetNotificationsResponse{}
-	mi := &file_proto_notification_proto_msgTypes[4]
+	mi := &file_proto_notification_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -333,7 +453,7 @@ func (x *GetNotificationsResponse) String() string {
 func (*GetNotificationsResponse) ProtoMessage() {}
 
 func (x *GetNotificationsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[4]
+	mi := &file_proto_notification_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -346,7 +466,7 @@ func (x *GetNotificationsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetNotificationsResponse.ProtoReflect.Descriptor instead.
 func (*GetNotificationsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{4}
+	return file_proto_notification_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GetNotificationsResponse) GetNotifications() []*Notification {
@@ -387,7 +507,7 @@ type MarkAsReadRequest struct {
 
 func (x *MarkAsReadRequest) Reset() {
 	*x = MarkAsReadRequest{}
-	mi := &file_proto_notification_proto_msgTypes[5]
+	mi := &file_proto_notification_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -399,7 +519,7 @@ func (x *MarkAsReadRequest) String() string {
 func (*MarkAsReadRequest) ProtoMessage() {}
 
 func (x *MarkAsReadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[5]
+	mi := &file_proto_notification_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -412,7 +532,7 @@ func (x *MarkAsReadRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MarkAsReadRequest.ProtoReflect.Descriptor instead.
 func (*MarkAsReadRequest) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{5}
+	return file_proto_notification_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *MarkAsReadRequest) GetNotificationId() int64 {
@@ -439,7 +559,7 @@ type MarkAsReadResponse struct {
 
 func (x *MarkAsReadResponse) Reset() {
 	*x = MarkAsReadResponse{}
-	mi := &file_proto_notification_proto_msgTypes[6]
+	mi := &file_proto_notification_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -451,7 +571,7 @@ func (x *MarkAsReadResponse) String() string {
 func (*MarkAsReadResponse) ProtoMessage() {}
 
 func (x *MarkAsReadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[6]
+	mi := &file_proto_notification_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -464,7 +584,7 @@ func (x *MarkAsReadResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MarkAsReadResponse.ProtoReflect.Descriptor instead.
 func (*MarkAsReadResponse) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{6}
+	return file_proto_notification_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *MarkAsReadResponse) GetSuccess() bool {
@@ -490,7 +610,7 @@ type GetUnreadCountRequest struct {
 
 func (x *GetUnreadCountRequest) Reset() {
 	*x = GetUnreadCountRequest{}
-	mi := &file_proto_notification_proto_msgTypes[7]
+	mi := &file_proto_notification_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -502,7 +622,7 @@ func (x *GetUnreadCountRequest) String() string {
 func (*GetUnreadCountRequest) ProtoMessage() {}
 
 func (x *GetUnreadCountRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[7]
+	mi := &file_proto_notification_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -515,7 +635,7 @@ func (x *GetUnreadCountRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetUnreadCountRequest.ProtoReflect.Descriptor instead.
 func (*GetUnreadCountRequest) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{7}
+	return file_proto_notification_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *GetUnreadCountRequest) GetUserId() int64 {
@@ -534,7 +654,7 @@ type GetUnreadCountResponse struct {
 
 func (x *GetUnreadCountResponse) Reset() {
 	*x = GetUnreadCountResponse{}
-	mi := &file_proto_notification_proto_msgTypes[8]
+	mi := &file_proto_notification_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -546,7 +666,7 @@ func (x *GetUnreadCountResponse) String() string {
 func (*GetUnreadCountResponse) ProtoMessage() {}
 
 func (x *GetUnreadCountResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[8]
+	mi := &file_proto_notification_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -559,7 +679,7 @@ func (x *GetUnreadCountResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetUnreadCountResponse.ProtoReflect.Descriptor instead.
 func (*GetUnreadCountResponse) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{8}
+	return file_proto_notification_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *GetUnreadCountResponse) GetCount() int64 {
@@ -578,7 +698,7 @@ type MarkAllAsReadRequest struct {
 
 func (x *MarkAllAsReadRequest) Reset() {
 	*x = MarkAllAsReadRequest{}
-	mi := &file_proto_notification_proto_msgTypes[9]
+	mi := &file_proto_notification_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -590,7 +710,7 @@ func (x *MarkAllAsReadRequest) String() string {
 func (*MarkAllAsReadRequest) ProtoMessage() {}
 
 func (x *MarkAllAsReadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[9]
+	mi := &file_proto_notification_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -603,7 +723,7 @@ func (x *MarkAllAsReadRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MarkAllAsReadRequest.ProtoReflect.Descriptor instead.
 func (*MarkAllAsReadRequest) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{9}
+	return file_proto_notification_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *MarkAllAsReadRequest) GetUserId() int64 {
@@ -623,7 +743,7 @@ type MarkAllAsReadResponse struct {
 
 func (x *MarkAllAsReadResponse) Reset() {
 	*x = MarkAllAsReadResponse{}
-	mi := &file_proto_notification_proto_msgTypes[10]
+	mi := &file_proto_notification_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -635,7 +755,7 @@ func (x *MarkAllAsReadResponse) String() string {
 func (*MarkAllAsReadResponse) ProtoMessage() {}
 
 func (x *MarkAllAsReadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_notification_proto_msgTypes[10]
+	mi := &file_proto_notification_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -648,7 +768,7 @@ func (x *MarkAllAsReadResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MarkAllAsReadResponse.ProtoReflect.Descriptor instead.
 func (*MarkAllAsReadResponse) Descriptor() ([]byte, []int) {
-	return file_proto_notification_proto_rawDescGZIP(), []int{10}
+	return file_proto_notification_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *MarkAllAsReadResponse) GetSuccess() bool {
@@ -678,7 +798,15 @@ const file_proto_notification_proto_rawDesc = "" +
 	"\x18SendNotificationResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12'\n" +
-	"\x0fnotification_id\x18\x03 \x01(\x03R\x0enotificationId\"}\n" +
+	"\x0fnotification_id\x18\x03 \x01(\x03R\x0enotificationId\"|\n" +
+	"\x1bSendBulkNotificationRequest\x12\x19\n" +
+	"\buser_ids\x18\x01 \x03(\x03R\auserIds\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"N\n" +
+	"\x1cSendBulkNotificationResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\"}\n" +
 	"\x17GetNotificationsRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x12\n" +
 	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x14\n" +
@@ -713,9 +841,10 @@ const file_proto_notification_proto_rawDesc = "" +
 	"\auser_id\x18\x01 \x01(\x03R\x06userId\"G\n" +
 	"\x15MarkAllAsReadResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05count\x18\x02 \x01(\x05R\x05count2\xe3\x03\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count2\xd2\x04\n" +
 	"\x13NotificationService\x12a\n" +
-	"\x10SendNotification\x12%.notification.SendNotificationRequest\x1a&.notification.SendNotificationResponse\x12a\n" +
+	"\x10SendNotification\x12%.notification.SendNotificationRequest\x1a&.notification.SendNotificationResponse\x12m\n" +
+	"\x14SendBulkNotification\x12).notification.SendBulkNotificationRequest\x1a*.notification.SendBulkNotificationResponse\x12a\n" +
 	"\x10GetNotifications\x12%.notification.GetNotificationsRequest\x1a&.notification.GetNotificationsResponse\x12O\n" +
 	"\n" +
 	"MarkAsRead\x12\x1f.notification.MarkAsReadRequest\x1a .notification.MarkAsReadResponse\x12[\n" +
@@ -734,34 +863,38 @@ func file_proto_notification_proto_rawDescGZIP() []byte {
 	return file_proto_notification_proto_rawDescData
 }
 
-var file_proto_notification_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proto_notification_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_proto_notification_proto_goTypes = []any{
-	(*SendNotificationRequest)(nil),  // 0: notification.SendNotificationRequest
-	(*SendNotificationResponse)(nil), // 1: notification.SendNotificationResponse
-	(*GetNotificationsRequest)(nil),  // 2: notification.GetNotificationsRequest
-	(*Notification)(nil),             // 3: notification.Notification
-	(*GetNotificationsResponse)(nil), // 4: notification.GetNotificationsResponse
-	(*MarkAsReadRequest)(nil),        // 5: notification.MarkAsReadRequest
-	(*MarkAsReadResponse)(nil),       // 6: notification.MarkAsReadResponse
-	(*GetUnreadCountRequest)(nil),    // 7: notification.GetUnreadCountRequest
-	(*GetUnreadCountResponse)(nil),   // 8: notification.GetUnreadCountResponse
-	(*MarkAllAsReadRequest)(nil),     // 9: notification.MarkAllAsReadRequest
-	(*MarkAllAsReadResponse)(nil),    // 10: notification.MarkAllAsReadResponse
+	(*SendNotificationRequest)(nil),      // 0: notification.SendNotificationRequest
+	(*SendNotificationResponse)(nil),     // 1: notification.SendNotificationResponse
+	(*SendBulkNotificationRequest)(nil),  // 2: notification.SendBulkNotificationRequest
+	(*SendBulkNotificationResponse)(nil), // 3: notification.SendBulkNotificationResponse
+	(*GetNotificationsRequest)(nil),      // 4: notification.GetNotificationsRequest
+	(*Notification)(nil),                 // 5: notification.Notification
+	(*GetNotificationsResponse)(nil),     // 6: notification.GetNotificationsResponse
+	(*MarkAsReadRequest)(nil),            // 7: notification.MarkAsReadRequest
+	(*MarkAsReadResponse)(nil),           // 8: notification.MarkAsReadResponse
+	(*GetUnreadCountRequest)(nil),        // 9: notification.GetUnreadCountRequest
+	(*GetUnreadCountResponse)(nil),       // 10: notification.GetUnreadCountResponse
+	(*MarkAllAsReadRequest)(nil),         // 11: notification.MarkAllAsReadRequest
+	(*MarkAllAsReadResponse)(nil),        // 12: notification.MarkAllAsReadResponse
 }
 var file_proto_notification_proto_depIdxs = []int32{
-	3,  // 0: notification.GetNotificationsResponse.notifications:type_name -> notification.Notification
+	5,  // 0: notification.GetNotificationsResponse.notifications:type_name -> notification.Notification
 	0,  // 1: notification.NotificationService.SendNotification:input_type -> notification.SendNotificationRequest
-	2,  // 2: notification.NotificationService.GetNotifications:input_type -> notification.GetNotificationsRequest
-	5,  // 3: notification.NotificationService.MarkAsRead:input_type -> notification.MarkAsReadRequest
-	7,  // 4: notification.NotificationService.GetUnreadCount:input_type -> notification.GetUnreadCountRequest
-	9,  // 5: notification.NotificationService.MarkAllAsRead:input_type -> notification.MarkAllAsReadRequest
-	1,  // 6: notification.NotificationService.SendNotification:output_type -> notification.SendNotificationResponse
-	4,  // 7: notification.NotificationService.GetNotifications:output_type -> notification.GetNotificationsResponse
-	6,  // 8: notification.NotificationService.MarkAsRead:output_type -> notification.MarkAsReadResponse
-	8,  // 9: notification.NotificationService.GetUnreadCount:output_type -> notification.GetUnreadCountResponse
-	10, // 10: notification.NotificationService.MarkAllAsRead:output_type -> notification.MarkAllAsReadResponse
-	6,  // [6:11] is the sub-list for method output_type
-	1,  // [1:6] is the sub-list for method input_type
+	2,  // 2: notification.NotificationService.SendBulkNotification:input_type -> notification.SendBulkNotificationRequest
+	4,  // 3: notification.NotificationService.GetNotifications:input_type -> notification.GetNotificationsRequest
+	7,  // 4: notification.NotificationService.MarkAsRead:input_type -> notification.MarkAsReadRequest
+	9,  // 5: notification.NotificationService.GetUnreadCount:input_type -> notification.GetUnreadCountRequest
+	11, // 6: notification.NotificationService.MarkAllAsRead:input_type -> notification.MarkAllAsReadRequest
+	1,  // 7: notification.NotificationService.SendNotification:output_type -> notification.SendNotificationResponse
+	3,  // 8: notification.NotificationService.SendBulkNotification:output_type -> notification.SendBulkNotificationResponse
+	6,  // 9: notification.NotificationService.GetNotifications:output_type -> notification.GetNotificationsResponse
+	8,  // 10: notification.NotificationService.MarkAsRead:output_type -> notification.MarkAsReadResponse
+	10, // 11: notification.NotificationService.GetUnreadCount:output_type -> notification.GetUnreadCountResponse
+	12, // 12: notification.NotificationService.MarkAllAsRead:output_type -> notification.MarkAllAsReadResponse
+	7,  // [7:13] is the sub-list for method output_type
+	1,  // [1:7] is the sub-list for method input_type
 	1,  // [1:1] is the sub-list for extension type_name
 	1,  // [1:1] is the sub-list for extension extendee
 	0,  // [0:1] is the sub-list for field type_name
@@ -778,7 +911,7 @@ func file_proto_notification_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_notification_proto_rawDesc), len(file_proto_notification_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   11,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   1,
 		},